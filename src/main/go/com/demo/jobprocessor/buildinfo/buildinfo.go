@@ -0,0 +1,57 @@
+package buildinfo
+
+import (
+	"net/http"
+	"os"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build metadata, injected at build time via -ldflags (see Makefile `build`):
+//
+//	go build -ldflags "-X distributed-job-processor/buildinfo.Version=v1.4.0 \
+//	  -X distributed-job-processor/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//	  -X distributed-job-processor/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Unset values report "unknown" (e.g. `go run`).
+var (
+	Version   = "unknown"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Info is the build metadata returned by GET /version.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build metadata of the running binary.
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// Enabled returns whether build info is exposed over HTTP.
+// On by default; EXPOSE_BUILD_INFO=false hides it.
+func Enabled() bool {
+	return os.Getenv("EXPOSE_BUILD_INFO") != "false"
+}
+
+// Handler returns the build metadata as JSON. Unauthenticated.
+// GET /version
+// Use as: r.GET("/version", buildinfo.Handler)
+func Handler(c *gin.Context) {
+	if !Enabled() {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, Get())
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -27,6 +28,37 @@ func GetBootstrapServers() string {
 	return servers
 }
 
+// GetConsumerClusters returns the broker list of every Kafka cluster workers consume from.
+//
+// For multi-region setups where jobs are produced into region-local clusters and
+// processed by a central worker pool. KAFKA_CONSUMER_CLUSTERS separates clusters
+// with ';' and brokers within a cluster with ',', e.g.
+// "kafka-us-east:9092;kafka-eu-west-1:9092,kafka-eu-west-2:9092".
+// Unset means the single cluster at KAFKA_BOOTSTRAP_SERVERS.
+func GetConsumerClusters() [][]string {
+	val := os.Getenv("KAFKA_CONSUMER_CLUSTERS")
+	if val == "" {
+		return [][]string{{GetBootstrapServers()}}
+	}
+
+	var clusters [][]string
+	for _, cluster := range strings.Split(val, ";") {
+		var brokers []string
+		for _, broker := range strings.Split(cluster, ",") {
+			if broker = strings.TrimSpace(broker); broker != "" {
+				brokers = append(brokers, broker)
+			}
+		}
+		if len(brokers) > 0 {
+			clusters = append(clusters, brokers)
+		}
+	}
+	if len(clusters) == 0 {
+		return [][]string{{GetBootstrapServers()}}
+	}
+	return clusters
+}
+
 // GetConsumerGroupID returns the consumer group ID from env or default.
 func GetConsumerGroupID() string {
 	groupID := os.Getenv("KAFKA_CONSUMER_GROUP_ID")
@@ -45,8 +77,13 @@ func GetConsumerGroupID() string {
 // - Fetch configuration for better throughput
 // - Session timeout and heartbeat settings
 func NewKafkaConsumerReader(topic string) *kafka.Reader {
+	return NewKafkaConsumerReaderForBrokers([]string{GetBootstrapServers()}, topic)
+}
+
+// NewKafkaConsumerReaderForBrokers creates a reader like NewKafkaConsumerReader for a specific cluster.
+func NewKafkaConsumerReaderForBrokers(brokers []string, topic string) *kafka.Reader {
 	return kafka.NewReader(kafka.ReaderConfig{
-			Brokers: []string{GetBootstrapServers()},
+			Brokers: brokers,
 			Topic:   topic,
 			GroupID: GetConsumerGroupID(),
 
@@ -55,6 +92,7 @@ func NewKafkaConsumerReader(topic string) *kafka.Reader {
 
 			// Fetch configuration for better throughput
 			MinBytes: 1,
+			MaxBytes: 10e6, // kafka-go rejects MinBytes > MaxBytes, so the 0 default can't be left
 			MaxWait:  500 * time.Millisecond,
 
 			// Session timeout and heartbeat
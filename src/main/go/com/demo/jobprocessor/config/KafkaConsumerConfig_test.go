@@ -0,0 +1,28 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestGetConsumerClusters verifies cluster lists parse and fall back to the single default cluster.
+func TestGetConsumerClusters(t *testing.T) {
+	t.Setenv("KAFKA_BOOTSTRAP_SERVERS", "kafka:9092")
+
+	cases := []struct {
+		value string
+		want  [][]string
+	}{
+		{"", [][]string{{"kafka:9092"}}},
+		{" ; ", [][]string{{"kafka:9092"}}},
+		{"us-east:9092", [][]string{{"us-east:9092"}}},
+		{"us-east:9092; eu-west-1:9092, eu-west-2:9092", [][]string{{"us-east:9092"}, {"eu-west-1:9092", "eu-west-2:9092"}}},
+	}
+
+	for _, tc := range cases {
+		t.Setenv("KAFKA_CONSUMER_CLUSTERS", tc.value)
+		if got := GetConsumerClusters(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("KAFKA_CONSUMER_CLUSTERS=%q: got %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
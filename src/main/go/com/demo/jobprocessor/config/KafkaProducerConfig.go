@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -20,6 +21,14 @@ import (
 // - enable.idempotence=true: Prevent duplicate messages
 // - retries=3: Retry failed sends automatically
 
+// JobTypeHeader is the Kafka message header carrying the job type, so consumers
+// can filter messages without loading the job (see WORKER_TYPES).
+const JobTypeHeader = "job-type"
+
+// JobPriorityHeader is the Kafka message header carrying the job priority, so
+// workers can order buffered messages without loading the jobs (see WORKER_PRIORITY_WINDOW).
+const JobPriorityHeader = "job-priority"
+
 // GetJobQueueTopic returns the Kafka topic name from env or default.
 func GetJobQueueTopic() string {
 	topic := os.Getenv("KAFKA_TOPIC_JOB_QUEUE")
@@ -29,6 +38,77 @@ func GetJobQueueTopic() string {
 	return topic
 }
 
+// Priority topics (KAFKA_PRIORITY_TOPICS=true, default off = single topic):
+// - Jobs with priority <= KAFKA_HIGH_PRIORITY_MAX (default 3) go to the high-priority topic
+//   (KAFKA_TOPIC_JOB_QUEUE_HIGH, default "<job queue topic>-high")
+// - All other jobs go to the regular job queue topic
+// - Workers always drain the high-priority topic before taking from the regular one
+
+// GetPriorityTopicsEnabled returns whether jobs are split across high/low priority topics.
+func GetPriorityTopicsEnabled() bool {
+	return os.Getenv("KAFKA_PRIORITY_TOPICS") == "true"
+}
+
+// GetHighPriorityTopic returns the high-priority Kafka topic name from env or default.
+func GetHighPriorityTopic() string {
+	topic := os.Getenv("KAFKA_TOPIC_JOB_QUEUE_HIGH")
+	if topic == "" {
+		return GetJobQueueTopic() + "-high"
+	}
+	return topic
+}
+
+// Worker pools (KAFKA_WORKER_POOLS, e.g. KAFKA_WORKER_POOLS=onprem,gpu, default none):
+// - Jobs labeled workerPool=<pool> are published to the pool's topic
+//   ("<job queue topic>-pool-<pool>") regardless of priority
+// - Only workers started with WORKER_POOL=<pool> consume that topic, and they
+//   consume nothing else
+// - Jobs labeled with a pool that isn't configured are rejected at creation
+
+// GetWorkerPools returns the configured worker pool names from env, empty when none.
+func GetWorkerPools() []string {
+	var pools []string
+	for _, pool := range strings.Split(os.Getenv("KAFKA_WORKER_POOLS"), ",") {
+		if pool = strings.TrimSpace(pool); pool != "" {
+			pools = append(pools, pool)
+		}
+	}
+	return pools
+}
+
+// GetWorkerPoolTopic returns the Kafka topic consumed by the given worker pool.
+func GetWorkerPoolTopic(pool string) string {
+	return GetJobQueueTopic() + "-pool-" + pool
+}
+
+// GetWorkerPool returns the pool this worker belongs to from env, empty for the general pool.
+func GetWorkerPool() string {
+	return strings.TrimSpace(os.Getenv("WORKER_POOL"))
+}
+
+// GetDLQTopic returns the dead-letter Kafka topic name from env or default ("<job queue topic>-dlq").
+// Dead-lettered jobs are published there for alerting and replay tooling.
+func GetDLQTopic() string {
+	topic := os.Getenv("KAFKA_TOPIC_DLQ")
+	if topic == "" {
+		return GetJobQueueTopic() + "-dlq"
+	}
+	return topic
+}
+
+// GetHighPriorityMax returns the largest priority value routed to the high-priority topic.
+func GetHighPriorityMax() int {
+	p := os.Getenv("KAFKA_HIGH_PRIORITY_MAX")
+	if p == "" {
+		return 3
+	}
+	val, err := strconv.Atoi(p)
+	if err != nil {
+		return 3
+	}
+	return val
+}
+
 // GetPartitions returns the number of partitions from env or default.
 func GetPartitions() int {
 	p := os.Getenv("KAFKA_TOPIC_PARTITIONS")
@@ -64,9 +144,15 @@ func GetReplicationFactor() int {
 // - Compression = gzip: Works with Alpine (snappy doesn't)
 // - Balancer = LeastBytes: Distributes messages across partitions
 func NewKafkaProducerWriter() *kafka.Writer {
+	return NewKafkaProducerWriterForTopic(GetJobQueueTopic())
+}
+
+// NewKafkaProducerWriterForTopic creates a writer with the same settings as
+// NewKafkaProducerWriter for the given topic (e.g. the high-priority topic).
+func NewKafkaProducerWriterForTopic(topic string) *kafka.Writer {
 	return &kafka.Writer{
 		Addr:  kafka.TCP(GetBootstrapServers()),
-		Topic: GetJobQueueTopic(),
+		Topic: topic,
 
 		// Durability: Wait for all replicas to acknowledge
 		RequiredAcks: kafka.RequireAll,
@@ -85,8 +171,15 @@ func NewKafkaProducerWriter() *kafka.Writer {
 	}
 }
 
+// NewKafkaDLQWriter creates a writer for the dead-letter topic, with the same settings as NewKafkaProducerWriter.
+func NewKafkaDLQWriter() *kafka.Writer {
+	return NewKafkaProducerWriterForTopic(GetDLQTopic())
+}
+
 // CreateTopicIfNotExists creates the Kafka topic if it doesn't exist.
 // 16 partitions allow up to 16 parallel workers.
+// The dead-letter topic is created too, the high-priority topic when priority topics are enabled,
+// one topic per configured worker pool, and the compacted job state topic when enabled.
 func CreateTopicIfNotExists() error {
 	conn, err := kafka.Dial("tcp", GetBootstrapServers())
 	if err != nil {
@@ -111,6 +204,36 @@ func CreateTopicIfNotExists() error {
 			NumPartitions:     GetPartitions(),
 			ReplicationFactor: GetReplicationFactor(),
 		},
+		{
+			Topic:             GetDLQTopic(),
+			NumPartitions:     GetPartitions(),
+			ReplicationFactor: GetReplicationFactor(),
+		},
+	}
+	if GetPriorityTopicsEnabled() {
+		topicConfigs = append(topicConfigs, kafka.TopicConfig{
+			Topic:             GetHighPriorityTopic(),
+			NumPartitions:     GetPartitions(),
+			ReplicationFactor: GetReplicationFactor(),
+		})
+	}
+	for _, pool := range GetWorkerPools() {
+		topicConfigs = append(topicConfigs, kafka.TopicConfig{
+			Topic:             GetWorkerPoolTopic(pool),
+			NumPartitions:     GetPartitions(),
+			ReplicationFactor: GetReplicationFactor(),
+		})
+	}
+	if GetJobStateTopicEnabled() {
+		topicConfigs = append(topicConfigs, kafka.TopicConfig{
+			Topic:             GetJobStateTopic(),
+			NumPartitions:     GetPartitions(),
+			ReplicationFactor: GetReplicationFactor(),
+			ConfigEntries: []kafka.ConfigEntry{
+				{ConfigName: "cleanup.policy", ConfigValue: "compact,delete"},
+				{ConfigName: "retention.ms", ConfigValue: strconv.FormatInt(GetJobStateRetention().Milliseconds(), 10)},
+			},
+		})
 	}
 
 	return controllerConn.CreateTopics(topicConfigs...)
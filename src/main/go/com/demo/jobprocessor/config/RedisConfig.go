@@ -2,8 +2,10 @@ package config
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"strconv"
 	"time"
@@ -40,11 +42,53 @@ func GetRedisPort() int {
 
 // NewRedisClient creates a configured Redis client.
 // Equivalent to Java's RedisConnectionFactory + RedisTemplate.
+//
+// Configuration, for secured managed Redis (e.g. ElastiCache with in-transit encryption):
+// - REDIS_PASSWORD (default none): AUTH password
+// - REDIS_DB (default 0): logical database number
+// - REDIS_TLS_ENABLED=true: connect over TLS (minimum TLS 1.2), off by default
+// - REDIS_POOL_SIZE (default 0 = go-redis' 10 per CPU): maximum connections
+// - REDIS_MIN_IDLE_CONNS (default 0): connections kept open between bursts
 func NewRedisClient() *redis.Client {
-	return redis.NewClient(&redis.Options{
-		Addr: fmt.Sprintf("%s:%d", GetRedisHost(), GetRedisPort()),
-		DB:   0,
-	})
+	return redis.NewClient(redisOptionsFromEnv())
+}
+
+// redisOptionsFromEnv returns the options NewRedisClient connects with.
+func redisOptionsFromEnv() *redis.Options {
+	options := &redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", GetRedisHost(), GetRedisPort()),
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       0,
+	}
+
+	if val := os.Getenv("REDIS_DB"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			options.DB = parsed
+		} else {
+			log.Printf("Ignoring invalid REDIS_DB %q: must be a non-negative integer", val)
+		}
+	}
+	if val := os.Getenv("REDIS_POOL_SIZE"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			options.PoolSize = parsed
+		} else {
+			log.Printf("Ignoring invalid REDIS_POOL_SIZE %q: must be a non-negative integer", val)
+		}
+	}
+	if val := os.Getenv("REDIS_MIN_IDLE_CONNS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			options.MinIdleConns = parsed
+		} else {
+			log.Printf("Ignoring invalid REDIS_MIN_IDLE_CONNS %q: must be a non-negative integer", val)
+		}
+	}
+	if os.Getenv("REDIS_TLS_ENABLED") == "true" {
+		options.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ServerName: GetRedisHost(),
+		}
+	}
+	return options
 }
 
 // PingRedis checks if the Redis connection is alive.
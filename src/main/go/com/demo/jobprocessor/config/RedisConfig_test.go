@@ -0,0 +1,34 @@
+package config
+
+import "testing"
+
+// TestRedisOptionsFromEnv verifies the defaults when nothing is set, and that
+// auth, database, pool, and TLS settings are taken from env.
+func TestRedisOptionsFromEnv(t *testing.T) {
+	options := redisOptionsFromEnv()
+	if options.Addr != "localhost:6379" || options.Password != "" || options.DB != 0 ||
+		options.PoolSize != 0 || options.MinIdleConns != 0 || options.TLSConfig != nil {
+		t.Fatalf("expected default options, got %+v", options)
+	}
+
+	t.Setenv("REDIS_HOST", "cache.example.com")
+	t.Setenv("REDIS_PASSWORD", "s3cret")
+	t.Setenv("REDIS_DB", "2")
+	t.Setenv("REDIS_TLS_ENABLED", "true")
+	t.Setenv("REDIS_POOL_SIZE", "50")
+	t.Setenv("REDIS_MIN_IDLE_CONNS", "5")
+
+	options = redisOptionsFromEnv()
+	if options.Addr != "cache.example.com:6379" || options.Password != "s3cret" || options.DB != 2 ||
+		options.PoolSize != 50 || options.MinIdleConns != 5 {
+		t.Fatalf("expected options from env, got %+v", options)
+	}
+	if options.TLSConfig == nil || options.TLSConfig.ServerName != "cache.example.com" {
+		t.Fatalf("expected TLS config for cache.example.com, got %+v", options.TLSConfig)
+	}
+
+	t.Setenv("REDIS_DB", "-1")
+	if options = redisOptionsFromEnv(); options.DB != 0 {
+		t.Fatalf("expected invalid REDIS_DB ignored, got %d", options.DB)
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"distributed-job-processor/exception"
+)
+
+// Authentication context shared between API-key auth and the handlers.
+//
+// API-key authentication resolves the caller's key to a client identity and
+// stores it in the Gin context under AuthenticatedClientIDKey. Unlike the
+// X-Client-Id header, this identity can't be chosen by the client.
+// Client keys live in Redis, provisioned out of band:
+// Redis Key Format: apikey:{key}
+// Redis Value: the client ID the key belongs to
+//
+// Admin endpoints (/api/admin) use separate keys from ADMIN_API_KEYS, a
+// comma-separated list of name:key pairs (e.g. "alice:s3cret,ops-bot:t0ken").
+// The name is the admin identity recorded in the audit log.
+
+// AuthenticatedClientIDKey is the Gin context key holding the authenticated client ID.
+const AuthenticatedClientIDKey = "authenticatedClientId"
+
+// AdminIdentityKey is the Gin context key holding the authenticated admin's name.
+const AdminIdentityKey = "adminIdentity"
+
+// GetAuthenticatedClientID returns the client ID resolved by API-key auth, if any.
+func GetAuthenticatedClientID(c *gin.Context) (string, bool) {
+	clientID := c.GetString(AuthenticatedClientIDKey)
+	return clientID, clientID != ""
+}
+
+// GetAdminIdentity returns the admin name resolved by AdminAuthMiddleware, if any.
+func GetAdminIdentity(c *gin.Context) (string, bool) {
+	name := c.GetString(AdminIdentityKey)
+	return name, name != ""
+}
+
+// GetAPIKeyAuthEnabled returns whether client endpoints require an API key
+// (API_KEY_AUTH=true, default off: the X-Client-Id header is trusted).
+func GetAPIKeyAuthEnabled() bool {
+	return os.Getenv("API_KEY_AUTH") == "true"
+}
+
+// AuthMiddleware requires an "Authorization: Bearer <apiKey>" header whose key is
+// provisioned in Redis, and stores the key's client ID under AuthenticatedClientIDKey.
+// Missing, malformed, and unknown keys get 401; a failed lookup gets 503 rather
+// than letting the request through.
+func AuthMiddleware(redisClient *redis.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, apiKey, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		apiKey = strings.TrimSpace(apiKey)
+		if !strings.EqualFold(scheme, "Bearer") || apiKey == "" {
+			abortUnauthorized(c, "An Authorization: Bearer <apiKey> header is required")
+			return
+		}
+
+		clientID, err := redisClient.Get(c.Request.Context(), apiKeyRedisKey(apiKey)).Result()
+		if errors.Is(err, redis.Nil) || (err == nil && clientID == "") {
+			abortUnauthorized(c, "Invalid API key")
+			return
+		}
+		if err != nil {
+			log.Printf("Error looking up API key: %v", err)
+			exception.HandleServiceUnavailable(c, "API key lookup unavailable")
+			c.Abort()
+			return
+		}
+		c.Set(AuthenticatedClientIDKey, clientID)
+		c.Next()
+	}
+}
+
+// abortUnauthorized stops the request with a 401 in the standard ErrorResponse shape.
+func abortUnauthorized(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, exception.NewErrorResponse(
+		http.StatusUnauthorized,
+		"Unauthorized",
+		message,
+	))
+}
+
+// apiKeyRedisKey returns the Redis key mapping an API key to its client ID.
+func apiKeyRedisKey(apiKey string) string {
+	return "apikey:" + apiKey
+}
+
+// GetAdminAPIKeys parses ADMIN_API_KEYS into key -> admin name.
+// Malformed entries are logged and skipped.
+func GetAdminAPIKeys() map[string]string {
+	keys := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv("ADMIN_API_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, key, ok := strings.Cut(entry, ":")
+		if !ok || name == "" || key == "" {
+			log.Printf("Ignoring malformed ADMIN_API_KEYS entry (expected name:key)")
+			continue
+		}
+		keys[key] = name
+	}
+	return keys
+}
+
+// AdminAuthMiddleware requires a valid X-Admin-Key header and stores the admin's
+// name under AdminIdentityKey. With no ADMIN_API_KEYS configured every request is refused.
+// Use as: admin := r.Group("/api/admin"); admin.Use(AdminAuthMiddleware())
+func AdminAuthMiddleware() gin.HandlerFunc {
+	keys := GetAdminAPIKeys()
+	if len(keys) == 0 {
+		log.Println("ADMIN_API_KEYS is not set, admin endpoints are disabled")
+	}
+
+	return func(c *gin.Context) {
+		provided := c.GetHeader("X-Admin-Key")
+		name, ok := lookupAdminKey(keys, provided)
+		if provided == "" || !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "A valid X-Admin-Key header is required"})
+			return
+		}
+		c.Set(AdminIdentityKey, name)
+		c.Next()
+	}
+}
+
+// lookupAdminKey finds the admin owning the provided key, comparing in constant time.
+func lookupAdminKey(keys map[string]string, provided string) (string, bool) {
+	found := ""
+	for key, name := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(provided)) == 1 {
+			found = name
+		}
+	}
+	return found, found != ""
+}
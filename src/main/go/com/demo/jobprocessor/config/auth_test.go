@@ -0,0 +1,104 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"distributed-job-processor/exception"
+)
+
+// TestAdminAuthMiddleware verifies only configured admin keys are accepted and resolve to the admin's name.
+func TestAdminAuthMiddleware(t *testing.T) {
+	t.Setenv("ADMIN_API_KEYS", "alice:key-a, ops-bot:key-b,malformed")
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(AdminAuthMiddleware())
+	r.GET("/whoami", func(c *gin.Context) {
+		name, _ := GetAdminIdentity(c)
+		c.String(http.StatusOK, name)
+	})
+
+	cases := []struct {
+		key        string
+		wantStatus int
+		wantBody   string
+	}{
+		{"key-a", http.StatusOK, "alice"},
+		{"key-b", http.StatusOK, "ops-bot"},
+		{"wrong", http.StatusUnauthorized, ""},
+		{"", http.StatusUnauthorized, ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		if tc.key != "" {
+			req.Header.Set("X-Admin-Key", tc.key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.wantStatus {
+			t.Errorf("key %q: expected status %d, got %d", tc.key, tc.wantStatus, w.Code)
+		}
+		if tc.wantBody != "" && w.Body.String() != tc.wantBody {
+			t.Errorf("key %q: expected identity %q, got %q", tc.key, tc.wantBody, w.Body.String())
+		}
+	}
+}
+
+// TestAuthMiddleware verifies a provisioned API key resolves to its client, and
+// missing, malformed, or unknown keys are refused with a 401 ErrorResponse.
+func TestAuthMiddleware(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	mr.Set("apikey:key-1", "customer-1")
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(AuthMiddleware(client))
+	r.GET("/whoami", func(c *gin.Context) {
+		clientID, _ := GetAuthenticatedClientID(c)
+		c.String(http.StatusOK, clientID)
+	})
+
+	cases := []struct {
+		authorization string
+		wantStatus    int
+		wantBody      string
+	}{
+		{"Bearer key-1", http.StatusOK, "customer-1"},
+		{"bearer key-1", http.StatusOK, "customer-1"},
+		{"Bearer unknown", http.StatusUnauthorized, ""},
+		{"Basic key-1", http.StatusUnauthorized, ""},
+		{"Bearer ", http.StatusUnauthorized, ""},
+		{"", http.StatusUnauthorized, ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.wantStatus {
+			t.Errorf("%q: expected status %d, got %d", tc.authorization, tc.wantStatus, w.Code)
+		}
+		if tc.wantBody != "" && w.Body.String() != tc.wantBody {
+			t.Errorf("%q: expected client %q, got %q", tc.authorization, tc.wantBody, w.Body.String())
+		}
+		if tc.wantStatus == http.StatusUnauthorized {
+			var response exception.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Status != http.StatusUnauthorized || response.Message == "" {
+				t.Errorf("%q: expected a 401 ErrorResponse, got %s", tc.authorization, w.Body.String())
+			}
+		}
+	}
+}
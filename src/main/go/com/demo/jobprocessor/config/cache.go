@@ -0,0 +1,25 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// defaultCacheWarmupLimit caps the startup cache warm-up when CACHE_WARMUP_LIMIT is unset.
+const defaultCacheWarmupLimit = 1000
+
+// GetCacheWarmupLimit returns how many of the most recent PENDING jobs are cached
+// at startup (CACHE_WARMUP_LIMIT, default 1000). 0 disables the warm-up.
+func GetCacheWarmupLimit() int {
+	val := os.Getenv("CACHE_WARMUP_LIMIT")
+	if val == "" {
+		return defaultCacheWarmupLimit
+	}
+	limit, err := strconv.Atoi(val)
+	if err != nil || limit < 0 {
+		log.Printf("Ignoring invalid CACHE_WARMUP_LIMIT %q: must be a non-negative integer", val)
+		return defaultCacheWarmupLimit
+	}
+	return limit
+}
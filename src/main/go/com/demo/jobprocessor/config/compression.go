@@ -0,0 +1,23 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// GetPayloadCompressionThreshold returns the payload size in bytes at or above which
+// payloads are stored gzip compressed in the database and cache.
+// Disabled (0) unless PAYLOAD_COMPRESSION_THRESHOLD is set, e.g. 4096.
+func GetPayloadCompressionThreshold() int {
+	val := os.Getenv("PAYLOAD_COMPRESSION_THRESHOLD")
+	if val == "" {
+		return 0
+	}
+	threshold, err := strconv.Atoi(val)
+	if err != nil || threshold < 0 {
+		log.Printf("Ignoring invalid PAYLOAD_COMPRESSION_THRESHOLD %q: must be a non-negative byte count", val)
+		return 0
+	}
+	return threshold
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"distributed-job-processor/exception"
+)
+
+// TestConcurrencyLimitShedsLoad verifies requests beyond the limit get a 503 ErrorResponse
+// with Retry-After, and in-flight requests are counted.
+func TestConcurrencyLimitShedsLoad(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(NewConcurrencyLimitMiddleware(1, 2))
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	r.GET("/slow", func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.Status(http.StatusOK)
+	})
+
+	m := GetMetrics()
+	inFlightBefore := m.httpInFlight.Load()
+	shedBefore := m.httpShedRequests.Load()
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		done <- w.Code
+	}()
+	<-entered
+
+	if got := m.httpInFlight.Load() - inFlightBefore; got != 1 {
+		t.Fatalf("expected 1 request in flight, got %d", got)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "2" {
+		t.Fatalf("expected 503 with Retry-After 2, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	var response exception.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Status != http.StatusServiceUnavailable {
+		t.Fatalf("expected a 503 ErrorResponse, got %s", w.Body.String())
+	}
+	if got := m.httpShedRequests.Load() - shedBefore; got != 1 {
+		t.Fatalf("expected 1 shed request, got %d", got)
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("expected the in-flight request to succeed, got %d", code)
+	}
+	if got := m.httpInFlight.Load() - inFlightBefore; got != 0 {
+		t.Fatalf("expected no requests in flight, got %d", got)
+	}
+}
+
+// TestConcurrencyLimitPerRouteGroup verifies the middleware takes its cap as a
+// parameter, so a route group can have its own.
+func TestConcurrencyLimitPerRouteGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	unlimited := r.Group("/unlimited", ConcurrencyLimitMiddleware(0))
+	unlimited.GET("", func(c *gin.Context) { c.Status(http.StatusOK) })
+	closed := r.Group("/closed", ConcurrencyLimitMiddleware(1))
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	closed.GET("", func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.Status(http.StatusOK)
+	})
+
+	go r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/closed", nil))
+	<-entered
+	defer close(release)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/closed", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the full group to shed, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unlimited", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected an unlimited group unaffected, got %d", w.Code)
+	}
+}
+
+// TestConcurrencyLimitShedsOverflow verifies that of limit+1 concurrent requests,
+// exactly one is shed while the limit's worth are in flight, with the server-wide
+// HTTP_MAX_CONCURRENT cap registered by RegisterServerMiddleware.
+func TestConcurrencyLimitShedsOverflow(t *testing.T) {
+	t.Setenv("HTTP_MAX_CONCURRENT", "3")
+	limit := GetMaxConcurrentRequests()
+	if limit != 3 {
+		t.Fatalf("expected HTTP_MAX_CONCURRENT=3, got %d", limit)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterServerMiddleware(r)
+
+	var entered sync.WaitGroup
+	entered.Add(limit)
+	release := make(chan struct{})
+	r.GET("/slow", func(c *gin.Context) {
+		entered.Done()
+		<-release
+		c.Status(http.StatusOK)
+	})
+
+	codes := make(chan int, limit+1)
+	serve := func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		codes <- w.Code
+	}
+	for i := 0; i < limit; i++ {
+		go serve()
+	}
+	entered.Wait()
+
+	go serve()
+	if code := <-codes; code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the overflow request to get 503, got %d", code)
+	}
+
+	close(release)
+	for i := 0; i < limit; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Fatalf("expected in-flight requests to succeed, got %d", code)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+// Kafka consumer lag (KAFKA_LAG_INTERVAL, see service.JobWorker): every worker
+// samples the lag of each of its readers on a ticker and reports it here, exposed
+// as kafka_consumer_lag{topic, reader} on GET /metrics, under
+// kafka.consumer_lag on GET /metrics/json, and as kafka.consumer_lag.<topic>.<reader>
+// gauges by the StatsD sink.
+//
+// kafka-go reports a consumer group reader's lag as that of the partition it last
+// fetched from (high watermark minus the next offset), so each gauge is one consumer
+// of the group rather than one partition. A reader that keeps up reports 0.
+
+// kafkaLagKey identifies a lag gauge: a topic and the index of the worker's reader of it.
+type kafkaLagKey struct {
+	topic  string
+	reader string
+}
+
+// SetKafkaConsumerLag records the lag of a reader of topic, in messages.
+func (m *Metrics) SetKafkaConsumerLag(topic, reader string, lag int64) {
+	m.kafkaLagMu.Lock()
+	defer m.kafkaLagMu.Unlock()
+	if m.kafkaConsumerLag == nil {
+		m.kafkaConsumerLag = make(map[kafkaLagKey]int64)
+	}
+	m.kafkaConsumerLag[kafkaLagKey{topic: topic, reader: reader}] = lag
+}
+
+// KafkaConsumerLag returns the last lag recorded for a reader of topic, or false if none was.
+func (m *Metrics) KafkaConsumerLag(topic, reader string) (int64, bool) {
+	m.kafkaLagMu.RLock()
+	defer m.kafkaLagMu.RUnlock()
+	lag, ok := m.kafkaConsumerLag[kafkaLagKey{topic: topic, reader: reader}]
+	return lag, ok
+}
+
+// kafkaConsumerLagSnapshot copies the lag gauges.
+func (m *Metrics) kafkaConsumerLagSnapshot() map[kafkaLagKey]int64 {
+	m.kafkaLagMu.RLock()
+	defer m.kafkaLagMu.RUnlock()
+	snapshot := make(map[kafkaLagKey]int64, len(m.kafkaConsumerLag))
+	for key, lag := range m.kafkaConsumerLag {
+		snapshot[key] = lag
+	}
+	return snapshot
+}
+
+// kafkaConsumerLagJSON returns the lag gauges keyed "topic/reader", for the JSON view.
+func (m *Metrics) kafkaConsumerLagJSON() map[string]int64 {
+	lags := make(map[string]int64)
+	for key, lag := range m.kafkaConsumerLagSnapshot() {
+		lags[key.topic+"/"+key.reader] = lag
+	}
+	return lags
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"database/sql"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Database connection pool metrics (see repository.ConfigureDBPoolFromEnv), read from
+// sql.DB.Stats at scrape time and exposed on GET /metrics:
+// - db_max_open_connections: the DB_MAX_OPEN_CONNS limit (0 = unlimited)
+// - db_open_connections, _in_use_connections, _idle_connections
+// - db_wait_count_total, _wait_duration_seconds_total: queries that had
+//   to wait for a free connection, a sign the pool is too small
+
+// dbPoolSource is the pool reported, set once the database is opened.
+var dbPoolSource atomic.Pointer[sql.DB]
+
+var (
+	dbMaxOpenDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "db", "max_open_connections"),
+		"Maximum number of open database connections, 0 for unlimited.", nil, nil)
+	dbOpenDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "db", "open_connections"),
+		"Open database connections, in use and idle.", nil, nil)
+	dbInUseDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "db", "in_use_connections"),
+		"Database connections currently in use.", nil, nil)
+	dbIdleDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "db", "idle_connections"),
+		"Idle database connections.", nil, nil)
+	dbWaitCountDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "db", "wait_count_total"),
+		"Queries that waited for a free database connection.", nil, nil)
+	dbWaitDurationDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "db", "wait_duration_seconds_total"),
+		"Total time queries waited for a free database connection.", nil, nil)
+)
+
+// ObserveDBPool reports the pool's stats on GET /metrics from now on.
+func (m *Metrics) ObserveDBPool(db *sql.DB) {
+	dbPoolSource.Store(db)
+}
+
+// dbPoolCollector exports the observed pool's stats. Reports nothing until a pool is observed.
+type dbPoolCollector struct{}
+
+// Describe implements prometheus.Collector.
+func (dbPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbMaxOpenDesc
+	ch <- dbOpenDesc
+	ch <- dbInUseDesc
+	ch <- dbIdleDesc
+	ch <- dbWaitCountDesc
+	ch <- dbWaitDurationDesc
+}
+
+// Collect implements prometheus.Collector.
+func (dbPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	db := dbPoolSource.Load()
+	if db == nil {
+		return
+	}
+	stats := db.Stats()
+	ch <- prometheus.MustNewConstMetric(dbMaxOpenDesc, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbOpenDesc, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbInUseDesc, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(dbIdleDesc, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(dbWaitCountDesc, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(dbWaitDurationDesc, prometheus.CounterValue, stats.WaitDuration.Seconds())
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DurationFromEnv reads a positive duration, falling back to def when unset or invalid.
+func DurationFromEnv(key string, def time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(val)
+	if err != nil || parsed <= 0 {
+		log.Printf("Ignoring invalid %s %q: must be a positive duration", key, val)
+		return def
+	}
+	return parsed
+}
+
+// NonNegativeIntFromEnv reads a non-negative integer, falling back to def when unset or invalid.
+func NonNegativeIntFromEnv(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(val)
+	if err != nil || parsed < 0 {
+		log.Printf("Ignoring invalid %s %q: must be a non-negative integer", key, val)
+		return def
+	}
+	return parsed
+}
+
+// PositiveIntFromEnv reads a positive integer, falling back to def when unset or invalid.
+func PositiveIntFromEnv(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(val)
+	if err != nil || parsed <= 0 {
+		log.Printf("Ignoring invalid %s %q: must be a positive integer", key, val)
+		return def
+	}
+	return parsed
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// Health check jobs (HEALTH_CHECK_JOBS_ENABLED=true, default off):
+// - Clients may submit HEALTH_CHECK jobs, which the worker completes without doing any work
+// - An external monitor submits one and expects COMPLETED within its SLA, validating the
+//   whole create → schedule → Kafka → worker → complete pipeline, not just each component
+// - They are left out of job statistics, dead-letter reasons, and the daily report
+// - Finished ones are purged by the scheduler after HEALTH_CHECK_JOB_RETENTION (default 10m),
+//   always keeping the latest completed one, whose time GET /api/jobs/health reports
+
+// GetHealthCheckJobsEnabled returns whether HEALTH_CHECK jobs are accepted.
+func GetHealthCheckJobsEnabled() bool {
+	return os.Getenv("HEALTH_CHECK_JOBS_ENABLED") == "true"
+}
+
+// GetHealthCheckJobRetention returns how long finished HEALTH_CHECK jobs are kept, from env or default.
+func GetHealthCheckJobRetention() time.Duration {
+	retention := 10 * time.Minute
+	if val := os.Getenv("HEALTH_CHECK_JOB_RETENTION"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed > 0 {
+			retention = parsed
+		} else {
+			log.Printf("Ignoring invalid HEALTH_CHECK_JOB_RETENTION %q: must be a positive duration", val)
+		}
+	}
+	return retention
+}
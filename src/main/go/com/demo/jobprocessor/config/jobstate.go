@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Job state topic (KAFKA_JOB_STATE_TOPIC=true, default off = job IDs only):
+// - The scheduler also writes each job it publishes, as JSON keyed by job ID, to a
+//   compacted topic (KAFKA_TOPIC_JOB_STATE, default "<job queue topic>-state")
+// - Workers read that topic into memory and take the job from it instead of
+//   looking it up in Redis or the database (see service.JobStateTable)
+// - The database stays the source of truth; every status change is still saved there
+
+// JobStateVersionHeader is the Kafka message header on a job queue message naming
+// the job state record written with it, so workers never use an older record.
+const JobStateVersionHeader = "job-state-version"
+
+// GetJobStateTopicEnabled returns whether jobs are also published to the job state topic.
+func GetJobStateTopicEnabled() bool {
+	return os.Getenv("KAFKA_JOB_STATE_TOPIC") == "true"
+}
+
+// GetJobStateTopic returns the compacted job state topic name from env or default.
+func GetJobStateTopic() string {
+	topic := os.Getenv("KAFKA_TOPIC_JOB_STATE")
+	if topic == "" {
+		return GetJobQueueTopic() + "-state"
+	}
+	return topic
+}
+
+// GetJobStateRetention returns how long job state records are kept at most
+// (KAFKA_JOB_STATE_RETENTION, default 24h), so records that were never
+// tombstoned don't accumulate in the compacted topic.
+func GetJobStateRetention() time.Duration {
+	if val := os.Getenv("KAFKA_JOB_STATE_RETENTION"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return 24 * time.Hour
+}
+
+// NewKafkaJobStateWriter creates a writer for the job state topic, with the same
+// settings as NewKafkaProducerWriter except that records are partitioned by key:
+// compaction only keeps the latest record of a key within a partition.
+func NewKafkaJobStateWriter() *kafka.Writer {
+	writer := NewKafkaProducerWriterForTopic(GetJobStateTopic())
+	writer.Balancer = &kafka.Hash{}
+	return writer
+}
+
+// NewKafkaJobStateReaders creates one reader per partition of the job state topic,
+// outside any consumer group: every worker needs every job's record, and reads
+// the topic from the beginning on startup.
+func NewKafkaJobStateReaders() []*kafka.Reader {
+	readers := make([]*kafka.Reader, 0, GetPartitions())
+	for partition := 0; partition < GetPartitions(); partition++ {
+		readers = append(readers, kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     []string{GetBootstrapServers()},
+			Topic:       GetJobStateTopic(),
+			Partition:   partition,
+			StartOffset: kafka.FirstOffset,
+			MinBytes:    1,
+			MaxBytes:    10e6,
+			MaxWait:     500 * time.Millisecond,
+		}))
+	}
+	return readers
+}
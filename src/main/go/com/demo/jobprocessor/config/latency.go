@@ -0,0 +1,175 @@
+package config
+
+import (
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// End-to-end job latency: created_at to completed_at, recorded by the worker when a
+// job completes. Unlike the processing time metric, which only covers the worker's
+// own run, it includes scheduling delay, retries, and time queued in Kafka, so it
+// is the latency clients actually see.
+//
+// Exposed per job type:
+// - GET /metrics: the jobs_end_to_end_seconds histogram, with buckets
+//   from E2E_LATENCY_BUCKETS (comma-separated seconds)
+// - GET /metrics/json: avg/p95/p99 over the last E2E_LATENCY_WINDOW completions
+//   (default 1000) of each type
+
+// defaultEndToEndLatencyBuckets spans a health check finishing in a second to a
+// payment stuck behind a backlog for several minutes.
+var defaultEndToEndLatencyBuckets = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// GetEndToEndLatencyBuckets returns the end-to-end latency histogram buckets, in
+// seconds, from env or default.
+func GetEndToEndLatencyBuckets() []float64 {
+	val := os.Getenv("E2E_LATENCY_BUCKETS")
+	if val == "" {
+		return defaultEndToEndLatencyBuckets
+	}
+	var buckets []float64
+	for _, part := range strings.Split(val, ",") {
+		bucket, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || bucket <= 0 || (len(buckets) > 0 && bucket <= buckets[len(buckets)-1]) {
+			log.Printf("Ignoring invalid E2E_LATENCY_BUCKETS %q: must be increasing positive seconds", val)
+			return defaultEndToEndLatencyBuckets
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// GetEndToEndLatencyWindow returns how many recent completions per type the JSON
+// percentiles are computed over, from env or default.
+func GetEndToEndLatencyWindow() int {
+	val := os.Getenv("E2E_LATENCY_WINDOW")
+	if val == "" {
+		return 1000
+	}
+	window, err := strconv.Atoi(val)
+	if err != nil || window <= 0 {
+		log.Printf("Ignoring invalid E2E_LATENCY_WINDOW %q: must be a positive integer", val)
+		return 1000
+	}
+	return window
+}
+
+// endToEndLatency is the end-to-end latency histogram, labelled by job type.
+var endToEndLatency = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "jobs",
+		Name:      "end_to_end_seconds",
+		Help:      "Time from job creation to completion by job type, including scheduling and queue time.",
+		Buckets:   GetEndToEndLatencyBuckets(),
+	},
+	[]string{"type"},
+)
+
+// LatencyStats summarizes the recent end-to-end latencies of one job type.
+type LatencyStats struct {
+	Count int64   `json:"count"`
+	AvgMs float64 `json:"avg_ms"`
+	P95Ms float64 `json:"p95_ms"`
+	P99Ms float64 `json:"p99_ms"`
+}
+
+// latencyWindows keeps the last size latencies of each job type in a ring buffer.
+type latencyWindows struct {
+	mu      sync.Mutex
+	size    int
+	samples map[string]*latencyRing
+}
+
+type latencyRing struct {
+	values []time.Duration
+	next   int
+	count  int64
+}
+
+func newLatencyWindows(size int) *latencyWindows {
+	return &latencyWindows{size: size, samples: make(map[string]*latencyRing)}
+}
+
+// add records d for jobType, overwriting the oldest sample once the window is full.
+func (w *latencyWindows) add(jobType string, d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ring, ok := w.samples[jobType]
+	if !ok {
+		ring = &latencyRing{}
+		w.samples[jobType] = ring
+	}
+	if len(ring.values) < w.size {
+		ring.values = append(ring.values, d)
+	} else {
+		ring.values[ring.next] = d
+		ring.next = (ring.next + 1) % w.size
+	}
+	ring.count++
+}
+
+// stats summarizes each type's window. Count is the total recorded, not the window size.
+func (w *latencyWindows) stats() map[string]LatencyStats {
+	w.mu.Lock()
+	snapshot := make(map[string][]time.Duration, len(w.samples))
+	counts := make(map[string]int64, len(w.samples))
+	for jobType, ring := range w.samples {
+		snapshot[jobType] = append([]time.Duration(nil), ring.values...)
+		counts[jobType] = ring.count
+	}
+	w.mu.Unlock()
+
+	stats := make(map[string]LatencyStats, len(snapshot))
+	for jobType, values := range snapshot {
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		var sum time.Duration
+		for _, v := range values {
+			sum += v
+		}
+		stats[jobType] = LatencyStats{
+			Count: counts[jobType],
+			AvgMs: durationMs(sum) / float64(len(values)),
+			P95Ms: durationMs(percentile(values, 0.95)),
+			P99Ms: durationMs(percentile(values, 0.99)),
+		}
+	}
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted, non-empty values.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// RecordEndToEndLatency records the time from a job's creation to its completion.
+func (m *Metrics) RecordEndToEndLatency(jobType string, d time.Duration) {
+	endToEndLatency.WithLabelValues(jobType).Observe(d.Seconds())
+	if m.endToEndLatency != nil {
+		m.endToEndLatency.add(jobType, d)
+	}
+}
+
+// EndToEndLatency returns avg/p95/p99 end-to-end latency per job type over the recent window.
+func (m *Metrics) EndToEndLatency() map[string]LatencyStats {
+	if m.endToEndLatency == nil {
+		return map[string]LatencyStats{}
+	}
+	return m.endToEndLatency.stats()
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// defaultMaxPayloadBytes caps job payloads when MAX_PAYLOAD_BYTES is unset.
+const defaultMaxPayloadBytes = 8 * 1024
+
+// jobRequestEnvelopeBytes is the room left in a job request body for everything but
+// the payload (type, labels, jobId, JSON escaping...).
+const jobRequestEnvelopeBytes = 4 * 1024
+
+// GetMaxPayloadBytes returns the largest accepted job payload in bytes
+// (MAX_PAYLOAD_BYTES, default 8KB), so a client can't bloat the jobs table
+// and Kafka messages with multi-megabyte payloads.
+func GetMaxPayloadBytes() int {
+	val := os.Getenv("MAX_PAYLOAD_BYTES")
+	if val == "" {
+		return defaultMaxPayloadBytes
+	}
+	limit, err := strconv.Atoi(val)
+	if err != nil || limit <= 0 {
+		log.Printf("Ignoring invalid MAX_PAYLOAD_BYTES %q: must be a positive byte count", val)
+		return defaultMaxPayloadBytes
+	}
+	return limit
+}
+
+// GetMaxJobRequestBytes returns the largest accepted POST /api/jobs body in bytes:
+// the payload limit plus room for the request's other fields.
+func GetMaxJobRequestBytes() int64 {
+	return int64(GetMaxPayloadBytes() + jobRequestEnvelopeBytes)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Structured logging for the job hot paths (worker, scheduler, job controller).
+//
+// Each line is one JSON object with consistent keys, so a job can be followed
+// across processes in Loki/ELK by querying job_id:
+//
+//	{"time":"...","level":"INFO","msg":"Job completed","job_id":"550e8400-...","client_id":"customer-1","worker_id":3}
+//
+// Keys: time, level, msg, plus job_id, client_id, and worker_id wherever they are known.
+// LOG_LEVEL sets the minimum level: debug, info (default), warn, or error.
+// Startup and configuration messages still go through the standard log package.
+
+// Structured log keys shared by every component.
+const (
+	LogKeyJobID    = "job_id"
+	LogKeyClientID = "client_id"
+	LogKeyWorkerID = "worker_id"
+)
+
+// appLogger is created on first use, so LOG_LEVEL is read after env is loaded.
+var appLogger atomic.Pointer[slog.Logger]
+
+// GetLogLevel returns the minimum structured log level from env or default (info).
+func GetLogLevel() slog.Level {
+	val := os.Getenv("LOG_LEVEL")
+	if val == "" {
+		return slog.LevelInfo
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(val))); err != nil {
+		log.Printf("Ignoring invalid LOG_LEVEL %q: must be debug, info, warn or error", val)
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// NewJSONLogger creates a logger writing one JSON object per line to w.
+func NewJSONLogger(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// Logger returns the global structured logger, writing JSON to stderr at LOG_LEVEL.
+func Logger() *slog.Logger {
+	if logger := appLogger.Load(); logger != nil {
+		return logger
+	}
+	appLogger.CompareAndSwap(nil, NewJSONLogger(os.Stderr, GetLogLevel()))
+	return appLogger.Load()
+}
+
+// SetLogger replaces the global structured logger, e.g. to capture output in tests.
+func SetLogger(logger *slog.Logger) {
+	appLogger.Store(logger)
+}
+
+// JobLogger returns the global logger with the job_id and client_id keys set.
+func JobLogger(jobID, clientID string) *slog.Logger {
+	return Logger().With(LogKeyJobID, jobID, LogKeyClientID, clientID)
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"log/slog"
+	"testing"
+)
+
+// TestGetLogLevel verifies LOG_LEVEL names are parsed and invalid values fall back to info.
+func TestGetLogLevel(t *testing.T) {
+	cases := map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"debug":   slog.LevelDebug,
+		"WARN":    slog.LevelWarn,
+		"error":   slog.LevelError,
+		"verbose": slog.LevelInfo,
+	}
+	for val, want := range cases {
+		t.Setenv("LOG_LEVEL", val)
+		if got := GetLogLevel(); got != want {
+			t.Errorf("LOG_LEVEL=%q: expected %v, got %v", val, want, got)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"log"
+	"os"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -11,14 +13,19 @@ import (
 
 // Metrics provides lightweight application metrics for monitoring.
 // Exposes counters and histograms at GET /metrics for Prometheus scraping
-// or manual inspection.
+// (see prometheus.go) and at GET /metrics/json for manual inspection.
 //
 // Tracked metrics:
-// - HTTP request count and latency (by endpoint, method, status)
+// - HTTP request count and latency (by route template, method, status)
+// - HTTP requests in flight and requests shed at the concurrency limit
 // - Job processing count (by type, status)
-// - Kafka message count (produced, consumed, failed)
-// - Redis cache hit/miss ratio
+// - End-to-end job latency per type, creation to completion (see latency.go)
+// - Kafka message count (produced, consumed, failed) and consumer lag (see consumerlag.go)
+// - Redis cache hit/miss ratio, write failures, and local fallback hits
 // - Rate limit rejections per client
+// - In-flight jobs per type and bulkhead rejections
+// - Scheduler batch size (current, adapts to backlog)
+// - PENDING backlog per type (sampled by the scheduler when SCHEDULER_BACKLOG_GAUGE=true)
 
 type Metrics struct {
 	// HTTP metrics
@@ -26,28 +33,46 @@ type Metrics struct {
 	httpLatencySum      map[string]*atomic.Int64
 	httpLatencyCount    map[string]*atomic.Int64
 	httpMu              sync.RWMutex
+	httpMaxKeys         int
+	httpInFlight        atomic.Int64
+	httpShedRequests    atomic.Int64
 
 	// Job metrics
 	jobsCreated         atomic.Int64
 	jobsCompleted       atomic.Int64
 	jobsFailed          atomic.Int64
 	jobsDeadLettered    atomic.Int64
+	jobsExpired         atomic.Int64
 	jobsRetried         atomic.Int64
 
 	// Kafka metrics
 	kafkaMessagesProduced atomic.Int64
 	kafkaMessagesConsumed atomic.Int64
 	kafkaProduceErrors    atomic.Int64
+	kafkaFetchFailures    atomic.Int64
+	kafkaConsumerLag      map[kafkaLagKey]int64
+	kafkaLagMu            sync.RWMutex
 
 	// Redis metrics
 	cacheHits           atomic.Int64
 	cacheMisses         atomic.Int64
+	cacheWriteFailures  atomic.Int64
+	cacheFallbackHits   atomic.Int64
 	rateLimitRejections atomic.Int64
 
 	// Worker metrics
 	activeWorkers       atomic.Int64
 	processingTimeSum   atomic.Int64
 	processingTimeCount atomic.Int64
+	jobsInFlight        map[string]*atomic.Int64
+	inFlightMu          sync.RWMutex
+	bulkheadRejections  atomic.Int64
+	endToEndLatency     *latencyWindows
+
+	// Scheduler metrics
+	schedulerBatchSize  atomic.Int64
+	pendingJobs         map[string]int64
+	pendingMu           sync.RWMutex
 }
 
 // Global metrics instance
@@ -55,6 +80,31 @@ var appMetrics = &Metrics{
 	httpRequestsTotal: make(map[string]*atomic.Int64),
 	httpLatencySum:    make(map[string]*atomic.Int64),
 	httpLatencyCount:  make(map[string]*atomic.Int64),
+	httpMaxKeys:       GetHTTPMetricsMaxKeys(),
+	jobsInFlight:      make(map[string]*atomic.Int64),
+	endToEndLatency:   newLatencyWindows(GetEndToEndLatencyWindow()),
+}
+
+// httpOverflowKey collects requests once the distinct HTTP metric keys hit the cap.
+const httpOverflowKey = "other"
+
+// httpUnmatchedPath replaces the empty route of requests that matched no route (404s),
+// so unknown paths never become metric keys.
+const httpUnmatchedPath = "unmatched"
+
+// GetHTTPMetricsMaxKeys returns the cap on distinct HTTP metric keys from env or default.
+// Guards the metrics maps against unbounded growth from high-cardinality keys.
+func GetHTTPMetricsMaxKeys() int {
+	val := os.Getenv("HTTP_METRICS_MAX_KEYS")
+	if val == "" {
+		return 200
+	}
+	maxKeys, err := strconv.Atoi(val)
+	if err != nil || maxKeys <= 0 {
+		log.Printf("Ignoring invalid HTTP_METRICS_MAX_KEYS %q: must be a positive integer", val)
+		return 200
+	}
+	return maxKeys
 }
 
 // GetMetrics returns the global metrics instance.
@@ -63,29 +113,67 @@ func GetMetrics() *Metrics {
 }
 
 // RecordHTTPRequest records an HTTP request metric.
+// path must be a route template (/api/jobs/:id), never the raw request path.
+// Once httpMaxKeys distinct keys exist, new keys are counted under "other".
+//
+// Runs on every request, so it takes exactly one lock: the shared read lock when
+// the key exists (every request but a key's first), the write lock otherwise.
 func (m *Metrics) RecordHTTPRequest(method, path string, status int, duration time.Duration) {
 	key := method + " " + path + " " + strconv.Itoa(status)
 
-	m.httpMu.Lock()
-	if _, ok := m.httpRequestsTotal[key]; !ok {
-		m.httpRequestsTotal[key] = &atomic.Int64{}
-		m.httpLatencySum[key] = &atomic.Int64{}
-		m.httpLatencyCount[key] = &atomic.Int64{}
+	m.httpMu.RLock()
+	key, recorded := m.addHTTPRequestLocked(key, duration)
+	m.httpMu.RUnlock()
+
+	if !recorded {
+		m.httpMu.Lock()
+		key, recorded = m.addHTTPRequestLocked(key, duration)
+		if !recorded {
+			m.httpRequestsTotal[key] = &atomic.Int64{}
+			m.httpLatencySum[key] = &atomic.Int64{}
+			m.httpLatencyCount[key] = &atomic.Int64{}
+			key, _ = m.addHTTPRequestLocked(key, duration)
+		}
+		m.httpMu.Unlock()
 	}
-	m.httpMu.Unlock()
 
-	m.httpMu.RLock()
-	m.httpRequestsTotal[key].Add(1)
+	route := path
+	if key == httpOverflowKey {
+		route = httpOverflowKey
+	}
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// addHTTPRequestLocked counts a request under key, or under "other" once the key cap
+// is reached, and returns the key used. Reports false, counting nothing, when that key
+// doesn't exist yet. The caller holds httpMu, read or write.
+func (m *Metrics) addHTTPRequestLocked(key string, duration time.Duration) (string, bool) {
+	requests, ok := m.httpRequestsTotal[key]
+	if !ok && len(m.httpRequestsTotal) >= m.httpMaxKeys {
+		key = httpOverflowKey
+		requests, ok = m.httpRequestsTotal[key]
+	}
+	if !ok {
+		return key, false
+	}
+	requests.Add(1)
 	m.httpLatencySum[key].Add(duration.Microseconds())
 	m.httpLatencyCount[key].Add(1)
-	m.httpMu.RUnlock()
+	return key, true
 }
 
+// Server concurrency helpers
+func (m *Metrics) IncHTTPInFlight()    { m.httpInFlight.Add(1) }
+func (m *Metrics) DecHTTPInFlight()    { m.httpInFlight.Add(-1) }
+func (m *Metrics) IncHTTPShedRequest() { m.httpShedRequests.Add(1) }
+
 // Job metric helpers
 func (m *Metrics) IncJobsCreated()      { m.jobsCreated.Add(1) }
 func (m *Metrics) IncJobsCompleted()    { m.jobsCompleted.Add(1) }
 func (m *Metrics) IncJobsFailed()       { m.jobsFailed.Add(1) }
 func (m *Metrics) IncJobsDeadLettered() { m.jobsDeadLettered.Add(1) }
+func (m *Metrics) IncJobsExpired()      { m.jobsExpired.Add(1) }
+func (m *Metrics) JobsExpired() int64   { return m.jobsExpired.Load() }
 func (m *Metrics) IncJobsRetried()      { m.jobsRetried.Add(1) }
 
 // Kafka metric helpers
@@ -93,9 +181,18 @@ func (m *Metrics) IncKafkaProduced()     { m.kafkaMessagesProduced.Add(1) }
 func (m *Metrics) IncKafkaConsumed()     { m.kafkaMessagesConsumed.Add(1) }
 func (m *Metrics) IncKafkaProduceError() { m.kafkaProduceErrors.Add(1) }
 
+// AddKafkaFetchFailures adjusts the consecutive fetch failure gauge: +1 per failed
+// fetch, minus a loop's streak once it fetches successfully again.
+func (m *Metrics) AddKafkaFetchFailures(n int64) { m.kafkaFetchFailures.Add(n) }
+func (m *Metrics) KafkaFetchFailures() int64     { return m.kafkaFetchFailures.Load() }
+
 // Cache metric helpers
 func (m *Metrics) IncCacheHit()             { m.cacheHits.Add(1) }
 func (m *Metrics) IncCacheMiss()            { m.cacheMisses.Add(1) }
+func (m *Metrics) IncCacheWriteFailure()    { m.cacheWriteFailures.Add(1) }
+func (m *Metrics) CacheWriteFailures() int64 { return m.cacheWriteFailures.Load() }
+func (m *Metrics) IncCacheFallbackHit()      { m.cacheFallbackHits.Add(1) }
+func (m *Metrics) CacheFallbackHits() int64 { return m.cacheFallbackHits.Load() }
 func (m *Metrics) IncRateLimitRejection()   { m.rateLimitRejections.Add(1) }
 
 // Worker metric helpers
@@ -106,18 +203,87 @@ func (m *Metrics) RecordProcessingTime(d time.Duration) {
 	m.processingTimeCount.Add(1)
 }
 
+// In-flight jobs per type (bulkhead)
+func (m *Metrics) IncJobsInFlight(jobType string) { m.inFlightCounter(jobType).Add(1) }
+func (m *Metrics) DecJobsInFlight(jobType string) { m.inFlightCounter(jobType).Add(-1) }
+func (m *Metrics) IncBulkheadRejection()          { m.bulkheadRejections.Add(1) }
+
+// inFlightCounter returns the in-flight gauge for a job type, creating it on first use.
+func (m *Metrics) inFlightCounter(jobType string) *atomic.Int64 {
+	m.inFlightMu.RLock()
+	counter, ok := m.jobsInFlight[jobType]
+	m.inFlightMu.RUnlock()
+	if ok {
+		return counter
+	}
+
+	m.inFlightMu.Lock()
+	defer m.inFlightMu.Unlock()
+	if counter, ok = m.jobsInFlight[jobType]; !ok {
+		counter = &atomic.Int64{}
+		m.jobsInFlight[jobType] = counter
+	}
+	return counter
+}
+
+// jobsInFlightByType snapshots the in-flight gauges.
+func (m *Metrics) jobsInFlightByType() map[string]int64 {
+	m.inFlightMu.RLock()
+	defer m.inFlightMu.RUnlock()
+	snapshot := make(map[string]int64, len(m.jobsInFlight))
+	for jobType, counter := range m.jobsInFlight {
+		snapshot[jobType] = counter.Load()
+	}
+	return snapshot
+}
+
+// Scheduler metric helpers
+func (m *Metrics) SetSchedulerBatchSize(n int) { m.schedulerBatchSize.Store(int64(n)) }
+
+// SetPendingJobs replaces the PENDING backlog gauges with the given counts per type.
+// Types missing from counts are dropped, so pass zero for a drained type to keep reporting it.
+func (m *Metrics) SetPendingJobs(counts map[string]int64) {
+	snapshot := make(map[string]int64, len(counts))
+	for jobType, n := range counts {
+		snapshot[jobType] = n
+	}
+	m.pendingMu.Lock()
+	m.pendingJobs = snapshot
+	m.pendingMu.Unlock()
+}
+
+// pendingJobsByType snapshots the PENDING backlog gauges.
+func (m *Metrics) pendingJobsByType() map[string]int64 {
+	m.pendingMu.RLock()
+	defer m.pendingMu.RUnlock()
+	snapshot := make(map[string]int64, len(m.pendingJobs))
+	for jobType, n := range m.pendingJobs {
+		snapshot[jobType] = n
+	}
+	return snapshot
+}
+
+// PendingJobs returns the PENDING backlog per type as last sampled by the scheduler.
+func (m *Metrics) PendingJobs() map[string]int64 { return m.pendingJobsByType() }
+
 // MetricsMiddleware records HTTP request metrics for every request.
 func MetricsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		c.Next()
 		duration := time.Since(start)
-		GetMetrics().RecordHTTPRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), duration)
+
+		// FullPath is the matched route template, so IDs in the URL don't multiply keys
+		path := c.FullPath()
+		if path == "" {
+			path = httpUnmatchedPath
+		}
+		GetMetrics().RecordHTTPRequest(c.Request.Method, path, c.Writer.Status(), duration)
 	}
 }
 
 // MetricsHandler returns current metrics as JSON.
-// GET /metrics
+// GET /metrics/json
 func MetricsHandler(c *gin.Context) {
 	m := GetMetrics()
 
@@ -156,17 +322,22 @@ func MetricsHandler(c *gin.Context) {
 			"completed":     m.jobsCompleted.Load(),
 			"failed":        m.jobsFailed.Load(),
 			"dead_lettered": m.jobsDeadLettered.Load(),
+			"expired":       m.jobsExpired.Load(),
 			"retried":       m.jobsRetried.Load(),
 		},
 		"kafka": gin.H{
-			"messages_produced": m.kafkaMessagesProduced.Load(),
-			"messages_consumed": m.kafkaMessagesConsumed.Load(),
-			"produce_errors":    m.kafkaProduceErrors.Load(),
+			"messages_produced":          m.kafkaMessagesProduced.Load(),
+			"messages_consumed":          m.kafkaMessagesConsumed.Load(),
+			"produce_errors":             m.kafkaProduceErrors.Load(),
+			"consecutive_fetch_failures": m.kafkaFetchFailures.Load(),
+			"consumer_lag":               m.kafkaConsumerLagJSON(),
 		},
 		"cache": gin.H{
 			"hits":      hits,
 			"misses":    misses,
 			"hit_ratio": hitRatio,
+			"write_failures": m.cacheWriteFailures.Load(),
+			"fallback_hits": m.cacheFallbackHits.Load(),
 		},
 		"rate_limiting": gin.H{
 			"rejections": m.rateLimitRejections.Load(),
@@ -174,6 +345,18 @@ func MetricsHandler(c *gin.Context) {
 		"workers": gin.H{
 			"active":                m.activeWorkers.Load(),
 			"avg_processing_time_ms": avgProcessing,
+			"in_flight_by_type":      m.jobsInFlightByType(),
+			"bulkhead_rejections":    m.bulkheadRejections.Load(),
+		},
+		// Creation to completion, including queue time; avg_processing_time_ms excludes it
+		"end_to_end_latency_by_type": m.EndToEndLatency(),
+		"scheduler": gin.H{
+			"batch_size":      m.schedulerBatchSize.Load(),
+			"pending_by_type": m.pendingJobsByType(),
+		},
+		"http": gin.H{
+			"in_flight":     m.httpInFlight.Load(),
+			"shed_requests": m.httpShedRequests.Load(),
 		},
 		"http_endpoints": httpMetrics,
 	})
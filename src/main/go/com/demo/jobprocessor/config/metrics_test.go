@@ -0,0 +1,159 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TestMetricsMiddlewareUsesRouteTemplate verifies requests for different job IDs share one metric key.
+func TestMetricsMiddlewareUsesRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(MetricsMiddleware())
+	r.GET("/api/jobs/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for i := 0; i < 50; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/jobs/"+uuid.New().String(), nil))
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unknown/"+uuid.New().String(), nil))
+	}
+
+	m := GetMetrics()
+	m.httpMu.RLock()
+	defer m.httpMu.RUnlock()
+
+	var jobKeys []string
+	for key := range m.httpRequestsTotal {
+		if strings.Contains(key, "/api/jobs") || strings.Contains(key, "/unknown") {
+			jobKeys = append(jobKeys, key)
+		}
+	}
+	if len(jobKeys) != 1 || jobKeys[0] != "GET /api/jobs/:id 200" {
+		t.Fatalf("expected only the route template key, got %v", jobKeys)
+	}
+	if m.httpRequestsTotal["GET unmatched 404"].Load() < 50 {
+		t.Fatalf("expected unmatched requests bucketed together, got %v", m.httpRequestsTotal["GET unmatched 404"])
+	}
+}
+
+// TestRecordHTTPRequestCapsKeys verifies keys beyond the cap are bucketed into "other".
+func TestRecordHTTPRequestCapsKeys(t *testing.T) {
+	m := &Metrics{
+		httpRequestsTotal: make(map[string]*atomic.Int64),
+		httpLatencySum:    make(map[string]*atomic.Int64),
+		httpLatencyCount:  make(map[string]*atomic.Int64),
+		httpMaxKeys:       3,
+	}
+
+	for i := 0; i < 10; i++ {
+		m.RecordHTTPRequest(http.MethodGet, "/path/"+uuid.New().String(), http.StatusOK, time.Millisecond)
+	}
+
+	if len(m.httpRequestsTotal) != 4 {
+		t.Fatalf("expected 3 keys plus other, got %d", len(m.httpRequestsTotal))
+	}
+	if got := m.httpRequestsTotal[httpOverflowKey].Load(); got != 7 {
+		t.Fatalf("expected 7 requests under other, got %d", got)
+	}
+}
+
+// BenchmarkRecordHTTPRequestParallel measures the HTTP metrics path under parallel load.
+// Runs on every request, so it must not serialize request goroutines.
+// Run: go test ./config -bench=RecordHTTPRequest -cpu=1,4,8
+func BenchmarkRecordHTTPRequestParallel(b *testing.B) {
+	m := &Metrics{
+		httpRequestsTotal: make(map[string]*atomic.Int64),
+		httpLatencySum:    make(map[string]*atomic.Int64),
+		httpLatencyCount:  make(map[string]*atomic.Int64),
+		httpMaxKeys:       200,
+	}
+	paths := []string{"/api/jobs", "/api/jobs/:id", "/api/jobs/stats", "/api/jobs/health"}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.RecordHTTPRequest(http.MethodGet, paths[i%len(paths)], http.StatusOK, time.Millisecond)
+			i++
+		}
+	})
+}
+
+// TestEndToEndLatencyPercentilesPerType verifies avg/p95/p99 are computed per type over
+// the most recent window only.
+func TestEndToEndLatencyPercentilesPerType(t *testing.T) {
+	m := &Metrics{endToEndLatency: newLatencyWindows(100)}
+
+	// Samples older than the window must not count
+	for i := 0; i < 50; i++ {
+		m.RecordEndToEndLatency("PAYMENT_PROCESS", time.Hour)
+	}
+	for i := 1; i <= 100; i++ {
+		m.RecordEndToEndLatency("PAYMENT_PROCESS", time.Duration(i)*time.Second)
+	}
+	m.RecordEndToEndLatency("HEALTH_CHECK", 200*time.Millisecond)
+
+	stats := m.EndToEndLatency()
+	payment := stats["PAYMENT_PROCESS"]
+	if payment.Count != 150 || payment.AvgMs != 50500 || payment.P95Ms != 95000 || payment.P99Ms != 99000 {
+		t.Fatalf("unexpected PAYMENT_PROCESS latency stats %+v", payment)
+	}
+	health := stats["HEALTH_CHECK"]
+	if health.Count != 1 || health.AvgMs != 200 || health.P95Ms != 200 || health.P99Ms != 200 {
+		t.Fatalf("unexpected HEALTH_CHECK latency stats %+v", health)
+	}
+}
+
+// TestKafkaConsumerLagGauge verifies lag gauges are set per topic and reader, the
+// latest sample replacing the previous one.
+func TestKafkaConsumerLagGauge(t *testing.T) {
+	m := &Metrics{}
+	if _, ok := m.KafkaConsumerLag("job-queue", "0"); ok {
+		t.Fatal("expected no lag before the first sample")
+	}
+
+	m.SetKafkaConsumerLag("job-queue", "0", 120)
+	m.SetKafkaConsumerLag("job-queue", "1", 7)
+	m.SetKafkaConsumerLag("job-queue", "0", 30)
+	m.SetKafkaConsumerLag("job-queue-high", "0", 2)
+
+	for _, tc := range []struct {
+		topic, reader string
+		want          int64
+	}{
+		{"job-queue", "0", 30},
+		{"job-queue", "1", 7},
+		{"job-queue-high", "0", 2},
+	} {
+		if lag, ok := m.KafkaConsumerLag(tc.topic, tc.reader); !ok || lag != tc.want {
+			t.Errorf("%s reader %s: expected lag %d, got %d (recorded: %v)", tc.topic, tc.reader, tc.want, lag, ok)
+		}
+	}
+	if lags := m.kafkaConsumerLagJSON(); len(lags) != 3 || lags["job-queue/0"] != 30 {
+		t.Fatalf("unexpected JSON lags %v", lags)
+	}
+}
+
+// TestGetEndToEndLatencyBuckets verifies the default, a custom list, and that an
+// unordered list falls back to the default.
+func TestGetEndToEndLatencyBuckets(t *testing.T) {
+	if got := GetEndToEndLatencyBuckets(); len(got) != len(defaultEndToEndLatencyBuckets) {
+		t.Fatalf("expected the default buckets, got %v", got)
+	}
+	t.Setenv("E2E_LATENCY_BUCKETS", "1, 5,30")
+	if got := GetEndToEndLatencyBuckets(); len(got) != 3 || got[1] != 5 {
+		t.Fatalf("expected [1 5 30], got %v", got)
+	}
+	t.Setenv("E2E_LATENCY_BUCKETS", "5,1")
+	if got := GetEndToEndLatencyBuckets(); len(got) != len(defaultEndToEndLatencyBuckets) {
+		t.Fatalf("expected the default buckets for an unordered list, got %v", got)
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus exposition of the application Metrics via the official client library.
+//
+// GET /metrics is the canonical endpoint (text exposition format); the JSON view
+// stays at GET /metrics/json unless METRICS_JSON_ENABLED=false.
+//
+// Counters and gauges keep their atomic storage in Metrics (also read by the JSON
+// view and the StatsD sink) and are exported by metricsCollector at scrape time.
+// HTTP latency and end-to-end job latency are real histograms, observed in
+// RecordHTTPRequest and RecordEndToEndLatency; SLA breaches and at-risk jobs are
+// labelled vectors too (see sla.go), as are type circuit breakers (see
+// typecircuit.go). Database pool stats are read at scrape time once a pool is
+// observed (see dbpool.go).
+// Go runtime and process collectors are included.
+// Metric names are bare by default, such as jobs_created_total; set
+// PROMETHEUS_NAMESPACE (e.g. "parallelis") to prefix them all, as in
+// parallelis_jobs_created_total.
+
+// GetPrometheusNamespace returns the metric name namespace from env, empty by default.
+// Read once at startup, when the metric descriptors are built.
+func GetPrometheusNamespace() string {
+	return os.Getenv("PROMETHEUS_NAMESPACE")
+}
+
+var prometheusNamespace = GetPrometheusNamespace()
+
+// httpRequestDuration is the HTTP latency histogram, labelled by route template.
+// Routes beyond the HTTP_METRICS_MAX_KEYS cap are labelled "other".
+var httpRequestDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: prometheusNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	},
+	[]string{"method", "route", "status"},
+)
+
+// prometheusCounter maps one Metrics counter to a Prometheus counter.
+type prometheusCounter struct {
+	desc  *prometheus.Desc
+	value func(m *Metrics) int64
+}
+
+func newPrometheusCounter(name, help string, value func(m *Metrics) int64) prometheusCounter {
+	return prometheusCounter{
+		desc:  prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "", name), help, nil, nil),
+		value: value,
+	}
+}
+
+var prometheusCounters = []prometheusCounter{
+	newPrometheusCounter("jobs_created_total", "Jobs accepted by the API.", func(m *Metrics) int64 { return m.jobsCreated.Load() }),
+	newPrometheusCounter("jobs_completed_total", "Jobs processed successfully.", func(m *Metrics) int64 { return m.jobsCompleted.Load() }),
+	newPrometheusCounter("jobs_failed_total", "Failed job attempts.", func(m *Metrics) int64 { return m.jobsFailed.Load() }),
+	newPrometheusCounter("jobs_dead_lettered_total", "Jobs moved to DEAD_LETTER after exhausting retries.", func(m *Metrics) int64 { return m.jobsDeadLettered.Load() }),
+	newPrometheusCounter("jobs_expired_total", "PENDING jobs expired past their expiresAt or their type's MAX_JOB_AGE.", func(m *Metrics) int64 { return m.jobsExpired.Load() }),
+	newPrometheusCounter("jobs_retried_total", "Job attempts scheduled for retry.", func(m *Metrics) int64 { return m.jobsRetried.Load() }),
+	newPrometheusCounter("kafka_messages_produced_total", "Job IDs published to Kafka.", func(m *Metrics) int64 { return m.kafkaMessagesProduced.Load() }),
+	newPrometheusCounter("kafka_messages_consumed_total", "Job IDs consumed from Kafka.", func(m *Metrics) int64 { return m.kafkaMessagesConsumed.Load() }),
+	newPrometheusCounter("kafka_produce_errors_total", "Failed Kafka publishes.", func(m *Metrics) int64 { return m.kafkaProduceErrors.Load() }),
+	newPrometheusCounter("cache_hits_total", "Redis job cache hits.", func(m *Metrics) int64 { return m.cacheHits.Load() }),
+	newPrometheusCounter("cache_misses_total", "Redis job cache misses.", func(m *Metrics) int64 { return m.cacheMisses.Load() }),
+	newPrometheusCounter("cache_write_failures_total", "Best-effort Redis cache writes that failed.", func(m *Metrics) int64 { return m.cacheWriteFailures.Load() }),
+	newPrometheusCounter("cache_fallback_hits_total", "Job lookups served by the local fallback cache while Redis was failing.", func(m *Metrics) int64 { return m.cacheFallbackHits.Load() }),
+	newPrometheusCounter("rate_limit_rejections_total", "Requests rejected by the rate limiter.", func(m *Metrics) int64 { return m.rateLimitRejections.Load() }),
+	newPrometheusCounter("http_shed_requests_total", "Requests rejected with 503 at the concurrency limit.", func(m *Metrics) int64 { return m.httpShedRequests.Load() }),
+	newPrometheusCounter("bulkhead_rejections_total", "Jobs deferred because their type's bulkhead was full.", func(m *Metrics) int64 { return m.bulkheadRejections.Load() }),
+}
+
+var (
+	httpInFlightDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "http", "in_flight_requests"),
+		"HTTP requests currently being served.", nil, nil)
+	kafkaFetchFailuresDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "kafka", "consecutive_fetch_failures"),
+		"Consecutive failed Kafka fetches, summed over fetch loops; 0 when Kafka is healthy.", nil, nil)
+	kafkaConsumerLagDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "kafka", "consumer_lag"),
+		"Messages behind the high watermark, by topic and worker reader (see consumerlag.go).", []string{"topic", "reader"}, nil)
+	activeWorkersDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "workers", "active"),
+		"Jobs currently being processed.", nil, nil)
+	jobsInFlightDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "workers", "in_flight"),
+		"Jobs currently being processed by job type.", []string{"type"}, nil)
+	schedulerBatchSizeDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "scheduler", "batch_size"),
+		"Current scheduler batch size.", nil, nil)
+	pendingJobsDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "", "pending_jobs"),
+		"PENDING jobs by job type, sampled by the scheduler each poll (SCHEDULER_BACKLOG_GAUGE=true).", []string{"type"}, nil)
+	processingTimeDesc = prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "jobs", "processing_seconds"),
+		"Job processing time, excluding scheduling and queue time (see jobs_end_to_end_seconds).", nil, nil)
+)
+
+// metricsCollector exports a Metrics instance as Prometheus metrics.
+type metricsCollector struct {
+	metrics *Metrics
+}
+
+// Describe implements prometheus.Collector.
+func (c metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, counter := range prometheusCounters {
+		ch <- counter.desc
+	}
+	ch <- httpInFlightDesc
+	ch <- kafkaFetchFailuresDesc
+	ch <- kafkaConsumerLagDesc
+	ch <- activeWorkersDesc
+	ch <- jobsInFlightDesc
+	ch <- schedulerBatchSizeDesc
+	ch <- pendingJobsDesc
+	ch <- processingTimeDesc
+}
+
+// Collect implements prometheus.Collector, reading current values at scrape time.
+func (c metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	m := c.metrics
+	for _, counter := range prometheusCounters {
+		ch <- prometheus.MustNewConstMetric(counter.desc, prometheus.CounterValue, float64(counter.value(m)))
+	}
+
+	ch <- prometheus.MustNewConstMetric(httpInFlightDesc, prometheus.GaugeValue, float64(m.httpInFlight.Load()))
+	ch <- prometheus.MustNewConstMetric(kafkaFetchFailuresDesc, prometheus.GaugeValue, float64(m.kafkaFetchFailures.Load()))
+	for key, lag := range m.kafkaConsumerLagSnapshot() {
+		ch <- prometheus.MustNewConstMetric(kafkaConsumerLagDesc, prometheus.GaugeValue, float64(lag), key.topic, key.reader)
+	}
+	ch <- prometheus.MustNewConstMetric(activeWorkersDesc, prometheus.GaugeValue, float64(m.activeWorkers.Load()))
+	for jobType, n := range m.jobsInFlightByType() {
+		ch <- prometheus.MustNewConstMetric(jobsInFlightDesc, prometheus.GaugeValue, float64(n), jobType)
+	}
+	ch <- prometheus.MustNewConstMetric(schedulerBatchSizeDesc, prometheus.GaugeValue, float64(m.schedulerBatchSize.Load()))
+	for jobType, n := range m.pendingJobsByType() {
+		ch <- prometheus.MustNewConstMetric(pendingJobsDesc, prometheus.GaugeValue, float64(n), jobType)
+	}
+
+	// Processing time is tracked in microseconds
+	sumSeconds := float64(m.processingTimeSum.Load()) / 1e6
+	ch <- prometheus.MustNewConstSummary(processingTimeDesc, uint64(m.processingTimeCount.Load()), sumSeconds, nil)
+}
+
+// newPrometheusRegistry registers the application metrics and the runtime collectors.
+func newPrometheusRegistry(m *Metrics) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		metricsCollector{metrics: m},
+		httpRequestDuration,
+		endToEndLatency,
+		slaBreaches,
+		slaAtRisk,
+		typeCircuitState,
+		typeCircuitShortCircuits,
+		dbPoolCollector{},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+var prometheusRegistry = newPrometheusRegistry(appMetrics)
+
+// PrometheusHandler serves the metrics in Prometheus text exposition format.
+// GET /metrics
+func PrometheusHandler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(prometheusRegistry, promhttp.HandlerOpts{}))
+}
+
+// RegisterMetricsRoutes registers GET /metrics (Prometheus) and, unless
+// METRICS_JSON_ENABLED=false, GET /metrics/json (MetricsHandler).
+func RegisterMetricsRoutes(r gin.IRoutes) {
+	r.GET("/metrics", PrometheusHandler())
+	if os.Getenv("METRICS_JSON_ENABLED") != "false" {
+		r.GET("/metrics/json", MetricsHandler)
+	}
+}
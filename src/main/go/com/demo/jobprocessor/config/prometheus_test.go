@@ -0,0 +1,75 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestPrometheusHandlerExposesMetrics verifies counters, the HTTP histogram, and runtime
+// collectors are served in the Prometheus text format.
+func TestPrometheusHandlerExposesMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterMetricsRoutes(r)
+
+	GetMetrics().IncJobsCreated()
+	GetMetrics().RecordHTTPRequest(http.MethodGet, "/api/jobs/:id", http.StatusOK, 20*time.Millisecond)
+	GetMetrics().RecordEndToEndLatency("EMAIL_CONFIRMATION", 3*time.Second)
+	GetMetrics().IncSLABreach("EMAIL_CONFIRMATION")
+	GetMetrics().SetSLAAtRisk(map[string]int64{"PAYMENT_PROCESS": 4})
+	GetMetrics().SetTypeCircuitState("PAYMENT_PROCESS", TypeCircuitOpen)
+	GetMetrics().IncTypeCircuitShortCircuit("PAYMENT_PROCESS")
+	GetMetrics().SetKafkaConsumerLag("job-queue", "0", 42)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE jobs_created_total counter",
+		`http_request_duration_seconds_count{method="GET",route="/api/jobs/:id",status="200"}`,
+		"jobs_processing_seconds_count",
+		`jobs_end_to_end_seconds_count{type="EMAIL_CONFIRMATION"}`,
+		`sla_breaches_total{type="EMAIL_CONFIRMATION"} 1`,
+		`sla_at_risk_jobs{type="PAYMENT_PROCESS"} 4`,
+		`type_circuit_state{type="PAYMENT_PROCESS"} 1`,
+		`type_circuit_short_circuits_total{type="PAYMENT_PROCESS"} 1`,
+		`kafka_consumer_lag{reader="0",topic="job-queue"} 42`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in exposition", want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics/json", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"end_to_end_latency_by_type"`) {
+		t.Fatalf("expected JSON metrics, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestGetPrometheusNamespace verifies names are bare by default and prefixed once a namespace is set.
+func TestGetPrometheusNamespace(t *testing.T) {
+	// Unset for this test only, whatever the machine running it has set
+	if val, ok := os.LookupEnv("PROMETHEUS_NAMESPACE"); ok {
+		t.Cleanup(func() { os.Setenv("PROMETHEUS_NAMESPACE", val) })
+	}
+	os.Unsetenv("PROMETHEUS_NAMESPACE")
+	if got := GetPrometheusNamespace(); got != "" {
+		t.Fatalf("expected no default namespace, got %q", got)
+	}
+	t.Setenv("PROMETHEUS_NAMESPACE", "parallelis")
+	if got := GetPrometheusNamespace(); got != "parallelis" {
+		t.Fatalf("expected the configured namespace, got %q", got)
+	}
+}
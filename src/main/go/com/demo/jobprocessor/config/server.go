@@ -0,0 +1,102 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HTTP server timeouts and limits, so a slow or idle client can't hold a connection
+// (and its goroutine) open indefinitely, e.g. a slowloris trickling headers:
+// - HTTP_READ_HEADER_TIMEOUT (default 5s): to read the request headers
+// - HTTP_READ_TIMEOUT (default 30s): to read the whole request, body included
+// - HTTP_WRITE_TIMEOUT (default 30s): from the end of the headers to the end of the
+//   response; a handler still running past it has its connection closed
+// - HTTP_IDLE_TIMEOUT (default 2m): a keep-alive connection waits for its next request
+// - HTTP_MAX_HEADER_BYTES (default 1MB): request line and headers
+// Request bodies are limited per endpoint (see GetMaxJobRequestBytes).
+//
+// Graceful shutdown (HTTP_SHUTDOWN_TIMEOUT, default 30s): RunHTTPServer stops accepting
+// connections once its context is done, e.g. on SIGTERM, and waits that long for
+// in-flight requests to finish. Wiring:
+//
+//	config.RegisterServerMiddleware(router)
+//	srv := config.NewHTTPServer(router)
+//	listener, err := net.Listen("tcp", ":8080")
+//	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+//	defer stop()
+//	err = config.RunHTTPServer(ctx, srv, listener)
+
+// NewHTTPServer returns an http.Server serving handler (e.g. the Gin engine) with the
+// timeouts and header limit from env.
+func NewHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: DurationFromEnv("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       DurationFromEnv("HTTP_READ_TIMEOUT", 30*time.Second),
+		WriteTimeout:      DurationFromEnv("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       DurationFromEnv("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+		MaxHeaderBytes:    getMaxHeaderBytes(),
+	}
+}
+
+// RegisterServerMiddleware registers the middleware guarding the whole server on the
+// engine, before any route: the HTTP_MAX_CONCURRENT in-flight request cap.
+func RegisterServerMiddleware(r *gin.Engine) {
+	r.Use(ConcurrencyLimitMiddleware(GetMaxConcurrentRequests()))
+}
+
+// GetHTTPShutdownTimeout returns how long shutdown waits for in-flight requests, from env or default.
+func GetHTTPShutdownTimeout() time.Duration {
+	return DurationFromEnv("HTTP_SHUTDOWN_TIMEOUT", 30*time.Second)
+}
+
+// RunHTTPServer serves on listener until ctx is done, then shuts the server down
+// gracefully within HTTP_SHUTDOWN_TIMEOUT. Returns nil after a clean shutdown, the
+// error otherwise (including a serve error before ctx was done).
+func RunHTTPServer(ctx context.Context, srv *http.Server, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+	log.Printf("HTTP server listening on %s", listener.Addr())
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := GetHTTPShutdownTimeout()
+	log.Printf("Shutting down HTTP server, waiting up to %v for in-flight requests", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// getMaxHeaderBytes returns HTTP_MAX_HEADER_BYTES, or http.DefaultMaxHeaderBytes when unset or invalid.
+func getMaxHeaderBytes() int {
+	val := os.Getenv("HTTP_MAX_HEADER_BYTES")
+	if val == "" {
+		return http.DefaultMaxHeaderBytes
+	}
+	limit, err := strconv.Atoi(val)
+	if err != nil || limit <= 0 {
+		log.Printf("Ignoring invalid HTTP_MAX_HEADER_BYTES %q: must be a positive byte count", val)
+		return http.DefaultMaxHeaderBytes
+	}
+	return limit
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestHTTPServerAbortsSlowHandler verifies a handler running past HTTP_WRITE_TIMEOUT gets
+// its connection closed while fast ones are served, and that the server shuts down cleanly
+// once its context is done.
+func TestHTTPServerAbortsSlowHandler(t *testing.T) {
+	t.Setenv("HTTP_WRITE_TIMEOUT", "100ms")
+	t.Setenv("HTTP_SHUTDOWN_TIMEOUT", "1s")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/fast", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/slow", func(c *gin.Context) {
+		time.Sleep(300 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+
+	srv := NewHTTPServer(r)
+	if srv.WriteTimeout != 100*time.Millisecond || srv.ReadHeaderTimeout != 5*time.Second {
+		t.Fatalf("expected timeouts from env and defaults, got write %v, read header %v", srv.WriteTimeout, srv.ReadHeaderTimeout)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- RunHTTPServer(ctx, srv, listener) }()
+
+	base := "http://" + listener.Addr().String()
+	resp, err := http.Get(base + "/fast")
+	if err != nil {
+		t.Fatalf("fast request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from the fast handler, got %d", resp.StatusCode)
+	}
+
+	if resp, err := http.Get(base + "/slow"); err == nil {
+		resp.Body.Close()
+		t.Fatalf("expected the slow handler's connection closed, got %d", resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Per-type SLAs (SLA_<TYPE>, e.g. SLA_EMAIL_CONFIRMATION=60s, see service.JobSLAs):
+// a job breaches its type's SLA when its end-to-end latency (creation to completion)
+// exceeds it. Exposed per job type on GET /metrics:
+// - sla_breaches_total: jobs that completed past their SLA
+// - sla_at_risk_jobs: unfinished jobs already past their SLA, sampled by
+//   the scheduler every minute (SCHEDULER_SLA_AT_RISK_GAUGE=true)
+
+// slaBreaches counts completed jobs that breached their type's SLA, labelled by job type.
+var slaBreaches = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Name:      "sla_breaches_total",
+		Help:      "Jobs completed later after creation than their type's SLA, by job type.",
+	},
+	[]string{"type"},
+)
+
+// slaAtRisk is the number of unfinished jobs already past their type's SLA, labelled by job type.
+var slaAtRisk = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: prometheusNamespace,
+		Name:      "sla_at_risk_jobs",
+		Help:      "PENDING or RUNNING jobs created longer ago than their type's SLA, by job type.",
+	},
+	[]string{"type"},
+)
+
+// IncSLABreach counts a job of the type that completed past its SLA.
+func (m *Metrics) IncSLABreach(jobType string) {
+	slaBreaches.WithLabelValues(jobType).Inc()
+}
+
+// SetSLAAtRisk replaces the at-risk gauges with the given counts per type.
+func (m *Metrics) SetSLAAtRisk(counts map[string]int64) {
+	slaAtRisk.Reset()
+	for jobType, n := range counts {
+		slaAtRisk.WithLabelValues(jobType).Set(float64(n))
+	}
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StatsDSink pushes the application Metrics to a StatsD/DogStatsD agent over UDP.
+//
+// Disabled by default; enabled by setting STATSD_ADDR (e.g. "localhost:8125").
+// Coexists with the HTTP /metrics endpoints, all read the same Metrics values.
+//
+// Every flush interval the sink sends:
+// - Counters (|c): deltas since the previous flush (jobs, kafka, cache, rate limiting)
+// - Gauges (|g): HTTP requests in flight, consecutive Kafka fetch failures, Kafka consumer lag per topic and reader, active workers, in-flight jobs per type, scheduler batch size, PENDING jobs per type, cache hit ratio
+// - Timers (|ms): average processing time of jobs finished during the interval
+//
+// Metric names are prefixed with STATSD_PREFIX (default "parallelis.").
+
+// statsdMaxPacketSize keeps datagrams under a typical Ethernet MTU.
+const statsdMaxPacketSize = 1432
+
+// GetStatsDAddr returns the StatsD agent address from env, empty when disabled.
+func GetStatsDAddr() string {
+	return os.Getenv("STATSD_ADDR")
+}
+
+// GetStatsDPrefix returns the metric name prefix from env or default.
+func GetStatsDPrefix() string {
+	prefix, ok := os.LookupEnv("STATSD_PREFIX")
+	if !ok {
+		return "parallelis."
+	}
+	return prefix
+}
+
+// GetStatsDFlushInterval returns the flush interval from env or default.
+func GetStatsDFlushInterval() time.Duration {
+	val := os.Getenv("STATSD_FLUSH_INTERVAL")
+	if val == "" {
+		return 10 * time.Second
+	}
+	interval, err := time.ParseDuration(val)
+	if err != nil || interval <= 0 {
+		return 10 * time.Second
+	}
+	return interval
+}
+
+// statsdCounters is a point-in-time copy of the cumulative counters,
+// used to compute per-interval deltas.
+type statsdCounters struct {
+	values              map[string]int64
+	processingTimeSum   int64
+	processingTimeCount int64
+}
+
+// StatsDSink periodically pushes metric deltas to a StatsD agent.
+type StatsDSink struct {
+	conn     net.Conn
+	prefix   string
+	interval time.Duration
+	metrics  *Metrics
+	last     statsdCounters
+	stopCh   chan struct{}
+}
+
+// NewStatsDSink creates a sink sending the global metrics to the given address.
+func NewStatsDSink(addr string, prefix string, interval time.Duration) (*StatsDSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	m := GetMetrics()
+	return &StatsDSink{
+		conn:     conn,
+		prefix:   prefix,
+		interval: interval,
+		metrics:  m,
+		last:     m.statsdCounters(),
+		stopCh:   make(chan struct{}),
+	}, nil
+}
+
+// NewStatsDSinkFromEnv creates a sink from STATSD_* env vars.
+// Returns nil (and no error) when STATSD_ADDR is unset.
+func NewStatsDSinkFromEnv() (*StatsDSink, error) {
+	addr := GetStatsDAddr()
+	if addr == "" {
+		return nil, nil
+	}
+	return NewStatsDSink(addr, GetStatsDPrefix(), GetStatsDFlushInterval())
+}
+
+// Start begins flushing metrics in a goroutine.
+func (s *StatsDSink) Start() {
+	go func() {
+		log.Printf("StatsD sink started (addr: %s, flush interval: %v)", s.conn.RemoteAddr(), s.interval)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+				s.flush()
+			}
+		}
+	}()
+}
+
+// Stop flushes any pending deltas and closes the UDP socket.
+func (s *StatsDSink) Stop() {
+	close(s.stopCh)
+	s.flush()
+	if err := s.conn.Close(); err != nil {
+		log.Printf("Error closing StatsD connection: %v", err)
+	}
+}
+
+// flush sends one interval's worth of metrics. UDP is fire-and-forget,
+// so write errors are logged and the interval is dropped.
+func (s *StatsDSink) flush() {
+	current := s.metrics.statsdCounters()
+	lines := s.buildLines(current)
+	s.last = current
+
+	for _, packet := range packStatsDLines(lines) {
+		if _, err := s.conn.Write(packet); err != nil {
+			log.Printf("Error sending metrics to StatsD: %v", err)
+			return
+		}
+	}
+}
+
+// buildLines renders counters, gauges, and timers in StatsD line format.
+func (s *StatsDSink) buildLines(current statsdCounters) []string {
+	var lines []string
+
+	for _, name := range statsdCounterNames {
+		delta := current.values[name] - s.last.values[name]
+		lines = append(lines, fmt.Sprintf("%s%s:%d|c", s.prefix, name, delta))
+	}
+
+	lines = append(lines, fmt.Sprintf("%shttp.in_flight:%d|g", s.prefix, s.metrics.httpInFlight.Load()))
+	lines = append(lines, fmt.Sprintf("%skafka.consecutive_fetch_failures:%d|g", s.prefix, s.metrics.kafkaFetchFailures.Load()))
+	lags := s.metrics.kafkaConsumerLagSnapshot()
+	lagKeys := make([]kafkaLagKey, 0, len(lags))
+	for key := range lags {
+		lagKeys = append(lagKeys, key)
+	}
+	sort.Slice(lagKeys, func(i, j int) bool {
+		if lagKeys[i].topic != lagKeys[j].topic {
+			return lagKeys[i].topic < lagKeys[j].topic
+		}
+		return lagKeys[i].reader < lagKeys[j].reader
+	})
+	for _, key := range lagKeys {
+		lines = append(lines, fmt.Sprintf("%skafka.consumer_lag.%s.%s:%d|g", s.prefix, key.topic, key.reader, lags[key]))
+	}
+	lines = append(lines, fmt.Sprintf("%sworkers.active:%d|g", s.prefix, s.metrics.activeWorkers.Load()))
+	inFlight := s.metrics.jobsInFlightByType()
+	jobTypes := make([]string, 0, len(inFlight))
+	for jobType := range inFlight {
+		jobTypes = append(jobTypes, jobType)
+	}
+	sort.Strings(jobTypes)
+	for _, jobType := range jobTypes {
+		lines = append(lines, fmt.Sprintf("%sworkers.in_flight.%s:%d|g", s.prefix, strings.ToLower(jobType), inFlight[jobType]))
+	}
+	lines = append(lines, fmt.Sprintf("%sscheduler.batch_size:%d|g", s.prefix, s.metrics.schedulerBatchSize.Load()))
+	pending := s.metrics.pendingJobsByType()
+	pendingTypes := make([]string, 0, len(pending))
+	for jobType := range pending {
+		pendingTypes = append(pendingTypes, jobType)
+	}
+	sort.Strings(pendingTypes)
+	for _, jobType := range pendingTypes {
+		lines = append(lines, fmt.Sprintf("%sscheduler.pending.%s:%d|g", s.prefix, strings.ToLower(jobType), pending[jobType]))
+	}
+
+	hits := s.metrics.cacheHits.Load()
+	misses := s.metrics.cacheMisses.Load()
+	if hits+misses > 0 {
+		hitRatio := float64(hits) / float64(hits+misses) * 100
+		lines = append(lines, fmt.Sprintf("%scache.hit_ratio:%.2f|g", s.prefix, hitRatio))
+	}
+
+	processedCount := current.processingTimeCount - s.last.processingTimeCount
+	if processedCount > 0 {
+		avgMs := float64(current.processingTimeSum-s.last.processingTimeSum) / float64(processedCount) / 1000
+		lines = append(lines, fmt.Sprintf("%sjobs.processing_time:%.3f|ms", s.prefix, avgMs))
+	}
+
+	return lines
+}
+
+// statsdCounterNames fixes the order counters are emitted in.
+var statsdCounterNames = []string{
+	"jobs.created",
+	"jobs.completed",
+	"jobs.failed",
+	"jobs.dead_lettered",
+	"jobs.expired",
+	"jobs.retried",
+	"kafka.messages_produced",
+	"kafka.messages_consumed",
+	"kafka.produce_errors",
+	"http.shed_requests",
+	"workers.bulkhead_rejections",
+	"cache.hits",
+	"cache.misses",
+	"cache.write_failures",
+	"cache.fallback_hits",
+	"rate_limiting.rejections",
+}
+
+// statsdCounters snapshots the cumulative counters keyed by StatsD metric name.
+func (m *Metrics) statsdCounters() statsdCounters {
+	return statsdCounters{
+		values: map[string]int64{
+			"jobs.created":                m.jobsCreated.Load(),
+			"jobs.completed":              m.jobsCompleted.Load(),
+			"jobs.failed":                 m.jobsFailed.Load(),
+			"jobs.dead_lettered":          m.jobsDeadLettered.Load(),
+			"jobs.expired":                m.jobsExpired.Load(),
+			"jobs.retried":                m.jobsRetried.Load(),
+			"kafka.messages_produced":     m.kafkaMessagesProduced.Load(),
+			"kafka.messages_consumed":     m.kafkaMessagesConsumed.Load(),
+			"kafka.produce_errors":        m.kafkaProduceErrors.Load(),
+			"http.shed_requests":          m.httpShedRequests.Load(),
+			"workers.bulkhead_rejections": m.bulkheadRejections.Load(),
+			"cache.hits":                  m.cacheHits.Load(),
+			"cache.misses":                m.cacheMisses.Load(),
+			"cache.write_failures":        m.cacheWriteFailures.Load(),
+			"cache.fallback_hits":         m.cacheFallbackHits.Load(),
+			"rate_limiting.rejections":    m.rateLimitRejections.Load(),
+		},
+		processingTimeSum:   m.processingTimeSum.Load(),
+		processingTimeCount: m.processingTimeCount.Load(),
+	}
+}
+
+// packStatsDLines joins lines into newline-separated datagrams no larger than statsdMaxPacketSize.
+func packStatsDLines(lines []string) [][]byte {
+	var packets [][]byte
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > statsdMaxPacketSize {
+			packets = append(packets, append([]byte(nil), buf.Bytes()...))
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+	if buf.Len() > 0 {
+		packets = append(packets, buf.Bytes())
+	}
+	return packets
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStatsDSinkFlushSendsDeltas verifies counters are sent as per-interval deltas.
+func TestStatsDSinkFlushSendsDeltas(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+
+	sink, err := NewStatsDSink(listener.LocalAddr().String(), "test.", time.Hour)
+	if err != nil {
+		t.Fatalf("new sink: %v", err)
+	}
+	defer sink.conn.Close()
+
+	GetMetrics().IncJobsCreated()
+	GetMetrics().IncJobsCreated()
+	GetMetrics().IncCacheFallbackHit()
+	GetMetrics().SetKafkaConsumerLag("job-queue", "1", 7)
+	GetMetrics().SetKafkaConsumerLag("job-queue", "0", 42)
+	sink.flush()
+
+	got := readStatsDPacket(t, listener)
+	if !strings.Contains(got, "test.jobs.created:2|c") {
+		t.Fatalf("expected jobs.created delta of 2, got:\n%s", got)
+	}
+	if !strings.Contains(got, "test.cache.fallback_hits:1|c") {
+		t.Fatalf("expected cache.fallback_hits delta of 1, got:\n%s", got)
+	}
+	lag0 := strings.Index(got, "test.kafka.consumer_lag.job-queue.0:42|g")
+	lag1 := strings.Index(got, "test.kafka.consumer_lag.job-queue.1:7|g")
+	if lag0 < 0 || lag1 < 0 || lag0 > lag1 {
+		t.Fatalf("expected consumer lag gauges per reader in sorted order, got:\n%s", got)
+	}
+
+	// Nothing new since the last flush: the delta resets to zero
+	sink.flush()
+	if got := readStatsDPacket(t, listener); !strings.Contains(got, "test.jobs.created:0|c") {
+		t.Fatalf("expected jobs.created delta of 0, got:\n%s", got)
+	}
+}
+
+// TestPackStatsDLinesRespectsPacketSize verifies large flushes are split into MTU-sized datagrams.
+func TestPackStatsDLinesRespectsPacketSize(t *testing.T) {
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines, "parallelis.jobs.created:1|c")
+	}
+
+	packets := packStatsDLines(lines)
+	if len(packets) < 2 {
+		t.Fatalf("expected multiple packets, got %d", len(packets))
+	}
+	total := 0
+	for _, p := range packets {
+		if len(p) > statsdMaxPacketSize {
+			t.Fatalf("packet of %d bytes exceeds max %d", len(p), statsdMaxPacketSize)
+		}
+		total += strings.Count(string(p), "\n") + 1
+	}
+	if total != len(lines) {
+		t.Fatalf("expected %d lines across packets, got %d", len(lines), total)
+	}
+}
+
+func readStatsDPacket(t *testing.T, conn net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 64*1024)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read packet: %v", err)
+	}
+	return string(buf[:n])
+}
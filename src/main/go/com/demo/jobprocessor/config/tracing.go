@@ -0,0 +1,151 @@
+package config
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenTelemetry tracing across the job lifecycle.
+//
+// One trace covers a job from submission to completion:
+// - job.create: JobService.CreateJob, a child of the submitting request's traceparent if it
+//   has one (see ExtractHTTPTraceContext), else the root span; its context is stored on the job row
+// - job.schedule: JobScheduler publishes the job, injecting the context into the Kafka headers
+// - job.process: JobWorker extracts it and adds cache.lookup, job.handle, and db.save children
+//
+// Disabled by default; spans are exported over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT
+// is set (e.g. "http://localhost:4318"). The exporter also honours the other standard
+// OTEL_EXPORTER_OTLP_* variables. The service name comes from OTEL_SERVICE_NAME (default
+// "parallelis"). Trace context propagation is always on, so traces started upstream pass
+// through even when this process doesn't export.
+
+// tracerName identifies this application's spans.
+const tracerName = "distributed-job-processor"
+
+// GetOTLPEndpoint returns the OTLP collector endpoint from env, empty when tracing is disabled.
+func GetOTLPEndpoint() string {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+}
+
+// GetOTelServiceName returns the service name reported on spans from env or default.
+func GetOTelServiceName() string {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		return name
+	}
+	return "parallelis"
+}
+
+// InitTracing installs the global tracer provider and W3C trace context propagator.
+// The returned function flushes pending spans and must be called on shutdown.
+// Without OTEL_EXPORTER_OTLP_ENDPOINT spans are not recorded and shutdown is a no-op.
+func InitTracing(c context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	endpoint := GetOTLPEndpoint()
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(c)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(GetOTelServiceName()),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	log.Printf("Tracing enabled (OTLP endpoint: %s, service: %s)", endpoint, GetOTelServiceName())
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the application tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// KafkaHeaderCarrier adapts Kafka message headers to a propagation.TextMapCarrier.
+type KafkaHeaderCarrier struct {
+	Headers *[]kafka.Header
+}
+
+// Get returns the value of the first header with the key, empty if absent.
+func (k KafkaHeaderCarrier) Get(key string) string {
+	for _, header := range *k.Headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces the value of the header with the key, or adds it.
+func (k KafkaHeaderCarrier) Set(key string, value string) {
+	for i, header := range *k.Headers {
+		if header.Key == key {
+			(*k.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*k.Headers = append(*k.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+// Keys returns the header keys.
+func (k KafkaHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*k.Headers))
+	for _, header := range *k.Headers {
+		keys = append(keys, header.Key)
+	}
+	return keys
+}
+
+// InjectTraceHeaders adds the trace context of c to the message headers.
+func InjectTraceHeaders(c context.Context, headers *[]kafka.Header) {
+	otel.GetTextMapPropagator().Inject(c, KafkaHeaderCarrier{Headers: headers})
+}
+
+// ExtractTraceContext returns c carrying the trace context found in the message headers.
+func ExtractTraceContext(c context.Context, headers []kafka.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(c, KafkaHeaderCarrier{Headers: &headers})
+}
+
+// ExtractHTTPTraceContext returns the request's context carrying the trace context found
+// in its headers (traceparent), so a trace started upstream continues into the job.
+func ExtractHTTPTraceContext(r *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+}
+
+// InjectTraceParent returns the W3C traceparent of c's span, empty if there is none.
+// Used to persist a trace across the scheduler's database hop.
+func InjectTraceParent(c context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(c, carrier)
+	return carrier.Get("traceparent")
+}
+
+// ExtractTraceParent returns c carrying the span context of a stored traceparent.
+func ExtractTraceParent(c context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return c
+	}
+	return propagation.TraceContext{}.Extract(c, propagation.MapCarrier{"traceparent": traceParent})
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestTraceContextRoundTripsThroughKafkaHeaders verifies the worker continues the
+// scheduler's trace, through both the stored traceparent and the message headers.
+func TestTraceContextRoundTripsThroughKafkaHeaders(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	if _, err := InitTracing(context.Background()); err != nil {
+		t.Fatalf("init tracing: %v", err)
+	}
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+
+	createCtx, createSpan := tracer.Start(context.Background(), "job.create")
+	defer createSpan.End()
+	traceParent := InjectTraceParent(createCtx)
+	if traceParent == "" {
+		t.Fatal("expected a traceparent for a recording span")
+	}
+
+	// Scheduler: continue from the stored traceparent, publish with trace headers
+	scheduleCtx, scheduleSpan := tracer.Start(ExtractTraceParent(context.Background(), traceParent), "job.schedule")
+	defer scheduleSpan.End()
+	headers := []kafka.Header{{Key: JobTypeHeader, Value: []byte("PAYMENT_PROCESS")}}
+	InjectTraceHeaders(scheduleCtx, &headers)
+
+	if got := (KafkaHeaderCarrier{Headers: &headers}).Get(JobTypeHeader); got != "PAYMENT_PROCESS" {
+		t.Fatalf("expected the type header to be kept, got %q", got)
+	}
+
+	// Worker: the extracted remote span is the scheduler's span in the same trace
+	remote := trace.SpanContextFromContext(ExtractTraceContext(context.Background(), headers))
+	if !remote.IsValid() || !remote.IsRemote() {
+		t.Fatalf("expected a valid remote span context, got %+v", remote)
+	}
+	if remote.TraceID() != createSpan.SpanContext().TraceID() {
+		t.Fatalf("expected trace %s, got %s", createSpan.SpanContext().TraceID(), remote.TraceID())
+	}
+	if remote.SpanID() != scheduleSpan.SpanContext().SpanID() {
+		t.Fatalf("expected parent span %s, got %s", scheduleSpan.SpanContext().SpanID(), remote.SpanID())
+	}
+
+	// Messages from before tracing carry no context
+	if untraced := trace.SpanContextFromContext(ExtractTraceContext(context.Background(), nil)); untraced.IsValid() {
+		t.Fatalf("expected no span context without trace headers, got %+v", untraced)
+	}
+}
+
+// TestKafkaHeaderCarrierSetReplacesExistingKey verifies re-injecting doesn't duplicate headers.
+func TestKafkaHeaderCarrierSetReplacesExistingKey(t *testing.T) {
+	headers := []kafka.Header{{Key: "traceparent", Value: []byte("old")}}
+	carrier := KafkaHeaderCarrier{Headers: &headers}
+
+	carrier.Set("traceparent", "new")
+	carrier.Set("tracestate", "vendor=1")
+
+	if len(headers) != 2 {
+		t.Fatalf("expected 2 headers, got %d: %v", len(headers), carrier.Keys())
+	}
+	if got := carrier.Get("traceparent"); got != "new" {
+		t.Fatalf("expected traceparent to be replaced, got %q", got)
+	}
+}
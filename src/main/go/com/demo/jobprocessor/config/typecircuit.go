@@ -0,0 +1,48 @@
+package config
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Type circuit breakers (see service.TypeCircuitBreaker) are exposed per job type on
+// GET /metrics:
+// - type_circuit_state: 0 closed, 1 open, 2 half-open, as last seen by this process
+// - type_circuit_short_circuits_total: attempts deferred by this process
+//   without calling the handler while the breaker was open
+
+// Type circuit breaker states, as the values of the state gauge.
+const (
+	TypeCircuitClosed   = 0
+	TypeCircuitOpen     = 1
+	TypeCircuitHalfOpen = 2
+)
+
+// typeCircuitState is the state of each job type's circuit breaker.
+var typeCircuitState = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: prometheusNamespace,
+		Name:      "type_circuit_state",
+		Help:      "Type circuit breaker state by job type: 0 closed, 1 open, 2 half-open.",
+	},
+	[]string{"type"},
+)
+
+// typeCircuitShortCircuits counts attempts deferred without calling the handler, by job type.
+var typeCircuitShortCircuits = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Name:      "type_circuit_short_circuits_total",
+		Help:      "Job attempts deferred without calling the handler because their type's circuit breaker was open, by job type.",
+	},
+	[]string{"type"},
+)
+
+// SetTypeCircuitState records the state of the type's circuit breaker.
+func (m *Metrics) SetTypeCircuitState(jobType string, state int) {
+	typeCircuitState.WithLabelValues(jobType).Set(float64(state))
+}
+
+// IncTypeCircuitShortCircuit counts an attempt of the type deferred by an open breaker.
+func (m *Metrics) IncTypeCircuitShortCircuit(jobType string) {
+	typeCircuitShortCircuits.WithLabelValues(jobType).Inc()
+}
@@ -0,0 +1,499 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/dto"
+	"distributed-job-processor/exception"
+	"distributed-job-processor/model"
+	"distributed-job-processor/service"
+)
+
+// AdminController handles operator endpoints for managing the system.
+//
+// Endpoints (mounted at /api/admin, all require X-Admin-Key):
+// - GET /api/admin/audit?action={action}&limit={n} - Review the audit log
+// - DELETE /api/admin/cache/jobs - Clear all cached jobs
+// - DELETE /api/admin/cache/clients/:clientId - Clear one client's cached jobs
+// - DELETE /api/admin/rate-limits/:clientId - Reset a client's rate limit
+// - POST /api/admin/jobs/replay-range - Re-run completed jobs of a type in a time window
+// - GET /api/admin/dead-letters/reasons - Count dead-lettered jobs by failure reason
+// - POST /api/admin/dead-letters/replay - Requeue dead-lettered jobs in bulk, optionally of one type
+// - GET /api/admin/retry-rules - Show the effective retry classification rules
+// - GET /api/admin/partitions/paused - List paused Kafka partitions
+// - POST /api/admin/partitions/pause - Stop workers processing one Kafka partition
+// - POST /api/admin/partitions/resume - Resume a paused Kafka partition
+// - GET /api/admin/jobs/report?date={yyyy-mm-dd}&tz={zone}&format=csv - Per-type outcomes of one day
+// - GET /api/admin/sla/breaches?type={type}&limit={n} - List jobs that recently breached their SLA
+//
+// Every mutation is recorded in the audit log with the admin's identity.
+type AdminController struct {
+	jobService       *service.JobService
+	cacheService     *service.CacheService
+	rateLimitService *service.RateLimitService
+	auditService     *service.AuditService
+	retryClassifier  *service.RetryClassifier
+	partitionPauses  *service.PartitionPauseService
+	slas             service.JobSLAs
+}
+
+// NewAdminController creates a new AdminController with the given services.
+func NewAdminController(jobService *service.JobService, cacheService *service.CacheService, rateLimitService *service.RateLimitService, auditService *service.AuditService) *AdminController {
+	return &AdminController{
+		jobService:       jobService,
+		cacheService:     cacheService,
+		rateLimitService: rateLimitService,
+		auditService:     auditService,
+		retryClassifier:  service.NewRetryClassifierFromEnv(),
+		slas:             service.NewJobSLAsFromEnv(),
+	}
+}
+
+// SetPartitionPauseService enables the partition pause endpoints.
+// Without it they answer 503.
+func (ac *AdminController) SetPartitionPauseService(pauses *service.PartitionPauseService) {
+	ac.partitionPauses = pauses
+}
+
+// RegisterRoutes registers all admin routes, behind admin authentication.
+func (ac *AdminController) RegisterRoutes(r *gin.RouterGroup) {
+	r.Use(config.AdminAuthMiddleware())
+	r.GET("/audit", ac.GetAuditLog)
+	r.DELETE("/cache/jobs", ac.ClearJobCache)
+	r.DELETE("/cache/clients/:clientId", ac.ClearClientCache)
+	r.DELETE("/rate-limits/:clientId", ac.ResetRateLimit)
+	r.POST("/jobs/replay-range", ac.ReplayRange)
+	r.GET("/dead-letters/reasons", ac.GetDeadLetterReasons)
+	r.POST("/dead-letters/replay", ac.ReplayDeadLetter)
+	r.GET("/retry-rules", ac.GetRetryRules)
+	r.GET("/partitions/paused", ac.ListPausedPartitions)
+	r.POST("/partitions/pause", ac.PausePartition)
+	r.POST("/partitions/resume", ac.ResumePartition)
+	r.GET("/jobs/report", ac.GetDailyReport)
+	r.GET("/sla/breaches", ac.GetSLABreaches)
+}
+
+// GetAuditLog returns recent admin actions, newest first.
+//
+// Query parameters:
+// - action: only entries for this action (optional)
+// - limit: max entries to return (default 100, max 1000)
+//
+// Example request:
+// GET /api/admin/audit?action=rate_limit.reset&limit=20
+func (ac *AdminController) GetAuditLog(c *gin.Context) {
+	limit := 100
+	if val := c.Query("limit"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			exception.HandleBadRequest(c, "limit must be between 1 and 1000")
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := ac.auditService.GetRecent(c.Query("action"), limit)
+	if err != nil {
+		log.Printf("Failed to read audit log: %v", err)
+		exception.HandleInternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// ClearJobCache removes every cached job from Redis.
+//
+// Example request:
+// DELETE /api/admin/cache/jobs
+func (ac *AdminController) ClearJobCache(c *gin.Context) {
+	ac.cacheService.ClearAllJobCaches()
+	ac.audit(c, "cache.clear", "", nil)
+
+	c.JSON(http.StatusOK, gin.H{"status": "cleared"})
+}
+
+// ClearClientCache removes every cached job of one client from Redis, e.g. when the
+// client is offboarded.
+//
+// Example request:
+// DELETE /api/admin/cache/clients/customer-12345
+func (ac *AdminController) ClearClientCache(c *gin.Context) {
+	clientID := c.Param("clientId")
+	cleared := ac.cacheService.InvalidateClient(clientID)
+	ac.audit(c, "cache.clear_client", clientID, map[string]interface{}{"cleared": cleared})
+
+	c.JSON(http.StatusOK, gin.H{"status": "cleared", "clientId": clientID, "cleared": cleared})
+}
+
+// ResetRateLimit resets a client's rate limit bucket.
+//
+// Example request:
+// DELETE /api/admin/rate-limits/customer-12345
+func (ac *AdminController) ResetRateLimit(c *gin.Context) {
+	clientID := c.Param("clientId")
+	ac.rateLimitService.ResetRateLimit(clientID)
+	ac.audit(c, "rate_limit.reset", clientID, nil)
+
+	c.JSON(http.StatusOK, gin.H{"status": "reset", "clientId": clientID})
+}
+
+// ReplayRange clones COMPLETED jobs of a type completed in [from, to) into new PENDING jobs.
+//
+// For disaster recovery, e.g. a downstream silently dropped emails for an hour.
+// The originals are left untouched; replays are spread over spreadSeconds.
+// Requires "confirm": true.
+//
+// Example request:
+// POST /api/admin/jobs/replay-range
+// Body: {
+//   "type": "EMAIL_CONFIRMATION",
+//   "from": "2024-01-15T10:00:00Z",
+//   "to": "2024-01-15T11:00:00Z",
+//   "confirm": true
+// }
+func (ac *AdminController) ReplayRange(c *gin.Context) {
+	var request dto.ReplayRangeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		exception.HandleInvalidInput(c, err)
+		return
+	}
+	if _, ok := model.LookupJobTypeSpec(request.Type); !ok {
+		exception.HandleBadRequest(c, "Unknown job type: "+string(request.Type))
+		return
+	}
+	if !request.To.After(request.From) {
+		exception.HandleBadRequest(c, "to must be after from")
+		return
+	}
+	if !request.Confirm {
+		exception.HandleBadRequest(c, "Replaying jobs requires \"confirm\": true")
+		return
+	}
+
+	spread := service.DefaultReplaySpread()
+	if request.SpreadSeconds != nil {
+		spread = time.Duration(*request.SpreadSeconds) * time.Second
+	}
+
+	replayed, err := ac.jobService.ReplayCompletedJobs(request.Type, request.From, request.To, spread)
+	ac.audit(c, "jobs.replay_range", string(request.Type), map[string]interface{}{
+		"from":     request.From,
+		"to":       request.To,
+		"spread":   spread.String(),
+		"replayed": replayed,
+	})
+	if err != nil {
+		log.Printf("Replay of %s jobs failed after %d replays: %v", request.Type, replayed, err)
+		exception.HandleInternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed})
+}
+
+// audit records an admin mutation against the authenticated admin.
+// The action has already happened, so a failed write is logged, not returned to the caller.
+func (ac *AdminController) audit(c *gin.Context, action string, target string, params map[string]interface{}) {
+	actor, _ := config.GetAdminIdentity(c)
+	if err := ac.auditService.Record(actor, action, target, params); err != nil {
+		log.Printf("Admin action %s by %s was not persisted to the audit log: %v", action, actor, err)
+	}
+}
+
+// GetDeadLetterReasons counts dead-lettered jobs by failure reason.
+//
+// Example response:
+// {
+//   "total": 42,
+//   "reasons": {"timeout": 30, "declined": 9, "invalid_payload": 0, "downstream_5xx": 2, "needs_reconciliation": 0, "unknown": 1}
+// }
+func (ac *AdminController) GetDeadLetterReasons(c *gin.Context) {
+	reasons, err := ac.jobService.CountDeadLettersByReason()
+	if err != nil {
+		exception.HandleInternalError(c)
+		return
+	}
+
+	var total int64
+	for _, count := range reasons {
+		total += count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":   total,
+		"reasons": reasons,
+	})
+}
+
+// ReplayDeadLetter requeues dead-lettered jobs in bulk, e.g. once a downstream outage
+// is over, resetting them to PENDING with attempts 0 in a single transaction.
+// Oldest dead letters go first.
+//
+// Optional body fields: type (only replay that type) and limit (default and max
+// MAX_REPLAY_BATCH). Returns the number of jobs replayed.
+//
+// Example request:
+// POST /api/admin/dead-letters/replay
+// Body: {"type": "PAYMENT_PROCESS", "limit": 200}
+func (ac *AdminController) ReplayDeadLetter(c *gin.Context) {
+	var request dto.DeadLetterReplayRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		exception.HandleInvalidInput(c, err)
+		return
+	}
+
+	var jobType *model.JobType
+	if request.Type != nil {
+		parsed := model.JobType(strings.ToUpper(string(*request.Type)))
+		if _, known := model.LookupJobTypeSpec(parsed); !known {
+			exception.HandleBadRequest(c, "Unknown job type: "+string(*request.Type))
+			return
+		}
+		jobType = &parsed
+	}
+
+	maxBatch := ac.jobService.MaxReplayBatch()
+	if request.Limit > maxBatch {
+		exception.HandleBadRequest(c, fmt.Sprintf("limit must be at most %d", maxBatch))
+		return
+	}
+
+	replayed, err := ac.jobService.ReplayDeadLetter(jobType, request.Limit)
+	target := ""
+	if jobType != nil {
+		target = string(*jobType)
+	}
+	ac.audit(c, "dead_letters.replay", target, map[string]interface{}{
+		"limit":    request.Limit,
+		"replayed": replayed,
+	})
+	if err != nil {
+		exception.HandleInternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed})
+}
+
+// GetSLABreaches lists jobs that completed past their type's SLA (SLA_<TYPE>),
+// most recently completed first, with the configured SLAs.
+//
+// Query parameters:
+// - type: only jobs of this type (optional)
+// - limit: max jobs to return (default 50, max 500)
+//
+// Example response:
+// {
+//   "slaSeconds": {"EMAIL_CONFIRMATION": 60},
+//   "breaches": [{"jobId": "...", "type": "EMAIL_CONFIRMATION", "slaBreached": true, ...}]
+// }
+func (ac *AdminController) GetSLABreaches(c *gin.Context) {
+	var jobType *model.JobType
+	if val := c.Query("type"); val != "" {
+		parsed := model.JobType(strings.ToUpper(val))
+		if _, known := model.LookupJobTypeSpec(parsed); !known {
+			exception.HandleBadRequest(c, "Unknown job type: "+val)
+			return
+		}
+		jobType = &parsed
+	}
+
+	limit := 50
+	if val := c.Query("limit"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil || parsed < 1 || parsed > 500 {
+			exception.HandleBadRequest(c, "limit must be between 1 and 500")
+			return
+		}
+		limit = parsed
+	}
+
+	jobs, err := ac.jobService.GetSLABreaches(jobType, limit)
+	if err != nil {
+		log.Printf("Failed to read SLA breaches: %v", err)
+		exception.HandleInternalError(c)
+		return
+	}
+
+	breaches := make([]dto.JobResponse, 0, len(jobs))
+	for i := range jobs {
+		breaches = append(breaches, dto.JobResponseFrom(&jobs[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"slaSeconds": ac.slas.Seconds(),
+		"breaches":   breaches,
+	})
+}
+
+// GetRetryRules returns the retry classification rules workers apply to failed attempts,
+// in the order they are tried. Errors matching no rule are retried.
+//
+// Example response:
+// {
+//   "rules": [
+//     {"substring": "connection reset", "classification": "retryable"},
+//     {"substring": "invalid card", "classification": "permanent"}
+//   ],
+//   "default": "retryable"
+// }
+func (ac *AdminController) GetRetryRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"rules":   ac.retryClassifier.Rules(),
+		"default": service.RetryClassRetryable,
+	})
+}
+
+// ListPausedPartitions returns the paused Kafka partitions.
+//
+// Example response:
+// {"paused": [{"topic": "job-queue", "partition": 3, "pausedAt": "2024-01-15T10:00:00Z"}]}
+func (ac *AdminController) ListPausedPartitions(c *gin.Context) {
+	if ac.partitionPauses == nil {
+		exception.HandleServiceUnavailable(c, "Partition pausing is not configured")
+		return
+	}
+	paused, err := ac.partitionPauses.List()
+	if err != nil {
+		log.Printf("Failed to list paused partitions: %v", err)
+		exception.HandleInternalError(c)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"paused": paused})
+}
+
+// PausePartition stops workers processing one Kafka partition while the others keep running,
+// e.g. while its jobs wedge their consumer. Workers apply it within PARTITION_PAUSE_REFRESH.
+//
+// Messages of the paused partition are held uncommitted by the workers that fetch them;
+// if a worker stops or the partition is rebalanced meanwhile, they are redelivered, so
+// a job may run twice (see JobWorker).
+//
+// Example request:
+// POST /api/admin/partitions/pause
+// Body: {"topic": "job-queue", "partition": 3}
+func (ac *AdminController) PausePartition(c *gin.Context) {
+	topic, partition, ok := ac.bindPartition(c)
+	if !ok {
+		return
+	}
+	if err := ac.partitionPauses.Pause(topic, partition); err != nil {
+		log.Printf("Failed to pause partition %s/%d: %v", topic, partition, err)
+		exception.HandleInternalError(c)
+		return
+	}
+	ac.audit(c, "partition.pause", fmt.Sprintf("%s/%d", topic, partition), nil)
+
+	c.JSON(http.StatusOK, gin.H{"status": "paused", "topic": topic, "partition": partition})
+}
+
+// ResumePartition resumes a paused Kafka partition. Workers first process the messages
+// they parked meanwhile, in offset order. Returns 404 if the partition isn't paused.
+//
+// Example request:
+// POST /api/admin/partitions/resume
+// Body: {"topic": "job-queue", "partition": 3}
+func (ac *AdminController) ResumePartition(c *gin.Context) {
+	topic, partition, ok := ac.bindPartition(c)
+	if !ok {
+		return
+	}
+	resumed, err := ac.partitionPauses.Resume(topic, partition)
+	if err != nil {
+		log.Printf("Failed to resume partition %s/%d: %v", topic, partition, err)
+		exception.HandleInternalError(c)
+		return
+	}
+	if !resumed {
+		c.JSON(http.StatusNotFound, exception.NewErrorResponse(
+			http.StatusNotFound,
+			"Not Found",
+			fmt.Sprintf("Partition %s/%d is not paused", topic, partition),
+		))
+		return
+	}
+	ac.audit(c, "partition.resume", fmt.Sprintf("%s/%d", topic, partition), nil)
+
+	c.JSON(http.StatusOK, gin.H{"status": "resumed", "topic": topic, "partition": partition})
+}
+
+// bindPartition reads a PartitionRequest, writing the error response and returning
+// false if it is invalid or partition pausing isn't configured.
+func (ac *AdminController) bindPartition(c *gin.Context) (string, int, bool) {
+	if ac.partitionPauses == nil {
+		exception.HandleServiceUnavailable(c, "Partition pausing is not configured")
+		return "", 0, false
+	}
+	var request dto.PartitionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		exception.HandleInvalidInput(c, err)
+		return "", 0, false
+	}
+	topic := request.Topic
+	if topic == "" {
+		topic = config.GetJobQueueTopic()
+	}
+	return topic, *request.Partition, true
+}
+
+// GetDailyReport returns per-type counts of jobs completed, failed, and dead-lettered
+// on one calendar day, for daily reconciliation.
+//
+// Query parameters:
+// - date: the day, yyyy-mm-dd (required)
+// - tz: IANA timezone the day is taken in (default REPORT_TIMEZONE, else UTC)
+// - format: json (default) or csv
+//
+// Example request:
+// GET /api/admin/jobs/report?date=2024-01-15&tz=America/New_York&format=csv
+func (ac *AdminController) GetDailyReport(c *gin.Context) {
+	loc := ac.jobService.ReportLocation()
+	if tz := c.Query("tz"); tz != "" {
+		parsed, err := time.LoadLocation(tz)
+		if err != nil {
+			exception.HandleBadRequest(c, "Unknown timezone: "+tz)
+			return
+		}
+		loc = parsed
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", c.Query("date"), loc)
+	if err != nil {
+		exception.HandleBadRequest(c, "date must be given as yyyy-mm-dd")
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		exception.HandleBadRequest(c, "format must be json or csv")
+		return
+	}
+
+	report, err := ac.jobService.GetDailyReport(date, loc)
+	if err != nil {
+		exception.HandleInternalError(c)
+		return
+	}
+
+	if format == "csv" {
+		c.Header("Content-Disposition", `attachment; filename="jobs-`+report.Date+`.csv"`)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := report.WriteCSV(c.Writer); err != nil {
+			log.Printf("Failed to write daily report CSV: %v", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -1,15 +1,23 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"distributed-job-processor/buildinfo"
+	"distributed-job-processor/config"
 	"distributed-job-processor/dto"
+	"distributed-job-processor/exception"
 	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
 	"distributed-job-processor/service"
 )
 
@@ -17,34 +25,90 @@ import (
 //
 // Endpoints:
 // - POST /api/jobs - Create a new job (returns 202 Accepted)
+// - POST /api/jobs/batch - Create up to 500 jobs in one call (202; 207 if some are refused, 422 if an atomic batch is)
 // - GET /api/jobs/:id - Get job status by ID
-// - GET /api/jobs?clientId={id} - Get all jobs for a client
+// - GET /api/jobs/:id/attempts - Get the job's processing attempts, oldest first
+// - POST /api/jobs/:id/retry - Requeue a DEAD_LETTER or FAILED job
+// - POST /api/jobs/:id/compensate - Enqueue the job undoing a COMPLETED one (a payment's refund)
+// - GET /api/jobs?clientId={id}&label.{key}={value}&status={status}&page={n}&size={n} - Page through the client's jobs, optionally with labels
+// - GET /api/jobs/search?orderId={prefix}&payloadContains={text}&from={time}&to={time} - Search the client's jobs
+// - GET /api/jobs/dead-letter?type={type}&since={time}&page={n}&size={n} - Page through the client's dead-lettered jobs
 // - GET /api/jobs/stats - Get system statistics
+// - GET /api/jobs/types - List supported job types and their payload schemas
+// - GET /api/jobs/health - Liveness probe
+// - GET /api/jobs/ready - Readiness probe (Postgres, Redis, Kafka)
 //
 // Features:
 // - Rate limiting: 100 requests/minute per client (via Redis)
-// - Input validation
+// - API-key authentication of every client-scoped endpoint (API_KEY_AUTH=true, see SetAuthMiddleware)
+// - Client scoping: a client only sees and acts on its own jobs; another client's job is 404
+// - Input validation (payload checked against the job type's schema)
 // - Error handling
 type JobController struct {
-	jobService      *service.JobService
+	jobService       *service.JobService
 	rateLimitService *service.RateLimitService
+	dbBreaker        *repository.DBCircuitBreaker
+	readiness        *service.ReadinessChecker
+	partitionPauses  *service.PartitionPauseService
+	typeBreaker      *service.TypeCircuitBreaker
+	authMiddleware   gin.HandlerFunc
+
+	// Largest accepted POST /api/jobs body, see config.GetMaxJobRequestBytes;
+	// POST /api/jobs/batch accepts MaxJobBatchSize times as much
+	maxRequestBytes int64
 }
 
 // NewJobController creates a new JobController with the given services.
 func NewJobController(jobService *service.JobService, rateLimitService *service.RateLimitService) *JobController {
 	return &JobController{
-		jobService:      jobService,
+		jobService:       jobService,
 		rateLimitService: rateLimitService,
+		maxRequestBytes:  config.GetMaxJobRequestBytes(),
 	}
 }
 
+// SetDBCircuitBreaker reports the breaker's state in the health check.
+func (jc *JobController) SetDBCircuitBreaker(breaker *repository.DBCircuitBreaker) {
+	jc.dbBreaker = breaker
+}
+
+// SetReadinessChecker sets the dependency checks behind the readiness probe.
+func (jc *JobController) SetReadinessChecker(checker *service.ReadinessChecker) {
+	jc.readiness = checker
+}
+
+// SetPartitionPauseService reports the paused Kafka partitions in the health check.
+func (jc *JobController) SetPartitionPauseService(pauses *service.PartitionPauseService) {
+	jc.partitionPauses = pauses
+}
+
+// SetTypeCircuitBreaker reports the job types with an open breaker in the health check.
+func (jc *JobController) SetTypeCircuitBreaker(breaker *service.TypeCircuitBreaker) {
+	jc.typeBreaker = breaker
+}
+
+// SetAuthMiddleware requires the middleware (config.AuthMiddleware) to pass on every
+// client-scoped route, which then acts for the authenticated client ID instead of the
+// X-Client-Id header. Call before RegisterRoutes, when config.GetAPIKeyAuthEnabled.
+func (jc *JobController) SetAuthMiddleware(middleware gin.HandlerFunc) {
+	jc.authMiddleware = middleware
+}
+
 // RegisterRoutes registers all job-related routes with the Gin router.
 func (jc *JobController) RegisterRoutes(r *gin.RouterGroup) {
-	r.POST("", jc.CreateJob)
+	r.POST("", jc.clientRoute(jc.CreateJob)...)
+	r.POST("/batch", jc.clientRoute(jc.CreateJobBatch)...)
+	r.GET("/dead-letter", jc.clientRoute(jc.ListDeadLetter)...)
+	r.GET("/search", jc.clientRoute(jc.SearchJobs)...)
 	r.GET("/stats", jc.GetStats)
+	r.GET("/types", jc.GetJobTypes)
 	r.GET("/health", jc.Health)
-	r.GET("/:id", jc.GetJob)
-	r.GET("", jc.GetJobsByClient)
+	r.GET("/ready", jc.Ready)
+	r.GET("/:id", jc.clientRoute(jc.GetJob)...)
+	r.GET("/:id/attempts", jc.clientRoute(jc.GetJobAttempts)...)
+	r.POST("/:id/retry", jc.clientRoute(jc.RetryJob)...)
+	r.POST("/:id/compensate", jc.clientRoute(jc.CompensateJob)...)
+	r.GET("", jc.clientRoute(jc.GetJobsByClient)...)
 }
 
 // CreateJob creates a new order processing job.
@@ -58,6 +122,18 @@ func (jc *JobController) RegisterRoutes(r *gin.RouterGroup) {
 // - Enforced via Redis token bucket
 // - Returns 429 Too Many Requests if exceeded
 //
+// Load shedding (SHED_WHEN_CIRCUIT_OPEN_<TYPE>=true, see service.TypeCircuitBreaker):
+// - Returns 503 with Retry-After while the type's downstream breaker is open
+//
+// Size limits:
+// - Returns 413 Payload Too Large for a payload over MAX_PAYLOAD_BYTES (default 8KB)
+// - The body is capped before binding, so an oversized request is never read whole
+//
+// Authentication (API_KEY_AUTH=true):
+// - Returns 401 without a valid Authorization: Bearer <apiKey> header
+// - The job belongs to the key's client; X-Client-Id may be omitted, and a
+//   different X-Client-Id returns 403
+//
 // Example request:
 // POST /api/jobs
 // Headers: X-Client-Id: customer-12345
@@ -66,69 +142,262 @@ func (jc *JobController) RegisterRoutes(r *gin.RouterGroup) {
 //   "payload": "order_ORD123|user@email.com|$99.99"
 // }
 func (jc *JobController) CreateJob(c *gin.Context) {
-	clientID := c.GetHeader("X-Client-Id")
-	if clientID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Client-Id header is required"})
+	clientID, ok := jc.requestClientID(c)
+	if !ok {
+		return
+	}
+
+	if !limitRequestBody(c, jc.maxRequestBytes) {
 		return
 	}
 
 	var request dto.JobRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		handleBindError(c, err)
 		return
 	}
 
-	log.Printf("Received job creation request: clientId=%s, type=%s", clientID, request.Type)
+	logger := config.Logger().With(config.LogKeyClientID, clientID)
+	logger.Info("Received job creation request", "type", request.Type)
 
 	// Rate limiting check
-	if !jc.rateLimitService.IsAllowed(clientID) {
-		remaining := jc.rateLimitService.GetRemainingRequests(clientID)
-		log.Printf("Rate limit exceeded for client: %s, remaining: %d", clientID, remaining)
+	rateLimitKey := jc.rateLimitKey(c, clientID)
+	if !jc.rateLimitService.IsAllowed(rateLimitKey) {
+		remaining := jc.rateLimitService.GetRemainingRequests(rateLimitKey)
+		logger.Warn("Rate limit exceeded", "rate_limit_key", rateLimitKey, "remaining", remaining)
 
 		c.Header("X-RateLimit-Limit", "100")
 		c.Header("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
-		c.JSON(http.StatusTooManyRequests, nil)
+		exception.HandleRateLimited(c, jc.rateLimitService.GetSecondsUntilReset(rateLimitKey))
 		return
 	}
 
-	job, err := jc.jobService.CreateJob(clientID, &request)
+	job, err := jc.jobService.CreateJob(config.ExtractHTTPTraceContext(c.Request), clientID, &request)
 	if err != nil {
-		log.Printf("Failed to create job: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create job"})
+		if ve, ok := err.(*exception.PayloadValidationError); ok {
+			exception.HandlePayloadValidation(c, ve)
+			return
+		}
+		if sizeErr, ok := err.(*exception.PayloadTooLargeError); ok {
+			exception.HandlePayloadTooLarge(c, sizeErr)
+			return
+		}
+		if exception.IsJobRejectedError(err) {
+			exception.HandleJobRejected(c, err.Error())
+			return
+		}
+		if dupErr, ok := err.(*exception.DuplicateJobError); ok {
+			exception.HandleDuplicateJob(c, dupErr)
+			return
+		}
+		if shedErr, ok := err.(*exception.JobTypeUnavailableError); ok {
+			logger.Warn("Job type shed", "type", shedErr.Type)
+			exception.HandleJobTypeUnavailable(c, shedErr)
+			return
+		}
+		logger.Error("Failed to create job", "error", err)
+		exception.HandleInternalError(c)
 		return
 	}
 
 	response := dto.JobResponseFrom(job)
-	remaining := jc.rateLimitService.GetRemainingRequests(clientID)
+	remaining := jc.rateLimitService.GetRemainingRequests(rateLimitKey)
 
-	log.Printf("Job created: jobId=%s, status=%s, remaining requests: %d",
-		job.ID, job.Status, remaining)
+	logger.Info("Job created", config.LogKeyJobID, job.ID.String(), "status", job.Status, "remaining_requests", remaining)
 
 	c.Header("X-RateLimit-Limit", "100")
 	c.Header("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
 	c.JSON(http.StatusAccepted, response)
 }
 
+// CreateJobBatch creates many jobs in one call, e.g. for a bulk import.
+//
+// Every item is validated on its own and reported in "items" with its own status code
+// (see dto.JobBatchResponse); valid items are saved together in one transaction.
+//
+// Partial mode (best effort per item, the default):
+// - 202 when every item was created
+// - 207 Multi-Status when some were refused: the valid items were created, so the
+//   client retries only the items with an error status
+//
+// Atomic mode ("mode": "atomic", or the default for types with JOB_BATCH_ATOMIC_<TYPE>=true):
+// - 202 when every item was created
+// - 422 when any item was refused: nothing was created, valid items report 424
+//
+// Either mode responds 500 (nothing saved) if the insert fails.
+//
+// Rate limiting counts each job in the batch as one request, so a batch larger
+// than the client's remaining allowance is refused whole with 429.
+//
+// API-key authentication applies as for CreateJob. The body may be up to MaxJobBatchSize
+// times the largest POST /api/jobs body; a larger one is refused with 413.
+//
+// Example request:
+// POST /api/jobs/batch
+// Headers: X-Client-Id: customer-12345
+// Body: {"jobs": [{"type": "PAYMENT_PROCESS", "payload": "order_1|user@email.com|$99.99"}, ...]}
+func (jc *JobController) CreateJobBatch(c *gin.Context) {
+	clientID, ok := jc.requestClientID(c)
+	if !ok {
+		return
+	}
+
+	// Room for a full batch of the largest single jobs
+	if !limitRequestBody(c, jc.maxRequestBytes*dto.MaxJobBatchSize) {
+		return
+	}
+
+	var request dto.JobBatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		handleBindError(c, err)
+		return
+	}
+	if len(request.Jobs) > dto.MaxJobBatchSize {
+		exception.HandleBadRequest(c, fmt.Sprintf("A batch may contain at most %d jobs", dto.MaxJobBatchSize))
+		return
+	}
+
+	log.Printf("Received job batch request: clientId=%s, jobs=%d", clientID, len(request.Jobs))
+
+	rateLimitKey := jc.rateLimitKey(c, clientID)
+	if !jc.rateLimitService.IsAllowedN(rateLimitKey, len(request.Jobs)) {
+		remaining := jc.rateLimitService.GetRemainingRequests(rateLimitKey)
+		log.Printf("Rate limit exceeded for client: %s, batch: %d, remaining: %d", rateLimitKey, len(request.Jobs), remaining)
+
+		c.Header("X-RateLimit-Limit", "100")
+		c.Header("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+		exception.HandleRateLimited(c, jc.rateLimitService.GetSecondsUntilReset(rateLimitKey))
+		return
+	}
+
+	response, err := jc.jobService.CreateJobsBatch(config.ExtractHTTPTraceContext(c.Request), clientID, request.Jobs, request.Mode)
+	if err != nil {
+		log.Printf("Failed to create job batch: %v", err)
+		exception.HandleInternalError(c)
+		return
+	}
+
+	remaining := jc.rateLimitService.GetRemainingRequests(rateLimitKey)
+	c.Header("X-RateLimit-Limit", "100")
+	c.Header("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+
+	status := http.StatusAccepted
+	switch {
+	case len(response.Errors) == 0:
+	case response.Mode == dto.JobBatchModeAtomic:
+		status = http.StatusUnprocessableEntity
+	default:
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, response)
+}
+
+// limitRequestBody caps the request body at limit bytes, responding 413 right away when
+// its Content-Length is already over; a body that only turns out too large while being
+// read fails binding (see handleBindError). Reports whether the request may go on.
+func limitRequestBody(c *gin.Context, limit int64) bool {
+	if c.Request.ContentLength > limit {
+		exception.HandlePayloadTooLarge(c, exception.NewPayloadTooLargeError(-1, config.GetMaxPayloadBytes()))
+		return false
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	return true
+}
+
+// handleBindError responds 413 for a body cut off by limitRequestBody, else 400.
+func handleBindError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		exception.HandlePayloadTooLarge(c, exception.NewPayloadTooLargeError(-1, config.GetMaxPayloadBytes()))
+		return
+	}
+	exception.HandleInvalidInput(c, err)
+}
+
+// clientRoute returns the handler chain of a client-scoped route: handler, behind
+// the auth middleware when set.
+func (jc *JobController) clientRoute(handler gin.HandlerFunc) []gin.HandlerFunc {
+	if jc.authMiddleware == nil {
+		return []gin.HandlerFunc{handler}
+	}
+	return []gin.HandlerFunc{jc.authMiddleware, handler}
+}
+
+// requestClientID returns the client the request acts for: the authenticated client
+// when the request passed API-key auth, otherwise the X-Client-Id header.
+// Responds 400 without either, or 403 when the header names another client.
+func (jc *JobController) requestClientID(c *gin.Context) (string, bool) {
+	header := c.GetHeader("X-Client-Id")
+	if authenticatedID, ok := config.GetAuthenticatedClientID(c); ok {
+		if header != "" && header != authenticatedID {
+			exception.HandleForbidden(c, "X-Client-Id does not match the API key's client")
+			return "", false
+		}
+		return authenticatedID, true
+	}
+	if header == "" {
+		exception.HandleBadRequest(c, "X-Client-Id header is required")
+		return "", false
+	}
+	return header, true
+}
+
+// readClientID returns the client a read acts for: as requestClientID when the request
+// passed API-key auth or sends X-Client-Id, otherwise fallback (e.g. the clientId query
+// parameter), which may be empty. Without API-key auth neither the header nor a
+// parameter proves who the caller is, so reads keep accepting either, or neither.
+func (jc *JobController) readClientID(c *gin.Context, fallback string) (string, bool) {
+	if _, ok := config.GetAuthenticatedClientID(c); ok || c.GetHeader("X-Client-Id") != "" {
+		return jc.requestClientID(c)
+	}
+	return fallback, true
+}
+
+// rateLimitKey returns the identity the request's rate limit bucket is keyed by.
+// With RATE_LIMIT_KEY=apikey this is the authenticated key's client ID rather
+// than the client-controlled header; unauthenticated requests fall back to the header.
+func (jc *JobController) rateLimitKey(c *gin.Context, clientID string) string {
+	if jc.rateLimitService.KeyByAPIKey() {
+		if authenticatedID, ok := config.GetAuthenticatedClientID(c); ok {
+			return authenticatedID
+		}
+	}
+	return clientID
+}
+
 // GetJob gets job status by ID.
 //
 // Returns the current status and details of a job. Clients can poll this
 // endpoint to check if their order has been processed.
 //
+// Another client's job is 404 (see readClientID); without API-key auth, a request
+// naming no client gets any job, as before client scoping.
+//
 // Example request:
 // GET /api/jobs/550e8400-e29b-41d4-a716-446655440000
+// Headers: X-Client-Id: customer-12345
 func (jc *JobController) GetJob(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID format"})
+		exception.HandleBadRequest(c, "Invalid job ID format")
+		return
+	}
+	clientID, ok := jc.readClientID(c, "")
+	if !ok {
 		return
 	}
 
-	log.Printf("Retrieving job: %s", id)
+	config.JobLogger(id.String(), clientID).Debug("Retrieving job")
 
-	job, err := jc.jobService.GetJob(id)
+	var job *model.Job
+	if clientID == "" {
+		job, err = jc.jobService.GetJob(id)
+	} else {
+		job, err = jc.jobService.GetClientJob(clientID, id)
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found", "id": id.String()})
+		exception.HandleJobNotFound(c, err.Error())
 		return
 	}
 
@@ -136,24 +405,174 @@ func (jc *JobController) GetJob(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// GetJobsByClient gets all jobs for a specific client.
+// GetJobAttempts gets the job's processing history: one entry per attempt, oldest
+// first, with its outcome and error. Only attempts made by workers with an attempt
+// repository (see service.JobWorker.SetJobAttemptRepository) are listed.
 //
-// Useful for client-specific dashboards and order history.
+// Example request:
+// GET /api/jobs/550e8400-e29b-41d4-a716-446655440000/attempts
+// Headers: X-Client-Id: customer-12345
+func (jc *JobController) GetJobAttempts(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		exception.HandleBadRequest(c, "Invalid job ID format")
+		return
+	}
+	clientID, ok := jc.requestClientID(c)
+	if !ok {
+		return
+	}
+
+	attempts, err := jc.jobService.GetJobAttempts(clientID, id)
+	if err != nil {
+		if exception.IsJobNotFoundError(err) {
+			exception.HandleJobNotFound(c, err.Error())
+			return
+		}
+		config.Logger().Error("Failed to load job attempts", config.LogKeyJobID, id.String(), "error", err)
+		exception.HandleInternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
+
+// RetryJob requeues a job that landed in DEAD_LETTER (or FAILED) with its attempts reset.
+//
+// Returns 409 Conflict if the job is PENDING, RUNNING, or COMPLETED.
+//
+// Example request:
+// POST /api/jobs/550e8400-e29b-41d4-a716-446655440000/retry
+// Headers: X-Client-Id: customer-12345
+func (jc *JobController) RetryJob(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		exception.HandleBadRequest(c, "Invalid job ID format")
+		return
+	}
+	clientID, ok := jc.requestClientID(c)
+	if !ok {
+		return
+	}
+
+	logger := config.JobLogger(id.String(), clientID)
+	logger.Info("Retrying job")
+
+	job, err := jc.jobService.RetryJob(clientID, id)
+	if err != nil {
+		if exception.IsJobNotFoundError(err) {
+			exception.HandleJobNotFound(c, err.Error())
+			return
+		}
+		if exception.IsInvalidJobStateError(err) {
+			exception.HandleInvalidJobState(c, err.Error())
+			return
+		}
+		logger.Error("Failed to retry job", "error", err)
+		exception.HandleInternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.JobResponseFrom(job))
+}
+
+// CompensateJob enqueues the compensating job of a COMPLETED job, e.g. the REFUND of a
+// payment whose order couldn't be fulfilled (see service.CompensationHandler).
+//
+// Returns 202 Accepted with the new compensating job, or 200 OK with the existing one
+// if the job was already compensated. Returns 409 Conflict if the job isn't COMPLETED,
+// its type can't be compensated, or it has nothing to undo (a payment never charged).
+// Returns 404 for another client's job (see requestClientID).
 //
 // Example request:
-// GET /api/jobs?clientId=customer-12345
+// POST /api/jobs/550e8400-e29b-41d4-a716-446655440000/compensate
+// Headers: X-Client-Id: customer-12345
+func (jc *JobController) CompensateJob(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		exception.HandleBadRequest(c, "Invalid job ID format")
+		return
+	}
+	clientID, ok := jc.requestClientID(c)
+	if !ok {
+		return
+	}
+
+	logger := config.JobLogger(id.String(), clientID)
+	logger.Info("Compensating job")
+
+	compensation, created, err := jc.jobService.CompensateJob(clientID, id)
+	if err != nil {
+		if exception.IsJobNotFoundError(err) {
+			exception.HandleJobNotFound(c, err.Error())
+			return
+		}
+		if exception.IsInvalidJobStateError(err) {
+			exception.HandleInvalidJobState(c, err.Error())
+			return
+		}
+		logger.Error("Failed to compensate job", "error", err)
+		exception.HandleInternalError(c)
+		return
+	}
+
+	status := http.StatusAccepted
+	if !created {
+		status = http.StatusOK
+	}
+	c.JSON(status, dto.JobResponseFrom(compensation))
+}
+
+// GetJobsByClient gets one page of the calling client's jobs (see readClientID),
+// newest first, optionally filtered by labels and status.
+//
+// Useful for client-specific dashboards and order history.
+// Every label.{key}={value} parameter must match. Without API-key auth or X-Client-Id,
+// the clientId parameter names the client; one naming another client than the header
+// or API key returns 403. Pages are 0-based; size defaults to 20 and is capped at 100.
+//
+// Example requests (Headers: X-Client-Id: customer-12345):
+// GET /api/jobs
+// GET /api/jobs?status=FAILED&page=2&size=50
+// GET /api/jobs?label.region=eu-west&label.campaign=black-friday
+// GET /api/jobs?clientId=customer-12345 (without API-key auth, no header needed)
 func (jc *JobController) GetJobsByClient(c *gin.Context) {
-	clientID := c.Query("clientId")
+	requested := c.Query("clientId")
+	clientID, ok := jc.readClientID(c, requested)
+	if !ok {
+		return
+	}
 	if clientID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "clientId query parameter is required"})
+		exception.HandleBadRequest(c, "X-Client-Id header or clientId parameter is required")
 		return
 	}
+	if requested != "" && requested != clientID {
+		exception.HandleForbidden(c, "clientId does not match the calling client")
+		return
+	}
+	labels := labelsFromQuery(c)
+
+	page, size, ok := parsePageParams(c)
+	if !ok {
+		return
+	}
+
+	var status *model.JobStatus
+	if val := c.Query("status"); val != "" {
+		parsed := model.JobStatus(strings.ToUpper(val))
+		if !parsed.IsValid() {
+			exception.HandleBadRequest(c, "Invalid status: "+val)
+			return
+		}
+		status = &parsed
+	}
 
-	log.Printf("Retrieving jobs for client: %s", clientID)
+	log.Printf("Retrieving jobs for client: %s, labels: %v, status: %v, page: %d, size: %d",
+		clientID, labels, c.Query("status"), page, size)
 
-	jobs, err := jc.jobService.GetJobsByClient(clientID)
+	jobs, total, err := jc.jobService.GetJobsPage(clientID, labels, status, page, size)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve jobs"})
+		exception.HandleInternalError(c)
 		return
 	}
 
@@ -166,12 +585,208 @@ func (jc *JobController) GetJobsByClient(c *gin.Context) {
 		responses = []dto.JobResponse{}
 	}
 
-	c.JSON(http.StatusOK, responses)
+	c.JSON(http.StatusOK, dto.PagedJobResponse{
+		Items: responses,
+		Page:  page,
+		Size:  size,
+		Total: total,
+	})
+}
+
+// SearchJobs returns one page of jobs matching the query, newest first, e.g. for support
+// finding a customer's job by order ID without the job ID.
+//
+// Only the calling client's jobs are searched (see requestClientID), so the payload match
+// only scans the jobs idx_client_id narrows down to.
+//
+// Filters: orderId (an order ID or its prefix, matched against the start of the payload),
+// payloadContains (a substring of the payload), from and to (RFC 3339; created at or after
+// from, at or before to), and status. Prefer orderId: it is a prefix match, cheaper than
+// the substring scan.
+// Pages are 0-based; size defaults to 20 and is capped at 100.
+//
+// Example request:
+// GET /api/jobs/search?orderId=order_ORD12345&from=2024-01-15T00:00:00Z&to=2024-01-16T00:00:00Z
+// Headers: X-Client-Id: customer-1
+func (jc *JobController) SearchJobs(c *gin.Context) {
+	clientID, ok := jc.requestClientID(c)
+	if !ok {
+		return
+	}
+	criteria := repository.SearchCriteria{
+		ClientID:        clientID,
+		OrderIDPrefix:   c.Query("orderId"),
+		PayloadContains: c.Query("payloadContains"),
+	}
+
+	for _, param := range []struct {
+		name string
+		dest **time.Time
+	}{{"from", &criteria.From}, {"to", &criteria.To}} {
+		val := c.Query(param.name)
+		if val == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			exception.HandleBadRequest(c, param.name+" must be an RFC 3339 timestamp, e.g. 2024-01-15T00:00:00Z")
+			return
+		}
+		parsed = parsed.UTC()
+		*param.dest = &parsed
+	}
+	if criteria.From != nil && criteria.To != nil && criteria.To.Before(*criteria.From) {
+		exception.HandleBadRequest(c, "to must not be before from")
+		return
+	}
+
+	page, size, ok := parsePageParams(c)
+	if !ok {
+		return
+	}
+
+	if val := c.Query("status"); val != "" {
+		parsed := model.JobStatus(strings.ToUpper(val))
+		if !parsed.IsValid() {
+			exception.HandleBadRequest(c, "Invalid status: "+val)
+			return
+		}
+		criteria.Status = &parsed
+	}
+
+	jobs, total, err := jc.jobService.SearchJobs(criteria, page, size)
+	if err != nil {
+		exception.HandleInternalError(c)
+		return
+	}
+
+	responses := make([]dto.JobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		responses = append(responses, dto.JobResponseFrom(&job))
+	}
+
+	c.JSON(http.StatusOK, dto.PagedJobResponse{
+		Items: responses,
+		Page:  page,
+		Size:  size,
+		Total: total,
+	})
+}
+
+// ListDeadLetter returns one page of the calling client's dead-lettered jobs (see
+// requestClientID), most recently dead-lettered first, for triaging what failed for good.
+// Each item carries its errorMessage, failureReason, and attempts.
+//
+// Optional filters: type (a job type) and since (RFC 3339; jobs dead-lettered at or after it).
+// Pages are 0-based; size defaults to 20 and is capped at 100.
+//
+// Example requests (Headers: X-Client-Id: customer-12345):
+// GET /api/jobs/dead-letter
+// GET /api/jobs/dead-letter?type=PAYMENT_PROCESS&since=2024-01-15T00:00:00Z&page=1&size=50
+func (jc *JobController) ListDeadLetter(c *gin.Context) {
+	clientID, ok := jc.requestClientID(c)
+	if !ok {
+		return
+	}
+	page, size, ok := parsePageParams(c)
+	if !ok {
+		return
+	}
+
+	var jobType *model.JobType
+	if val := c.Query("type"); val != "" {
+		parsed := model.JobType(strings.ToUpper(val))
+		if _, known := model.LookupJobTypeSpec(parsed); !known {
+			exception.HandleBadRequest(c, "Unknown job type: "+val)
+			return
+		}
+		jobType = &parsed
+	}
+
+	var since *time.Time
+	if val := c.Query("since"); val != "" {
+		parsed, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			exception.HandleBadRequest(c, "since must be an RFC 3339 timestamp, e.g. 2024-01-15T00:00:00Z")
+			return
+		}
+		parsed = parsed.UTC()
+		since = &parsed
+	}
+
+	jobs, total, err := jc.jobService.GetDeadLetterPage(clientID, jobType, since, page, size)
+	if err != nil {
+		exception.HandleInternalError(c)
+		return
+	}
+
+	responses := make([]dto.JobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		responses = append(responses, dto.JobResponseFrom(&job))
+	}
+
+	c.JSON(http.StatusOK, dto.PagedJobResponse{
+		Items: responses,
+		Page:  page,
+		Size:  size,
+		Total: total,
+	})
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// parsePageParams reads the 0-based page and the page size from the query.
+// Sizes above maxPageSize are clamped; a negative page or a size below 1 is
+// answered with 400 and ok=false.
+func parsePageParams(c *gin.Context) (page, size int, ok bool) {
+	page, size = 0, defaultPageSize
+
+	if val := c.Query("page"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil || parsed < 0 {
+			exception.HandleBadRequest(c, "page must be a non-negative integer")
+			return 0, 0, false
+		}
+		page = parsed
+	}
+
+	if val := c.Query("size"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil || parsed < 1 {
+			exception.HandleBadRequest(c, "size must be a positive integer")
+			return 0, 0, false
+		}
+		size = min(parsed, maxPageSize)
+	}
+
+	return page, size, true
+}
+
+// labelsFromQuery collects label.{key}={value} query parameters into a label filter.
+func labelsFromQuery(c *gin.Context) model.JobLabels {
+	var labels model.JobLabels
+	for param, values := range c.Request.URL.Query() {
+		key := strings.TrimPrefix(param, "label.")
+		if key == param || key == "" || len(values) == 0 {
+			continue
+		}
+		if labels == nil {
+			labels = model.JobLabels{}
+		}
+		labels[key] = values[0]
+	}
+	return labels
 }
 
 // GetStats returns system statistics.
 //
-// Returns count of jobs by status, useful for monitoring dashboards.
+// Returns count of jobs by status, plus dead letters by failure reason and
+// counts per type and status in "byType", useful for monitoring dashboards.
+// If any count fails (e.g. the database is down) the response is 503 with an
+// error instead of zeros, so a dashboard never shows an outage as an empty system.
 //
 // Example response:
 // {
@@ -179,26 +794,135 @@ func (jc *JobController) GetJobsByClient(c *gin.Context) {
 //   "RUNNING": 25,
 //   "COMPLETED": 10450,
 //   "FAILED": 5,
-//   "DEAD_LETTER": 2
+//   "DEAD_LETTER": 2,
+//   "EXPIRED": 0,
+//   "deadLetterReasons": {"timeout": 1, "declined": 1, "invalid_payload": 0, "downstream_5xx": 0, "needs_reconciliation": 0, "unknown": 0},
+//   "byType": {
+//     "PAYMENT_PROCESS": {"PENDING": 100, "RUNNING": 20, "COMPLETED": 6200, "FAILED": 4, "DEAD_LETTER": 2, "EXPIRED": 0},
+//     "EMAIL_CONFIRMATION": {"PENDING": 50, "RUNNING": 5, "COMPLETED": 4250, "FAILED": 1, "DEAD_LETTER": 0, "EXPIRED": 0}
+//   }
 // }
 func (jc *JobController) GetStats(c *gin.Context) {
 	log.Println("Retrieving system statistics")
 
-	stats := map[string]int64{
-		"PENDING":     jc.jobService.CountJobsByStatus(model.StatusPending),
-		"RUNNING":     jc.jobService.CountJobsByStatus(model.StatusRunning),
-		"COMPLETED":   jc.jobService.CountJobsByStatus(model.StatusCompleted),
-		"FAILED":      jc.jobService.CountJobsByStatus(model.StatusFailed),
-		"DEAD_LETTER": jc.jobService.CountJobsByStatus(model.StatusDeadLetter),
+	statuses := model.JobStatuses()
+
+	stats := make(gin.H, len(statuses)+2)
+	for _, status := range statuses {
+		count, err := jc.jobService.CountJobsByStatus(status)
+		if err != nil {
+			exception.HandleServiceUnavailable(c, "Job statistics are unavailable")
+			return
+		}
+		stats[string(status)] = count
+	}
+
+	reasons, err := jc.jobService.CountDeadLettersByReason()
+	if err != nil {
+		exception.HandleServiceUnavailable(c, "Job statistics are unavailable")
+		return
 	}
+	stats["deadLetterReasons"] = reasons
+
+	byType, err := jc.jobService.CountJobsByTypeAndStatus()
+	if err != nil {
+		exception.HandleServiceUnavailable(c, "Job statistics are unavailable")
+		return
+	}
+	stats["byType"] = byType
 
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetJobTypes lists the supported job types with their payload schemas.
+//
+// Served from the same table the payload rules are defined in, so client
+// developers always see the formats that are actually enforced.
+//
+// Example response:
+// [
+//   {
+//     "type": "EMAIL_CONFIRMATION",
+//     "description": "Send the order confirmation email to the customer",
+//     "payloadFields": [
+//       {"name": "orderId", "format": "text", "required": true},
+//       {"name": "customerEmail", "format": "email", "required": true},
+//       {"name": "receiptUrl", "format": "text", "required": false}
+//     ],
+//     "processingTimeMs": 1000
+//   }
+// ]
+func (jc *JobController) GetJobTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, model.JobTypeSpecs())
+}
+
 // Health check endpoint.
+// Includes the build version unless EXPOSE_BUILD_INFO=false (full details at GET /version).
+//
+// With a DB circuit breaker set, "database" reports its state (CLOSED or OPEN). While it
+// is open the status is DEGRADED: the API is up but job reads and writes fail fast.
+// The response stays 200 so liveness probes don't restart instances over a database outage.
+//
+// With HEALTH_CHECK_JOBS_ENABLED=true, "canary" reports when the latest HEALTH_CHECK job
+// completed (null if none has), so a monitor can tell the whole pipeline is moving.
+//
+// With a PartitionPauseService set, "partitions" lists the Kafka partitions paused
+// through the admin API, e.g. {"paused": [{"topic": "job-queue", "partition": 3, ...}]}.
+//
+// With a TypeCircuitBreaker set, "jobTypes" lists the types whose downstream breaker is
+// open and whether new jobs of each are being shed (503), e.g.
+// {"circuitOpen": [{"type": "PAYMENT_PROCESS", "shedding": true, "retryAfterSeconds": 12}]}.
 func (jc *JobController) Health(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"status":  "UP",
 		"service": "job-processor-api",
-	})
-}
\ No newline at end of file
+	}
+	if jc.dbBreaker != nil {
+		response["database"] = gin.H{"circuitBreaker": jc.dbBreaker.State()}
+		if jc.dbBreaker.Open() {
+			response["status"] = "DEGRADED"
+		}
+	}
+	if jc.jobService != nil && jc.jobService.HealthCheckJobsEnabled() {
+		if lastSuccess, err := jc.jobService.LastHealthCheckCompletedAt(); err == nil {
+			response["canary"] = gin.H{"lastSuccessAt": lastSuccess}
+		} else {
+			log.Printf("Error reading last health check job: %v", err)
+			response["canary"] = gin.H{"error": "unavailable"}
+		}
+	}
+	if jc.partitionPauses != nil {
+		if paused, err := jc.partitionPauses.List(); err == nil {
+			response["partitions"] = gin.H{"paused": paused}
+		} else {
+			log.Printf("Error reading paused partitions: %v", err)
+			response["partitions"] = gin.H{"error": "unavailable"}
+		}
+	}
+	if jc.typeBreaker != nil {
+		response["jobTypes"] = gin.H{"circuitOpen": jc.typeBreaker.States()}
+	}
+	if buildinfo.Enabled() {
+		response["version"] = buildinfo.Version
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Ready is the readiness probe: 200 only when every dependency is reachable, 503 with
+// the status of each dependency otherwise, so traffic isn't routed to a broken instance.
+// Without a ReadinessChecker (see SetReadinessChecker) there is nothing to check and it answers 200.
+//
+// Example response (503):
+// {"status": "DOWN", "dependencies": {"postgres": "UP", "redis": "DOWN", "kafka": "UP"}}
+func (jc *JobController) Ready(c *gin.Context) {
+	if jc.readiness == nil {
+		c.JSON(http.StatusOK, gin.H{"status": service.DependencyUp})
+		return
+	}
+	ready, dependencies := jc.readiness.Check(c.Request.Context())
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": service.DependencyDown, "dependencies": dependencies})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": service.DependencyUp, "dependencies": dependencies})
+}
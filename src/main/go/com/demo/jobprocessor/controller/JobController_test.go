@@ -0,0 +1,485 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"distributed-job-processor/buildinfo"
+	"distributed-job-processor/config"
+	"distributed-job-processor/dto"
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+	"distributed-job-processor/service"
+)
+
+func newTestContext() *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	return c
+}
+
+// TestRateLimitKeyUsesAPIKeyIdentity verifies a spoofed header can't escape the authenticated bucket.
+func TestRateLimitKeyUsesAPIKeyIdentity(t *testing.T) {
+	t.Setenv("RATE_LIMIT_KEY", "apikey")
+	jc := NewJobController(nil, service.NewRateLimitService(nil))
+
+	c := newTestContext()
+	c.Set(config.AuthenticatedClientIDKey, "customer-1")
+
+	if got := jc.rateLimitKey(c, "spoofed-client"); got != "customer-1" {
+		t.Fatalf("expected authenticated identity, got %q", got)
+	}
+}
+
+// TestRateLimitKeyFallsBackToHeader verifies header keying when unauthenticated or configured.
+func TestRateLimitKeyFallsBackToHeader(t *testing.T) {
+	t.Setenv("RATE_LIMIT_KEY", "apikey")
+	jc := NewJobController(nil, service.NewRateLimitService(nil))
+	if got := jc.rateLimitKey(newTestContext(), "customer-2"); got != "customer-2" {
+		t.Fatalf("expected header fallback without auth, got %q", got)
+	}
+
+	t.Setenv("RATE_LIMIT_KEY", "header")
+	jc = NewJobController(nil, service.NewRateLimitService(nil))
+	c := newTestContext()
+	c.Set(config.AuthenticatedClientIDKey, "customer-1")
+	if got := jc.rateLimitKey(c, "customer-2"); got != "customer-2" {
+		t.Fatalf("expected header keying, got %q", got)
+	}
+}
+
+// TestHealthIncludesVersion verifies the build version is reported unless disabled.
+func TestHealthIncludesVersion(t *testing.T) {
+	previous := buildinfo.Version
+	buildinfo.Version = "v1.2.3"
+	t.Cleanup(func() { buildinfo.Version = previous })
+	jc := NewJobController(nil, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	jc.Health(c)
+	if !strings.Contains(w.Body.String(), `"version":"v1.2.3"`) {
+		t.Fatalf("expected version in health response, got %s", w.Body.String())
+	}
+
+	t.Setenv("EXPOSE_BUILD_INFO", "false")
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	jc.Health(c)
+	if strings.Contains(w.Body.String(), "version") {
+		t.Fatalf("expected no version with EXPOSE_BUILD_INFO=false, got %s", w.Body.String())
+	}
+}
+
+// TestParsePageParams verifies defaults, clamping, and rejection of bad paging parameters.
+func TestParsePageParams(t *testing.T) {
+	cases := []struct {
+		query      string
+		page, size int
+		ok         bool
+	}{
+		{"", 0, defaultPageSize, true},
+		{"page=3&size=50", 3, 50, true},
+		{"size=1000", 0, maxPageSize, true},
+		{"page=-1", 0, 0, false},
+		{"page=abc", 0, 0, false},
+		{"size=0", 0, 0, false},
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/jobs?"+tc.query, nil)
+
+		page, size, ok := parsePageParams(c)
+		if ok != tc.ok || page != tc.page || size != tc.size {
+			t.Fatalf("%q: got page=%d size=%d ok=%v, want page=%d size=%d ok=%v",
+				tc.query, page, size, ok, tc.page, tc.size, tc.ok)
+		}
+		if !ok && w.Code != 400 {
+			t.Fatalf("%q: expected 400, got %d", tc.query, w.Code)
+		}
+	}
+}
+
+// TestListDeadLetterRejectsBadFilters verifies unknown types and malformed since times are refused before querying.
+func TestListDeadLetterRejectsBadFilters(t *testing.T) {
+	jc := NewJobController(nil, nil)
+	for _, query := range []string{"type=NOT_A_TYPE", "since=yesterday", "since=2024-01-15"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/jobs/dead-letter?"+query, nil)
+		c.Request.Header.Set("X-Client-Id", "customer-1")
+
+		jc.ListDeadLetter(c)
+		if w.Code != 400 {
+			t.Fatalf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+// TestCreateJobRejectsOversizedBody verifies a body over the limit is refused with 413
+// before binding, whether its Content-Length announces it or not.
+func TestCreateJobRejectsOversizedBody(t *testing.T) {
+	t.Setenv("MAX_PAYLOAD_BYTES", "64")
+	jc := NewJobController(nil, nil)
+	body := `{"type": "EMAIL_CONFIRMATION", "payload": "` + strings.Repeat("x", int(config.GetMaxJobRequestBytes())) + `"}`
+
+	for _, announced := range []bool{true, false} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body))
+		c.Request.Header.Set("X-Client-Id", "customer-1")
+		if !announced {
+			c.Request.ContentLength = -1 // Chunked: only the MaxBytesReader catches it
+		}
+
+		jc.CreateJob(c)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("announced=%v: expected 413, got %d %s", announced, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestCreateJobBatchRejectsOversizedBody verifies a batch body over MaxJobBatchSize
+// times the single-job limit is refused with 413, announced or not.
+func TestCreateJobBatchRejectsOversizedBody(t *testing.T) {
+	t.Setenv("MAX_PAYLOAD_BYTES", "64")
+	jc := NewJobController(nil, nil)
+	item := `{"type": "EMAIL_CONFIRMATION", "payload": "x"},`
+	count := int(config.GetMaxJobRequestBytes())*dto.MaxJobBatchSize/len(item) + 1
+	body := `{"jobs": [` + strings.Repeat(item, count) + `{"type": "EMAIL_CONFIRMATION", "payload": "x"}]}`
+
+	for _, announced := range []bool{true, false} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/jobs/batch", strings.NewReader(body))
+		c.Request.Header.Set("X-Client-Id", "customer-1")
+		if !announced {
+			c.Request.ContentLength = -1 // Chunked: only the MaxBytesReader catches it
+		}
+
+		jc.CreateJobBatch(c)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("announced=%v: expected 413, got %d %s", announced, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestCreateJobAuthenticatesClient verifies job creation requires an API key once the
+// auth middleware is set, and the key's client can't be overridden by X-Client-Id.
+func TestCreateJobAuthenticatesClient(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+	mr.Set("apikey:key-1", "customer-1")
+
+	gin.SetMode(gin.TestMode)
+	jc := NewJobController(nil, nil)
+	jc.SetAuthMiddleware(config.AuthMiddleware(redisClient))
+	r := gin.New()
+	jc.RegisterRoutes(r.Group("/api/jobs"))
+
+	cases := []struct {
+		name       string
+		apiKey     string
+		clientID   string
+		wantStatus int
+	}{
+		{"missing key", "", "customer-1", http.StatusUnauthorized},
+		{"unknown key", "key-2", "customer-1", http.StatusUnauthorized},
+		{"header naming another client", "key-1", "customer-2", http.StatusForbidden},
+		// Past authentication: the body is then refused
+		{"valid key", "key-1", "", http.StatusBadRequest},
+		{"valid key and matching header", "key-1", "customer-1", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader("not json"))
+		if tc.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.apiKey)
+		}
+		if tc.clientID != "" {
+			req.Header.Set("X-Client-Id", tc.clientID)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.wantStatus {
+			t.Errorf("%s: expected %d, got %d %s", tc.name, tc.wantStatus, w.Code, w.Body.String())
+		}
+	}
+
+	c := newTestContext()
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/jobs", nil)
+	c.Set(config.AuthenticatedClientIDKey, "customer-1")
+	if clientID, ok := jc.requestClientID(c); !ok || clientID != "customer-1" {
+		t.Fatalf("expected the authenticated client without a header, got %q", clientID)
+	}
+}
+
+// TestClientRoutesRequireAPIKey verifies every client-scoped route is behind the auth
+// middleware once it is set, while the probes and the type listing stay open.
+func TestClientRoutesRequireAPIKey(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	gin.SetMode(gin.TestMode)
+	jc := NewJobController(nil, nil)
+	jc.SetAuthMiddleware(config.AuthMiddleware(redisClient))
+	r := gin.New()
+	jc.RegisterRoutes(r.Group("/api/jobs"))
+
+	jobPath := "/api/jobs/550e8400-e29b-41d4-a716-446655440000"
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/api/jobs"},
+		{http.MethodPost, "/api/jobs/batch"},
+		{http.MethodGet, "/api/jobs"},
+		{http.MethodGet, "/api/jobs/search"},
+		{http.MethodGet, "/api/jobs/dead-letter"},
+		{http.MethodGet, jobPath},
+		{http.MethodGet, jobPath + "/attempts"},
+		{http.MethodPost, jobPath + "/retry"},
+		{http.MethodPost, jobPath + "/compensate"},
+	} {
+		req := httptest.NewRequest(route.method, route.path, nil)
+		req.Header.Set("X-Client-Id", "customer-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without an API key, got %d", route.method, route.path, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/jobs/types", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the type listing open, got %d", w.Code)
+	}
+}
+
+// TestReadyReports503WhenADependencyIsDown verifies the readiness probe answers 200 only
+// while Postgres, Redis, and Kafka are all reachable, and names the dependency that isn't.
+func TestReadyReports503WhenADependencyIsDown(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { redisClient.Close() })
+
+	// Stands in for a broker: the probe only needs the TCP dial to succeed
+	broker, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+
+	jc := NewJobController(nil, nil)
+	jc.SetReadinessChecker(service.NewReadinessChecker(db, redisClient, broker.Addr().String()))
+
+	ready := func() (int, map[string]string) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/jobs/ready", nil)
+		jc.Ready(c)
+		var body struct {
+			Dependencies map[string]string `json:"dependencies"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode %s: %v", w.Body.String(), err)
+		}
+		return w.Code, body.Dependencies
+	}
+
+	if code, deps := ready(); code != http.StatusOK || len(deps) != 3 {
+		t.Fatalf("expected 200 with 3 dependencies UP, got %d %v", code, deps)
+	}
+
+	mr.Close()
+	code, deps := ready()
+	if code != http.StatusServiceUnavailable || deps["redis"] != service.DependencyDown || deps["postgres"] != service.DependencyUp {
+		t.Fatalf("expected 503 with only redis DOWN, got %d %v", code, deps)
+	}
+
+	sqlDB.Close()
+	broker.Close()
+	if code, deps := ready(); code != http.StatusServiceUnavailable || deps["postgres"] != service.DependencyDown || deps["kafka"] != service.DependencyDown {
+		t.Fatalf("expected 503 with postgres and kafka DOWN, got %d %v", code, deps)
+	}
+}
+
+// TestReadsWithoutAPIKeyAuthKeepClientIDParameter verifies that without API-key auth
+// a client's jobs can still be listed by the clientId parameter and a job read by ID
+// without X-Client-Id, while a header still scopes the read to that client.
+func TestReadsWithoutAPIKeyAuthKeepClientIDParameter(t *testing.T) {
+	repo := newTestRepository(t)
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@example.com")
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	jc := NewJobController(service.NewJobService(repo), nil)
+	r := gin.New()
+	jc.RegisterRoutes(r.Group("/api/jobs"))
+
+	cases := []struct {
+		name       string
+		path       string
+		clientID   string
+		wantStatus int
+	}{
+		{"list by parameter", "/api/jobs?clientId=customer-1", "", http.StatusOK},
+		{"list by header", "/api/jobs", "customer-1", http.StatusOK},
+		{"list naming no client", "/api/jobs", "", http.StatusBadRequest},
+		{"parameter naming another client", "/api/jobs?clientId=customer-1", "customer-2", http.StatusForbidden},
+		{"job without header", "/api/jobs/" + job.ID.String(), "", http.StatusOK},
+		{"job of the header's client", "/api/jobs/" + job.ID.String(), "customer-1", http.StatusOK},
+		{"another client's job", "/api/jobs/" + job.ID.String(), "customer-2", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.clientID != "" {
+			req.Header.Set("X-Client-Id", tc.clientID)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.wantStatus {
+			t.Errorf("%s: expected %d, got %d %s", tc.name, tc.wantStatus, w.Code, w.Body.String())
+		}
+		if tc.wantStatus == http.StatusOK && !strings.Contains(w.Body.String(), job.ID.String()) {
+			t.Errorf("%s: expected the job in %s", tc.name, w.Body.String())
+		}
+	}
+}
+
+// TestErrorResponsesShareSchema verifies every error path answers with the standard
+// ErrorResponse shape, including the 429, which reports when the limit resets.
+func TestErrorResponsesShareSchema(t *testing.T) {
+	t.Setenv("RATE_LIMIT_MAX_REQUESTS", "1")
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	// No schema: every query fails, so lookups miss and inserts error out
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	gin.SetMode(gin.TestMode)
+	rateLimitService := service.NewRateLimitService(redisClient)
+	jc := NewJobController(service.NewJobService(repository.NewJobRepository(db)), rateLimitService)
+	r := gin.New()
+	jc.RegisterRoutes(r.Group("/api/jobs"))
+
+	validJob := `{"type": "EMAIL_CONFIRMATION", "payload": "order_1|user@example.com"}`
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		clientID   string
+		body       string
+		wantStatus int
+	}{
+		{"malformed body", http.MethodPost, "/api/jobs", "customer-1", "not json", http.StatusBadRequest},
+		{"missing client", http.MethodPost, "/api/jobs", "", validJob, http.StatusBadRequest},
+		{"invalid id", http.MethodGet, "/api/jobs/not-a-uuid", "", "", http.StatusBadRequest},
+		{"bad page", http.MethodGet, "/api/jobs?page=-1", "customer-1", "", http.StatusBadRequest},
+		{"other client's jobs", http.MethodGet, "/api/jobs?clientId=customer-2", "customer-1", "", http.StatusForbidden},
+		{"bad dead-letter filter", http.MethodGet, "/api/jobs/dead-letter?since=yesterday", "customer-1", "", http.StatusBadRequest},
+		{"unknown job", http.MethodGet, "/api/jobs/00000000-0000-0000-0000-000000000001", "customer-1", "", http.StatusNotFound},
+		{"failed insert", http.MethodPost, "/api/jobs", "customer-1", validJob, http.StatusInternalServerError},
+		{"rate limited", http.MethodPost, "/api/jobs", "customer-1", validJob, http.StatusTooManyRequests},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.clientID != "" {
+			req.Header.Set("X-Client-Id", tc.clientID)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.wantStatus {
+			t.Fatalf("%s: expected %d, got %d %s", tc.name, tc.wantStatus, w.Code, w.Body.String())
+		}
+
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decode %q: %v", tc.name, w.Body.String(), err)
+		}
+		for _, field := range []string{"timestamp", "status", "error", "message"} {
+			if _, ok := body[field]; !ok {
+				t.Errorf("%s: missing %q in %s", tc.name, field, w.Body.String())
+			}
+		}
+		if status, _ := body["status"].(float64); int(status) != tc.wantStatus {
+			t.Errorf("%s: expected status %d in body, got %v", tc.name, tc.wantStatus, body["status"])
+		}
+
+		if tc.wantStatus == http.StatusTooManyRequests {
+			if body["retryAfterSeconds"] == nil || w.Header().Get("Retry-After") == "" {
+				t.Errorf("expected reset info on the 429, got %s (Retry-After %q)", w.Body.String(), w.Header().Get("Retry-After"))
+			}
+		}
+	}
+}
+
+// TestCreateJobContinuesUpstreamTrace verifies a job submitted with a traceparent header
+// is created in the caller's trace rather than starting a new one.
+func TestCreateJobContinuesUpstreamTrace(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	if _, err := config.InitTracing(context.Background()); err != nil {
+		t.Fatalf("init tracing: %v", err)
+	}
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	gin.SetMode(gin.TestMode)
+	repo := newTestRepository(t)
+	jc := NewJobController(service.NewJobService(repo), service.NewRateLimitService(redisClient))
+	r := gin.New()
+	jc.RegisterRoutes(r.Group("/api/jobs"))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/api/jobs",
+		strings.NewReader(`{"type": "EMAIL_CONFIRMATION", "payload": "order_1|user@example.com"}`))
+	req.Header.Set("X-Client-Id", "customer-1")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d %s", w.Code, w.Body.String())
+	}
+
+	var created dto.JobResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode %s: %v", w.Body.String(), err)
+	}
+	job, err := repo.FindByID(created.JobID)
+	if err != nil {
+		t.Fatalf("find job: %v", err)
+	}
+	if job.TraceParent == nil || !strings.Contains(*job.TraceParent, traceID) {
+		t.Fatalf("expected the job's traceparent in trace %s, got %v", traceID, job.TraceParent)
+	}
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"distributed-job-processor/repository"
+)
+
+// newTestDB opens an isolated in-memory SQLite database with the job schema migrated.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+
+	// SQLite has no gen_random_uuid() (IDs are always assigned in Go anyway)
+	// and no index methods, so the labels GIN index becomes a plain index
+	db.Callback().Raw().Before("gorm:raw").Register("test:strip_postgres_ddl", func(tx *gorm.DB) {
+		sql := strings.ReplaceAll(tx.Statement.SQL.String(), "DEFAULT gen_random_uuid()", "")
+		sql = strings.ReplaceAll(sql, "USING gin", "")
+		tx.Statement.SQL.Reset()
+		tx.Statement.SQL.WriteString(sql)
+	})
+
+	// Every connection to :memory: is a separate database, so pin the pool to one
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := repository.AutoMigrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// newTestRepository returns a JobRepository backed by newTestDB.
+func newTestRepository(t *testing.T) *repository.JobRepository {
+	t.Helper()
+	return repository.NewJobRepository(newTestDB(t))
+}
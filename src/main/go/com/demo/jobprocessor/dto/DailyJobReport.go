@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"distributed-job-processor/model"
+)
+
+// DailyJobReport is the response DTO for the per-type job outcomes of one calendar day.
+//
+// Example:
+// {
+//   "date": "2024-01-15",
+//   "timezone": "America/New_York",
+//   "from": "2024-01-15T00:00:00-05:00",
+//   "to": "2024-01-16T00:00:00-05:00",
+//   "types": [
+//     {"type": "PAYMENT_PROCESS", "completed": 10450, "failed": 3, "deadLettered": 2}
+//   ]
+// }
+type DailyJobReport struct {
+	Date     string               `json:"date"`
+	Timezone string               `json:"timezone"`
+	From     time.Time            `json:"from"`
+	To       time.Time            `json:"to"`
+	Types    []DailyJobTypeCounts `json:"types"`
+}
+
+// DailyJobTypeCounts is one job type's row of a DailyJobReport.
+type DailyJobTypeCounts struct {
+	Type         model.JobType `json:"type"`
+	Completed    int64         `json:"completed"`
+	Failed       int64         `json:"failed"`
+	DeadLettered int64         `json:"deadLettered"`
+}
+
+// WriteCSV writes the report as CSV: a header row, then one row per job type.
+func (r *DailyJobReport) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"date", "timezone", "type", "completed", "failed", "dead_lettered"})
+	for _, row := range r.Types {
+		out.Write([]string{
+			r.Date,
+			r.Timezone,
+			string(row.Type),
+			strconv.FormatInt(row.Completed, 10),
+			strconv.FormatInt(row.Failed, 10),
+			strconv.FormatInt(row.DeadLettered, 10),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
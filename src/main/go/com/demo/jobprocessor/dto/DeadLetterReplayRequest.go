@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"distributed-job-processor/model"
+)
+
+// DeadLetterReplayRequest is the request DTO for requeueing dead-lettered jobs in bulk.
+// Both fields are optional; an empty body replays the oldest MAX_REPLAY_BATCH dead letters.
+//
+// Example:
+// {
+//   "type": "PAYMENT_PROCESS",
+//   "limit": 200
+// }
+type DeadLetterReplayRequest struct {
+	// Only replay dead letters of this type (default all types)
+	Type *model.JobType `json:"type,omitempty"`
+
+	// Most dead letters to replay (default and max MAX_REPLAY_BATCH)
+	Limit int `json:"limit,omitempty" binding:"omitempty,min=1"`
+}
@@ -0,0 +1,85 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+
+	"distributed-job-processor/model"
+)
+
+// MaxJobBatchSize is the most jobs accepted by one POST /api/jobs/batch.
+const MaxJobBatchSize = 500
+
+// JobBatchRequest is the request DTO for creating many jobs in one call.
+//
+// Example:
+// {
+//   "jobs": [
+//     {"type": "PAYMENT_PROCESS", "payload": "order_1|customer@email.com|$99.99"},
+//     {"type": "EMAIL_CONFIRMATION", "payload": "order_1|customer@email.com"}
+//   ]
+// }
+//
+// Each item accepts the same fields as a single JobRequest.
+//
+// Optional mode (omitted = atomic if any item's type has JOB_BATCH_ATOMIC_<TYPE>=true,
+// partial otherwise):
+// - partial: valid items are created, refused ones are reported (best effort per item)
+// - atomic: one refused item refuses the whole batch, nothing is created
+type JobBatchRequest struct {
+	Jobs []JobRequest `json:"jobs" binding:"required,min=1"`
+	Mode string       `json:"mode,omitempty" binding:"omitempty,oneof=atomic partial"`
+}
+
+// Batch modes, see JobBatchRequest.
+const (
+	JobBatchModePartial = "partial"
+	JobBatchModeAtomic  = "atomic"
+)
+
+// JobBatchResponse reports the outcome of every item of a batch, by index in the request.
+//
+// items has one entry per request item, in request order, with an HTTP status code:
+// 202 created, 400 invalid payload, 409 job ID already used, 413 payload over
+// MAX_PAYLOAD_BYTES, 422 rejected by the enricher, 424 valid but not created
+// because the atomic batch was refused.
+// created and errors split the same outcomes into successes and failures.
+//
+// Example:
+// {
+//   "mode": "partial",
+//   "items": [
+//     {"index": 0, "status": 202, "jobId": "550e8400-..."},
+//     {"index": 1, "status": 400, "error": "Payload validation failed", "fieldErrors": {"amount": "..."}}
+//   ],
+//   "created": [{"index": 0, "jobId": "550e8400-...", "status": "PENDING"}],
+//   "errors": [{"index": 1, "error": "Payload validation failed", "fieldErrors": {"amount": "..."}}]
+// }
+type JobBatchResponse struct {
+	Mode    string              `json:"mode"`
+	Items   []JobBatchItem      `json:"items"`
+	Created []JobBatchCreated   `json:"created"`
+	Errors  []JobBatchItemError `json:"errors"`
+}
+
+// JobBatchItem is the outcome of one batch item, so clients can retry only the failed ones.
+type JobBatchItem struct {
+	Index       int               `json:"index"`
+	Status      int               `json:"status"`
+	JobID       *uuid.UUID        `json:"jobId,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	FieldErrors map[string]string `json:"fieldErrors,omitempty"`
+}
+
+// JobBatchCreated is a batch item that was saved as a new job.
+type JobBatchCreated struct {
+	Index  int             `json:"index"`
+	JobID  uuid.UUID       `json:"jobId"`
+	Status model.JobStatus `json:"status"`
+}
+
+// JobBatchItemError is a batch item that was refused, with per-field detail for invalid payloads.
+type JobBatchItemError struct {
+	Index       int               `json:"index"`
+	Error       string            `json:"error"`
+	FieldErrors map[string]string `json:"fieldErrors,omitempty"`
+}
@@ -2,6 +2,9 @@ package dto
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/google/uuid"
 
 	"distributed-job-processor/model"
 )
@@ -12,9 +15,27 @@ import (
 // - PAYMENT_PROCESS: "order_12345|customer@email.com|$99.99|card_tok_xyz"
 // - INVENTORY_UPDATE: "product_SKU123|quantity_5|warehouse_US_EAST"
 // - EMAIL_CONFIRMATION: "order_12345|customer@email.com|receipt_url"
+//
+// Optional settings (omitted = the client's defaults, else the global defaults):
+// - priority: 1 (most urgent) to 10, global default model.DefaultPriority
+// - maxRetries: 1 (no retries) to 10, default MAX_RETRIES_<TYPE>, else model.DefaultMaxRetries
+// - expiresAt: RFC 3339 deadline after which a job still PENDING is moved to EXPIRED
+//   instead of being run, default now + DEFAULT_JOB_TTL, else never
+//
+// Optional labels: {"region": "eu-west", "campaign": "black-friday"}, filterable
+// with GET /api/jobs?label.region=eu-west
+//
+// Optional jobId: a client-chosen UUID for idempotent submission. Resubmitting an
+// existing ID returns 409 Conflict with that job's status. Disabled with
+// CLIENT_JOB_IDS_ENABLED=false.
 type JobRequest struct {
-	Type    model.JobType `json:"type" binding:"required"`
-	Payload string        `json:"payload" binding:"required"`
+	JobID      *uuid.UUID      `json:"jobId,omitempty"`
+	Type       model.JobType   `json:"type" binding:"required"`
+	Payload    string          `json:"payload" binding:"required"`
+	Priority   *int            `json:"priority,omitempty" binding:"omitempty,min=1,max=10"`
+	MaxRetries *int            `json:"maxRetries,omitempty" binding:"omitempty,min=1,max=10"`
+	ExpiresAt  *time.Time      `json:"expiresAt,omitempty"`
+	Labels     model.JobLabels `json:"labels,omitempty"`
 }
 
 // ForPaymentProcess is a factory method to create a payment processing job request.
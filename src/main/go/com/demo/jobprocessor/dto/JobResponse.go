@@ -12,33 +12,47 @@ import (
 // Returned when creating a job or querying job status.
 // Fields with omitempty mirror Java's @JsonInclude(NON_NULL).
 type JobResponse struct {
-	JobID        uuid.UUID       `json:"jobId"`
-	ClientID     string          `json:"clientId"`
-	Type         model.JobType   `json:"type"`
-	Status       model.JobStatus `json:"status"`
-	Payload      string          `json:"payload"`
-	Attempts     int             `json:"attempts"`
-	MaxRetries   int             `json:"maxRetries"`
-	CreatedAt    time.Time       `json:"createdAt"`
-	ScheduledAt  *time.Time      `json:"scheduledAt,omitempty"`
-	CompletedAt  *time.Time      `json:"completedAt,omitempty"`
-	ErrorMessage *string         `json:"errorMessage,omitempty"`
+	JobID            uuid.UUID            `json:"jobId"`
+	ClientID         string               `json:"clientId"`
+	Type             model.JobType        `json:"type"`
+	Status           model.JobStatus      `json:"status"`
+	Payload          string               `json:"payload"`
+	Attempts         int                  `json:"attempts"`
+	Priority         int                  `json:"priority"`
+	MaxRetries       int                  `json:"maxRetries"`
+	Charged          bool                 `json:"charged"`
+	Labels           model.JobLabels      `json:"labels,omitempty"`
+	CreatedAt        time.Time            `json:"createdAt"`
+	ScheduledAt      *time.Time           `json:"scheduledAt,omitempty"`
+	ExpiresAt        *time.Time           `json:"expiresAt,omitempty"`
+	CompletedAt      *time.Time           `json:"completedAt,omitempty"`
+	ErrorMessage     *string              `json:"errorMessage,omitempty"`
+	FailureReason    *model.FailureReason `json:"failureReason,omitempty"`
+	SLABreached      bool                 `json:"slaBreached,omitempty"`
+	CompensatesJobID *uuid.UUID           `json:"compensatesJobId,omitempty"`
 }
 
 // JobResponseFrom converts a Job entity to a JobResponse DTO.
 func JobResponseFrom(job *model.Job) JobResponse {
 	return JobResponse{
-		JobID:        job.ID,
-		ClientID:     job.ClientID,
-		Type:         job.Type,
-		Status:       job.Status,
-		Payload:      job.Payload,
-		Attempts:     job.Attempts,
-		MaxRetries:   job.MaxRetries,
-		CreatedAt:    job.CreatedAt,
-		ScheduledAt:  job.ScheduledAt,
-		CompletedAt:  job.CompletedAt,
-		ErrorMessage: job.ErrorMessage,
+		JobID:            job.ID,
+		ClientID:         job.ClientID,
+		Type:             job.Type,
+		Status:           job.Status,
+		Payload:          job.Payload,
+		Attempts:         job.Attempts,
+		Priority:         job.Priority,
+		MaxRetries:       job.MaxRetries,
+		Charged:          job.Charged,
+		Labels:           job.Labels,
+		CreatedAt:        job.CreatedAt,
+		ScheduledAt:      job.ScheduledAt,
+		ExpiresAt:        job.ExpiresAt,
+		CompletedAt:      job.CompletedAt,
+		ErrorMessage:     job.ErrorMessage,
+		FailureReason:    job.FailureReason,
+		SLABreached:      job.SLABreached,
+		CompensatesJobID: job.CompensatesJobID,
 	}
 }
 
@@ -52,7 +66,9 @@ func JobResponseMinimal(job *model.Job) JobResponse {
 		Status:     job.Status,
 		Payload:    job.Payload,
 		Attempts:   job.Attempts,
+		Priority:   job.Priority,
 		MaxRetries: job.MaxRetries,
+		Labels:     job.Labels,
 		CreatedAt:  job.CreatedAt,
 	}
-}
\ No newline at end of file
+}
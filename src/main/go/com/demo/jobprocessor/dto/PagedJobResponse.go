@@ -0,0 +1,18 @@
+package dto
+
+// PagedJobResponse is the response DTO for one page of a job listing.
+// Equivalent to a trimmed-down Spring Data Page<JobResponse>.
+//
+// Example:
+// {
+//   "items": [ ... ],
+//   "page": 0,
+//   "size": 20,
+//   "total": 137
+// }
+type PagedJobResponse struct {
+	Items []JobResponse `json:"items"`
+	Page  int           `json:"page"`
+	Size  int           `json:"size"`
+	Total int64         `json:"total"`
+}
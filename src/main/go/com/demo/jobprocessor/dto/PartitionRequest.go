@@ -0,0 +1,12 @@
+package dto
+
+// PartitionRequest identifies a Kafka partition to pause or resume.
+//
+// Example request body:
+// {"topic": "job-queue", "partition": 3}
+//
+// topic defaults to the main job topic (KAFKA_TOPIC_JOB_QUEUE).
+type PartitionRequest struct {
+	Topic     string `json:"topic"`
+	Partition *int   `json:"partition" binding:"required,min=0"`
+}
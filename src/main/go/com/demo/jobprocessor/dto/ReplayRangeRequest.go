@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"time"
+
+	"distributed-job-processor/model"
+)
+
+// ReplayRangeRequest is the request DTO for replaying completed jobs of a type in a time window.
+//
+// Example:
+// {
+//   "type": "EMAIL_CONFIRMATION",
+//   "from": "2024-01-15T10:00:00Z",
+//   "to": "2024-01-15T11:00:00Z",
+//   "spreadSeconds": 600,
+//   "confirm": true
+// }
+type ReplayRangeRequest struct {
+	Type model.JobType `json:"type" binding:"required"`
+	From time.Time     `json:"from" binding:"required"`
+	To   time.Time     `json:"to" binding:"required"`
+
+	// Replays are scheduled evenly over this many seconds (default REPLAY_SPREAD_SECONDS)
+	SpreadSeconds *int `json:"spreadSeconds,omitempty" binding:"omitempty,min=0,max=86400"`
+
+	// Must be true; guards against replaying by accident
+	Confirm bool `json:"confirm"`
+}
@@ -0,0 +1,42 @@
+package exception
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"distributed-job-processor/model"
+)
+
+// DuplicateJobError is returned when a new job's ID is already taken by an
+// existing job. Carries the existing job's ID and current status when it is the
+// caller's own job, and neither when it is another client's. Implements the error interface.
+type DuplicateJobError struct {
+	JobID  uuid.UUID
+	Status model.JobStatus
+}
+
+// Error returns the error message string.
+func (e *DuplicateJobError) Error() string {
+	if e.Status == "" {
+		return "Job ID is already taken"
+	}
+	return fmt.Sprintf("Job already exists with id: %s (status %s)", e.JobID, e.Status)
+}
+
+// NewDuplicateJobError creates a new DuplicateJobError for the caller's existing job.
+func NewDuplicateJobError(jobID uuid.UUID, status model.JobStatus) *DuplicateJobError {
+	return &DuplicateJobError{JobID: jobID, Status: status}
+}
+
+// NewJobIDTakenError creates a new DuplicateJobError for an ID taken by another
+// client's job, without any of that job's details.
+func NewJobIDTakenError() *DuplicateJobError {
+	return &DuplicateJobError{}
+}
+
+// IsDuplicateJobError checks if an error is a DuplicateJobError.
+func IsDuplicateJobError(err error) bool {
+	_, ok := err.(*DuplicateJobError)
+	return ok
+}
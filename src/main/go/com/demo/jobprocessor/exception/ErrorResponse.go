@@ -20,6 +20,15 @@ type ErrorResponse struct {
 	// Validation errors (field name -> error message)
 	// Only present for validation failures
 	ValidationErrors map[string]string `json:"validationErrors,omitempty"`
+
+	// Existing job's ID and current status
+	// Only present for duplicate job conflicts
+	JobID     string `json:"jobId,omitempty"`
+	JobStatus string `json:"jobStatus,omitempty"`
+
+	// Seconds until the client's rate limit resets
+	// Only present for rate-limited requests
+	RetryAfterSeconds int64 `json:"retryAfterSeconds,omitempty"`
 }
 
 // NewErrorResponse creates a new ErrorResponse with the current timestamp.
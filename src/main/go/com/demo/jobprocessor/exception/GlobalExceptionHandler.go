@@ -1,8 +1,10 @@
 package exception
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -34,6 +36,65 @@ func ErrorHandlerMiddleware() gin.HandlerFunc {
 	}
 }
 
+// HandleBadRequest returns a 400 Bad Request response for malformed request parameters.
+func HandleBadRequest(c *gin.Context, message string) {
+	response := NewErrorResponse(
+		http.StatusBadRequest,
+		"Bad Request",
+		message,
+	)
+	c.JSON(http.StatusBadRequest, response)
+}
+
+// HandleInvalidInput returns a 400 Bad Request response for a request body that
+// couldn't be bound: field-level errors for validation failures, the parse error
+// otherwise (e.g. malformed JSON).
+func HandleInvalidInput(c *gin.Context, err error) {
+	if _, ok := err.(validator.ValidationErrors); ok {
+		HandleValidationError(c, err)
+		return
+	}
+	HandleBadRequest(c, "Invalid input: "+err.Error())
+}
+
+// HandleForbidden returns a 403 Forbidden response for requests acting for another client.
+func HandleForbidden(c *gin.Context, message string) {
+	response := NewErrorResponse(
+		http.StatusForbidden,
+		"Forbidden",
+		message,
+	)
+	c.JSON(http.StatusForbidden, response)
+}
+
+// HandleRateLimited returns a 429 Too Many Requests response, with Retry-After and
+// retryAfterSeconds when the client's limit resets in resetSeconds (0 if unknown).
+func HandleRateLimited(c *gin.Context, resetSeconds int64) {
+	message := "Rate limit exceeded"
+	if resetSeconds > 0 {
+		message = fmt.Sprintf("Rate limit exceeded, resets in %ds", resetSeconds)
+		c.Header("Retry-After", strconv.FormatInt(resetSeconds, 10))
+	}
+	response := NewErrorResponse(
+		http.StatusTooManyRequests,
+		"Too Many Requests",
+		message,
+	)
+	response.RetryAfterSeconds = resetSeconds
+	c.JSON(http.StatusTooManyRequests, response)
+}
+
+// HandleServiceUnavailable returns a 503 Service Unavailable response when a
+// dependency (e.g. the database) can't serve the request.
+func HandleServiceUnavailable(c *gin.Context, message string) {
+	response := NewErrorResponse(
+		http.StatusServiceUnavailable,
+		"Service Unavailable",
+		message,
+	)
+	c.JSON(http.StatusServiceUnavailable, response)
+}
+
 // HandleJobNotFound returns a 404 Not Found response for missing jobs.
 // Equivalent to Java's @ExceptionHandler(JobNotFoundException.class)
 func HandleJobNotFound(c *gin.Context, message string) {
@@ -45,6 +106,68 @@ func HandleJobNotFound(c *gin.Context, message string) {
 	c.JSON(http.StatusNotFound, response)
 }
 
+// HandleJobRejected returns a 422 Unprocessable Entity response for jobs refused before persistence.
+// Equivalent to Java's @ExceptionHandler(JobRejectedException.class)
+func HandleJobRejected(c *gin.Context, message string) {
+	response := NewErrorResponse(
+		http.StatusUnprocessableEntity,
+		"Job Rejected",
+		message,
+	)
+	c.JSON(http.StatusUnprocessableEntity, response)
+}
+
+// HandleInvalidJobState returns a 409 Conflict response for operations the job's status doesn't allow.
+// Equivalent to Java's @ExceptionHandler(InvalidJobStateException.class)
+func HandleInvalidJobState(c *gin.Context, message string) {
+	response := NewErrorResponse(
+		http.StatusConflict,
+		"Invalid Job State",
+		message,
+	)
+	c.JSON(http.StatusConflict, response)
+}
+
+// HandleDuplicateJob returns a 409 Conflict response when a new job's ID is already taken,
+// including the existing job's ID and status only when it is the caller's own job.
+// Equivalent to Java's @ExceptionHandler(DuplicateJobException.class)
+func HandleDuplicateJob(c *gin.Context, err *DuplicateJobError) {
+	response := NewErrorResponse(
+		http.StatusConflict,
+		"Duplicate Job",
+		err.Error(),
+	)
+	if err.Status != "" {
+		response.JobID = err.JobID.String()
+		response.JobStatus = string(err.Status)
+	}
+	c.JSON(http.StatusConflict, response)
+}
+
+// HandleJobTypeUnavailable returns a 503 Service Unavailable response with Retry-After
+// for jobs of a type shed while its downstream is failing.
+// Equivalent to Java's @ExceptionHandler(JobTypeUnavailableException.class)
+func HandleJobTypeUnavailable(c *gin.Context, err *JobTypeUnavailableError) {
+	response := NewErrorResponse(
+		http.StatusServiceUnavailable,
+		"Job Type Unavailable",
+		err.Error(),
+	)
+	c.Header("Retry-After", strconv.Itoa(err.RetryAfterSeconds()))
+	c.JSON(http.StatusServiceUnavailable, response)
+}
+
+// HandlePayloadTooLarge returns a 413 Payload Too Large response for payloads over MAX_PAYLOAD_BYTES.
+// Equivalent to Java's @ExceptionHandler(PayloadTooLargeException.class)
+func HandlePayloadTooLarge(c *gin.Context, err *PayloadTooLargeError) {
+	response := NewErrorResponse(
+		http.StatusRequestEntityTooLarge,
+		"Payload Too Large",
+		err.Error(),
+	)
+	c.JSON(http.StatusRequestEntityTooLarge, response)
+}
+
 // HandleValidationError returns a 400 Bad Request response for validation failures.
 // Equivalent to Java's @ExceptionHandler(MethodArgumentNotValidException.class)
 func HandleValidationError(c *gin.Context, err error) {
@@ -66,6 +189,18 @@ func HandleValidationError(c *gin.Context, err error) {
 	c.JSON(http.StatusBadRequest, response)
 }
 
+// HandlePayloadValidation returns a 400 Bad Request response for payloads that don't match their job type.
+// Equivalent to Java's @ExceptionHandler(PayloadValidationException.class)
+func HandlePayloadValidation(c *gin.Context, err *PayloadValidationError) {
+	response := NewValidationErrorResponse(
+		http.StatusBadRequest,
+		"Validation Failed",
+		"Invalid job payload",
+		err.FieldErrors,
+	)
+	c.JSON(http.StatusBadRequest, response)
+}
+
 // HandleInternalError returns a 500 Internal Server Error response.
 // Equivalent to Java's @ExceptionHandler(Exception.class)
 func HandleInternalError(c *gin.Context) {
@@ -75,4 +210,4 @@ func HandleInternalError(c *gin.Context) {
 		"An unexpected error occurred",
 	)
 	c.JSON(http.StatusInternalServerError, response)
-}
\ No newline at end of file
+}
@@ -0,0 +1,33 @@
+package exception
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"distributed-job-processor/model"
+)
+
+// InvalidJobStateError is returned when an operation isn't allowed in the job's
+// current status (e.g. retrying a RUNNING job). Implements the error interface.
+type InvalidJobStateError struct {
+	JobID     uuid.UUID
+	Status    model.JobStatus
+	Operation string
+}
+
+// Error returns the error message string.
+func (e *InvalidJobStateError) Error() string {
+	return fmt.Sprintf("Job %s is %s and cannot be %s", e.JobID, e.Status, e.Operation)
+}
+
+// NewInvalidJobStateError creates a new InvalidJobStateError for the given job, status, and operation.
+func NewInvalidJobStateError(jobID uuid.UUID, status model.JobStatus, operation string) *InvalidJobStateError {
+	return &InvalidJobStateError{JobID: jobID, Status: status, Operation: operation}
+}
+
+// IsInvalidJobStateError checks if an error is an InvalidJobStateError.
+func IsInvalidJobStateError(err error) bool {
+	_, ok := err.(*InvalidJobStateError)
+	return ok
+}
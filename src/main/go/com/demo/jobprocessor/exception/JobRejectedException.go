@@ -0,0 +1,25 @@
+package exception
+
+import "fmt"
+
+// JobRejectedError is returned when a job is refused before being persisted
+// (e.g. by a JobEnricher). Implements the error interface.
+type JobRejectedError struct {
+	Reason string
+}
+
+// Error returns the error message string.
+func (e *JobRejectedError) Error() string {
+	return fmt.Sprintf("Job rejected: %s", e.Reason)
+}
+
+// NewJobRejectedError creates a new JobRejectedError with the given reason.
+func NewJobRejectedError(reason string) *JobRejectedError {
+	return &JobRejectedError{Reason: reason}
+}
+
+// IsJobRejectedError checks if an error is a JobRejectedError.
+func IsJobRejectedError(err error) bool {
+	_, ok := err.(*JobRejectedError)
+	return ok
+}
@@ -0,0 +1,39 @@
+package exception
+
+import (
+	"fmt"
+	"time"
+
+	"distributed-job-processor/model"
+)
+
+// JobTypeUnavailableError is returned when new jobs of a type are shed because its
+// downstream is failing (the type's circuit breaker is open). RetryAfter is how long
+// until the breaker closes. Implements the error interface.
+type JobTypeUnavailableError struct {
+	Type       model.JobType
+	RetryAfter time.Duration
+}
+
+// Error returns the error message string.
+func (e *JobTypeUnavailableError) Error() string {
+	return fmt.Sprintf("%s jobs are temporarily not accepted: downstream unavailable", e.Type)
+}
+
+// NewJobTypeUnavailableError creates a new JobTypeUnavailableError for the shed type.
+func NewJobTypeUnavailableError(jobType model.JobType, retryAfter time.Duration) *JobTypeUnavailableError {
+	return &JobTypeUnavailableError{Type: jobType, RetryAfter: retryAfter}
+}
+
+// IsJobTypeUnavailableError checks if an error is a JobTypeUnavailableError.
+func IsJobTypeUnavailableError(err error) bool {
+	_, ok := err.(*JobTypeUnavailableError)
+	return ok
+}
+
+// RetryAfterSeconds returns RetryAfter rounded up to whole seconds, at least 1,
+// for the Retry-After header.
+func (e *JobTypeUnavailableError) RetryAfterSeconds() int {
+	seconds := int((e.RetryAfter + time.Second - 1) / time.Second)
+	return max(seconds, 1)
+}
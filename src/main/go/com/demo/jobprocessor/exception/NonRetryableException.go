@@ -0,0 +1,34 @@
+package exception
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NonRetryableError is returned by a job handler for a failure that retrying can't
+// fix (card declined, invalid email address): the worker moves the job straight to
+// DEAD_LETTER instead of scheduling a retry. Implements the error interface.
+type NonRetryableError struct {
+	Err error
+}
+
+// Error returns the error message string.
+func (e *NonRetryableError) Error() string {
+	return fmt.Sprintf("Non-retryable: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *NonRetryableError) Unwrap() error {
+	return e.Err
+}
+
+// NewNonRetryableError wraps err as a NonRetryableError.
+func NewNonRetryableError(err error) *NonRetryableError {
+	return &NonRetryableError{Err: err}
+}
+
+// IsNonRetryableError checks if an error, or any error it wraps, is a NonRetryableError.
+func IsNonRetryableError(err error) bool {
+	var nonRetryable *NonRetryableError
+	return errors.As(err, &nonRetryable)
+}
@@ -0,0 +1,32 @@
+package exception
+
+import (
+	"fmt"
+)
+
+// PayloadTooLargeError is returned when a job payload (or the request carrying it)
+// exceeds MAX_PAYLOAD_BYTES. Implements the error interface.
+type PayloadTooLargeError struct {
+	// Size in bytes of the payload, or -1 when the request body was cut off unread
+	Size  int
+	Limit int
+}
+
+// Error returns the error message string.
+func (e *PayloadTooLargeError) Error() string {
+	if e.Size < 0 {
+		return fmt.Sprintf("Request body too large: payload must be at most %d bytes", e.Limit)
+	}
+	return fmt.Sprintf("Payload too large: %d bytes, must be at most %d bytes", e.Size, e.Limit)
+}
+
+// NewPayloadTooLargeError creates a new PayloadTooLargeError for a payload of size bytes.
+func NewPayloadTooLargeError(size int, limit int) *PayloadTooLargeError {
+	return &PayloadTooLargeError{Size: size, Limit: limit}
+}
+
+// IsPayloadTooLargeError checks if an error is a PayloadTooLargeError.
+func IsPayloadTooLargeError(err error) bool {
+	_, ok := err.(*PayloadTooLargeError)
+	return ok
+}
@@ -0,0 +1,35 @@
+package exception
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PayloadValidationError is returned when a job payload does not match its
+// job type's schema. Implements the error interface.
+type PayloadValidationError struct {
+	// Field name -> error message
+	FieldErrors map[string]string
+}
+
+// Error returns the error message string.
+func (e *PayloadValidationError) Error() string {
+	fields := make([]string, 0, len(e.FieldErrors))
+	for field, msg := range e.FieldErrors {
+		fields = append(fields, fmt.Sprintf("%s %s", field, msg))
+	}
+	sort.Strings(fields)
+	return fmt.Sprintf("Invalid payload: %s", strings.Join(fields, "; "))
+}
+
+// NewPayloadValidationError creates a new PayloadValidationError with the given field errors.
+func NewPayloadValidationError(fieldErrors map[string]string) *PayloadValidationError {
+	return &PayloadValidationError{FieldErrors: fieldErrors}
+}
+
+// IsPayloadValidationError checks if an error is a PayloadValidationError.
+func IsPayloadValidationError(err error) bool {
+	_, ok := err.(*PayloadValidationError)
+	return ok
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// AuditEntry records one admin mutation: who did what, to what, with which parameters.
+type AuditEntry struct {
+	// Sequential identifier
+	ID uint64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+
+	// Admin identity resolved from the admin API key
+	Actor string `json:"actor" gorm:"column:actor;not null;size:100;index:idx_audit_actor"`
+
+	// Action performed, e.g. "cache.clear", "rate_limit.reset"
+	Action string `json:"action" gorm:"column:action;not null;size:50;index:idx_audit_action"`
+
+	// Target of the action (job ID, client ID, ...), empty for global actions
+	Target string `json:"target,omitempty" gorm:"column:target;size:200"`
+
+	// Request parameters as JSON
+	Parameters string `json:"parameters,omitempty" gorm:"column:parameters;type:text"`
+
+	// Timestamp when the action was performed
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;not null;autoCreateTime;index:idx_audit_created_at"`
+}
+
+// TableName specifies the database table name for the AuditEntry model.
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// ClientDefaults holds per-client job settings applied when a create request omits them.
+//
+// A premium client might default to a higher priority and more retries, so its
+// requests don't have to repeat them. Nil fields fall back to the global defaults;
+// values in the request always win.
+type ClientDefaults struct {
+	// Client identifier (X-Client-Id)
+	ClientID string `json:"clientId" gorm:"column:client_id;primaryKey;size:100"`
+
+	// Default maximum retry attempts, nil = global default
+	MaxRetries *int `json:"maxRetries,omitempty" gorm:"column:max_retries"`
+
+	// Default priority (1 = most urgent), nil = DefaultPriority
+	Priority *int `json:"priority,omitempty" gorm:"column:priority"`
+
+	// Timestamp when the defaults were last changed
+	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// TableName specifies the database table name for the ClientDefaults model.
+func (ClientDefaults) TableName() string {
+	return "client_defaults"
+}
@@ -0,0 +1,48 @@
+package model
+
+// FailureReason categorizes why a job attempt failed, so dead letters can be
+// aggregated ("how many due to gateway timeouts vs declined cards").
+type FailureReason string
+
+const (
+	// FailureTimeout - Processing ran past its timeout (e.g. gateway not answering)
+	FailureTimeout FailureReason = "timeout"
+
+	// FailureDeclined - The downstream refused the operation (e.g. card declined)
+	FailureDeclined FailureReason = "declined"
+
+	// FailureInvalidPayload - The payload couldn't be processed as given
+	FailureInvalidPayload FailureReason = "invalid_payload"
+
+	// FailureDownstream5xx - The downstream service returned a server error
+	FailureDownstream5xx FailureReason = "downstream_5xx"
+
+	// FailureNeedsReconciliation - Whether a side effect happened is unknown (e.g. a
+	// crash mid-charge), so an operator must check the downstream before any rerun
+	FailureNeedsReconciliation FailureReason = "needs_reconciliation"
+
+	// FailureUnknown - Anything not classified above
+	FailureUnknown FailureReason = "unknown"
+)
+
+// FailureReasons returns every failure reason, in a stable order.
+func FailureReasons() []FailureReason {
+	return []FailureReason{
+		FailureTimeout,
+		FailureDeclined,
+		FailureInvalidPayload,
+		FailureDownstream5xx,
+		FailureNeedsReconciliation,
+		FailureUnknown,
+	}
+}
+
+// IsValid reports whether r is one of the defined failure reasons.
+func (r FailureReason) IsValid() bool {
+	for _, reason := range FailureReasons() {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
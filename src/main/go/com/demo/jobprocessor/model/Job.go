@@ -1,6 +1,8 @@
 package model
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,14 +31,24 @@ type Job struct {
 	Type JobType `json:"type" gorm:"column:type;not null;size:50"`
 
 	// Current status of the job in its lifecycle
-	Status JobStatus `json:"status" gorm:"column:status;not null;size:20;index:idx_status_scheduled_at"`
+	Status JobStatus `json:"status" gorm:"column:status;not null;size:20;index:idx_status_priority_scheduled_at,priority:1"`
 
 	// Job payload containing the data to be processed
 	Payload string `json:"payload" gorm:"column:payload;not null;type:text"`
 
+	// How Payload is stored: empty for plain text, "gzip" for compressed large payloads
+	PayloadEncoding string `json:"payloadEncoding,omitempty" gorm:"column:payload_encoding;not null;default:'';size:10"`
+
+	// Client metadata for routing/filtering, GIN-indexed for label queries
+	Labels JobLabels `json:"labels,omitempty" gorm:"column:labels;type:jsonb;index:idx_labels,type:gin"`
+
 	// Number of times this job has been attempted
 	Attempts int `json:"attempts" gorm:"column:attempts;not null;default:0"`
 
+	// Job priority from 1 (most urgent) to 10, lower = higher priority.
+	// NOT NULL DEFAULT 5, so rows that predate the column are migrated to priority 5
+	Priority int `json:"priority" gorm:"column:priority;not null;default:5;index:idx_status_priority_scheduled_at,priority:2"`
+
 	// Maximum number of retry attempts before moving to DEAD_LETTER
 	MaxRetries int `json:"maxRetries" gorm:"column:max_retries;not null;default:3"`
 
@@ -44,16 +56,96 @@ type Job struct {
 	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;not null;autoCreateTime;index:idx_created_at"`
 
 	// Timestamp when the job should be/was scheduled for processing
-	ScheduledAt *time.Time `json:"scheduledAt,omitempty" gorm:"column:scheduled_at;not null;index:idx_status_scheduled_at"`
+	ScheduledAt *time.Time `json:"scheduledAt,omitempty" gorm:"column:scheduled_at;not null;index:idx_status_priority_scheduled_at,priority:3"`
+
+	// Deadline after which a still-PENDING job is moved to EXPIRED instead of being run; nil never expires
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" gorm:"column:expires_at"`
+
+	// Timestamp when the scheduler's Kafka write of the current attempt was confirmed; nil while
+	// claimed but not yet published
+	PublishedAt *time.Time `json:"publishedAt,omitempty" gorm:"column:published_at"`
+
+	// Timestamp when a worker began processing the current attempt; nil while still queued in Kafka
+	ProcessingStartedAt *time.Time `json:"processingStartedAt,omitempty" gorm:"column:processing_started_at;index:idx_processing_started_at"`
 
 	// Timestamp when the job completed (successfully or failed permanently)
-	CompletedAt *time.Time `json:"completedAt,omitempty" gorm:"column:completed_at"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" gorm:"column:completed_at;index:idx_completed_at;index:idx_sla_breached_completed_at,priority:2"`
+
+	// Set at completion when the job took longer from creation than its type's SLA (SLA_<TYPE>)
+	SLABreached bool `json:"slaBreached" gorm:"column:sla_breached;not null;default:false;index:idx_sla_breached_completed_at,priority:1"`
+
+	// Set the instant a payment charge succeeds, so a reprocessed job never charges twice
+	Charged bool `json:"charged" gorm:"column:charged;not null;default:false"`
+
+	// Set the instant the handler succeeds, before the job is saved COMPLETED, so a job
+	// redelivered or reaped after a crash in between is completed without redoing its work
+	WorkDoneAt *time.Time `json:"workDoneAt,omitempty" gorm:"column:work_done_at"`
+
+	// Job this one compensates (e.g. the payment a REFUND undoes); unique, so a job is
+	// compensated at most once
+	CompensatesJobID *uuid.UUID `json:"compensatesJobId,omitempty" gorm:"column:compensates_job_id;type:uuid;uniqueIndex:idx_compensates_job_id"`
 
 	// Optional error message if job failed
 	ErrorMessage *string `json:"errorMessage,omitempty" gorm:"column:error_message;type:text"`
 
+	// Category of the last failure, set alongside ErrorMessage; aggregated for dead letters
+	FailureReason *FailureReason `json:"failureReason,omitempty" gorm:"column:failure_reason;type:varchar(32);index:idx_failure_reason"`
+
+	// W3C traceparent of the job.create span, linking the scheduler and worker spans to it
+	TraceParent *string `json:"traceParent,omitempty" gorm:"column:trace_parent;size:64"`
+
 	// Timestamp when the job was last updated
 	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+
+	// Optimistic lock, incremented by every save (see repository.JobRepository.UpdateJobSafe).
+	// NOT NULL DEFAULT 0, so rows that predate the column start at version 0
+	Version int `json:"version" gorm:"column:version;not null;default:0"`
+}
+
+// Job priority bounds and default. Lower values are more urgent.
+const (
+	MinPriority     = 1
+	MaxPriority     = 10
+	DefaultPriority = 5
+)
+
+// Max retries bounds and global default. MaxRetries is the total number of attempts,
+// so 1 means no retries. MAX_RETRIES_<TYPE> overrides the default per job type.
+const (
+	MinMaxRetries     = 1
+	MaxMaxRetries     = 10
+	DefaultMaxRetries = 3
+)
+
+// ErrInconsistentAttempts is returned when saving a job whose Attempts is negative
+// or more than one past MaxRetries, which no state transition produces.
+var ErrInconsistentAttempts = errors.New("inconsistent job attempts")
+
+// maxAttempts is the highest consistent Attempts value. Attempts normally stops at
+// MaxRetries; one extra is tolerated for an attempt counted while dead-lettering.
+func (j *Job) maxAttempts() int {
+	return max(j.MaxRetries, 0) + 1
+}
+
+// CheckAttempts returns ErrInconsistentAttempts unless 0 <= Attempts <= MaxRetries+1.
+func (j *Job) CheckAttempts() error {
+	if j.Attempts < 0 || j.Attempts > j.maxAttempts() {
+		return fmt.Errorf("%w: job %s has attempts %d with maxRetries %d",
+			ErrInconsistentAttempts, j.ID, j.Attempts, j.MaxRetries)
+	}
+	return nil
+}
+
+// ClampAttempts pulls Attempts back into [0, MaxRetries+1], so a corrupt row still
+// behaves sanely (e.g. is dead-lettered rather than retried forever).
+// Returns true if Attempts was changed.
+func (j *Job) ClampAttempts() bool {
+	clamped := min(max(j.Attempts, 0), j.maxAttempts())
+	if clamped == j.Attempts {
+		return false
+	}
+	j.Attempts = clamped
+	return true
 }
 
 // TableName specifies the database table name for the Job model.
@@ -75,7 +167,10 @@ func (j *Job) BeforeCreate(tx *gorm.DB) error {
 		j.Attempts = 0
 	}
 	if j.MaxRetries == 0 {
-		j.MaxRetries = 3
+		j.MaxRetries = DefaultMaxRetries
+	}
+	if j.Priority == 0 {
+		j.Priority = DefaultPriority
 	}
 	return nil
 }
@@ -94,14 +189,15 @@ func (j *Job) BeforeUpdate(tx *gorm.DB) error {
 func NewJob(clientID string, jobType JobType, payload string) *Job {
 	now := time.Now()
 	return &Job{
-		ID:         uuid.New(),
-		ClientID:   clientID,
-		Type:       jobType,
-		Status:     StatusPending,
-		Payload:    payload,
-		Attempts:   0,
-		MaxRetries: 3,
-		CreatedAt:  now,
+		ID:          uuid.New(),
+		ClientID:    clientID,
+		Type:        jobType,
+		Status:      StatusPending,
+		Payload:     payload,
+		Attempts:    0,
+		Priority:    DefaultPriority,
+		MaxRetries:  DefaultMaxRetries,
+		CreatedAt:   now,
 		ScheduledAt: &now,
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AttemptStatus is the outcome of one processing attempt.
+type AttemptStatus string
+
+const (
+	// AttemptSucceeded - The attempt completed the job
+	AttemptSucceeded AttemptStatus = "SUCCEEDED"
+
+	// AttemptFailed - The attempt failed; the job was retried or dead-lettered
+	AttemptFailed AttemptStatus = "FAILED"
+)
+
+// JobAttempt records one processing attempt of a job, so the history of failures
+// stays visible after later attempts overwrite the job's ErrorMessage.
+type JobAttempt struct {
+	// Sequential identifier
+	ID uint64 `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+
+	// Job the attempt belongs to
+	JobID uuid.UUID `json:"jobId" gorm:"column:job_id;type:uuid;not null;index:idx_job_attempts_job_id"`
+
+	// 1-based attempt number (the job's attempts count when it started, plus one)
+	AttemptNumber int `json:"attemptNumber" gorm:"column:attempt_number;not null"`
+
+	// Timestamp when the worker started processing
+	StartedAt time.Time `json:"startedAt" gorm:"column:started_at;not null"`
+
+	// Timestamp when processing returned
+	FinishedAt time.Time `json:"finishedAt" gorm:"column:finished_at;not null"`
+
+	// Outcome of the attempt
+	Status AttemptStatus `json:"status" gorm:"column:status;type:varchar(20);not null"`
+
+	// Error the attempt failed with, nil on success
+	Error *string `json:"error,omitempty" gorm:"column:error;type:text"`
+}
+
+// TableName specifies the database table name for the JobAttempt model.
+func (JobAttempt) TableName() string {
+	return "job_attempts"
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JobLabels is free-form client metadata attached to a job (region, campaign, tier, ...).
+// Stored as a JSONB object so jobs can be filtered by label without parsing the payload.
+type JobLabels map[string]string
+
+// WorkerPoolLabel pins a job to a worker pool, e.g. {"workerPool": "onprem"}: the job is
+// published to the pool's topic and only that pool's workers run it.
+const WorkerPoolLabel = "workerPool"
+
+// WorkerPool returns the worker pool the job is pinned to, empty for the general pool.
+func (l JobLabels) WorkerPool() string {
+	return l[WorkerPoolLabel]
+}
+
+// Value implements driver.Valuer, storing the labels as a JSON object (NULL when empty).
+func (l JobLabels) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(map[string]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+// Scan implements sql.Scanner, reading a JSON object column.
+func (l *JobLabels) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("unsupported labels column type %T", value)
+	}
+
+	var labels map[string]string
+	if err := json.Unmarshal(raw, &labels); err != nil {
+		return err
+	}
+	*l = labels
+	return nil
+}
@@ -18,4 +18,37 @@ const (
 
 	// StatusDeadLetter - Job has exceeded max retries and moved to dead letter
 	StatusDeadLetter JobStatus = "DEAD_LETTER"
-)
\ No newline at end of file
+
+	// StatusExpired - Job stayed PENDING past its expiresAt or its type's max age and will not be run
+	StatusExpired JobStatus = "EXPIRED"
+)
+
+// JobStatuses returns every job status, in lifecycle order.
+func JobStatuses() []JobStatus {
+	return []JobStatus{
+		StatusPending,
+		StatusRunning,
+		StatusCompleted,
+		StatusFailed,
+		StatusDeadLetter,
+		StatusExpired,
+	}
+}
+
+// IsValid reports whether s is one of the defined job statuses.
+func (s JobStatus) IsValid() bool {
+	switch s {
+	case StatusPending, StatusRunning, StatusCompleted, StatusFailed, StatusDeadLetter, StatusExpired:
+		return true
+	}
+	return false
+}
+
+// IsFinished reports whether s is a final status: COMPLETED, FAILED, DEAD_LETTER, or EXPIRED.
+func (s JobStatus) IsFinished() bool {
+	switch s {
+	case StatusCompleted, StatusFailed, StatusDeadLetter, StatusExpired:
+		return true
+	}
+	return false
+}
\ No newline at end of file
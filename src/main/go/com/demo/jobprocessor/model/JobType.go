@@ -56,4 +56,27 @@ const (
 	// Note: This job is only created AFTER payment succeeds. If payment fails,
 	// no email confirmation job is created.
 	TypeEmailConfirmation JobType = "EMAIL_CONFIRMATION"
+
+	// TypeHealthCheck is a synthetic canary job that completes without doing any work.
+	//
+	// An external monitor submits one and watches it reach COMPLETED, which proves the
+	// whole pipeline works end to end: API → database → scheduler → Kafka → worker.
+	//
+	// Payload format: "probe_20240115T100000Z"
+	//
+	// Only accepted with HEALTH_CHECK_JOBS_ENABLED=true. Kept out of job statistics and
+	// reports, and purged soon after finishing (see config.GetHealthCheckJobRetention).
+	TypeHealthCheck JobType = "HEALTH_CHECK"
+
+	// TypeRefund refunds the charge of a completed PAYMENT_PROCESS job.
+	//
+	// Simulated processing time: 1 second
+	// Real-world operation: Refund the charge through the payment gateway
+	//
+	// Payload format: "order_12345|customer@email.com|$99.99|<payment job ID>"
+	//
+	// Created by compensating the payment (POST /api/jobs/:id/compensate), when a later
+	// step of the order failed; the refund job's compensatesJobId links it to the payment.
+	// Never created through POST /api/jobs.
+	TypeRefund JobType = "REFUND"
 )
\ No newline at end of file
@@ -0,0 +1,88 @@
+package model
+
+// Payload field formats understood by the payload validator.
+const (
+	FormatText   = "text"   // Any non-empty value
+	FormatEmail  = "email"  // customer@email.com
+	FormatAmount = "amount" // $99.99 (currency symbol optional)
+)
+
+// PayloadField describes one field of a pipe-delimited job payload.
+type PayloadField struct {
+	Name     string `json:"name"`
+	Format   string `json:"format"`
+	Required bool   `json:"required"`
+}
+
+// JobTypeSpec describes a supported job type: the payload layout it expects
+// and its simulated processing time.
+type JobTypeSpec struct {
+	Type             JobType        `json:"type"`
+	Description      string         `json:"description"`
+	PayloadFields    []PayloadField `json:"payloadFields"`
+	ProcessingTimeMs int            `json:"processingTimeMs"`
+}
+
+// jobTypeSpecs is the single source of truth for job type payload rules.
+// Anything that validates payloads or describes job types (GET /api/jobs/types)
+// reads from this table, so the published schema matches what is enforced.
+var jobTypeSpecs = []JobTypeSpec{
+	{
+		Type:        TypePaymentProcess,
+		Description: "Charge the customer's card and decrement inventory",
+		PayloadFields: []PayloadField{
+			{Name: "orderId", Format: FormatText, Required: true},
+			{Name: "customerEmail", Format: FormatEmail, Required: true},
+			{Name: "amount", Format: FormatAmount, Required: true},
+			{Name: "productSku", Format: FormatText, Required: false},
+			{Name: "quantity", Format: FormatText, Required: false},
+		},
+		ProcessingTimeMs: 2000,
+	},
+	{
+		Type:        TypeEmailConfirmation,
+		Description: "Send the order confirmation email to the customer",
+		PayloadFields: []PayloadField{
+			{Name: "orderId", Format: FormatText, Required: true},
+			{Name: "customerEmail", Format: FormatEmail, Required: true},
+			{Name: "receiptUrl", Format: FormatText, Required: false},
+		},
+		ProcessingTimeMs: 1000,
+	},
+	{
+		Type:        TypeHealthCheck,
+		Description: "Canary that completes immediately, to verify the pipeline end to end",
+		PayloadFields: []PayloadField{
+			{Name: "probeId", Format: FormatText, Required: true},
+		},
+		ProcessingTimeMs: 100,
+	},
+	{
+		Type:        TypeRefund,
+		Description: "Refund the charge of a completed payment",
+		PayloadFields: []PayloadField{
+			{Name: "orderId", Format: FormatText, Required: true},
+			{Name: "customerEmail", Format: FormatEmail, Required: true},
+			{Name: "amount", Format: FormatAmount, Required: true},
+			{Name: "paymentJobId", Format: FormatText, Required: true},
+		},
+		ProcessingTimeMs: 1000,
+	},
+}
+
+// JobTypeSpecs returns the specs of all supported job types.
+func JobTypeSpecs() []JobTypeSpec {
+	specs := make([]JobTypeSpec, len(jobTypeSpecs))
+	copy(specs, jobTypeSpecs)
+	return specs
+}
+
+// LookupJobTypeSpec returns the spec for a job type, if it is supported.
+func LookupJobTypeSpec(jobType JobType) (JobTypeSpec, bool) {
+	for _, spec := range jobTypeSpecs {
+		if spec.Type == jobType {
+			return spec, true
+		}
+	}
+	return JobTypeSpec{}, false
+}
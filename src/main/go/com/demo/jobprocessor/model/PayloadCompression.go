@@ -0,0 +1,91 @@
+package model
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// Payload encodings recorded in Job.PayloadEncoding.
+const (
+	PayloadEncodingPlain = ""     // Payload stored as-is
+	PayloadEncodingGzip  = "gzip" // Payload stored as base64(gzip(payload))
+)
+
+// EncodePayload compresses a payload for storage when it is at least threshold bytes.
+// A threshold of 0 or less disables compression. Returns the stored form and its encoding.
+//
+// Small payloads stay plain: gzip + base64 overhead outweighs the savings
+// and every read would pay for decompression.
+func EncodePayload(payload string, threshold int) (string, string, error) {
+	if threshold <= 0 || len(payload) < threshold {
+		return payload, PayloadEncodingPlain, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(payload)); err != nil {
+		return "", "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", "", err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	if len(encoded) >= len(payload) {
+		// Incompressible (already compressed or random data), keep it plain
+		return payload, PayloadEncodingPlain, nil
+	}
+	return encoded, PayloadEncodingGzip, nil
+}
+
+// DecodePayload reverses EncodePayload.
+func DecodePayload(stored string, encoding string) (string, error) {
+	switch encoding {
+	case PayloadEncodingPlain:
+		return stored, nil
+	case PayloadEncodingGzip:
+		raw, err := base64.StdEncoding.DecodeString(stored)
+		if err != nil {
+			return "", fmt.Errorf("decoding gzip payload: %w", err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return "", fmt.Errorf("decoding gzip payload: %w", err)
+		}
+		defer zr.Close()
+		payload, err := io.ReadAll(zr)
+		if err != nil {
+			return "", fmt.Errorf("decoding gzip payload: %w", err)
+		}
+		return string(payload), nil
+	default:
+		return "", fmt.Errorf("unknown payload encoding %q", encoding)
+	}
+}
+
+// EncodedCopy returns a copy of the job with its payload in stored form.
+// The job itself is left untouched, so callers keep working with the plain payload.
+func (j *Job) EncodedCopy(threshold int) (*Job, error) {
+	stored, encoding, err := EncodePayload(j.Payload, threshold)
+	if err != nil {
+		return nil, err
+	}
+	encoded := *j
+	encoded.Payload = stored
+	encoded.PayloadEncoding = encoding
+	return &encoded, nil
+}
+
+// DecodePayloadInPlace replaces a stored payload with the plain payload.
+func (j *Job) DecodePayloadInPlace() error {
+	payload, err := DecodePayload(j.Payload, j.PayloadEncoding)
+	if err != nil {
+		return err
+	}
+	j.Payload = payload
+	j.PayloadEncoding = PayloadEncodingPlain
+	return nil
+}
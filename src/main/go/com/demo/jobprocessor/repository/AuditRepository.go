@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"distributed-job-processor/model"
+)
+
+// AuditRepository provides persistence operations for the admin audit log.
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new AuditRepository with the given database connection.
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Save appends an entry to the audit log.
+func (r *AuditRepository) Save(entry *model.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
+
+// FindRecent returns up to limit entries, newest first, optionally filtered by action.
+func (r *AuditRepository) FindRecent(action string, limit int) ([]model.AuditEntry, error) {
+	var entries []model.AuditEntry
+	query := r.db.Order("created_at DESC, id DESC").Limit(limit)
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	err := query.Find(&entries).Error
+	return entries, err
+}
+
+// DeleteCreatedBefore removes entries older than the given time and returns how many were removed.
+func (r *AuditRepository) DeleteCreatedBefore(createdBefore time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", createdBefore).Delete(&model.AuditEntry{})
+	return result.RowsAffected, result.Error
+}
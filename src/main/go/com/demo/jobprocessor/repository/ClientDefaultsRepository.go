@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"distributed-job-processor/model"
+)
+
+// ClientDefaultsRepository provides persistence operations for per-client job defaults.
+type ClientDefaultsRepository struct {
+	db *gorm.DB
+}
+
+// NewClientDefaultsRepository creates a new ClientDefaultsRepository with the given database connection.
+func NewClientDefaultsRepository(db *gorm.DB) *ClientDefaultsRepository {
+	return &ClientDefaultsRepository{db: db}
+}
+
+// FindByClientID returns the client's defaults, or nil (and no error) if none are configured.
+func (r *ClientDefaultsRepository) FindByClientID(clientID string) (*model.ClientDefaults, error) {
+	var defaults model.ClientDefaults
+	err := r.db.First(&defaults, "client_id = ?", clientID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &defaults, nil
+}
+
+// Save creates or updates a client's defaults.
+func (r *ClientDefaultsRepository) Save(defaults *model.ClientDefaults) error {
+	return r.db.Save(defaults).Error
+}
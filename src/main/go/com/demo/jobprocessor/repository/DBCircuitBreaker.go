@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrDBUnavailable is returned by every query while the DB circuit breaker is open.
+var ErrDBUnavailable = errors.New("database unavailable: circuit breaker open")
+
+// IsDBUnavailable reports whether err is a query short-circuited by an open breaker.
+func IsDBUnavailable(err error) bool {
+	return errors.Is(err, ErrDBUnavailable)
+}
+
+// DBCircuitBreaker fails database calls fast during an outage instead of letting
+// every repository call wait on, and log, a dead connection.
+//
+// It hooks into GORM's callbacks, so it covers every repository sharing the *gorm.DB:
+// - Closed: calls run normally; DB_CIRCUIT_FAILURE_THRESHOLD consecutive connection
+//   failures (refused, reset, bad connection) open the breaker. Query errors such as
+//   constraint violations or missing rows count as successes: the database answered.
+// - Open: calls fail immediately with ErrDBUnavailable. A health probe pings the
+//   database every DB_CIRCUIT_PROBE_INTERVAL and closes the breaker on the first success.
+//
+// Configuration: DB_CIRCUIT_FAILURE_THRESHOLD (default 5, 0 disables the breaker) and
+// DB_CIRCUIT_PROBE_INTERVAL (default 5s). The scheduler skips polls while the breaker
+// is open, workers hold their messages uncommitted, and GET /api/jobs/health reports its state.
+type DBCircuitBreaker struct {
+	db            *gorm.DB
+	threshold     int
+	probeInterval time.Duration
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	stopCh   chan struct{}
+}
+
+// DB circuit breaker states, as reported by State.
+const (
+	DBCircuitClosed = "CLOSED"
+	DBCircuitOpen   = "OPEN"
+)
+
+// dbProbeTimeout bounds each health probe ping.
+const dbProbeTimeout = 2 * time.Second
+
+// NewDBCircuitBreakerFromEnv installs a breaker on db from DB_CIRCUIT_* env vars.
+// Returns nil (and no error) when DB_CIRCUIT_FAILURE_THRESHOLD is 0.
+func NewDBCircuitBreakerFromEnv(db *gorm.DB) (*DBCircuitBreaker, error) {
+	threshold := 5
+	if val := os.Getenv("DB_CIRCUIT_FAILURE_THRESHOLD"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			threshold = parsed
+		} else {
+			log.Printf("Ignoring invalid DB_CIRCUIT_FAILURE_THRESHOLD %q: must be a non-negative integer", val)
+		}
+	}
+	if threshold == 0 {
+		return nil, nil
+	}
+
+	probeInterval := 5 * time.Second
+	if val := os.Getenv("DB_CIRCUIT_PROBE_INTERVAL"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed > 0 {
+			probeInterval = parsed
+		} else {
+			log.Printf("Ignoring invalid DB_CIRCUIT_PROBE_INTERVAL %q: must be a positive duration", val)
+		}
+	}
+	return NewDBCircuitBreaker(db, threshold, probeInterval)
+}
+
+// NewDBCircuitBreaker installs a breaker on db that opens after threshold
+// consecutive connection failures and probes every probeInterval while open.
+func NewDBCircuitBreaker(db *gorm.DB, threshold int, probeInterval time.Duration) (*DBCircuitBreaker, error) {
+	b := &DBCircuitBreaker{
+		db:            db,
+		threshold:     threshold,
+		probeInterval: probeInterval,
+		stopCh:        make(chan struct{}),
+	}
+
+	// First before, and last after, every other callback of each operation
+	callbacks := db.Callback()
+	registrations := []error{
+		callbacks.Create().Before("*").Register("circuit_breaker:before_create", b.before),
+		callbacks.Create().After("*").Register("circuit_breaker:after_create", b.after),
+		callbacks.Query().Before("*").Register("circuit_breaker:before_query", b.before),
+		callbacks.Query().After("*").Register("circuit_breaker:after_query", b.after),
+		callbacks.Update().Before("*").Register("circuit_breaker:before_update", b.before),
+		callbacks.Update().After("*").Register("circuit_breaker:after_update", b.after),
+		callbacks.Delete().Before("*").Register("circuit_breaker:before_delete", b.before),
+		callbacks.Delete().After("*").Register("circuit_breaker:after_delete", b.after),
+		callbacks.Row().Before("*").Register("circuit_breaker:before_row", b.before),
+		callbacks.Row().After("*").Register("circuit_breaker:after_row", b.after),
+		callbacks.Raw().Before("*").Register("circuit_breaker:before_raw", b.before),
+		callbacks.Raw().After("*").Register("circuit_breaker:after_raw", b.after),
+	}
+	if err := errors.Join(registrations...); err != nil {
+		return nil, err
+	}
+
+	log.Printf("DB circuit breaker enabled (failure threshold: %d, probe interval: %v)", threshold, probeInterval)
+	return b, nil
+}
+
+// Open reports whether calls are currently short-circuited. A nil breaker is never open.
+func (b *DBCircuitBreaker) Open() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// State returns DBCircuitOpen or DBCircuitClosed.
+func (b *DBCircuitBreaker) State() string {
+	if b.Open() {
+		return DBCircuitOpen
+	}
+	return DBCircuitClosed
+}
+
+// Stop ends a running health probe.
+func (b *DBCircuitBreaker) Stop() {
+	close(b.stopCh)
+}
+
+// before short-circuits the call while the breaker is open.
+// GORM's own callbacks skip their work once the statement has an error.
+func (b *DBCircuitBreaker) before(tx *gorm.DB) {
+	if b.Open() {
+		tx.AddError(ErrDBUnavailable)
+	}
+}
+
+// after records the outcome of a call that reached the database.
+func (b *DBCircuitBreaker) after(tx *gorm.DB) {
+	if IsDBUnavailable(tx.Error) {
+		return
+	}
+	b.recordResult(tx.Error)
+}
+
+// recordResult counts consecutive connection failures, opening the breaker at the threshold.
+func (b *DBCircuitBreaker) recordResult(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.open {
+		return
+	}
+	if !isConnectionError(err) {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures < b.threshold {
+		return
+	}
+	b.open = true
+	b.openedAt = time.Now()
+	log.Printf("DB circuit breaker opened after %d consecutive connection failures (last: %v); failing fast until the database responds", b.failures, err)
+	go b.probe()
+}
+
+// probe pings the database until it responds, then closes the breaker.
+func (b *DBCircuitBreaker) probe() {
+	ticker := time.NewTicker(b.probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stopCh:
+			return
+		case <-ticker.C:
+			if err := b.ping(); err != nil {
+				log.Printf("DB health probe failed: %v", err)
+				continue
+			}
+			b.mu.Lock()
+			b.open = false
+			b.failures = 0
+			downtime := time.Since(b.openedAt)
+			b.mu.Unlock()
+			log.Printf("DB circuit breaker closed: database responding again after %v", downtime.Round(time.Second))
+			return
+		}
+	}
+}
+
+// ping checks the connection without going through GORM, so it isn't short-circuited.
+func (b *DBCircuitBreaker) ping() error {
+	sqlDB, err := b.db.DB()
+	if err != nil {
+		return err
+	}
+	pingCtx, cancel := context.WithTimeout(context.Background(), dbProbeTimeout)
+	defer cancel()
+	return sqlDB.PingContext(pingCtx)
+}
+
+// isConnectionError reports whether err means the database couldn't be reached,
+// as opposed to the database rejecting the statement.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, substr := range []string{"connection refused", "connection reset", "broken pipe", "failed to connect", "server closed the connection", "no connection to the server"} {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"distributed-job-processor/config"
+)
+
+// Connection pool defaults. database/sql's own defaults (unlimited open connections,
+// 2 idle, never recycled) exhaust Postgres under a flash sale and then churn
+// connections once the load drops.
+const (
+	defaultDBMaxOpenConns    = 25
+	defaultDBMaxIdleConns    = 10
+	defaultDBConnMaxLifetime = 30 * time.Minute
+)
+
+// ConfigureDBPoolFromEnv sizes the database connection pool and reports its stats
+// on GET /metrics (see config.Metrics.ObserveDBPool). Call once at startup, after opening
+// the database connection.
+//
+// Configuration:
+// - DB_MAX_OPEN_CONNS (default 25, 0 = unlimited): should stay below Postgres'
+//   max_connections divided by the number of instances
+// - DB_MAX_IDLE_CONNS (default 10): connections kept open between bursts; capped at
+//   DB_MAX_OPEN_CONNS by database/sql
+// - DB_CONN_MAX_LIFETIME (default 30m, 0 = forever): connections are recycled after
+//   this long, so a failover or load balancer change is picked up
+func ConfigureDBPoolFromEnv(db *gorm.DB) error {
+	maxOpen := defaultDBMaxOpenConns
+	if val := os.Getenv("DB_MAX_OPEN_CONNS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			maxOpen = parsed
+		} else {
+			log.Printf("Ignoring invalid DB_MAX_OPEN_CONNS %q: must be a non-negative integer", val)
+		}
+	}
+
+	maxIdle := defaultDBMaxIdleConns
+	if val := os.Getenv("DB_MAX_IDLE_CONNS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			maxIdle = parsed
+		} else {
+			log.Printf("Ignoring invalid DB_MAX_IDLE_CONNS %q: must be a non-negative integer", val)
+		}
+	}
+
+	maxLifetime := defaultDBConnMaxLifetime
+	if val := os.Getenv("DB_CONN_MAX_LIFETIME"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed >= 0 {
+			maxLifetime = parsed
+		} else {
+			log.Printf("Ignoring invalid DB_CONN_MAX_LIFETIME %q: must be a non-negative duration", val)
+		}
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxOpenConns(maxOpen)
+	sqlDB.SetMaxIdleConns(maxIdle)
+	sqlDB.SetConnMaxLifetime(maxLifetime)
+	config.GetMetrics().ObserveDBPool(sqlDB)
+
+	log.Printf("Database pool: max open %d, max idle %d, max lifetime %v", maxOpen, maxIdle, maxLifetime)
+	return nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"distributed-job-processor/model"
+)
+
+// JobAttemptRepository provides persistence operations for the per-job attempt history.
+type JobAttemptRepository struct {
+	db *gorm.DB
+}
+
+// NewJobAttemptRepository creates a new JobAttemptRepository with the given database connection.
+func NewJobAttemptRepository(db *gorm.DB) *JobAttemptRepository {
+	return &JobAttemptRepository{db: db}
+}
+
+// Save appends an attempt to its job's history.
+func (r *JobAttemptRepository) Save(attempt *model.JobAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+// FindByJobID returns the job's attempts, oldest first.
+func (r *JobAttemptRepository) FindByJobID(jobID uuid.UUID) ([]model.JobAttempt, error) {
+	attempts := []model.JobAttempt{}
+	err := r.db.Where("job_id = ?", jobID).Order("started_at ASC, id ASC").Find(&attempts).Error
+	return attempts, err
+}
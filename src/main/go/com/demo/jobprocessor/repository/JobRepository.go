@@ -1,28 +1,178 @@
 package repository
 
 import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"distributed-job-processor/config"
 	"distributed-job-processor/model"
 )
 
 // JobRepository provides persistence operations for the Job entity.
 // Equivalent to Spring Data JPA's JpaRepository with custom queries.
+//
+// Payloads of at least PAYLOAD_COMPRESSION_THRESHOLD bytes are stored gzip
+// compressed (see model.EncodePayload); every method returns plain payloads.
+//
+// Attempts consistency (VALIDATE_JOB_ATTEMPTS=false disables, default on):
+// - Writes of a job with attempts < 0 or attempts > maxRetries+1 are rejected
+//   with model.ErrInconsistentAttempts (see model.Job.CheckAttempts)
+// - Rows loaded with such a value (e.g. from a manual bulk update) are clamped
+//   into range and logged, so the retry logic never sees them
+//
+// scheduled_at is NOT NULL, but Job.ScheduledAt is a pointer: an update of a job whose
+// ScheduledAt was cleared in memory keeps the stored value instead of failing on the
+// constraint (or rescheduling the job). PRESERVE_SCHEDULED_AT=false skips the lookup
+// and writes the current time instead.
+//
+// Optimistic locking: every job row carries a version, incremented by each write.
+// UpdateJobSafe only writes when the stored version is still the one the job was
+// loaded with, returning ErrStaleJob otherwise, so a stale copy (e.g. a worker's
+// cached job, claimed RUNNING since by the scheduler) never silently overwrites a newer one.
 type JobRepository struct {
-	db *gorm.DB
+	db                *gorm.DB
+	compressThreshold int
+	validateAttempts  bool
+	keepScheduledAt   bool
 }
 
 // NewJobRepository creates a new JobRepository with the given database connection.
 func NewJobRepository(db *gorm.DB) *JobRepository {
-	return &JobRepository{db: db}
+	return &JobRepository{
+		db:                db,
+		compressThreshold: config.GetPayloadCompressionThreshold(),
+		validateAttempts:  os.Getenv("VALIDATE_JOB_ATTEMPTS") != "false",
+		keepScheduledAt:   os.Getenv("PRESERVE_SCHEDULED_AT") != "false",
+	}
+}
+
+// Create inserts a new job. Fails if a job with the same ID already exists,
+// so a job the caller believes is new can never overwrite an existing row.
+// Runs the BeforeCreate hook.
+func (r *JobRepository) Create(job *model.Job) error {
+	if err := r.checkAttempts(job); err != nil {
+		return err
+	}
+	return r.withEncodedPayload(job, func() error {
+		return r.db.Create(job).Error
+	})
+}
+
+// ErrStaleJob is returned by UpdateJobSafe when the job was saved by someone else since
+// it was loaded. Reload the job and reapply the change before saving again.
+var ErrStaleJob = errors.New("job was modified concurrently")
+
+// UpdateJobSafe writes every field of an existing job if its stored version is still
+// job.Version, incrementing the version on success. Returns ErrStaleJob if the row
+// has been written since the job was loaded, or gorm.ErrRecordNotFound if the job no
+// longer exists, so a stale copy never resurrects a deleted row.
+// CreatedAt is never overwritten; a nil ScheduledAt keeps the stored one.
+// PublishedAt is never written either: MarkPublished sets it without bumping the
+// version, so a copy loaded before the mark (e.g. a worker's cached one) would
+// otherwise write it back to NULL.
+//
+// Equivalent to:
+// UPDATE jobs SET ... (but published_at), version = :version + 1 WHERE id = :id AND version = :version
+func (r *JobRepository) UpdateJobSafe(job *model.Job) error {
+	if err := r.checkAttempts(job); err != nil {
+		return err
+	}
+	r.normalizeScheduledAt(job)
+	loaded := job.Version
+	job.Version++
+	err := r.withEncodedPayload(job, func() error {
+		result := r.db.Model(job).Where("version = ?", loaded).Select("*").Omit("id", "created_at", "published_at").Updates(job)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return r.missingOrStale(job.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		job.Version = loaded
+	}
+	return err
+}
+
+// missingOrStale tells why a versioned write of the job matched no row: ErrStaleJob
+// if the job still exists, gorm.ErrRecordNotFound if it doesn't.
+func (r *JobRepository) missingOrStale(id uuid.UUID) error {
+	var count int64
+	if err := r.db.Model(&model.Job{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return ErrStaleJob
 }
 
-// Save creates or updates a job in the database.
-func (r *JobRepository) Save(job *model.Job) error {
-	return r.db.Save(job).Error
+// withEncodedPayload runs write with the job's payload compressed, restoring the
+// plain payload afterwards so the caller's job is unchanged.
+func (r *JobRepository) withEncodedPayload(job *model.Job, write func() error) error {
+	plain := job.Payload
+	stored, encoding, err := model.EncodePayload(plain, r.compressThreshold)
+	if err != nil {
+		return err
+	}
+
+	job.Payload, job.PayloadEncoding = stored, encoding
+	err = write()
+	job.Payload, job.PayloadEncoding = plain, model.PayloadEncodingPlain
+	return err
+}
+
+// CreateAll inserts new jobs in one statement, compressing payloads like Create.
+func (r *JobRepository) CreateAll(jobs []model.Job) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	stored := make([]model.Job, len(jobs))
+	for i := range jobs {
+		if err := r.checkAttempts(&jobs[i]); err != nil {
+			return err
+		}
+		encoded, err := jobs[i].EncodedCopy(r.compressThreshold)
+		if err != nil {
+			return err
+		}
+		stored[i] = *encoded
+	}
+	return r.db.Create(&stored).Error
+}
+
+// CreateBatch inserts new jobs in one transaction, batchSize rows per statement,
+// compressing payloads like Create. Either every job is saved or none is.
+func (r *JobRepository) CreateBatch(jobs []*model.Job, batchSize int) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	stored := make([]model.Job, len(jobs))
+	for i, job := range jobs {
+		if err := r.checkAttempts(job); err != nil {
+			return err
+		}
+		encoded, err := job.EncodedCopy(r.compressThreshold)
+		if err != nil {
+			return err
+		}
+		stored[i] = *encoded
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&stored, batchSize).Error
+	})
 }
 
 // FindByID finds a job by its UUID.
@@ -32,6 +182,26 @@ func (r *JobRepository) FindByID(id uuid.UUID) (*model.Job, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := r.prepareLoaded(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FindByCompensatesJobID returns the job compensating the given job, or nil (and no
+// error) if it hasn't been compensated.
+func (r *JobRepository) FindByCompensatesJobID(id uuid.UUID) (*model.Job, error) {
+	var job model.Job
+	err := r.db.First(&job, "compensates_job_id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.prepareLoaded(&job); err != nil {
+		return nil, err
+	}
 	return &job, nil
 }
 
@@ -39,7 +209,7 @@ func (r *JobRepository) FindByID(id uuid.UUID) (*model.Job, error) {
 func (r *JobRepository) FindAll() ([]model.Job, error) {
 	var jobs []model.Job
 	err := r.db.Find(&jobs).Error
-	return jobs, err
+	return r.decodePayloads(jobs, err)
 }
 
 // Delete removes a job from the database.
@@ -47,48 +217,646 @@ func (r *JobRepository) Delete(job *model.Job) error {
 	return r.db.Delete(job).Error
 }
 
-// FindByStatusAndScheduledAtBefore finds all jobs with a specific status
-// that are scheduled to run before the given time.
-// This is the primary query used by the scheduler to find jobs ready for processing.
+// FindByStatusAndScheduledAtBefore finds jobs with a specific status
+// that are scheduled to run before the given time, most urgent first, then oldest first.
+// The scheduler claims jobs with ClaimPendingJobs, which selects in the same order.
+// A limit of 0 or less returns all matching jobs.
 //
 // Equivalent to:
-// SELECT j FROM Job j WHERE j.status = :status AND j.scheduledAt <= :scheduledAt ORDER BY j.scheduledAt ASC
-func (r *JobRepository) FindByStatusAndScheduledAtBefore(status model.JobStatus, scheduledAt time.Time) ([]model.Job, error) {
+// SELECT j FROM Job j WHERE j.status = :status AND j.scheduledAt <= :scheduledAt
+//   ORDER BY j.priority ASC, j.scheduledAt ASC LIMIT :limit
+func (r *JobRepository) FindByStatusAndScheduledAtBefore(status model.JobStatus, scheduledAt time.Time, limit int) ([]model.Job, error) {
 	var jobs []model.Job
-	err := r.db.Where("status = ? AND scheduled_at <= ?", status, scheduledAt).
-		Order("scheduled_at ASC").
-		Find(&jobs).Error
-	return jobs, err
+	query := r.db.Where("status = ? AND scheduled_at <= ?", status, scheduledAt).
+		Order("priority ASC, scheduled_at ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&jobs).Error
+	return r.decodePayloads(jobs, err)
+}
+
+// ClaimPendingJobs claims up to limit PENDING jobs scheduled to run by now, most urgent
+// first, then oldest first, and returns them: in one transaction they are selected,
+// skipping rows another transaction holds, and saved RUNNING, so concurrent schedulers
+// (e.g. two instances for HA) never claim the same job. PublishedAt stays nil until
+// the job's publish is confirmed (see MarkPublished), ProcessingStartedAt until a worker
+// picks the job up. A limit of 0 or less claims all of them.
+//
+// Equivalent to:
+// SELECT * FROM jobs WHERE status = 'PENDING' AND scheduled_at <= now()
+// ORDER BY priority, scheduled_at LIMIT :limit FOR UPDATE SKIP LOCKED;
+// UPDATE jobs SET status = 'RUNNING', version = version + 1, ... WHERE id IN (...)
+func (r *JobRepository) ClaimPendingJobs(limit int) ([]model.Job, error) {
+	var jobs []model.Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND scheduled_at <= ?", model.StatusPending, now).
+			Order("priority ASC, scheduled_at ASC")
+		if limit > 0 {
+			query = query.Limit(limit)
+		}
+		if err := query.Find(&jobs).Error; err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(jobs))
+		for i := range jobs {
+			ids[i] = jobs[i].ID
+		}
+		if err := tx.Model(&model.Job{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":                model.StatusRunning,
+				"published_at":          nil,
+				"processing_started_at": nil,
+				"updated_at":            now,
+				"version":               gorm.Expr("version + 1"),
+			}).Error; err != nil {
+			return err
+		}
+		for i := range jobs {
+			jobs[i].Status = model.StatusRunning
+			jobs[i].PublishedAt = nil
+			jobs[i].ProcessingStartedAt = nil
+			jobs[i].UpdatedAt = now
+			jobs[i].Version++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.decodePayloads(jobs, nil)
+}
+
+// ReleaseClaims returns claimed jobs that weren't published (see ClaimPendingJobs) to
+// PENDING for the next poll. Jobs no longer RUNNING, or already picked up by a
+// worker, are left alone.
+func (r *JobRepository) ReleaseClaims(ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.Job{}).
+		Where("id IN ? AND status = ? AND processing_started_at IS NULL", ids, model.StatusRunning).
+		Updates(map[string]interface{}{
+			"status":       model.StatusPending,
+			"published_at": nil,
+			"updated_at":   time.Now(),
+			"version":      gorm.Expr("version + 1"),
+		}).Error
+}
+
+// MarkPublished records that a claimed job's Kafka write was confirmed at publishedAt.
+// Only a RUNNING job not yet marked is updated. The version and updatedAt are left alone:
+// a worker may already hold the job, and the stuck-job reaper measures from the claim.
+// UpdateJobSafe never writes published_at, so the worker's saves keep the mark.
+// Reports whether the job was marked.
+//
+// Equivalent to:
+// UPDATE jobs SET published_at = :publishedAt
+// WHERE id = :id AND status = 'RUNNING' AND published_at IS NULL
+func (r *JobRepository) MarkPublished(id uuid.UUID, publishedAt time.Time) (bool, error) {
+	result := r.db.Model(&model.Job{}).
+		Where("id = ? AND status = ? AND published_at IS NULL", id, model.StatusRunning).
+		UpdateColumn("published_at", publishedAt)
+	return result.RowsAffected > 0, result.Error
 }
 
 // FindByClientID finds all jobs by client ID (useful for tracking and analytics).
 func (r *JobRepository) FindByClientID(clientID string) ([]model.Job, error) {
 	var jobs []model.Job
 	err := r.db.Where("client_id = ?", clientID).Find(&jobs).Error
-	return jobs, err
+	return r.decodePayloads(jobs, err)
+}
+
+// FindByClientIDPaged finds one page of a client's jobs, newest first, optionally only with
+// one status. Returns the page and the total number of matching jobs.
+//
+// Equivalent to:
+// SELECT * FROM jobs WHERE client_id = :clientId [AND status = :status]
+// ORDER BY created_at DESC, id DESC LIMIT :limit OFFSET :offset
+func (r *JobRepository) FindByClientIDPaged(clientID string, status *model.JobStatus, offset, limit int) ([]model.Job, int64, error) {
+	return r.findPaged(r.db.Where("client_id = ?", clientID), status, offset, limit)
+}
+
+// FindByLabelsPaged finds one page of jobs carrying all the given labels, newest first,
+// optionally only for one client and one status. An empty clientID matches every client.
+// Returns the page and the total number of matching jobs.
+//
+// Equivalent to:
+// SELECT * FROM jobs WHERE [client_id = :clientId AND] labels @> :labels::jsonb [AND status = :status]
+// ORDER BY created_at DESC, id DESC LIMIT :limit OFFSET :offset
+func (r *JobRepository) FindByLabelsPaged(clientID string, labels model.JobLabels, status *model.JobStatus, offset, limit int) ([]model.Job, int64, error) {
+	query := r.db
+	if clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+	if len(labels) > 0 {
+		encoded, err := json.Marshal(labels)
+		if err != nil {
+			return nil, 0, err
+		}
+		// Containment is what the GIN index on labels serves
+		query = query.Where("labels @> ?::jsonb", string(encoded))
+	}
+	return r.findPaged(query, status, offset, limit)
+}
+
+// findPaged counts the jobs matching query (and status, when set) and loads one page of them.
+// The id tiebreak keeps pages stable for jobs created in the same instant.
+func (r *JobRepository) findPaged(query *gorm.DB, status *model.JobStatus, offset, limit int) ([]model.Job, int64, error) {
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Model(&model.Job{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []model.Job
+	err := query.Order("created_at DESC").Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&jobs).Error
+	jobs, err = r.decodePayloads(jobs, err)
+	return jobs, total, err
+}
+
+// FindDeadLetter finds one page of a client's DEAD_LETTER jobs, most recently
+// dead-lettered first, optionally only of one type and only dead-lettered at or after since.
+// Returns the page and the total number of matching jobs.
+//
+// Equivalent to:
+// SELECT * FROM jobs WHERE client_id = :clientId AND status = 'DEAD_LETTER'
+// [AND type = :type] [AND completed_at >= :since]
+// ORDER BY completed_at DESC, id DESC LIMIT :limit OFFSET :offset
+func (r *JobRepository) FindDeadLetter(clientID string, typ *model.JobType, since *time.Time, offset, limit int) ([]model.Job, int64, error) {
+	query := r.db.Where("client_id = ? AND status = ?", clientID, model.StatusDeadLetter)
+	if typ != nil {
+		query = query.Where("type = ?", *typ)
+	}
+	if since != nil {
+		query = query.Where("completed_at >= ?", *since)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Model(&model.Job{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []model.Job
+	err := query.Order("completed_at DESC").Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&jobs).Error
+	jobs, err = r.decodePayloads(jobs, err)
+	return jobs, total, err
+}
+
+// SearchCriteria narrows a job search (see Search). ClientID is required; other
+// zero-valued fields don't filter.
+type SearchCriteria struct {
+	ClientID        string
+	OrderIDPrefix   string // Payload starts with it: every order job's payload leads with its order ID
+	PayloadContains string
+	From            *time.Time // Created at or after
+	To              *time.Time // Created at or before
+	Status          *model.JobStatus
+	Offset          int
+	Limit           int
+}
+
+// likeEscaper escapes LIKE wildcards so a search term matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Search finds one page of one client's jobs matching criteria, newest first.
+// Returns the page and the total number of matching jobs.
+//
+// The search always filters by client, so the payload matches only filter the rows
+// idx_client_id selects. The order ID match is a prefix LIKE, anchored at the start of the
+// payload; the substring LIKE scans each payload whole. Payloads stored compressed are
+// not searched.
+//
+// Equivalent to:
+// SELECT * FROM jobs WHERE client_id = :clientId
+// [AND payload_encoding = '' AND payload LIKE :orderIdPrefix || '%']
+// [AND payload_encoding = '' AND payload LIKE '%' || :payloadContains || '%']
+// [AND created_at BETWEEN :from AND :to] [AND status = :status]
+// ORDER BY created_at DESC, id DESC LIMIT :limit OFFSET :offset
+func (r *JobRepository) Search(criteria SearchCriteria) ([]model.Job, int64, error) {
+	query := r.db.Where("client_id = ?", criteria.ClientID)
+	if criteria.OrderIDPrefix != "" {
+		query = query.Where("payload_encoding = ? AND payload LIKE ? ESCAPE '\\'",
+			model.PayloadEncodingPlain, likeEscaper.Replace(criteria.OrderIDPrefix)+"%")
+	}
+	if criteria.PayloadContains != "" {
+		query = query.Where("payload_encoding = ? AND payload LIKE ? ESCAPE '\\'",
+			model.PayloadEncodingPlain, "%"+likeEscaper.Replace(criteria.PayloadContains)+"%")
+	}
+	switch {
+	case criteria.From != nil && criteria.To != nil:
+		query = query.Where("created_at BETWEEN ? AND ?", *criteria.From, *criteria.To)
+	case criteria.From != nil:
+		query = query.Where("created_at >= ?", *criteria.From)
+	case criteria.To != nil:
+		query = query.Where("created_at <= ?", *criteria.To)
+	}
+	return r.findPaged(query, criteria.Status, criteria.Offset, criteria.Limit)
+}
+
+// RequeueDeadLetter resets up to limit DEAD_LETTER jobs to PENDING in one transaction,
+// oldest dead letter first, optionally only of one type: attempts 0, scheduled at
+// scheduledAt, error and completion cleared (as a single retry does).
+// Returns the requeued jobs with only ID, ClientID and the FailureReason they were
+// dead-lettered with loaded, enough to invalidate their cache entries.
+//
+// Equivalent to:
+// SELECT id, client_id, failure_reason FROM jobs WHERE status = 'DEAD_LETTER' [AND type = :type]
+// ORDER BY completed_at, id LIMIT :limit FOR UPDATE;
+// UPDATE jobs SET status = 'PENDING', attempts = 0, ... WHERE id IN (...) AND status = 'DEAD_LETTER'
+func (r *JobRepository) RequeueDeadLetter(typ *model.JobType, limit int, scheduledAt time.Time) ([]model.Job, error) {
+	var requeued []model.Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&model.Job{}).Where("status = ?", model.StatusDeadLetter)
+		if typ != nil {
+			query = query.Where("type = ?", *typ)
+		}
+		if err := query.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "client_id", "failure_reason").
+			Order("completed_at").Order("id").
+			Limit(limit).
+			Find(&requeued).Error; err != nil {
+			return err
+		}
+		if len(requeued) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(requeued))
+		for i := range requeued {
+			ids[i] = requeued[i].ID
+		}
+
+		return tx.Model(&model.Job{}).
+			Where("id IN ? AND status = ?", ids, model.StatusDeadLetter).
+			Updates(map[string]interface{}{
+				"status":         model.StatusPending,
+				"attempts":       0,
+				"scheduled_at":   scheduledAt,
+				"error_message":  nil,
+				"failure_reason": nil,
+				"completed_at":   nil,
+				"version":        gorm.Expr("version + 1"),
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return requeued, nil
 }
 
 // FindByStatus finds all jobs by status.
 func (r *JobRepository) FindByStatus(status model.JobStatus) ([]model.Job, error) {
 	var jobs []model.Job
 	err := r.db.Where("status = ?", status).Find(&jobs).Error
-	return jobs, err
+	return r.decodePayloads(jobs, err)
+}
+
+// FindRecentByStatus finds the most recently created jobs with the given status,
+// newest first. A limit of 0 or less returns all matching jobs.
+func (r *JobRepository) FindRecentByStatus(status model.JobStatus, limit int) ([]model.Job, error) {
+	var jobs []model.Job
+	query := r.db.Where("status = ?", status).Order("created_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&jobs).Error
+	return r.decodePayloads(jobs, err)
 }
 
 // CountByStatus counts jobs by status (useful for monitoring and dashboards).
+// HEALTH_CHECK canaries are not counted.
 func (r *JobRepository) CountByStatus(status model.JobStatus) (int64, error) {
 	var count int64
-	err := r.db.Model(&model.Job{}).Where("status = ?", status).Count(&count).Error
+	err := r.db.Model(&model.Job{}).Where("status = ?", status).
+		Where("type <> ?", model.TypeHealthCheck).
+		Count(&count).Error
 	return count, err
 }
 
-// FindStuckJobs finds jobs that have been running for longer than expected (potential stuck jobs).
+// CountByStatusGroupedByType counts jobs with a status per type, in one query
+// on the status-led index.
+//
+// Equivalent to:
+// SELECT type, COUNT(*) AS count FROM jobs WHERE status = :status GROUP BY type
+func (r *JobRepository) CountByStatusGroupedByType(status model.JobStatus) (map[model.JobType]int64, error) {
+	var rows []struct {
+		Type  model.JobType
+		Count int64
+	}
+	err := r.db.Model(&model.Job{}).
+		Select("type, COUNT(*) AS count").
+		Where("status = ?", status).
+		Group("type").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[model.JobType]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Type] = row.Count
+	}
+	return counts, nil
+}
+
+// CountByTypeAndStatus counts jobs per type and status, in one grouped query.
+// HEALTH_CHECK canaries are not counted.
+//
+// Equivalent to:
+// SELECT type, status, COUNT(*) AS count FROM jobs WHERE type <> 'HEALTH_CHECK' GROUP BY type, status
+func (r *JobRepository) CountByTypeAndStatus() (map[model.JobType]map[model.JobStatus]int64, error) {
+	var rows []JobOutcomeCount
+	err := r.db.Model(&model.Job{}).
+		Select("type, status, COUNT(*) AS count").
+		Where("type <> ?", model.TypeHealthCheck).
+		Group("type, status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[model.JobType]map[model.JobStatus]int64)
+	for _, row := range rows {
+		if counts[row.Type] == nil {
+			counts[row.Type] = make(map[model.JobStatus]int64)
+		}
+		counts[row.Type][row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// CountDeadLetteredByFailureReason counts DEAD_LETTER jobs per failure reason.
+// Jobs dead-lettered before reasons were recorded count as unknown. HEALTH_CHECK
+// canaries are not counted.
 //
 // Equivalent to:
-// SELECT j FROM Job j WHERE j.status = :status AND j.updatedAt < :updatedBefore
-func (r *JobRepository) FindStuckJobs(status model.JobStatus, updatedBefore time.Time) ([]model.Job, error) {
+// SELECT COALESCE(failure_reason, 'unknown') AS reason, COUNT(*) AS count FROM jobs
+// WHERE status = 'DEAD_LETTER' AND type <> 'HEALTH_CHECK' GROUP BY COALESCE(failure_reason, 'unknown')
+func (r *JobRepository) CountDeadLetteredByFailureReason() (map[model.FailureReason]int64, error) {
+	var rows []struct {
+		Reason model.FailureReason
+		Count  int64
+	}
+	reasonExpr := "COALESCE(failure_reason, '" + string(model.FailureUnknown) + "')"
+	err := r.db.Model(&model.Job{}).
+		Select(reasonExpr+" AS reason, COUNT(*) AS count").
+		Where("status = ?", model.StatusDeadLetter).
+		Where("type <> ?", model.TypeHealthCheck).
+		Group(reasonExpr).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[model.FailureReason]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Reason] += row.Count
+	}
+	return counts, nil
+}
+
+// JobOutcomeCount is the number of jobs of one type that finished with one status.
+type JobOutcomeCount struct {
+	Type   model.JobType
+	Status model.JobStatus
+	Count  int64
+}
+
+// CountFinishedByTypeBetween counts jobs that finished in [from, to) with one of the
+// given statuses, per type and status. The completed_at range uses idx_completed_at.
+// HEALTH_CHECK canaries are not counted.
+//
+// Equivalent to:
+// SELECT type, status, COUNT(*) AS count FROM jobs
+// WHERE completed_at >= :from AND completed_at < :to AND status IN (:statuses) AND type <> 'HEALTH_CHECK'
+// GROUP BY type, status
+func (r *JobRepository) CountFinishedByTypeBetween(from time.Time, to time.Time, statuses []model.JobStatus) ([]JobOutcomeCount, error) {
+	var counts []JobOutcomeCount
+	err := r.db.Model(&model.Job{}).
+		Select("type, status, COUNT(*) AS count").
+		Where("completed_at >= ? AND completed_at < ? AND status IN ?", from, to, statuses).
+		Where("type <> ?", model.TypeHealthCheck).
+		Group("type, status").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// FindLatestCompletedAt returns when the most recently completed job of a type
+// completed, nil if none has.
+//
+// Equivalent to:
+// SELECT completed_at FROM jobs WHERE status = 'COMPLETED' AND type = :type
+// ORDER BY completed_at DESC LIMIT 1
+func (r *JobRepository) FindLatestCompletedAt(jobType model.JobType) (*time.Time, error) {
+	var job model.Job
+	err := r.db.Select("completed_at").
+		Where("status = ? AND type = ? AND completed_at IS NOT NULL", model.StatusCompleted, jobType).
+		Order("completed_at DESC").
+		Limit(1).
+		Find(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return job.CompletedAt, nil
+}
+
+// DeleteFinishedByTypeBefore deletes jobs of a type that finished (COMPLETED, FAILED,
+// DEAD_LETTER, or EXPIRED) before the given time, returning how many were deleted.
+//
+// Equivalent to:
+// DELETE FROM jobs WHERE type = :type AND status IN (...) AND completed_at < :before
+func (r *JobRepository) DeleteFinishedByTypeBefore(jobType model.JobType, before time.Time) (int64, error) {
+	finished := []model.JobStatus{model.StatusCompleted, model.StatusFailed, model.StatusDeadLetter, model.StatusExpired}
+	result := r.db.Where("type = ? AND status IN ? AND completed_at < ?", jobType, finished, before).
+		Delete(&model.Job{})
+	return result.RowsAffected, result.Error
+}
+
+// CountCompletedByTypeBetween counts COMPLETED jobs of a type completed in [from, to).
+func (r *JobRepository) CountCompletedByTypeBetween(jobType model.JobType, from time.Time, to time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Job{}).
+		Where("status = ? AND type = ? AND completed_at >= ? AND completed_at < ?", model.StatusCompleted, jobType, from, to).
+		Count(&count).Error
+	return count, err
+}
+
+// FindCompletedByTypeBetween pages through COMPLETED jobs of a type completed in [from, to),
+// oldest completion first.
+//
+// Equivalent to:
+// SELECT j FROM Job j WHERE j.status = 'COMPLETED' AND j.type = :type
+//   AND j.completedAt >= :from AND j.completedAt < :to ORDER BY j.completedAt, j.id
+func (r *JobRepository) FindCompletedByTypeBetween(jobType model.JobType, from time.Time, to time.Time, offset int, limit int) ([]model.Job, error) {
+	var jobs []model.Job
+	err := r.db.Where("status = ? AND type = ? AND completed_at >= ? AND completed_at < ?", model.StatusCompleted, jobType, from, to).
+		Order("completed_at ASC, id ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&jobs).Error
+	return r.decodePayloads(jobs, err)
+}
+
+// FindSLABreached finds completed jobs that breached their SLA, most recently
+// completed first, optionally of one type. Uses idx_sla_breached_completed_at.
+//
+// Equivalent to:
+// SELECT j FROM Job j WHERE j.slaBreached = true [AND j.type = :type]
+//   ORDER BY j.completedAt DESC LIMIT :limit
+func (r *JobRepository) FindSLABreached(jobType *model.JobType, limit int) ([]model.Job, error) {
+	var jobs []model.Job
+	query := r.db.Where("sla_breached = ?", true)
+	if jobType != nil {
+		query = query.Where("type = ?", *jobType)
+	}
+	err := query.Order("completed_at DESC").Limit(limit).Find(&jobs).Error
+	return r.decodePayloads(jobs, err)
+}
+
+// CountUnfinishedCreatedBefore counts PENDING and RUNNING jobs of a type created before the given time.
+func (r *JobRepository) CountUnfinishedCreatedBefore(jobType model.JobType, createdBefore time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Job{}).
+		Where("status IN ? AND type = ? AND created_at < ?",
+			[]model.JobStatus{model.StatusPending, model.StatusRunning}, jobType, createdBefore).
+		Count(&count).Error
+	return count, err
+}
+
+// FindStuckJobs finds RUNNING jobs of a type whose worker started processing before startedBefore.
+//
+// Equivalent to:
+// SELECT j FROM Job j WHERE j.status = 'RUNNING' AND j.type = :type
+//   AND j.processingStartedAt < :startedBefore
+func (r *JobRepository) FindStuckJobs(jobType model.JobType, startedBefore time.Time) ([]model.Job, error) {
+	var jobs []model.Job
+	err := r.db.Where("status = ? AND type = ? AND processing_started_at < ?", model.StatusRunning, jobType, startedBefore).
+		Find(&jobs).Error
+	return r.decodePayloads(jobs, err)
+}
+
+// FindUnstartedJobs finds RUNNING jobs no worker has picked up, published before publishedBefore.
+//
+// Equivalent to:
+// SELECT j FROM Job j WHERE j.status = 'RUNNING' AND j.processingStartedAt IS NULL
+//   AND j.updatedAt < :publishedBefore
+func (r *JobRepository) FindUnstartedJobs(publishedBefore time.Time) ([]model.Job, error) {
+	var jobs []model.Job
+	err := r.db.Where("status = ? AND processing_started_at IS NULL AND updated_at < ?", model.StatusRunning, publishedBefore).
+		Find(&jobs).Error
+	return r.decodePayloads(jobs, err)
+}
+
+// FindRunningUpdatedBefore finds RUNNING jobs whose row hasn't changed since updatedBefore,
+// e.g. because the worker processing them crashed.
+//
+// Equivalent to:
+// SELECT j FROM Job j WHERE j.status = 'RUNNING' AND j.updatedAt < :updatedBefore
+func (r *JobRepository) FindRunningUpdatedBefore(updatedBefore time.Time) ([]model.Job, error) {
 	var jobs []model.Job
-	err := r.db.Where("status = ? AND updated_at < ?", status, updatedBefore).
+	err := r.db.Where("status = ? AND updated_at < ?", model.StatusRunning, updatedBefore).
 		Find(&jobs).Error
-	return jobs, err
+	return r.decodePayloads(jobs, err)
+}
+
+// UpdateIfStillRunning updates an existing job like UpdateJobSafe, but only while its row
+// is still RUNNING, unchanged since updatedBefore, and at the loaded version, so a worker
+// that finishes the job in the meantime wins. Returns false when the row had moved on.
+//
+// Equivalent to:
+// UPDATE jobs SET ..., version = :version + 1
+// WHERE id = :id AND version = :version AND status = 'RUNNING' AND updated_at < :updatedBefore
+func (r *JobRepository) UpdateIfStillRunning(job *model.Job, updatedBefore time.Time) (bool, error) {
+	if err := r.checkAttempts(job); err != nil {
+		return false, err
+	}
+	r.normalizeScheduledAt(job)
+	loaded := job.Version
+	job.Version++
+	var updated bool
+	err := r.withEncodedPayload(job, func() error {
+		result := r.db.Model(job).
+			Where("version = ? AND status = ? AND updated_at < ?", loaded, model.StatusRunning, updatedBefore).
+			Select("*").Omit("id", "created_at").
+			Updates(job)
+		updated = result.RowsAffected > 0
+		return result.Error
+	})
+	if !updated {
+		job.Version = loaded
+	}
+	return updated, err
+}
+
+// decodePayloads prepares every job loaded by a query, see prepareLoaded.
+func (r *JobRepository) decodePayloads(jobs []model.Job, err error) ([]model.Job, error) {
+	if err != nil {
+		return jobs, err
+	}
+	for i := range jobs {
+		if err := r.prepareLoaded(&jobs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
+
+// prepareLoaded restores a loaded job's plain payload and clamps inconsistent attempts.
+func (r *JobRepository) prepareLoaded(job *model.Job) error {
+	if err := job.DecodePayloadInPlace(); err != nil {
+		return err
+	}
+	if r.validateAttempts {
+		stored := job.Attempts
+		if job.ClampAttempts() {
+			log.Printf("Job %s loaded with inconsistent attempts %d (maxRetries %d), clamped to %d",
+				job.ID, stored, job.MaxRetries, job.Attempts)
+		}
+	}
+	return nil
+}
+
+// normalizeScheduledAt fills in a nil ScheduledAt before an update, which would otherwise
+// write NULL into the NOT NULL scheduled_at column: with the stored value when there is
+// one (and PRESERVE_SCHEDULED_AT isn't false), the current time otherwise.
+func (r *JobRepository) normalizeScheduledAt(job *model.Job) {
+	if job.ScheduledAt != nil {
+		return
+	}
+	if r.keepScheduledAt {
+		var stored []time.Time
+		err := r.db.Model(&model.Job{}).Where("id = ?", job.ID).Limit(1).Pluck("scheduled_at", &stored).Error
+		if err == nil && len(stored) == 1 {
+			log.Printf("Job %s updated with no scheduledAt, keeping the stored %v", job.ID, stored[0])
+			job.ScheduledAt = &stored[0]
+			return
+		}
+	}
+	now := time.Now()
+	job.ScheduledAt = &now
+}
+
+// checkAttempts rejects writing a job with inconsistent attempts, unless disabled.
+func (r *JobRepository) checkAttempts(job *model.Job) error {
+	if !r.validateAttempts {
+		return nil
+	}
+	return job.CheckAttempts()
 }
\ No newline at end of file
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"distributed-job-processor/model"
+)
+
+// AutoMigrate creates or updates the tables for all persisted models.
+// Call once at startup, after opening the database connection.
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&model.Job{},
+		&model.ClientDefaults{},
+		&model.AuditEntry{},
+		&model.JobAttempt{},
+	)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+// AuditService records admin mutations for accountability.
+//
+// Every entry is written twice:
+// - A structured log line with an "AUDIT" marker, emitted first so the action
+//   is on record even if the database write fails
+// - A row in the audit_log table, reviewable at GET /api/admin/audit
+//
+// Retention: entries older than AUDIT_RETENTION_DAYS (default 90, 0 = keep
+// forever) are pruned hourly once Start is called.
+type AuditService struct {
+	auditRepository *repository.AuditRepository
+	retention       time.Duration
+	stopCh          chan struct{}
+}
+
+// NewAuditService creates a new AuditService with the given repository.
+func NewAuditService(auditRepository *repository.AuditRepository) *AuditService {
+	retentionDays := 90 // default
+	if val := os.Getenv("AUDIT_RETENTION_DAYS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			retentionDays = parsed
+		} else {
+			log.Printf("Ignoring invalid AUDIT_RETENTION_DAYS %q: must be a non-negative number of days", val)
+		}
+	}
+
+	return &AuditService{
+		auditRepository: auditRepository,
+		retention:       time.Duration(retentionDays) * 24 * time.Hour,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start begins pruning expired entries hourly in a goroutine.
+func (s *AuditService) Start() {
+	if s.retention == 0 {
+		log.Println("Audit log retention disabled, entries are kept forever")
+		return
+	}
+
+	go func() {
+		log.Printf("Audit log pruning started (retention: %v)", s.retention)
+		s.PruneExpired()
+		ticker := time.NewTicker(1 * time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+				s.PruneExpired()
+			}
+		}
+	}()
+}
+
+// Stop stops the pruning loop.
+func (s *AuditService) Stop() {
+	close(s.stopCh)
+}
+
+// Record appends an admin action to the audit log.
+// target may be empty for global actions; params may be nil.
+func (s *AuditService) Record(actor string, action string, target string, params map[string]interface{}) error {
+	parameters := ""
+	if len(params) > 0 {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		parameters = string(data)
+	}
+
+	log.Printf("AUDIT actor=%q action=%q target=%q params=%s", actor, action, target, parameters)
+
+	entry := &model.AuditEntry{
+		Actor:      actor,
+		Action:     action,
+		Target:     target,
+		Parameters: parameters,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.auditRepository.Save(entry); err != nil {
+		log.Printf("Failed to persist audit entry (actor=%s, action=%s): %v", actor, action, err)
+		return err
+	}
+	return nil
+}
+
+// GetRecent returns up to limit audit entries, newest first, optionally filtered by action.
+func (s *AuditService) GetRecent(action string, limit int) ([]model.AuditEntry, error) {
+	return s.auditRepository.FindRecent(action, limit)
+}
+
+// PruneExpired removes entries older than the retention period.
+func (s *AuditService) PruneExpired() {
+	if s.retention == 0 {
+		return
+	}
+
+	deleted, err := s.auditRepository.DeleteCreatedBefore(time.Now().Add(-s.retention))
+	if err != nil {
+		log.Printf("Error pruning audit log: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Pruned %d audit entries older than %v", deleted, s.retention)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+// TestAuditRecordAndReview verifies entries are stored with their parameters and listed newest first.
+func TestAuditRecordAndReview(t *testing.T) {
+	s := NewAuditService(repository.NewAuditRepository(newTestDB(t)))
+
+	if err := s.Record("alice", "cache.clear", "", nil); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err := s.Record("ops-bot", "rate_limit.reset", "customer-1", map[string]interface{}{"reason": "support ticket"}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	entries, err := s.GetRecent("", 10)
+	if err != nil {
+		t.Fatalf("get recent: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != "rate_limit.reset" {
+		t.Fatalf("expected 2 entries newest first, got %+v", entries)
+	}
+	if entries[0].Actor != "ops-bot" || entries[0].Target != "customer-1" || entries[0].Parameters != `{"reason":"support ticket"}` {
+		t.Fatalf("unexpected entry: %+v", entries[0])
+	}
+
+	filtered, _ := s.GetRecent("cache.clear", 10)
+	if len(filtered) != 1 || filtered[0].Actor != "alice" {
+		t.Fatalf("expected only the cache.clear entry, got %+v", filtered)
+	}
+}
+
+// TestAuditPruneExpired verifies entries past AUDIT_RETENTION_DAYS are removed.
+func TestAuditPruneExpired(t *testing.T) {
+	t.Setenv("AUDIT_RETENTION_DAYS", "30")
+	repo := repository.NewAuditRepository(newTestDB(t))
+	s := NewAuditService(repo)
+
+	old := &model.AuditEntry{Actor: "alice", Action: "cache.clear", CreatedAt: time.Now().AddDate(0, 0, -31)}
+	if err := repo.Save(old); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if err := s.Record("alice", "cache.clear", "", nil); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	s.PruneExpired()
+
+	entries, _ := s.GetRecent("", 10)
+	if len(entries) != 1 || entries[0].ID == old.ID {
+		t.Fatalf("expected only the recent entry to remain, got %+v", entries)
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"log"
+	"os"
+
+	"distributed-job-processor/config"
+)
+
+// batchSizer decides how many PENDING jobs the scheduler fetches per poll.
+//
+// Env:
+// - SCHEDULER_BATCH_SIZE: baseline batch size (default 500)
+// - SCHEDULER_BATCH_SIZE_MIN / SCHEDULER_BATCH_SIZE_MAX: bounds (default 50 / 5000)
+// - SCHEDULER_BATCH_AUTOSCALE=true: adapt the size to the backlog (default off,
+//   the baseline is used for every poll)
+//
+// Autoscaling:
+// - Two consecutive full batches mean a backlog: double the size, up to max
+// - A poll returning under a quarter of the batch means the backlog is gone:
+//   halve the size, down to min while the scheduler stays idle
+type batchSizer struct {
+	min        int
+	max        int
+	baseline   int
+	autoscale  bool
+	current    int
+	fullStreak int
+}
+
+// consecutiveFullToGrow is how many full batches in a row trigger growth.
+const consecutiveFullToGrow = 2
+
+// newBatchSizerFromEnv creates a batchSizer configured from SCHEDULER_BATCH_* env vars.
+func newBatchSizerFromEnv() *batchSizer {
+	baseline := config.PositiveIntFromEnv("SCHEDULER_BATCH_SIZE", 500)
+	minSize := config.PositiveIntFromEnv("SCHEDULER_BATCH_SIZE_MIN", 50)
+	maxSize := config.PositiveIntFromEnv("SCHEDULER_BATCH_SIZE_MAX", 5000)
+	autoscale := os.Getenv("SCHEDULER_BATCH_AUTOSCALE") == "true"
+	return newBatchSizer(minSize, maxSize, baseline, autoscale)
+}
+
+// newBatchSizer creates a batchSizer, clamping the baseline into [min, max].
+func newBatchSizer(minSize, maxSize, baseline int, autoscale bool) *batchSizer {
+	if minSize < 1 {
+		minSize = 1
+	}
+	if maxSize < minSize {
+		log.Printf("SCHEDULER_BATCH_SIZE_MAX %d is below the minimum %d, using the minimum", maxSize, minSize)
+		maxSize = minSize
+	}
+	if baseline < minSize {
+		baseline = minSize
+	}
+	if baseline > maxSize {
+		baseline = maxSize
+	}
+
+	return &batchSizer{
+		min:       minSize,
+		max:       maxSize,
+		baseline:  baseline,
+		autoscale: autoscale,
+		current:   baseline,
+	}
+}
+
+// Size returns the batch size to use for the next poll.
+func (b *batchSizer) Size() int {
+	return b.current
+}
+
+// Observe records how many jobs the last poll returned and adjusts the size.
+func (b *batchSizer) Observe(fetched int) {
+	if !b.autoscale {
+		return
+	}
+
+	if fetched >= b.current {
+		b.fullStreak++
+		if b.fullStreak >= consecutiveFullToGrow && b.current < b.max {
+			b.current *= 2
+			if b.current > b.max {
+				b.current = b.max
+			}
+			b.fullStreak = 0
+			log.Printf("Scheduler backlog detected, batch size grown to %d", b.current)
+		}
+		return
+	}
+
+	b.fullStreak = 0
+	if fetched < b.current/4 && b.current > b.min {
+		b.current /= 2
+		if b.current < b.min {
+			b.current = b.min
+		}
+		log.Printf("Scheduler backlog drained, batch size shrunk to %d", b.current)
+	}
+}
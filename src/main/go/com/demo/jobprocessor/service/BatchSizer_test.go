@@ -0,0 +1,73 @@
+package service
+
+import "testing"
+
+// TestBatchSizerGrowsOnBacklogAndShrinksWhenIdle verifies the batch adapts between min and max.
+func TestBatchSizerGrowsOnBacklogAndShrinksWhenIdle(t *testing.T) {
+	b := newBatchSizer(10, 400, 100, true)
+
+	// One full batch is not yet a backlog
+	b.Observe(100)
+	if b.Size() != 100 {
+		t.Fatalf("expected 100 after one full batch, got %d", b.Size())
+	}
+
+	// Consecutive full batches double the size, capped at max
+	b.Observe(100)
+	if b.Size() != 200 {
+		t.Fatalf("expected 200 after two full batches, got %d", b.Size())
+	}
+	b.Observe(200)
+	b.Observe(200)
+	b.Observe(400)
+	b.Observe(400)
+	if b.Size() != 400 {
+		t.Fatalf("expected size capped at 400, got %d", b.Size())
+	}
+
+	// Sparse polls shrink back through the baseline
+	b.Observe(5)
+	if b.Size() != 200 {
+		t.Fatalf("expected 200 after a sparse poll, got %d", b.Size())
+	}
+	b.Observe(0)
+	if b.Size() != 100 {
+		t.Fatalf("expected baseline 100, got %d", b.Size())
+	}
+
+	// An idle scheduler keeps shrinking down to min, never below it
+	for i := 0; i < 5; i++ {
+		b.Observe(0)
+	}
+	if b.Size() != 10 {
+		t.Fatalf("expected min 10 while idle, got %d", b.Size())
+	}
+
+	// A backlog grows it again from there
+	b.Observe(10)
+	b.Observe(10)
+	if b.Size() != 20 {
+		t.Fatalf("expected 20 after two full batches at min, got %d", b.Size())
+	}
+}
+
+// TestBatchSizerFixedWithoutAutoscale verifies the baseline is always used when autoscaling is off.
+func TestBatchSizerFixedWithoutAutoscale(t *testing.T) {
+	b := newBatchSizer(10, 400, 100, false)
+	for i := 0; i < 5; i++ {
+		b.Observe(100)
+	}
+	if b.Size() != 100 {
+		t.Fatalf("expected fixed size 100, got %d", b.Size())
+	}
+}
+
+// TestNewBatchSizerClampsBaseline verifies a baseline outside [min, max] is clamped.
+func TestNewBatchSizerClampsBaseline(t *testing.T) {
+	if got := newBatchSizer(10, 400, 1000, true).Size(); got != 400 {
+		t.Fatalf("expected baseline clamped to max 400, got %d", got)
+	}
+	if got := newBatchSizer(10, 400, 5, true).Size(); got != 10 {
+		t.Fatalf("expected baseline clamped to min 10, got %d", got)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+)
+
+// Bulkhead caps how many jobs of each type a worker processes at once, so a flood
+// of one type can't take every worker goroutine (and the DB/Redis calls they make)
+// and starve the others.
+//
+// Configuration: BULKHEAD_MAX_<TYPE> (e.g. BULKHEAD_MAX_PAYMENT_PROCESS=6).
+// Types without a limit are unbounded (apart from the worker concurrency).
+//
+// A job whose type is at its limit is not waited for: the worker hands it back
+// to the scheduler (see JobWorker.deferJob) and moves on to other work.
+// In-flight counts per type are exposed as metrics for every type.
+type Bulkhead struct {
+	slots map[model.JobType]chan struct{}
+}
+
+// NewBulkheadFromEnv creates a Bulkhead from BULKHEAD_MAX_<TYPE> env vars.
+func NewBulkheadFromEnv() *Bulkhead {
+	limits := make(map[model.JobType]int)
+	for _, spec := range model.JobTypeSpecs() {
+		key := "BULKHEAD_MAX_" + string(spec.Type)
+		if val := os.Getenv(key); val != "" {
+			if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+				limits[spec.Type] = parsed
+			} else {
+				log.Printf("Ignoring invalid %s %q: must be a positive integer", key, val)
+			}
+		}
+	}
+	return NewBulkhead(limits)
+}
+
+// NewBulkhead creates a Bulkhead with the given per-type concurrency limits.
+func NewBulkhead(limits map[model.JobType]int) *Bulkhead {
+	slots := make(map[model.JobType]chan struct{}, len(limits))
+	for jobType, limit := range limits {
+		slots[jobType] = make(chan struct{}, limit)
+		log.Printf("Bulkhead: at most %d %s jobs in flight", limit, jobType)
+	}
+	return &Bulkhead{slots: slots}
+}
+
+// TryAcquire takes a slot for the job type without waiting.
+// Returns false if the type is at its limit. A nil Bulkhead never limits.
+func (b *Bulkhead) TryAcquire(jobType model.JobType) bool {
+	if b != nil {
+		if slots, ok := b.slots[jobType]; ok {
+			select {
+			case slots <- struct{}{}:
+			default:
+				config.GetMetrics().IncBulkheadRejection()
+				return false
+			}
+		}
+	}
+	config.GetMetrics().IncJobsInFlight(string(jobType))
+	return true
+}
+
+// Release returns a slot taken by a successful TryAcquire.
+func (b *Bulkhead) Release(jobType model.JobType) {
+	config.GetMetrics().DecJobsInFlight(string(jobType))
+	if b != nil {
+		if slots, ok := b.slots[jobType]; ok {
+			<-slots
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+// TestBulkheadIsolatesJobTypes verifies a full type is turned away without affecting other types.
+func TestBulkheadIsolatesJobTypes(t *testing.T) {
+	b := NewBulkhead(map[model.JobType]int{model.TypePaymentProcess: 2})
+
+	if !b.TryAcquire(model.TypePaymentProcess) || !b.TryAcquire(model.TypePaymentProcess) {
+		t.Fatal("expected two payment slots")
+	}
+	if b.TryAcquire(model.TypePaymentProcess) {
+		t.Fatal("expected the third payment to be turned away")
+	}
+
+	// Emails have no limit and are unaffected by the payment flood
+	for i := 0; i < 10; i++ {
+		if !b.TryAcquire(model.TypeEmailConfirmation) {
+			t.Fatal("expected unlimited email slots")
+		}
+	}
+
+	b.Release(model.TypePaymentProcess)
+	if !b.TryAcquire(model.TypePaymentProcess) {
+		t.Fatal("expected a payment slot after release")
+	}
+}
+
+// TestDeferJobDoesNotCountAttempt verifies a turned-away job is rescheduled without using a retry.
+func TestDeferJobDoesNotCountAttempt(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+
+	job := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	if err := w.deferJob(job, bulkheadDeferDelay); err != nil {
+		t.Fatalf("defer job: %v", err)
+	}
+
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusPending || saved.Attempts != 0 {
+		t.Fatalf("expected PENDING with 0 attempts, got status=%s attempts=%d", saved.Status, saved.Attempts)
+	}
+	if !saved.ScheduledAt.After(time.Now()) {
+		t.Fatalf("expected the job scheduled in the future, got %v", saved.ScheduledAt)
+	}
+}
+
+// TestDeferMessageLeavesMessageUncommittedWhenSaveFails verifies a job that can't be
+// saved PENDING keeps its message uncommitted instead of being stranded RUNNING.
+func TestDeferMessageLeavesMessageUncommittedWhenSaveFails(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewJobRepository(db)
+	w := newTestWorker(t, repo)
+
+	job := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	err := db.Callback().Update().Before("gorm:update").Register("test:update_fails", func(tx *gorm.DB) {
+		tx.AddError(errors.New("connection reset"))
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	var events []string
+	w.deferMessage(job, bulkheadDeferDelay, kafka.Message{Value: []byte(job.ID.String())}, recordingCommitter{&events}, config.Logger())
+	if len(events) != 0 {
+		t.Fatalf("expected the message left uncommitted, got %v", events)
+	}
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusRunning {
+		t.Fatalf("expected the job still RUNNING for the reaper, got %s", saved.Status)
+	}
+}
@@ -7,12 +7,16 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 
+	"distributed-job-processor/config"
 	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
 )
 
 // CacheService provides caching for job details using Redis.
@@ -31,18 +35,74 @@ import (
 // Redis Key Format: job:{jobId}
 // Redis Value: Serialized Job object (JSON)
 // TTL: 15 minutes (configurable)
+// Payloads over PAYLOAD_COMPRESSION_THRESHOLD are stored gzip compressed
+//
+// Each client's cached job IDs are also kept in the sorted set client_jobs:{clientId},
+// scored by when each entry expires, so InvalidateClient can drop all of a client's
+// entries (e.g. when its jobs are purged or it is offboarded) without a SCAN. Expired
+// members are trimmed on every write, so the set of a client that keeps caching jobs
+// (and so never lets the set expire) stays as small as its live entries.
+//
+// Redis outages:
+// - Jobs cached or read are also kept in an in-process LRU (CACHE_FALLBACK_SIZE jobs,
+//   default 1000, 0 disables; each for CACHE_FALLBACK_TTL, default 30s), which GetJob
+//   serves from when Redis errors, counted as cache.fallback_hits
+// - CACHE_REDIS_FAILURE_THRESHOLD (default 3, 0 disables) consecutive Redis errors
+//   suppress reads and writes for CACHE_REDIS_COOLDOWN (default 10s), so lookups go
+//   straight to the local cache (then the database) instead of waiting on a dead Redis.
+//   The first call after the cooldown tries Redis again; one more error restarts it
+// - Invalidations are skipped during the cooldown too, so they don't block on a dead
+//   Redis either; an entry they miss is served stale until its TTL expires
 //
 // Example Performance:
 // - Without cache: 10ms DB query per job
 // - With cache (80% hit rate): 2ms average (0.8 * 1ms + 0.2 * 10ms)
 // - At 1000 jobs/min: Saves 8000ms = 8 seconds of DB time
 type CacheService struct {
-	redisClient      *redis.Client
+	redisClient        *redis.Client
 	jobCacheTTLMinutes int
+	compressThreshold  int
+	fallback           *localJobCache
+	health             *redisHealth
+}
+
+// redisHealth suppresses Redis calls for a cooldown after threshold consecutive errors.
+type redisHealth struct {
+	threshold int32
+	cooldown  time.Duration
+	now       func() time.Time
+	failures  atomic.Int32
+	downUntil atomic.Int64 // Unix nanoseconds
+}
+
+// available reports whether Redis may be called, false during a cooldown.
+func (h *redisHealth) available() bool {
+	return h.threshold <= 0 || h.now().UnixNano() >= h.downUntil.Load()
+}
+
+// failed records a Redis error, starting a cooldown at the threshold. The count is
+// only reset by a success, so an error right after a cooldown starts another.
+func (h *redisHealth) failed() {
+	if h.threshold <= 0 {
+		return
+	}
+	if h.failures.Add(1) >= h.threshold {
+		h.downUntil.Store(h.now().Add(h.cooldown).UnixNano())
+		log.Printf("Redis unreachable after %d consecutive errors, skipping cache calls for %v", h.failures.Load(), h.cooldown)
+	}
+}
+
+// succeeded records a Redis call that got an answer.
+func (h *redisHealth) succeeded() {
+	h.failures.Store(0)
 }
 
 var ctx = context.Background()
 
+// cacheScanBatchSize is the COUNT hint of each SCAN over the job keys and the most
+// keys deleted per UNLINK, so no single command walks the whole keyspace.
+const cacheScanBatchSize = 100
+
 // NewCacheService creates a new CacheService with the given Redis client.
 func NewCacheService(redisClient *redis.Client) *CacheService {
 	ttl := 15 // default
@@ -52,37 +112,73 @@ func NewCacheService(redisClient *redis.Client) *CacheService {
 		}
 	}
 	return &CacheService{
-		redisClient:      redisClient,
+		redisClient:        redisClient,
 		jobCacheTTLMinutes: ttl,
+		compressThreshold:  config.GetPayloadCompressionThreshold(),
+		fallback: newLocalJobCache(
+			config.NonNegativeIntFromEnv("CACHE_FALLBACK_SIZE", 1000),
+			config.DurationFromEnv("CACHE_FALLBACK_TTL", 30*time.Second)),
+		health: &redisHealth{
+			threshold: int32(config.NonNegativeIntFromEnv("CACHE_REDIS_FAILURE_THRESHOLD", 3)),
+			cooldown:  config.DurationFromEnv("CACHE_REDIS_COOLDOWN", 10*time.Second),
+			now:       time.Now,
+		},
 	}
 }
 
 // GetJob retrieves a job from cache.
-// Returns the Job if found in cache, nil otherwise.
+// Returns the Job if found in cache, nil otherwise. While Redis is failing, the
+// local fallback cache answers instead.
 func (cs *CacheService) GetJob(jobID uuid.UUID) *model.Job {
+	if !cs.health.available() {
+		return cs.getFallback(jobID)
+	}
 	key := cs.getJobCacheKey(jobID)
 
 	data, err := cs.redisClient.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
+			cs.health.succeeded()
 			log.Printf("Cache MISS for job: %s", jobID)
-		} else {
-			log.Printf("Error getting job %s from cache: %v", jobID, err)
+			return nil
 		}
-		return nil
+		cs.health.failed()
+		log.Printf("Error getting job %s from cache: %v", jobID, err)
+		return cs.getFallback(jobID)
 	}
+	cs.health.succeeded()
 
 	var job model.Job
 	if err := json.Unmarshal(data, &job); err != nil {
 		log.Printf("Error deserializing job %s from cache: %v", jobID, err)
 		return nil
 	}
+	if err := job.DecodePayloadInPlace(); err != nil {
+		log.Printf("Error decompressing payload of job %s from cache: %v", jobID, err)
+		return nil
+	}
 
 	log.Printf("Cache HIT for job: %s", jobID)
+	cs.fallback.put(&job)
 	return &job
 }
 
+// getFallback returns the job from the local fallback cache, nil if it isn't there.
+func (cs *CacheService) getFallback(jobID uuid.UUID) *model.Job {
+	job := cs.fallback.get(jobID)
+	if job == nil {
+		return nil
+	}
+	config.GetMetrics().IncCacheFallbackHit()
+	log.Printf("Cache fallback HIT for job: %s", jobID)
+	return job
+}
+
 // CacheJob stores a job in the cache.
+//
+// Best-effort: the database is the source of truth, so a failed write is logged
+// and counted (cache.write_failures) but never reported to the caller. A Redis
+// blip must not fail job creation or processing; the next read is a cache miss.
 func (cs *CacheService) CacheJob(job *model.Job) {
 	if job == nil || job.ID == uuid.Nil {
 		return
@@ -91,68 +187,279 @@ func (cs *CacheService) CacheJob(job *model.Job) {
 	key := cs.getJobCacheKey(job.ID)
 	ttl := time.Duration(cs.jobCacheTTLMinutes) * time.Minute
 
-	data, err := json.Marshal(job)
-	if err != nil {
-		log.Printf("Error serializing job %s for cache: %v", job.ID, err)
+	cs.fallback.put(job)
+	if !cs.health.available() {
+		return
+	}
+
+	data, ok := cs.serialize(job)
+	if !ok {
 		return
 	}
 
-	if err := cs.redisClient.Set(ctx, key, data, ttl).Err(); err != nil {
+	pipe := cs.redisClient.Pipeline()
+	pipe.Set(ctx, key, data, ttl)
+	cs.trackClientJob(pipe, job, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		cs.health.failed()
 		log.Printf("Error caching job %s: %v", job.ID, err)
+		config.GetMetrics().IncCacheWriteFailure()
 		return
 	}
+	cs.health.succeeded()
 
 	log.Printf("Cached job: %s (TTL: %d minutes)", job.ID, cs.jobCacheTTLMinutes)
 }
 
-// InvalidateJob deletes a job from cache.
-// Call this when job is updated to keep cache consistent.
-func (cs *CacheService) InvalidateJob(jobID uuid.UUID) {
-	key := cs.getJobCacheKey(jobID)
+// serialize returns the cached form of a job, or false (logged and counted) if it can't be encoded.
+func (cs *CacheService) serialize(job *model.Job) ([]byte, bool) {
+	// Large payloads are cached compressed, same as in the database
+	encoded, err := job.EncodedCopy(cs.compressThreshold)
+	if err != nil {
+		log.Printf("Error compressing payload of job %s for cache: %v", job.ID, err)
+		config.GetMetrics().IncCacheWriteFailure()
+		return nil, false
+	}
+
+	data, err := json.Marshal(encoded)
+	if err != nil {
+		log.Printf("Error serializing job %s for cache: %v", job.ID, err)
+		config.GetMetrics().IncCacheWriteFailure()
+		return nil, false
+	}
+	return data, true
+}
+
+// WarmUp caches the given jobs in a single Redis pipeline (one round-trip),
+// so a cold start doesn't send the first burst of workers to the database.
+//
+// Best-effort like CacheJob: jobs that can't be encoded are skipped, and a failed
+// pipeline is logged and counted but not reported to the caller.
+func (cs *CacheService) WarmUp(jobs []model.Job) {
+	if len(jobs) == 0 || !cs.health.available() {
+		return
+	}
+	ttl := time.Duration(cs.jobCacheTTLMinutes) * time.Minute
+
+	pipe := cs.redisClient.Pipeline()
+	queued := 0
+	for i := range jobs {
+		job := &jobs[i]
+		if job.ID == uuid.Nil {
+			continue
+		}
+		data, ok := cs.serialize(job)
+		if !ok {
+			continue
+		}
+		pipe.Set(ctx, cs.getJobCacheKey(job.ID), data, ttl)
+		cs.trackClientJob(pipe, job, ttl)
+		queued++
+	}
+	if queued == 0 {
+		return
+	}
 
-	if err := cs.redisClient.Del(ctx, key).Err(); err != nil {
+	if _, err := pipe.Exec(ctx); err != nil {
+		cs.health.failed()
+		log.Printf("Error warming up cache with %d jobs: %v", queued, err)
+		config.GetMetrics().IncCacheWriteFailure()
+		return
+	}
+	cs.health.succeeded()
+
+	log.Printf("Cache warm-up: cached %d jobs (TTL: %d minutes)", queued, cs.jobCacheTTLMinutes)
+}
+
+// WarmUpPendingJobs caches the most recent PENDING jobs, up to CACHE_WARMUP_LIMIT.
+// Call once at startup, after the database and Redis are connected and before the
+// worker starts consuming.
+func WarmUpPendingJobs(jobRepository *repository.JobRepository, cacheService *CacheService) {
+	limit := config.GetCacheWarmupLimit()
+	if limit == 0 {
+		return
+	}
+	jobs, err := jobRepository.FindRecentByStatus(model.StatusPending, limit)
+	if err != nil {
+		log.Printf("Skipping cache warm-up: failed to load pending jobs: %v", err)
+		return
+	}
+	cacheService.WarmUp(jobs)
+}
+
+// trackClientJob queues adding the job to its client's set of cached job IDs, scored by
+// the entry's expiry, and trimming the members whose entries have already expired.
+// The set is kept at least as long as the job's entry.
+func (cs *CacheService) trackClientJob(pipe redis.Pipeliner, job *model.Job, ttl time.Duration) {
+	if job.ClientID == "" {
+		return
+	}
+	setKey := cs.getClientJobsKey(job.ClientID)
+	now := time.Now()
+	pipe.ZRemRangeByScore(ctx, setKey, "-inf", strconv.FormatInt(now.Unix(), 10))
+	pipe.ZAdd(ctx, setKey, redis.Z{Score: float64(now.Add(ttl).Unix()), Member: job.ID.String()})
+	pipe.Expire(ctx, setKey, ttl)
+}
+
+// InvalidateJob deletes a job from cache, and from the set of cached jobs of clientID,
+// the job's client. Call this when job is updated to keep cache consistent.
+func (cs *CacheService) InvalidateJob(jobID uuid.UUID, clientID string) {
+	cs.fallback.remove(jobID)
+	if !cs.health.available() {
+		return
+	}
+
+	pipe := cs.redisClient.Pipeline()
+	pipe.Del(ctx, cs.getJobCacheKey(jobID))
+	if clientID != "" {
+		pipe.ZRem(ctx, cs.getClientJobsKey(clientID), jobID.String())
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		cs.health.failed()
 		log.Printf("Error invalidating job %s: %v", jobID, err)
 		return
 	}
+	cs.health.succeeded()
 
 	log.Printf("Invalidated cache for job: %s", jobID)
 }
 
+// InvalidateClient deletes every cached job of a client, and the client's set of cached
+// jobs, in one pipeline. Returns the number of job entries deleted.
+// A job cached while this runs may be missed; its entry then expires with its TTL.
+func (cs *CacheService) InvalidateClient(clientID string) int {
+	cs.fallback.removeClient(clientID)
+	if !cs.health.available() {
+		return 0
+	}
+	setKey := cs.getClientJobsKey(clientID)
+	ids, err := cs.redisClient.ZRange(ctx, setKey, 0, -1).Result()
+	if err != nil {
+		cs.health.failed()
+		log.Printf("Error listing cached jobs of client %s: %v", clientID, err)
+		return 0
+	}
+
+	pipe := cs.redisClient.Pipeline()
+	deletes := make([]*redis.IntCmd, 0, len(ids))
+	for _, id := range ids {
+		jobID, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		deletes = append(deletes, pipe.Del(ctx, cs.getJobCacheKey(jobID)))
+	}
+	pipe.Del(ctx, setKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		cs.health.failed()
+		log.Printf("Error invalidating cached jobs of client %s: %v", clientID, err)
+		return 0
+	}
+	cs.health.succeeded()
+
+	deleted := 0
+	for _, del := range deletes {
+		deleted += int(del.Val())
+	}
+	log.Printf("Invalidated cache for client %s: %d jobs", clientID, deleted)
+	return deleted
+}
+
 // UpdateJob updates a job in cache after modification.
+// Best-effort like CacheJob; if the rewrite fails the stale entry is already gone.
+// The job stays in its client's set, which CacheJob refreshes.
 func (cs *CacheService) UpdateJob(job *model.Job) {
-	cs.InvalidateJob(job.ID)
+	cs.fallback.remove(job.ID)
+	if cs.health.available() {
+		if err := cs.redisClient.Del(ctx, cs.getJobCacheKey(job.ID)).Err(); err != nil {
+			cs.health.failed()
+			log.Printf("Error invalidating job %s: %v", job.ID, err)
+		}
+	}
 	cs.CacheJob(job)
 }
 
 // GetCacheInfo returns cache statistics for monitoring.
+// Keys are counted page by page with SCAN rather than KEYS, which blocks Redis on a
+// large keyspace. SCAN can return a key twice while the keyspace is being resized,
+// so the count is approximate.
 func (cs *CacheService) GetCacheInfo() string {
-	keys, err := cs.redisClient.Keys(ctx, "job:*").Result()
+	cached := 0
+	err := cs.scanKeys("job:*", func(keys []string) error {
+		cached += len(keys)
+		return nil
+	})
 	if err != nil {
 		log.Printf("Error getting cache info: %v", err)
 		return "Cache info unavailable"
 	}
-	return fmt.Sprintf("Cached jobs: %d", len(keys))
+	return fmt.Sprintf("Cached jobs: %d", cached)
 }
 
-// ClearAllJobCaches clears all job caches (admin function).
+// ClearAllJobCaches clears all job caches (admin function), and the clients' sets of
+// cached jobs with them.
+// Keys are found with SCAN and deleted as they are found, at most cacheScanBatchSize per UNLINK.
 func (cs *CacheService) ClearAllJobCaches() {
-	keys, err := cs.redisClient.Keys(ctx, "job:*").Result()
+	cs.fallback.clear()
+	cleared := 0
+	unlinkAll := func(keys []string) error {
+		for start := 0; start < len(keys); start += cacheScanBatchSize {
+			chunk := keys[start:min(start+cacheScanBatchSize, len(keys))]
+			if err := cs.unlink(chunk); err != nil {
+				return err
+			}
+			cleared += len(chunk)
+		}
+		return nil
+	}
+	err := cs.scanKeys("job:*", unlinkAll)
+	if err == nil {
+		err = cs.scanKeys("client_jobs:*", unlinkAll)
+	}
 	if err != nil {
-		log.Printf("Error clearing job caches: %v", err)
+		log.Printf("Error clearing job caches after %d keys: %v", cleared, err)
 		return
 	}
 
-	if len(keys) > 0 {
-		if err := cs.redisClient.Del(ctx, keys...).Err(); err != nil {
-			log.Printf("Error clearing job caches: %v", err)
-			return
+	log.Printf("Cleared all job caches (%d keys)", cleared)
+}
+
+// scanKeys calls fn with each page of keys matching pattern returned by a full SCAN cursor loop.
+func (cs *CacheService) scanKeys(pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := cs.redisClient.Scan(ctx, cursor, pattern, cacheScanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
 		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
 	}
+}
 
-	log.Println("Cleared all job caches")
+// unlink deletes keys with UNLINK, which frees their memory in the background,
+// falling back to DEL on servers older than Redis 4.0.
+func (cs *CacheService) unlink(keys []string) error {
+	err := cs.redisClient.Unlink(ctx, keys...).Err()
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "unknown command") {
+		return cs.redisClient.Del(ctx, keys...).Err()
+	}
+	return err
 }
 
 // getJobCacheKey returns the Redis key for job caching.
 func (cs *CacheService) getJobCacheKey(jobID uuid.UUID) string {
 	return "job:" + jobID.String()
+}
+
+// getClientJobsKey returns the Redis key of the set of a client's cached job IDs.
+func (cs *CacheService) getClientJobsKey(clientID string) string {
+	return "client_jobs:" + clientID
 }
\ No newline at end of file
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+// TestLargePayloadRoundTripsCompressed verifies large payloads are stored compressed
+// in the database and cache and read back unchanged.
+func TestLargePayloadRoundTripsCompressed(t *testing.T) {
+	t.Setenv("PAYLOAD_COMPRESSION_THRESHOLD", "1024")
+
+	db := newTestDB(t)
+	repo := repository.NewJobRepository(db)
+	mr, client := newTestRedis(t)
+	cache := NewCacheService(client)
+
+	payload := "order_1|customer@email.com|" + strings.Repeat("<p>Thanks for your order!</p>", 200)
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, payload)
+
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if job.Payload != payload {
+		t.Fatal("Create must leave the caller's payload plain")
+	}
+
+	// Stored compressed in the database
+	var row struct {
+		Payload         string
+		PayloadEncoding string
+	}
+	db.Raw("SELECT payload, payload_encoding FROM jobs WHERE id = ?", job.ID).Scan(&row)
+	if row.PayloadEncoding != model.PayloadEncodingGzip || len(row.Payload) >= len(payload) {
+		t.Fatalf("expected compressed payload in db, got encoding %q and %d bytes", row.PayloadEncoding, len(row.Payload))
+	}
+
+	found, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if found.Payload != payload {
+		t.Fatal("payload read from the database does not match")
+	}
+
+	// Stored compressed in the cache
+	cache.CacheJob(found)
+	cached, err := mr.Get("job:" + job.ID.String())
+	if err != nil {
+		t.Fatalf("redis get: %v", err)
+	}
+	if len(cached) >= len(payload) {
+		t.Fatalf("expected compressed cache entry, got %d bytes", len(cached))
+	}
+	if got := cache.GetJob(job.ID); got == nil || got.Payload != payload {
+		t.Fatal("payload read from the cache does not match")
+	}
+}
+
+// TestSmallPayloadStaysPlain verifies payloads under the threshold are not compressed.
+func TestSmallPayloadStaysPlain(t *testing.T) {
+	t.Setenv("PAYLOAD_COMPRESSION_THRESHOLD", "1024")
+
+	db := newTestDB(t)
+	repo := repository.NewJobRepository(db)
+	job := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|customer@email.com|$10.00")
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	var encoding string
+	db.Raw("SELECT payload_encoding FROM jobs WHERE id = ?", job.ID).Scan(&encoding)
+	if encoding != model.PayloadEncodingPlain {
+		t.Fatalf("expected plain payload, got encoding %q", encoding)
+	}
+}
+
+// TestCacheJobFailureIsBestEffort verifies a Redis outage is counted but never surfaces to the caller.
+func TestCacheJobFailureIsBestEffort(t *testing.T) {
+	mr, client := newTestRedis(t)
+	cache := NewCacheService(client)
+	mr.Close()
+
+	before := config.GetMetrics().CacheWriteFailures()
+	cache.CacheJob(model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00"))
+
+	if got := config.GetMetrics().CacheWriteFailures() - before; got != 1 {
+		t.Fatalf("expected 1 cache write failure recorded, got %d", got)
+	}
+}
+
+// commandRecorder is a go-redis hook recording every command sent outside a
+// pipeline, and the number of pipelines (one round-trip each).
+type commandRecorder struct {
+	mu        sync.Mutex
+	commands  [][]any
+	pipelines int
+}
+
+func (r *commandRecorder) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (r *commandRecorder) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(c context.Context, cmd redis.Cmder) error {
+		r.mu.Lock()
+		r.commands = append(r.commands, cmd.Args())
+		r.mu.Unlock()
+		return next(c, cmd)
+	}
+}
+
+func (r *commandRecorder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(c context.Context, cmds []redis.Cmder) error {
+		r.mu.Lock()
+		r.pipelines++
+		r.mu.Unlock()
+		return next(c, cmds)
+	}
+}
+
+// TestJobCacheScanCountsAndClearsAllKeys verifies GetCacheInfo and ClearAllJobCaches
+// walk the job keys with SCAN instead of KEYS and delete them, and the clients' sets of
+// cached jobs, in bounded UNLINK chunks.
+func TestJobCacheScanCountsAndClearsAllKeys(t *testing.T) {
+	mr, client := newTestRedis(t)
+	recorder := &commandRecorder{}
+	client.AddHook(recorder)
+	cache := NewCacheService(client)
+
+	for i := range 1000 {
+		mr.Set(fmt.Sprintf("job:%d", i), "{}")
+	}
+	mr.ZAdd("client_jobs:customer-1", 1, "job-1")
+	mr.Set("ratelimit:customer-1", "1")
+
+	if got := cache.GetCacheInfo(); got != "Cached jobs: 1000" {
+		t.Fatalf("expected 1000 cached jobs, got %q", got)
+	}
+	cache.ClearAllJobCaches()
+
+	for i := range 1000 {
+		if mr.Exists(fmt.Sprintf("job:%d", i)) {
+			t.Fatalf("expected job:%d to be cleared", i)
+		}
+	}
+	if mr.Exists("client_jobs:customer-1") {
+		t.Fatal("expected the client set to be cleared")
+	}
+	if !mr.Exists("ratelimit:customer-1") {
+		t.Fatal("expected keys outside job:* and client_jobs:* to be left alone")
+	}
+
+	scans, unlinks := 0, 0
+	for _, args := range recorder.commands {
+		switch strings.ToUpper(fmt.Sprint(args[0])) {
+		case "KEYS":
+			t.Fatalf("expected no KEYS command, got %v", args)
+		case "SCAN":
+			scans++
+			if fmt.Sprint(args[len(args)-1]) != fmt.Sprint(cacheScanBatchSize) {
+				t.Fatalf("expected SCAN with COUNT %d, got %v", cacheScanBatchSize, args)
+			}
+		case "UNLINK":
+			unlinks++
+			if keys := len(args) - 1; keys > cacheScanBatchSize {
+				t.Fatalf("expected at most %d keys per UNLINK, got %d", cacheScanBatchSize, keys)
+			}
+		}
+	}
+	if scans < 2 || unlinks < 1000/cacheScanBatchSize {
+		t.Fatalf("expected a SCAN per walk and chunked UNLINKs, got %d scans and %d unlinks", scans, unlinks)
+	}
+}
+
+// TestWarmUpCachesJobsInOneRoundTrip verifies the startup warm-up caches the most
+// recent PENDING jobs, capped by CACHE_WARMUP_LIMIT, with a single pipeline.
+func TestWarmUpCachesJobsInOneRoundTrip(t *testing.T) {
+	t.Setenv("CACHE_WARMUP_LIMIT", "50")
+
+	repo := repository.NewJobRepository(newTestDB(t))
+	mr, client := newTestRedis(t)
+	recorder := &commandRecorder{}
+	client.AddHook(recorder)
+	cache := NewCacheService(client)
+
+	base := time.Now().Add(-time.Hour)
+	var jobs []*model.Job
+	for i := range 60 {
+		job := model.NewJob("customer-1", model.TypePaymentProcess, fmt.Sprintf("order_%d|user@email.com|$10.00", i))
+		job.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+	done := model.NewJob("customer-1", model.TypePaymentProcess, "order_done|user@email.com|$10.00")
+	done.Status = model.StatusCompleted
+	if err := repo.Create(done); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	// Open the connection first so its handshake isn't counted
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	recorder.commands, recorder.pipelines = nil, 0
+
+	WarmUpPendingJobs(repo, cache)
+
+	if recorder.pipelines != 1 || len(recorder.commands) != 0 {
+		t.Fatalf("expected 1 pipeline and no single commands, got %d pipelines and %d commands", recorder.pipelines, len(recorder.commands))
+	}
+	// The 10 oldest are left out by the limit, as is the COMPLETED job
+	for i, job := range jobs {
+		if cached := mr.Exists("job:" + job.ID.String()); cached != (i >= 10) {
+			t.Fatalf("job %d: expected cached=%v, got %v", i, i >= 10, cached)
+		}
+	}
+	if mr.Exists("job:" + done.ID.String()) {
+		t.Fatal("expected non-PENDING jobs not to be warmed up")
+	}
+	if got := cache.GetJob(jobs[59].ID); got == nil || got.Payload != jobs[59].Payload {
+		t.Fatalf("expected the warmed-up job to read back from cache, got %+v", got)
+	}
+}
+
+// TestInvalidateClientRemovesOnlyItsJobs verifies the client's set tracks the jobs cached
+// for it, trimming expired ones, and InvalidateClient deletes exactly those entries and
+// the set in one pipeline.
+func TestInvalidateClientRemovesOnlyItsJobs(t *testing.T) {
+	mr, client := newTestRedis(t)
+	recorder := &commandRecorder{}
+	client.AddHook(recorder)
+	cache := NewCacheService(client)
+
+	// A member whose entry has expired is trimmed by the next write
+	mr.ZAdd("client_jobs:customer-1", float64(time.Now().Add(-time.Minute).Unix()), uuid.NewString())
+
+	var jobs []*model.Job
+	for i := range 3 {
+		job := model.NewJob("customer-1", model.TypePaymentProcess, fmt.Sprintf("order_%d|user@email.com|$10.00", i))
+		cache.CacheJob(job)
+		jobs = append(jobs, job)
+	}
+	other := model.NewJob("customer-2", model.TypePaymentProcess, "order_9|user@email.com|$10.00")
+	cache.CacheJob(other)
+
+	members, err := mr.ZMembers("client_jobs:customer-1")
+	if err != nil || len(members) != 3 {
+		t.Fatalf("expected 3 jobs tracked for customer-1, got %v: %v", members, err)
+	}
+	if ttl := mr.TTL("client_jobs:customer-1"); ttl <= 0 {
+		t.Fatalf("expected the client set to expire, got TTL %v", ttl)
+	}
+
+	// An invalidated job leaves the set
+	cache.InvalidateJob(jobs[2].ID, "customer-1")
+	if members, _ := mr.ZMembers("client_jobs:customer-1"); slices.Contains(members, jobs[2].ID.String()) || mr.Exists("job:"+jobs[2].ID.String()) {
+		t.Fatal("expected the invalidated job removed from cache and from its client's set")
+	}
+	cache.CacheJob(jobs[2])
+
+	recorder.commands, recorder.pipelines = nil, 0
+	if cleared := cache.InvalidateClient("customer-1"); cleared != 3 {
+		t.Fatalf("expected 3 jobs cleared, got %d", cleared)
+	}
+	if recorder.pipelines != 1 || len(recorder.commands) != 1 {
+		t.Fatalf("expected ZRANGE then 1 pipeline, got %d commands and %d pipelines", len(recorder.commands), recorder.pipelines)
+	}
+	for _, job := range jobs {
+		if mr.Exists("job:" + job.ID.String()) {
+			t.Fatalf("expected job %s of customer-1 invalidated", job.ID)
+		}
+	}
+	if mr.Exists("client_jobs:customer-1") {
+		t.Fatal("expected the client set deleted")
+	}
+	if !mr.Exists("job:"+other.ID.String()) || !mr.Exists("client_jobs:customer-2") {
+		t.Fatal("expected another client's cached jobs left alone")
+	}
+}
+
+// TestGetJobFallsBackToLocalCacheWhileRedisIsDown verifies lookups are served from the
+// local cache when Redis errors, that repeated errors suppress Redis calls (invalidations
+// included) for the cooldown, and that Redis is tried again once it's over.
+func TestGetJobFallsBackToLocalCacheWhileRedisIsDown(t *testing.T) {
+	t.Setenv("CACHE_REDIS_FAILURE_THRESHOLD", "2")
+	t.Setenv("CACHE_REDIS_COOLDOWN", "10s")
+	mr, client := newTestRedis(t)
+	recorder := &commandRecorder{}
+	client.AddHook(recorder)
+	cache := NewCacheService(client)
+	clock := time.Now()
+	cache.health.now = func() time.Time { return clock }
+
+	job := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	cache.CacheJob(job)
+	uncached := uuid.New()
+
+	mr.SetError("connection refused")
+	before := config.GetMetrics().CacheFallbackHits()
+
+	if got := cache.GetJob(job.ID); got == nil || got.ID != job.ID || got.Payload != job.Payload {
+		t.Fatalf("expected the job from the local cache, got %+v", got)
+	}
+	if got := cache.GetJob(uncached); got != nil {
+		t.Fatalf("expected a miss for a job never cached, got %+v", got)
+	}
+	if got := config.GetMetrics().CacheFallbackHits() - before; got != 1 {
+		t.Fatalf("expected 1 fallback hit recorded, got %d", got)
+	}
+
+	// Two errors in a row: Redis is left alone for the cooldown
+	recorder.commands, recorder.pipelines = nil, 0
+	if got := cache.GetJob(job.ID); got == nil {
+		t.Fatal("expected the local cache to keep serving during the cooldown")
+	}
+	other := model.NewJob("customer-1", model.TypePaymentProcess, "order_2|user@email.com|$10.00")
+	cache.CacheJob(other)
+	cache.UpdateJob(other)
+	cache.InvalidateJob(other.ID, other.ClientID)
+	cache.InvalidateClient("customer-1")
+	if len(recorder.commands) != 0 || recorder.pipelines != 0 {
+		t.Fatalf("expected no Redis calls during the cooldown, got %v", recorder.commands)
+	}
+
+	// Invalidations still drop the local entries
+	if got := cache.GetJob(other.ID); got != nil {
+		t.Fatalf("expected the invalidated job gone from the local cache, got %+v", got)
+	}
+	cache.CacheJob(job)
+
+	mr.SetError("")
+	clock = clock.Add(11 * time.Second)
+	if got := cache.GetJob(job.ID); got == nil || len(recorder.commands) != 1 {
+		t.Fatalf("expected Redis read again after the cooldown, got %+v and %d commands", got, len(recorder.commands))
+	}
+	if got := config.GetMetrics().CacheFallbackHits() - before; got != 2 {
+		t.Fatalf("expected no fallback hit once Redis answers, got %d in total", got)
+	}
+}
+
+// TestLocalJobCacheEvictsAndExpires verifies the fallback cache keeps the most recently
+// used jobs up to its size, each for its TTL.
+func TestLocalJobCacheEvictsAndExpires(t *testing.T) {
+	cache := newLocalJobCache(2, time.Minute)
+	clock := time.Now()
+	cache.now = func() time.Time { return clock }
+
+	jobs := make([]*model.Job, 3)
+	for i := range jobs {
+		jobs[i] = model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	}
+	cache.put(jobs[0])
+	cache.put(jobs[1])
+	cache.get(jobs[0].ID) // jobs[1] is now the least recently used
+	cache.put(jobs[2])
+
+	if cache.get(jobs[1].ID) != nil || cache.get(jobs[0].ID) == nil || cache.get(jobs[2].ID) == nil {
+		t.Fatal("expected the least recently used job evicted")
+	}
+
+	clock = clock.Add(time.Minute)
+	if cache.get(jobs[0].ID) != nil {
+		t.Fatal("expected the entry expired after its TTL")
+	}
+	if newLocalJobCache(0, time.Minute).get(jobs[0].ID) != nil {
+		t.Fatal("expected a disabled cache to hold nothing")
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"distributed-job-processor/model"
+)
+
+// ChaosInjector fails a configured fraction of each type's attempts on purpose, so retry,
+// backoff, and dead-letter handling can be exercised end to end with deterministic failures.
+//
+// Configuration (all inert unless CHAOS_ENABLED=true; never enable it in production):
+// - CHAOS_FAILURE_RATE_<TYPE> (e.g. CHAOS_FAILURE_RATE_PAYMENT_PROCESS=0.3): the fraction
+//   of attempts, 0 to 1, failed instead of running the handler
+// - CHAOS_SEED: seeds the random source, so a run's sequence of failures can be
+//   reproduced; random when unset
+//
+// An injected failure is retryable (FailureDownstream5xx), like a flaky downstream, so
+// the job is retried with backoff and dead-lettered once out of attempts.
+type ChaosInjector struct {
+	rates map[model.JobType]float64
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewChaosInjectorFromEnv creates a ChaosInjector from CHAOS_* env vars.
+// Returns nil (injecting nothing) unless CHAOS_ENABLED=true and some type has a rate.
+func NewChaosInjectorFromEnv() *ChaosInjector {
+	if os.Getenv("CHAOS_ENABLED") != "true" {
+		return nil
+	}
+
+	rates := make(map[model.JobType]float64)
+	for _, spec := range model.JobTypeSpecs() {
+		key := "CHAOS_FAILURE_RATE_" + string(spec.Type)
+		if val := os.Getenv(key); val != "" {
+			if parsed, err := strconv.ParseFloat(val, 64); err == nil && parsed >= 0 && parsed <= 1 {
+				rates[spec.Type] = parsed
+			} else {
+				log.Printf("Ignoring invalid %s %q: must be a number from 0 to 1", key, val)
+			}
+		}
+	}
+	if len(rates) == 0 {
+		return nil
+	}
+
+	seed := uint64(time.Now().UnixNano())
+	if val := os.Getenv("CHAOS_SEED"); val != "" {
+		if parsed, err := strconv.ParseUint(val, 10, 64); err == nil {
+			seed = parsed
+		} else {
+			log.Printf("Ignoring invalid CHAOS_SEED %q: must be a non-negative integer", val)
+		}
+	}
+	return NewChaosInjector(rates, seed)
+}
+
+// NewChaosInjector creates a ChaosInjector failing each type's attempts at its rate,
+// drawing from a random source seeded with seed.
+func NewChaosInjector(rates map[model.JobType]float64, seed uint64) *ChaosInjector {
+	for jobType, rate := range rates {
+		log.Printf("Chaos: failing %.0f%% of %s attempts (seed %d)", rate*100, jobType, seed)
+	}
+	return &ChaosInjector{
+		rates: rates,
+		rng:   rand.New(rand.NewPCG(seed, seed)),
+	}
+}
+
+// Inject returns a simulated retryable failure for this attempt of the job, or nil to
+// let it run. A nil ChaosInjector never fails a job.
+func (c *ChaosInjector) Inject(job *model.Job) error {
+	if c == nil {
+		return nil
+	}
+	rate, ok := c.rates[job.Type]
+	if !ok || rate <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	draw := c.rng.Float64()
+	c.mu.Unlock()
+	if draw >= rate {
+		return nil
+	}
+	return NewJobFailure(model.FailureDownstream5xx,
+		fmt.Errorf("chaos: simulated failure of %s attempt %d", job.Type, job.Attempts+1))
+}
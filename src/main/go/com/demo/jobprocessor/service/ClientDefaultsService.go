@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+// ClientDefaultsService resolves per-client job defaults (client_defaults table).
+//
+// Every job create looks up the client's defaults, so they are cached in Redis:
+// - Redis Key Format: client_defaults:{clientId}
+// - Redis Value: Serialized ClientDefaults (JSON); clients without a row are
+//   cached too, so they don't cost a DB query per create
+// - TTL: CLIENT_DEFAULTS_CACHE_TTL_SECONDS (default 300), the longest a
+//   change made directly in the table takes to apply
+//
+// Lookups are best-effort: if Redis and the database both fail, the job is
+// created with the global defaults rather than rejected.
+type ClientDefaultsService struct {
+	repository  *repository.ClientDefaultsRepository
+	redisClient *redis.Client
+	cacheTTL    time.Duration
+}
+
+// NewClientDefaultsService creates a new ClientDefaultsService.
+func NewClientDefaultsService(repo *repository.ClientDefaultsRepository, redisClient *redis.Client) *ClientDefaultsService {
+	ttlSeconds := 300 // default
+	if val := os.Getenv("CLIENT_DEFAULTS_CACHE_TTL_SECONDS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil {
+			ttlSeconds = parsed
+		}
+	}
+
+	return &ClientDefaultsService{
+		repository:  repo,
+		redisClient: redisClient,
+		cacheTTL:    time.Duration(ttlSeconds) * time.Second,
+	}
+}
+
+// GetDefaults returns the client's defaults. The result is never nil;
+// a client without configured defaults gets an empty ClientDefaults.
+func (s *ClientDefaultsService) GetDefaults(clientID string) *model.ClientDefaults {
+	key := s.cacheKey(clientID)
+
+	data, err := s.redisClient.Get(ctx, key).Bytes()
+	if err == nil {
+		var defaults model.ClientDefaults
+		if err := json.Unmarshal(data, &defaults); err == nil {
+			return &defaults
+		}
+		log.Printf("Error deserializing client defaults for %s from cache: %v", clientID, err)
+	} else if err != redis.Nil {
+		log.Printf("Error getting client defaults for %s from cache: %v", clientID, err)
+	}
+
+	defaults, err := s.repository.FindByClientID(clientID)
+	if err != nil {
+		log.Printf("Error loading client defaults for %s, using global defaults: %v", clientID, err)
+		return &model.ClientDefaults{ClientID: clientID}
+	}
+	if defaults == nil {
+		defaults = &model.ClientDefaults{ClientID: clientID}
+	}
+
+	if data, err := json.Marshal(defaults); err == nil {
+		if err := s.redisClient.Set(ctx, key, data, s.cacheTTL).Err(); err != nil {
+			log.Printf("Error caching client defaults for %s: %v", clientID, err)
+		}
+	}
+	return defaults
+}
+
+// SaveDefaults stores a client's defaults and drops the cached copy so the change applies immediately.
+func (s *ClientDefaultsService) SaveDefaults(defaults *model.ClientDefaults) error {
+	if err := s.repository.Save(defaults); err != nil {
+		return err
+	}
+	if err := s.redisClient.Del(ctx, s.cacheKey(defaults.ClientID)).Err(); err != nil {
+		log.Printf("Error invalidating client defaults for %s: %v", defaults.ClientID, err)
+	}
+	return nil
+}
+
+// cacheKey returns the Redis key for a client's cached defaults.
+func (s *ClientDefaultsService) cacheKey(clientID string) string {
+	return "client_defaults:" + clientID
+}
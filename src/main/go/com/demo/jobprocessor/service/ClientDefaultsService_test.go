@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"distributed-job-processor/dto"
+	"distributed-job-processor/exception"
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+func intPtr(v int) *int { return &v }
+
+// TestCreateJobAppliesClientDefaults verifies settings resolve as request > client default > global default.
+func TestCreateJobAppliesClientDefaults(t *testing.T) {
+	db := newTestDB(t)
+	_, client := newTestRedis(t)
+	defaults := NewClientDefaultsService(repository.NewClientDefaultsRepository(db), client)
+	if err := defaults.SaveDefaults(&model.ClientDefaults{
+		ClientID:   "premium",
+		MaxRetries: intPtr(6),
+		Priority:   intPtr(2),
+	}); err != nil {
+		t.Fatalf("save defaults: %v", err)
+	}
+
+	s := NewJobService(repository.NewJobRepository(db))
+	s.SetClientDefaultsService(defaults)
+
+	cases := []struct {
+		name           string
+		clientID       string
+		request        dto.JobRequest
+		wantPriority   int
+		wantMaxRetries int
+	}{
+		{"client defaults", "premium", dto.JobRequest{}, 2, 6},
+		{"request overrides", "premium", dto.JobRequest{Priority: intPtr(9), MaxRetries: intPtr(1)}, 9, 1},
+		{"partial override", "premium", dto.JobRequest{MaxRetries: intPtr(4)}, 2, 4},
+		{"global defaults", "regular", dto.JobRequest{}, model.DefaultPriority, 3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.request.Type = model.TypePaymentProcess
+			tc.request.Payload = "order_1|user@email.com|$10.00"
+
+			job, err := s.CreateJob(context.Background(), tc.clientID, &tc.request)
+			if err != nil {
+				t.Fatalf("CreateJob: %v", err)
+			}
+			if job.Priority != tc.wantPriority || job.MaxRetries != tc.wantMaxRetries {
+				t.Fatalf("got priority=%d maxRetries=%d, want %d/%d",
+					job.Priority, job.MaxRetries, tc.wantPriority, tc.wantMaxRetries)
+			}
+		})
+	}
+}
+
+// TestGetDefaultsServedFromCache verifies defaults are read from Redis after the first lookup.
+func TestGetDefaultsServedFromCache(t *testing.T) {
+	db := newTestDB(t)
+	_, client := newTestRedis(t)
+	repo := repository.NewClientDefaultsRepository(db)
+	defaults := NewClientDefaultsService(repo, client)
+
+	if err := repo.Save(&model.ClientDefaults{ClientID: "premium", Priority: intPtr(1)}); err != nil {
+		t.Fatalf("save defaults: %v", err)
+	}
+	if got := defaults.GetDefaults("premium"); got.Priority == nil || *got.Priority != 1 {
+		t.Fatalf("expected priority 1, got %+v", got)
+	}
+
+	// Delete the row behind the cache's back: the cached copy is still served
+	db.Exec("DELETE FROM client_defaults")
+	if got := defaults.GetDefaults("premium"); got.Priority == nil || *got.Priority != 1 {
+		t.Fatalf("expected cached priority 1, got %+v", got)
+	}
+}
+
+// TestCreateJobResolvesMaxRetries verifies max retries resolve as request > client default >
+// MAX_RETRIES_<TYPE> > global default, and that out-of-range requests are rejected.
+func TestCreateJobResolvesMaxRetries(t *testing.T) {
+	t.Setenv("MAX_RETRIES_EMAIL_CONFIRMATION", "5")
+	t.Setenv("MAX_RETRIES_PAYMENT_PROCESS", "11")
+
+	db := newTestDB(t)
+	_, client := newTestRedis(t)
+	defaults := NewClientDefaultsService(repository.NewClientDefaultsRepository(db), client)
+	if err := defaults.SaveDefaults(&model.ClientDefaults{ClientID: "premium", MaxRetries: intPtr(7)}); err != nil {
+		t.Fatalf("save defaults: %v", err)
+	}
+
+	s := NewJobService(repository.NewJobRepository(db))
+	s.SetClientDefaultsService(defaults)
+
+	payloads := map[model.JobType]string{
+		model.TypeEmailConfirmation: "order_1|user@email.com|receipt",
+		model.TypePaymentProcess:    "order_1|user@email.com|$10.00",
+	}
+
+	cases := []struct {
+		name       string
+		clientID   string
+		jobType    model.JobType
+		maxRetries *int
+		want       int
+	}{
+		{"request over everything", "premium", model.TypeEmailConfirmation, intPtr(2), 2},
+		{"client default over type default", "premium", model.TypeEmailConfirmation, nil, 7},
+		{"type default", "regular", model.TypeEmailConfirmation, nil, 5},
+		{"invalid type default ignored", "regular", model.TypePaymentProcess, nil, model.DefaultMaxRetries},
+		{"request over type default", "regular", model.TypeEmailConfirmation, intPtr(10), 10},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := &dto.JobRequest{Type: tc.jobType, Payload: payloads[tc.jobType], MaxRetries: tc.maxRetries}
+			job, err := s.CreateJob(context.Background(), tc.clientID, request)
+			if err != nil {
+				t.Fatalf("CreateJob: %v", err)
+			}
+			if job.MaxRetries != tc.want {
+				t.Fatalf("got maxRetries=%d, want %d", job.MaxRetries, tc.want)
+			}
+		})
+	}
+
+	for _, invalid := range []int{-1, 0, 11} {
+		request := &dto.JobRequest{Type: model.TypeEmailConfirmation, Payload: payloads[model.TypeEmailConfirmation], MaxRetries: intPtr(invalid)}
+		if _, err := s.CreateJob(context.Background(), "regular", request); !exception.IsPayloadValidationError(err) {
+			t.Fatalf("maxRetries=%d: expected PayloadValidationError, got %v", invalid, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// defaultMaxConcurrentPerClient caps jobs in flight per client when MAX_CONCURRENT_PER_CLIENT is unset.
+const defaultMaxConcurrentPerClient = 4
+
+// ClientLimiter caps how many jobs of one client a worker processes at once, so a
+// single large client (e.g. during a flash sale) can't occupy every worker slot
+// across all partitions and starve the others.
+//
+// Configuration: MAX_CONCURRENT_PER_CLIENT (default 4).
+//
+// Like the Bulkhead, a client at its limit is not waited for: the worker hands the
+// job back to the scheduler (see JobWorker.deferJob) and moves on.
+// Semaphores are created on a client's first job and dropped once it has none in
+// flight, so the map only holds active clients.
+type ClientLimiter struct {
+	limit int64
+	mu    sync.Mutex
+	slots map[string]*clientSlots
+}
+
+// clientSlots is one client's semaphore and the number of jobs holding it.
+type clientSlots struct {
+	sem      *semaphore.Weighted
+	inFlight int
+}
+
+// NewClientLimiterFromEnv creates a ClientLimiter from MAX_CONCURRENT_PER_CLIENT.
+func NewClientLimiterFromEnv() *ClientLimiter {
+	limit := defaultMaxConcurrentPerClient
+	if val := os.Getenv("MAX_CONCURRENT_PER_CLIENT"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			limit = parsed
+		} else {
+			log.Printf("Ignoring invalid MAX_CONCURRENT_PER_CLIENT %q: must be a positive integer", val)
+		}
+	}
+	return NewClientLimiter(limit)
+}
+
+// NewClientLimiter creates a ClientLimiter allowing limit jobs in flight per client.
+func NewClientLimiter(limit int) *ClientLimiter {
+	return &ClientLimiter{
+		limit: int64(limit),
+		slots: make(map[string]*clientSlots),
+	}
+}
+
+// TryAcquire takes a slot for the client without waiting.
+// Returns false if the client is at its limit. A nil ClientLimiter never limits.
+func (l *ClientLimiter) TryAcquire(clientID string) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots, ok := l.slots[clientID]
+	if !ok {
+		slots = &clientSlots{sem: semaphore.NewWeighted(l.limit)}
+		l.slots[clientID] = slots
+	}
+	if !slots.sem.TryAcquire(1) {
+		return false
+	}
+	slots.inFlight++
+	return true
+}
+
+// Release returns a slot taken by a successful TryAcquire.
+func (l *ClientLimiter) Release(clientID string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots, ok := l.slots[clientID]
+	if !ok {
+		return
+	}
+	slots.sem.Release(1)
+	slots.inFlight--
+	if slots.inFlight == 0 {
+		delete(l.slots, clientID)
+	}
+}
+
+// InFlight returns the number of the client's jobs currently holding a slot.
+func (l *ClientLimiter) InFlight(clientID string) int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if slots, ok := l.slots[clientID]; ok {
+		return slots.inFlight
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"distributed-job-processor/model"
+)
+
+// TestClientLimiterDefersExcessJobs verifies a client past its limit has its excess
+// jobs deferred to the scheduler, while other clients keep being processed.
+func TestClientLimiterDefersExcessJobs(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+	w.clientLimiter = NewClientLimiter(2)
+
+	var admitted []*model.Job
+	var deferred []*model.Job
+	for i := 0; i < 5; i++ {
+		job := model.NewJob("flash-sale", model.TypePaymentProcess, fmt.Sprintf("order_%d|user@email.com|$10.00", i))
+		job.Status = model.StatusRunning
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		if w.clientLimiter.TryAcquire(job.ClientID) {
+			admitted = append(admitted, job)
+		} else {
+			if err := w.deferJob(job, bulkheadDeferDelay); err != nil {
+				t.Fatalf("defer job: %v", err)
+			}
+			deferred = append(deferred, job)
+		}
+	}
+	if len(admitted) != 2 || len(deferred) != 3 {
+		t.Fatalf("expected 2 admitted and 3 deferred, got %d and %d", len(admitted), len(deferred))
+	}
+	for _, job := range deferred {
+		saved, err := repo.FindByID(job.ID)
+		if err != nil {
+			t.Fatalf("reload job: %v", err)
+		}
+		if saved.Status != model.StatusPending || saved.Attempts != 0 {
+			t.Fatalf("expected deferred job PENDING with 0 attempts, got status=%s attempts=%d", saved.Status, saved.Attempts)
+		}
+	}
+
+	// Other clients are unaffected by the flood
+	if !w.clientLimiter.TryAcquire("customer-2") {
+		t.Fatal("expected another client to get a slot")
+	}
+
+	w.clientLimiter.Release("flash-sale")
+	if !w.clientLimiter.TryAcquire("flash-sale") {
+		t.Fatal("expected a slot after release")
+	}
+	w.clientLimiter.Release("flash-sale")
+	w.clientLimiter.Release("flash-sale")
+	if n := w.clientLimiter.InFlight("flash-sale"); n != 0 {
+		t.Fatalf("expected no jobs in flight after releasing all, got %d", n)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"distributed-job-processor/exception"
+	"distributed-job-processor/model"
+)
+
+// CompensationHandler undoes a COMPLETED job of one type when a later step of the
+// order fails, e.g. refunding a payment whose order couldn't be fulfilled.
+//
+// JobService.CompensateJob looks the handler up by the completed job's type (see
+// JobService.RegisterCompensationHandler) and saves the job it returns, linked to the
+// completed one by CompensatesJobID, so the compensation itself is retried and
+// dead-lettered like any job. Types without a handler can't be compensated.
+//
+// Compensate only builds the job; returning an error (e.g. an InvalidJobStateError
+// for a payment that was never charged) refuses the compensation.
+type CompensationHandler interface {
+	Compensate(completed *model.Job) (*model.Job, error)
+}
+
+// CompensationHandlerFunc adapts a function to a CompensationHandler.
+type CompensationHandlerFunc func(completed *model.Job) (*model.Job, error)
+
+// Compensate calls f(completed).
+func (f CompensationHandlerFunc) Compensate(completed *model.Job) (*model.Job, error) {
+	return f(completed)
+}
+
+// DefaultCompensationHandlers returns the compensation handlers of the built-in job
+// types, keyed by the type they compensate.
+func DefaultCompensationHandlers() map[model.JobType]CompensationHandler {
+	return map[model.JobType]CompensationHandler{
+		model.TypePaymentProcess: PaymentCompensation{},
+	}
+}
+
+// PaymentCompensation compensates a PAYMENT_PROCESS job with a REFUND of its charge.
+type PaymentCompensation struct{}
+
+// Compensate returns the REFUND job of the payment, carrying its order, customer and
+// amount and the payment's job ID, with the payment's client, priority and retries.
+// A payment that was never charged (e.g. completed by a dry-run worker) has nothing
+// to refund.
+func (PaymentCompensation) Compensate(payment *model.Job) (*model.Job, error) {
+	if !payment.Charged {
+		return nil, exception.NewInvalidJobStateError(payment.ID, payment.Status, "refunded: it was never charged")
+	}
+
+	fields := strings.Split(payment.Payload, "|")
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("payment %s has a malformed payload", payment.ID)
+	}
+	payload := strings.Join(append(fields[:3:3], payment.ID.String()), "|")
+
+	now := time.Now()
+	return &model.Job{
+		ID:          uuid.New(),
+		ClientID:    payment.ClientID,
+		Type:        model.TypeRefund,
+		Status:      model.StatusPending,
+		Payload:     payload,
+		Labels:      payment.Labels,
+		Priority:    payment.Priority,
+		MaxRetries:  payment.MaxRetries,
+		CreatedAt:   now,
+		ScheduledAt: &now,
+	}, nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"distributed-job-processor/config"
+)
+
+// defaultConsumerLagInterval is how often consumer lag is sampled when KAFKA_LAG_INTERVAL is unset.
+const defaultConsumerLagInterval = 15 * time.Second
+
+// consumerLagLoop samples the lag of every reader each KAFKA_LAG_INTERVAL (default
+// 15s, 0 disables) until the worker stops. Reading reader stats off the consume
+// path keeps lag reporting out of per-message processing.
+func (w *JobWorker) consumerLagLoop() {
+	ticker := time.NewTicker(w.lagInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			w.sampleConsumerLag()
+		}
+	}
+}
+
+// sampleConsumerLag records the current lag of the job queue and high-priority readers
+// (see config/consumerlag.go), labelled by each reader's index among the worker's
+// readers of its kind.
+func (w *JobWorker) sampleConsumerLag() {
+	for _, readers := range [][]*kafka.Reader{w.kafkaReaders, w.highPriorityReaders} {
+		for i, reader := range readers {
+			stats := reader.Stats()
+			config.GetMetrics().SetKafkaConsumerLag(stats.Topic, strconv.Itoa(i), stats.Lag)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"distributed-job-processor/model"
+)
+
+// DeadLetterNotifier turns a burst of dead letters (e.g. during a downstream outage)
+// into one summarized notification instead of an alert per job.
+//
+// The first dead letter after a flush opens a window of DEAD_LETTER_NOTIFY_WINDOW
+// (default 1m). Dead letters within the window are only counted; when it ends, one
+// notification summarizes them per type, e.g.
+// "42 PAYMENT_PROCESS jobs dead-lettered in the last 1m0s, top reason: timeout",
+// and the next dead letter opens a new window.
+//
+// Destination (DEAD_LETTER_NOTIFY, default off):
+// - log: the summary is logged
+// - webhook: the summary is POSTed as JSON to DEAD_LETTER_NOTIFY_WEBHOOK_URL, signed
+//   like every webhook (see WebhookSigner; the URL needs an entry in WEBHOOK_SECRETS)
+//
+// NewJobWorker and NewJobScheduler share one notifier built from these env vars (see
+// defaultDeadLetterNotifier), so a burst dead-lettered by both lands in one summary, and
+// their Stop sends what's pending. SetDeadLetterNotifier on either replaces it.
+type DeadLetterNotifier struct {
+	window time.Duration
+	sink   deadLetterSink
+
+	mu     sync.Mutex
+	counts map[model.JobType]map[model.FailureReason]int
+	timer  *time.Timer
+}
+
+// DeadLetterSummary is the notification for one window.
+type DeadLetterSummary struct {
+	Message string                  `json:"message"`
+	Window  string                  `json:"window"`
+	Total   int                     `json:"total"`
+	Types   []DeadLetterTypeSummary `json:"types"`
+}
+
+// DeadLetterTypeSummary counts the dead letters of one type within a window.
+type DeadLetterTypeSummary struct {
+	Type      model.JobType               `json:"type"`
+	Count     int                         `json:"count"`
+	TopReason model.FailureReason         `json:"topReason"`
+	Reasons   map[model.FailureReason]int `json:"reasons"`
+}
+
+// deadLetterSink delivers summaries to the configured destination.
+type deadLetterSink interface {
+	send(summary DeadLetterSummary) error
+}
+
+// Dead-letter notification destinations, see DEAD_LETTER_NOTIFY.
+const (
+	DeadLetterNotifyLog     = "log"
+	DeadLetterNotifyWebhook = "webhook"
+)
+
+// NewDeadLetterNotifierFromEnv creates a DeadLetterNotifier from DEAD_LETTER_NOTIFY_* env vars.
+// Returns nil when notifications are disabled or misconfigured.
+func NewDeadLetterNotifierFromEnv() *DeadLetterNotifier {
+	window := time.Minute
+	if val := os.Getenv("DEAD_LETTER_NOTIFY_WINDOW"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed > 0 {
+			window = parsed
+		} else {
+			log.Printf("Ignoring invalid DEAD_LETTER_NOTIFY_WINDOW %q: must be a positive duration", val)
+		}
+	}
+
+	var sink deadLetterSink
+	switch val := os.Getenv("DEAD_LETTER_NOTIFY"); val {
+	case "":
+		return nil
+	case DeadLetterNotifyLog:
+		sink = logDeadLetterSink{}
+	case DeadLetterNotifyWebhook:
+		url := os.Getenv("DEAD_LETTER_NOTIFY_WEBHOOK_URL")
+		if url == "" {
+			log.Println("Dead-letter notifications disabled: DEAD_LETTER_NOTIFY=webhook requires DEAD_LETTER_NOTIFY_WEBHOOK_URL")
+			return nil
+		}
+		sink = &webhookDeadLetterSink{
+			url:    url,
+			signer: NewWebhookSignerFromEnv(),
+			client: &http.Client{Timeout: deadLetterWebhookTimeout},
+		}
+	default:
+		log.Printf("Dead-letter notifications disabled: unknown DEAD_LETTER_NOTIFY %q, must be log or webhook", val)
+		return nil
+	}
+
+	log.Printf("Dead-letter notifications enabled (destination: %s, window: %v)", os.Getenv("DEAD_LETTER_NOTIFY"), window)
+	return newDeadLetterNotifier(window, sink)
+}
+
+var (
+	sharedDeadLetterNotifier     *DeadLetterNotifier
+	sharedDeadLetterNotifierOnce sync.Once
+)
+
+// defaultDeadLetterNotifier returns the notifier shared by every worker and scheduler in
+// the process, created from env on first use. Nil when notifications are disabled.
+func defaultDeadLetterNotifier() *DeadLetterNotifier {
+	sharedDeadLetterNotifierOnce.Do(func() {
+		sharedDeadLetterNotifier = NewDeadLetterNotifierFromEnv()
+	})
+	return sharedDeadLetterNotifier
+}
+
+func newDeadLetterNotifier(window time.Duration, sink deadLetterSink) *DeadLetterNotifier {
+	return &DeadLetterNotifier{
+		window: window,
+		sink:   sink,
+		counts: make(map[model.JobType]map[model.FailureReason]int),
+	}
+}
+
+// Record counts a dead-lettered job towards the current window, opening one if needed.
+// Safe to call on a nil notifier (notifications disabled).
+func (n *DeadLetterNotifier) Record(job *model.Job) {
+	if n == nil {
+		return
+	}
+	reason := model.FailureUnknown
+	if job.FailureReason != nil {
+		reason = *job.FailureReason
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	reasons, ok := n.counts[job.Type]
+	if !ok {
+		reasons = make(map[model.FailureReason]int)
+		n.counts[job.Type] = reasons
+	}
+	reasons[reason]++
+	if n.timer == nil {
+		n.timer = time.AfterFunc(n.window, n.flush)
+	}
+}
+
+// Stop sends the summary of the open window, if any, without waiting for it to end.
+func (n *DeadLetterNotifier) Stop() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	if n.timer != nil {
+		n.timer.Stop()
+	}
+	n.mu.Unlock()
+	n.flush()
+}
+
+// flush sends the summary of the window and resets it, so the next dead letter opens a new one.
+func (n *DeadLetterNotifier) flush() {
+	n.mu.Lock()
+	counts := n.counts
+	n.counts = make(map[model.JobType]map[model.FailureReason]int)
+	n.timer = nil
+	n.mu.Unlock()
+
+	if len(counts) == 0 {
+		return
+	}
+	if err := n.sink.send(summarizeDeadLetters(counts, n.window)); err != nil {
+		log.Printf("Failed to send dead-letter notification: %v", err)
+	}
+}
+
+// summarizeDeadLetters builds the summary of one window, largest types first.
+func summarizeDeadLetters(counts map[model.JobType]map[model.FailureReason]int, window time.Duration) DeadLetterSummary {
+	summary := DeadLetterSummary{Window: window.String()}
+	for jobType, reasons := range counts {
+		typeSummary := DeadLetterTypeSummary{Type: jobType, Reasons: reasons}
+		// Ties go to the reason listed first in model.FailureReasons
+		for _, reason := range model.FailureReasons() {
+			if reasons[reason] > reasons[typeSummary.TopReason] {
+				typeSummary.TopReason = reason
+			}
+		}
+		for _, count := range reasons {
+			typeSummary.Count += count
+		}
+		summary.Total += typeSummary.Count
+		summary.Types = append(summary.Types, typeSummary)
+	}
+	sort.Slice(summary.Types, func(i, j int) bool {
+		if summary.Types[i].Count != summary.Types[j].Count {
+			return summary.Types[i].Count > summary.Types[j].Count
+		}
+		return summary.Types[i].Type < summary.Types[j].Type
+	})
+
+	lines := make([]string, 0, len(summary.Types))
+	for _, typeSummary := range summary.Types {
+		lines = append(lines, fmt.Sprintf("%d %s jobs dead-lettered in the last %v, top reason: %s",
+			typeSummary.Count, typeSummary.Type, window, typeSummary.TopReason))
+	}
+	summary.Message = strings.Join(lines, "; ")
+	return summary
+}
+
+// logDeadLetterSink logs summaries.
+type logDeadLetterSink struct{}
+
+func (logDeadLetterSink) send(summary DeadLetterSummary) error {
+	log.Printf("Dead-letter summary: %s", summary.Message)
+	return nil
+}
+
+// deadLetterWebhookTimeout bounds each dead-letter notification webhook call.
+const deadLetterWebhookTimeout = 10 * time.Second
+
+// webhookDeadLetterSink POSTs summaries as signed JSON webhooks.
+type webhookDeadLetterSink struct {
+	url    string
+	signer *WebhookSigner
+	client *http.Client
+}
+
+func (s *webhookDeadLetterSink) send(summary DeadLetterSummary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := s.signer.SignRequest(req, body); err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s answered %s", s.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"distributed-job-processor/model"
+)
+
+// recordingDeadLetterSink collects sent summaries.
+type recordingDeadLetterSink struct {
+	mu        sync.Mutex
+	summaries []DeadLetterSummary
+}
+
+func (s *recordingDeadLetterSink) send(summary DeadLetterSummary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summaries = append(s.summaries, summary)
+	return nil
+}
+
+func (s *recordingDeadLetterSink) sent() []DeadLetterSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]DeadLetterSummary(nil), s.summaries...)
+}
+
+func deadLetteredJob(jobType model.JobType, reason model.FailureReason) *model.Job {
+	return &model.Job{Type: jobType, Status: model.StatusDeadLetter, FailureReason: &reason}
+}
+
+// TestDeadLetterNotifierBatchesWindow verifies a burst of dead letters produces one
+// summary with the top reason per type, and the next dead letter opens a new window.
+func TestDeadLetterNotifierBatchesWindow(t *testing.T) {
+	sink := &recordingDeadLetterSink{}
+	notifier := newDeadLetterNotifier(50*time.Millisecond, sink)
+
+	for i := 0; i < 3; i++ {
+		notifier.Record(deadLetteredJob(model.TypePaymentProcess, model.FailureTimeout))
+	}
+	notifier.Record(deadLetteredJob(model.TypePaymentProcess, model.FailureDownstream5xx))
+	notifier.Record(deadLetteredJob(model.TypeEmailConfirmation, model.FailureDownstream5xx))
+
+	waitForSummaries(t, sink, 1)
+	summary := sink.sent()[0]
+	if summary.Total != 5 || len(summary.Types) != 2 {
+		t.Fatalf("expected 5 dead letters over 2 types, got %+v", summary)
+	}
+	payments := summary.Types[0]
+	if payments.Type != model.TypePaymentProcess || payments.Count != 4 || payments.TopReason != model.FailureTimeout {
+		t.Fatalf("unexpected payment summary %+v", payments)
+	}
+	want := "4 PAYMENT_PROCESS jobs dead-lettered in the last 50ms, top reason: " + string(model.FailureTimeout)
+	if !strings.HasPrefix(summary.Message, want) {
+		t.Fatalf("expected message to start with %q, got %q", want, summary.Message)
+	}
+
+	notifier.Record(deadLetteredJob(model.TypeEmailConfirmation, model.FailureDownstream5xx))
+	waitForSummaries(t, sink, 2)
+	if next := sink.sent()[1]; next.Total != 1 {
+		t.Fatalf("expected the window to reset after flush, got %+v", next)
+	}
+
+	notifier.Stop()
+	if got := len(sink.sent()); got != 2 {
+		t.Fatalf("expected Stop on an empty window to send nothing, got %d summaries", got)
+	}
+}
+
+// TestDeadLetterNotifierWebhookIsSigned verifies the webhook destination POSTs the
+// summary as signed JSON.
+func TestDeadLetterNotifierWebhookIsSigned(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	t.Setenv("DEAD_LETTER_NOTIFY", DeadLetterNotifyWebhook)
+	t.Setenv("DEAD_LETTER_NOTIFY_WEBHOOK_URL", server.URL)
+	t.Setenv("DEAD_LETTER_NOTIFY_WINDOW", "1h")
+	t.Setenv("WEBHOOK_SECRETS", `{"`+server.URL+`": "whsec_test"}`)
+	notifier := NewDeadLetterNotifierFromEnv()
+	if notifier == nil {
+		t.Fatal("expected webhook notifier to be enabled")
+	}
+
+	notifier.Record(deadLetteredJob(model.TypePaymentProcess, model.FailureTimeout))
+	notifier.Stop()
+
+	req := <-received
+	body := <-bodies
+	if err := VerifyWebhookSignature("whsec_test", req.Header.Get(WebhookSignatureHeader), body, time.Minute, time.Now()); err != nil {
+		t.Fatalf("expected a valid signature, got %v", err)
+	}
+	var summary DeadLetterSummary
+	if err := json.Unmarshal(body, &summary); err != nil {
+		t.Fatalf("decode summary: %v", err)
+	}
+	if summary.Total != 1 || summary.Window != "1h0m0s" || summary.Types[0].TopReason != model.FailureTimeout {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+}
+
+func waitForSummaries(t *testing.T, sink *recordingDeadLetterSink, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sink.sent()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d summaries, got %d", n, len(sink.sent()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWorkerAndSchedulerShareTheNotifierFromEnv(t *testing.T) {
+	sharedDeadLetterNotifier, sharedDeadLetterNotifierOnce = nil, sync.Once{}
+	t.Cleanup(func() { sharedDeadLetterNotifier, sharedDeadLetterNotifierOnce = nil, sync.Once{} })
+	t.Setenv("DEAD_LETTER_NOTIFY", DeadLetterNotifyLog)
+
+	worker := NewJobWorker(nil, nil, 1)
+	scheduler := NewJobScheduler(newTestRepository(t), nil)
+	if worker.deadLetterNotifier == nil {
+		t.Fatal("expected the worker to get the notifier from DEAD_LETTER_NOTIFY")
+	}
+	if scheduler.deadLetterNotifier != worker.deadLetterNotifier {
+		t.Fatal("expected the scheduler to share the worker's notifier")
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+// deadLetterQueue is the one path every job takes to DEAD_LETTER, whether the worker
+// gives up on it or the scheduler does (out of attempts before scheduling, or unroutable):
+// 1. The job is saved DEAD_LETTER with its error and failure reason
+// 2. Its cache entry is updated, when there is a cache
+// 3. It is published to the dead-letter topic (KAFKA_TOPIC_DLQ), keyed by job ID with a
+//    deadLetterEnvelope value, when there is a DLQ writer
+// 4. It is reported to the DeadLetterNotifier, when one is set
+// Steps 2-4 only follow a successful save, so a job is never announced as dead-lettered
+// while the database still has it RUNNING or PENDING.
+type deadLetterQueue struct {
+	jobRepository *repository.JobRepository
+	cacheService  *CacheService
+	writer        *kafka.Writer
+	notifier      *DeadLetterNotifier
+}
+
+// deadLetter moves the job to DEAD_LETTER with reason and errMsg.
+//
+// change applies the rest of the job's final state (e.g. counting the last attempt) and
+// returns false when the stored job must no longer be dead-lettered (e.g. it finished in
+// the meantime). Like updateJobWithRetry's change, it runs again on the reloaded job
+// after losing to a concurrent save; when it declines, repository.ErrStaleJob is
+// returned and *job holds the stored state.
+func (q deadLetterQueue) deadLetter(job *model.Job, reason model.FailureReason, errMsg string, change func(job *model.Job) bool) error {
+	err := updateJobWithRetry(q.jobRepository, job, func(job *model.Job) bool {
+		if !change(job) {
+			return false
+		}
+		now := time.Now()
+		job.Status = model.StatusDeadLetter
+		job.ErrorMessage = &errMsg
+		job.FailureReason = &reason
+		job.CompletedAt = &now
+		job.UpdatedAt = now
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if q.cacheService != nil {
+		q.cacheService.UpdateJob(job)
+	}
+	// Only after the DB save, so a Kafka outage never holds up the status change
+	q.publish(job)
+	q.notifier.Record(job)
+	return nil
+}
+
+// deadLetterEnvelope is the value of a message on the dead-letter topic.
+type deadLetterEnvelope struct {
+	JobID         string               `json:"jobId"`
+	ClientID      string               `json:"clientId"`
+	Type          model.JobType        `json:"type"`
+	Attempts      int                  `json:"attempts"`
+	ErrorMessage  *string              `json:"errorMessage,omitempty"`
+	FailureReason *model.FailureReason `json:"failureReason,omitempty"`
+}
+
+// deadLetterMessage builds the dead-letter topic message for a job: key is the job ID.
+func deadLetterMessage(job *model.Job) (kafka.Message, error) {
+	value, err := json.Marshal(deadLetterEnvelope{
+		JobID:         job.ID.String(),
+		ClientID:      job.ClientID,
+		Type:          job.Type,
+		Attempts:      job.Attempts,
+		ErrorMessage:  job.ErrorMessage,
+		FailureReason: job.FailureReason,
+	})
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	return kafka.Message{Key: []byte(job.ID.String()), Value: value}, nil
+}
+
+// publish publishes a dead-lettered job to the DLQ topic.
+// Best-effort: the job is already DEAD_LETTER in the database, so failures are only logged.
+func (q deadLetterQueue) publish(job *model.Job) {
+	if q.writer == nil {
+		return
+	}
+	msg, err := deadLetterMessage(job)
+	if err != nil {
+		log.Printf("Failed to encode dead letter for job %s: %v", job.ID, err)
+		return
+	}
+	if err := q.writer.WriteMessages(ctx, msg); err != nil {
+		log.Printf("Failed to publish job %s to DLQ topic %s: %v", job.ID, q.writer.Topic, err)
+		return
+	}
+	log.Printf("Job %s published to DLQ topic %s", job.ID, q.writer.Topic)
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+)
+
+// EmailHandler handles EMAIL_CONFIRMATION jobs.
+//
+// In a real system this would call SendGrid/SES; here it sleeps 1 second.
+type EmailHandler struct{}
+
+// Handle sends the (simulated) confirmation email.
+func (EmailHandler) Handle(ctx context.Context, job *model.Job) error {
+	logger := config.JobLogger(job.ID.String(), job.ClientID)
+
+	// Simulate SendGrid API call (1 second)
+	logger.Debug("Simulating email send")
+	if err := sleepContext(ctx, 1*time.Second); err != nil {
+		return err
+	}
+	logger.Debug("Email sent", "payload", HandlerPayload(ctx, job))
+	return nil
+}
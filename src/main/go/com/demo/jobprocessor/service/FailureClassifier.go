@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+
+	"distributed-job-processor/model"
+)
+
+// JobFailure is a handler error that carries its failure category.
+// Handlers return one when they know why the attempt failed; FailureClassifier
+// falls back to inspecting the error otherwise.
+type JobFailure struct {
+	Reason model.FailureReason
+	Err    error
+}
+
+// NewJobFailure wraps err with a failure category.
+func NewJobFailure(reason model.FailureReason, err error) *JobFailure {
+	return &JobFailure{Reason: reason, Err: err}
+}
+
+func (e *JobFailure) Error() string {
+	return e.Err.Error()
+}
+
+func (e *JobFailure) Unwrap() error {
+	return e.Err
+}
+
+// FailureClassifier assigns a model.FailureReason to a failed attempt, in order:
+// 1. The reason of a JobFailure in the error chain
+// 2. timeout for context deadline errors
+// 3. The first FAILURE_REASON_PATTERNS entry whose substring occurs in the message
+// 4. unknown
+//
+// FAILURE_REASON_PATTERNS classifies errors from handlers that don't return a
+// JobFailure yet, as reason=substring[|substring...] entries separated by ';', e.g.
+// "declined=card declined|insufficient funds;downstream_5xx=503|502". Matching is
+// case-insensitive.
+type FailureClassifier struct {
+	patterns []failurePattern
+}
+
+// failurePattern classifies errors whose message contains substr as reason.
+type failurePattern struct {
+	reason model.FailureReason
+	substr string
+}
+
+// NewFailureClassifierFromEnv creates a FailureClassifier from FAILURE_REASON_PATTERNS.
+func NewFailureClassifierFromEnv() *FailureClassifier {
+	var patterns []failurePattern
+	for _, entry := range strings.Split(os.Getenv("FAILURE_REASON_PATTERNS"), ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, substrings, ok := strings.Cut(entry, "=")
+		reason := model.FailureReason(strings.TrimSpace(name))
+		if !ok || !reason.IsValid() {
+			log.Printf("Ignoring invalid FAILURE_REASON_PATTERNS entry %q: must be reason=substring with a known reason", entry)
+			continue
+		}
+		for _, substr := range strings.Split(substrings, "|") {
+			if substr = strings.TrimSpace(substr); substr != "" {
+				patterns = append(patterns, failurePattern{reason: reason, substr: strings.ToLower(substr)})
+			}
+		}
+	}
+	return &FailureClassifier{patterns: patterns}
+}
+
+// Classify returns the failure category of a handler error.
+// Safe to call on a nil *FailureClassifier (no patterns).
+func (c *FailureClassifier) Classify(err error) model.FailureReason {
+	var failure *JobFailure
+	if errors.As(err, &failure) {
+		return failure.Reason
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return model.FailureTimeout
+	}
+
+	if c != nil {
+		msg := strings.ToLower(err.Error())
+		for _, pattern := range c.patterns {
+			if strings.Contains(msg, pattern.substr) {
+				return pattern.reason
+			}
+		}
+	}
+	return model.FailureUnknown
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"distributed-job-processor/model"
+)
+
+// TestFailureClassifierClassify verifies classified errors win, then timeouts, then patterns.
+func TestFailureClassifierClassify(t *testing.T) {
+	t.Setenv("FAILURE_REASON_PATTERNS", "declined=Card Declined|insufficient funds; downstream_5xx=503; bogus=x")
+	c := NewFailureClassifierFromEnv()
+
+	cases := []struct {
+		name string
+		err  error
+		want model.FailureReason
+	}{
+		{"job failure", fmt.Errorf("charge: %w", NewJobFailure(model.FailureInvalidPayload, errors.New("bad amount"))), model.FailureInvalidPayload},
+		{"deadline", fmt.Errorf("gateway: %w", context.DeadlineExceeded), model.FailureTimeout},
+		{"pattern", errors.New("gateway said: card declined"), model.FailureDeclined},
+		{"second substring", errors.New("Insufficient funds"), model.FailureDeclined},
+		{"other pattern", errors.New("gateway returned 503"), model.FailureDownstream5xx},
+		{"unmatched", errors.New("something else"), model.FailureUnknown},
+	}
+	for _, tc := range cases {
+		if got := c.Classify(tc.err); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+
+	var none *FailureClassifier
+	if got := none.Classify(errors.New("card declined")); got != model.FailureUnknown {
+		t.Fatalf("expected unknown without patterns, got %s", got)
+	}
+}
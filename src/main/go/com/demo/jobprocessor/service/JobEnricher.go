@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+
+	"distributed-job-processor/model"
+)
+
+// JobEnricher is an extension point for deployment-specific job enrichment.
+//
+// JobService.CreateJob calls Enrich after the job has been built from the request
+// and before it is persisted, so an enricher may rewrite any field, e.g.:
+// - Look up the client's region to route the payment
+// - Normalize the payload format
+//
+// Returning an error rejects the job: nothing is saved and the API responds
+// with 422 Unprocessable Entity carrying the error message.
+type JobEnricher interface {
+	Enrich(ctx context.Context, job *model.Job) error
+}
+
+// NoopJobEnricher leaves jobs untouched. It is the default enricher.
+type NoopJobEnricher struct{}
+
+// Enrich implements JobEnricher.
+func (NoopJobEnricher) Enrich(ctx context.Context, job *model.Job) error {
+	return nil
+}
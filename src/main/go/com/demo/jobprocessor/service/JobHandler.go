@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+// JobHandler does the work of one job type, e.g. charging a card for PAYMENT_PROCESS.
+//
+// The worker looks the handler up by job type (see JobWorker.RegisterHandler) and calls
+// Handle within the type's processing timeout, so Handle must honour ctx. HandlerPayload
+// returns the payload to work on: the job's payload as rewritten by the transformer chain.
+//
+// Returning nil completes the job; the worker saves the completion. Errors are handled
+// like any failure (see FailureClassifier): retried with backoff unless wrapped in an
+// exception.NonRetryableError, and a JobFailure sets the failure reason.
+//
+// A handler with an external side effect (e.g. a charge) sets job.WorkDoneAt in
+// the same save that records the effect, the instant it happens (see PaymentHandler).
+// A redelivered job with workDoneAt set is then completed without running the handler
+// again, see the crash windows on JobWorker.
+//
+// A job whose type has no handler fails permanently and is dead-lettered right away.
+type JobHandler interface {
+	Handle(ctx context.Context, job *model.Job) error
+}
+
+// JobHandlerFunc adapts a function to a JobHandler.
+type JobHandlerFunc func(ctx context.Context, job *model.Job) error
+
+// Handle calls f(ctx, job).
+func (f JobHandlerFunc) Handle(ctx context.Context, job *model.Job) error {
+	return f(ctx, job)
+}
+
+// DefaultJobHandlers returns the handlers of the built-in job types, keyed by type.
+// The worker guards every handler's downstream with its TypeCircuitBreaker, if set.
+func DefaultJobHandlers(jobRepository *repository.JobRepository, cacheService *CacheService) map[model.JobType]JobHandler {
+	return map[model.JobType]JobHandler{
+		model.TypePaymentProcess:    NewPaymentHandler(jobRepository, cacheService),
+		model.TypeEmailConfirmation: EmailHandler{},
+		model.TypeHealthCheck:       HealthCheckHandler{},
+		model.TypeRefund:            RefundHandler{},
+	}
+}
+
+// handlerPayloadKey is the context key of the payload handed to the handler.
+type handlerPayloadKey struct{}
+
+// withHandlerPayload returns ctx carrying the payload the handler should work on.
+func withHandlerPayload(ctx context.Context, payload string) context.Context {
+	return context.WithValue(ctx, handlerPayloadKey{}, payload)
+}
+
+// HandlerPayload returns the payload a handler works on: job's payload as rewritten
+// by the worker's transformer chain, or job.Payload outside the worker.
+func HandlerPayload(ctx context.Context, job *model.Job) string {
+	if payload, ok := ctx.Value(handlerPayloadKey{}).(string); ok {
+		return payload
+	}
+	return job.Payload
+}
+
+// HealthCheckHandler handles HEALTH_CHECK canaries: nothing to do, the canary only
+// has to make it through the pipeline.
+type HealthCheckHandler struct{}
+
+// Handle completes the canary immediately.
+func (HealthCheckHandler) Handle(ctx context.Context, job *model.Job) error {
+	config.JobLogger(job.ID.String(), job.ClientID).Debug("Health check job reached the worker", "payload", HandlerPayload(ctx, job))
+	return nil
+}
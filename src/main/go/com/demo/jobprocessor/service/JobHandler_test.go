@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"distributed-job-processor/exception"
+	"distributed-job-processor/model"
+)
+
+// TestRegisteredHandlerIsInvoked verifies the worker runs the handler registered for the
+// job's type, with the transformed payload, and completes the job.
+func TestRegisteredHandlerIsInvoked(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+	w.SetTransformerChain(NewTransformerChain(
+		transformerFunc(func(ctx context.Context, job *model.Job, payload string) (string, error) {
+			return payload + "|warehouse_EU", nil
+		}),
+	))
+
+	var handled *model.Job
+	var handledPayload string
+	w.RegisterHandler("INVENTORY_UPDATE", JobHandlerFunc(func(ctx context.Context, job *model.Job) error {
+		handled = job
+		handledPayload = HandlerPayload(ctx, job)
+		return nil
+	}))
+
+	job := model.NewJob("customer-1", "INVENTORY_UPDATE", "product_SKU123|quantity_5")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	if err := w.processJobInternal(context.Background(), job); err != nil {
+		t.Fatalf("processJobInternal: %v", err)
+	}
+
+	if handled != job || handledPayload != "product_SKU123|quantity_5|warehouse_EU" {
+		t.Fatalf("expected the handler to get the job and transformed payload, got %v %q", handled, handledPayload)
+	}
+	if saved, _ := repo.FindByID(job.ID); saved == nil || saved.Status != model.StatusCompleted {
+		t.Fatalf("expected COMPLETED, got %+v", saved)
+	}
+}
+
+// TestUnregisteredTypeDeadLettersImmediately verifies a job without a handler is
+// dead-lettered on its first attempt rather than retried.
+func TestUnregisteredTypeDeadLettersImmediately(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+
+	job := model.NewJob("customer-1", "REPORT_EXPORT", "report_1")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	err := w.processJobInternal(context.Background(), job)
+	if !exception.IsNonRetryableError(err) {
+		t.Fatalf("expected a permanent failure, got %v", err)
+	}
+	w.handleJobFailure(job, err)
+
+	saved, _ := repo.FindByID(job.ID)
+	if saved == nil || saved.Status != model.StatusDeadLetter || saved.Attempts != 1 {
+		t.Fatalf("expected DEAD_LETTER after 1 attempt, got %+v", saved)
+	}
+}
+
+// TestNonRetryableHandlerErrorSkipsRetries verifies a retryable handler error schedules a
+// retry, while a wrapped exception.NonRetryableError dead-letters on the first failure.
+func TestNonRetryableHandlerErrorSkipsRetries(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		wantStatus model.JobStatus
+	}{
+		{"retryable", errors.New("gateway timeout"), model.StatusPending},
+		{"non-retryable", fmt.Errorf("charge: %w", exception.NewNonRetryableError(errors.New("card declined"))), model.StatusDeadLetter},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			w := newTestWorker(t, repo)
+			w.RegisterHandler(model.TypeHealthCheck, JobHandlerFunc(func(ctx context.Context, job *model.Job) error {
+				return tc.err
+			}))
+
+			job := model.NewJob("customer-1", model.TypeHealthCheck, "probe_1")
+			job.Status = model.StatusRunning
+			if err := repo.Create(job); err != nil {
+				t.Fatalf("seed job: %v", err)
+			}
+
+			err := w.processJobInternal(context.Background(), job)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected the handler error, got %v", err)
+			}
+			w.handleJobFailure(job, err)
+
+			saved, err := repo.FindByID(job.ID)
+			if err != nil {
+				t.Fatalf("reload job: %v", err)
+			}
+			if saved.Status != tc.wantStatus || saved.Attempts != 1 {
+				t.Fatalf("expected %s after 1 attempt, got status=%s attempts=%d", tc.wantStatus, saved.Status, saved.Attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"distributed-job-processor/model"
+)
+
+// JobSLAs is the end-to-end latency SLA of each job type: how long after creation a
+// job must have completed, e.g. SLA_EMAIL_CONFIRMATION=60s.
+//
+// - Workers compute the breach when a job completes (see JobWorker.completeJob), save
+//   it on the job (slaBreached) and count it in sla_breaches_total{type}
+// - GET /api/admin/sla/breaches lists the most recent breaches
+// - With SCHEDULER_SLA_AT_RISK_GAUGE=true the scheduler also counts, every minute,
+//   the unfinished jobs already past their SLA into sla_at_risk_jobs{type}
+//
+// Types without an SLA never breach.
+type JobSLAs map[model.JobType]time.Duration
+
+// NewJobSLAsFromEnv reads SLA_<TYPE> env vars.
+func NewJobSLAsFromEnv() JobSLAs {
+	slas := make(JobSLAs)
+	for _, spec := range model.JobTypeSpecs() {
+		key := "SLA_" + string(spec.Type)
+		if val := os.Getenv(key); val != "" {
+			if parsed, err := time.ParseDuration(val); err == nil && parsed > 0 {
+				slas[spec.Type] = parsed
+			} else {
+				log.Printf("Ignoring invalid %s %q: must be a positive duration", key, val)
+			}
+		}
+	}
+	return slas
+}
+
+// Breached reports whether a job of the type completing latency after its
+// creation missed the type's SLA.
+func (s JobSLAs) Breached(jobType model.JobType, latency time.Duration) bool {
+	sla, ok := s[jobType]
+	return ok && latency > sla
+}
+
+// Seconds returns each type's SLA in seconds, for API responses.
+func (s JobSLAs) Seconds() map[model.JobType]float64 {
+	seconds := make(map[model.JobType]float64, len(s))
+	for jobType, sla := range s {
+		seconds[jobType] = sla.Seconds()
+	}
+	return seconds
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"distributed-job-processor/model"
+)
+
+// TestCompleteJobRecordsSLABreach verifies the breach is computed at completion from
+// the end-to-end latency, saved on the job, and listed most recent first.
+func TestCompleteJobRecordsSLABreach(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+	w.slas = JobSLAs{model.TypeEmailConfirmation: time.Minute}
+	s := NewJobService(repo)
+
+	seed := func(jobType model.JobType, age time.Duration) *model.Job {
+		job := model.NewJob("customer-1", jobType, "order_1|user@email.com")
+		job.CreatedAt = time.Now().Add(-age)
+		job.Status = model.StatusRunning
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		if err := w.completeJob(context.Background(), job); err != nil {
+			t.Fatalf("complete job: %v", err)
+		}
+		return job
+	}
+	late := seed(model.TypeEmailConfirmation, 2*time.Minute)
+	seed(model.TypeEmailConfirmation, 10*time.Second)
+	seed(model.TypePaymentProcess, time.Hour) // No SLA for payments
+
+	saved, err := repo.FindByID(late.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if !saved.SLABreached {
+		t.Fatal("expected the late email saved as an SLA breach")
+	}
+
+	breaches, err := s.GetSLABreaches(nil, 10)
+	if err != nil {
+		t.Fatalf("list breaches: %v", err)
+	}
+	if len(breaches) != 1 || breaches[0].ID != late.ID {
+		t.Fatalf("expected only the late email listed, got %d breaches", len(breaches))
+	}
+	payments := model.TypePaymentProcess
+	if breaches, _ := s.GetSLABreaches(&payments, 10); len(breaches) != 0 {
+		t.Fatalf("expected no payment breaches, got %d", len(breaches))
+	}
+}
+
+// TestSampleSLAAtRiskCountsUnfinishedJobsPastSLA verifies only PENDING and RUNNING
+// jobs older than their type's SLA are counted at risk.
+func TestSampleSLAAtRiskCountsUnfinishedJobsPastSLA(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobScheduler(repo, nil)
+	s.slas = JobSLAs{model.TypeEmailConfirmation: time.Minute}
+	now := time.Now()
+
+	for _, seed := range []struct {
+		status model.JobStatus
+		age    time.Duration
+	}{
+		{model.StatusPending, 5 * time.Minute},
+		{model.StatusRunning, 2 * time.Minute},
+		{model.StatusPending, 10 * time.Second},
+		{model.StatusCompleted, 5 * time.Minute},
+	} {
+		job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com")
+		job.Status = seed.status
+		job.CreatedAt = now.Add(-seed.age)
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+	}
+
+	counts := s.sampleSLAAtRisk(now)
+	if len(counts) != 1 || counts[string(model.TypeEmailConfirmation)] != 2 {
+		t.Fatalf("expected 2 emails at risk, got %v", counts)
+	}
+}
@@ -2,12 +2,19 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"distributed-job-processor/config"
 	"distributed-job-processor/model"
@@ -17,18 +24,124 @@ import (
 // JobScheduler polls the database for PENDING jobs and publishes them to Kafka.
 //
 // Flow:
-// 1. Every 5 seconds, query database for PENDING jobs (scheduled_at <= now)
-// 2. For each job found:
+// 1. Every 5 seconds, claim PENDING jobs (scheduled_at <= now), ordered by priority
+//    then scheduled_at, so urgent jobs are published first: one transaction selects
+//    them FOR UPDATE SKIP LOCKED and saves them RUNNING (see ClaimPendingJobs)
+// 2. Cache the claimed jobs (see SetCacheService), so workers pick them up from the
+//    cache with the version the claim saved
+// 3. For each job claimed:
 //    a. Publish job ID to Kafka topic
-//    b. Update job status to RUNNING
-//    c. If Kafka publish fails, keep status as PENDING (retry next poll)
+//    b. If Kafka publish fails, return the job to PENDING (retry next poll)
+//    c. Once the write is confirmed, record publishedAt
+//
+// Claiming before publishing makes running several scheduler instances (HA) safe:
+// each job is claimed, and so published, by one instance only. A scheduler that
+// crashes between its claim and the publish leaves the job RUNNING without a
+// worker; the stuck-job reaper requeues it after STUCK_JOB_THRESHOLD. publishedAt
+// tells the reaper such a job apart from one a worker lost: it was never published,
+// so its attempt never ran and isn't counted.
+//
+// Batching (SCHEDULER_BATCH_SIZE, default 500, see batchSizer):
+// - Each query is LIMITed to one batch, so a backlog (e.g. after an outage) is never
+//   loaded into memory, or published to Kafka, all at once
+// - A full batch means more are waiting: the poll fetches the next batch right away,
+//   until a batch comes back short or the poll has run for SCHEDULER_CYCLE_BUDGET
+//   (default: the poll interval)
+// - A batch that couldn't all be moved on (a failed publish, or jobs held back by
+//   staggering) ends the poll, so its jobs aren't fetched again until the next one
+//
+// Idle polling (SCHEDULER_MAX_IDLE_INTERVAL, e.g. SCHEDULER_MAX_IDLE_INTERVAL=1m,
+// default: the poll interval, i.e. off):
+// - Each consecutive poll that finds no jobs doubles the wait before the next one,
+//   from the poll interval up to the max, saving queries while the queue is empty
+// - The first poll that finds jobs snaps the wait back to the poll interval
+// - Costs latency: a job created while idle waits up to the max to be published
 //
 // This decouples the API (fast response) from job processing (slow).
+//
+// Staggering (SCHEDULER_STAGGER_<TYPE>, e.g. SCHEDULER_STAGGER_PAYMENT_PROCESS=50ms):
+// - Publishes of that type within a poll are spaced at least the stagger apart
+// - At most pollInterval/stagger jobs of the type are published per poll;
+//   the rest stay PENDING for the next poll
+// - Protects a downstream that can't take 500 concurrent calls even though
+//   the worker pool could
+//
+// With KAFKA_JOB_STATE_TOPIC=true, each job is also written to the compacted job state
+// topic just before its ID is published, so workers can skip the lookup (see JobStateTable).
+//
+// With KAFKA_PRIORITY_TOPICS=true, urgent jobs are published to the high-priority
+// topic instead (see config.GetHighPriorityMax).
+//
+// Jobs pinned to a worker pool (workerPool label, see config.GetWorkerPools) are
+// published to the pool's topic. A job pinned to a pool that has since been removed
+// from KAFKA_WORKER_POOLS is moved to DEAD_LETTER rather than run by an incapable worker.
+//
+// Expiration:
+// - PENDING jobs whose expiresAt (set per job, or from DEFAULT_JOB_TTL) has passed are
+//   moved to EXPIRED instead of being published, e.g. a payment held up by a broker outage
+// - Max job age (MAX_JOB_AGE_<TYPE>, e.g. MAX_JOB_AGE_EMAIL_CONFIRMATION=2h) does the
+//   same for jobs of that type created longer ago than the limit
+// - Jobs with neither are scheduled regardless of age
+//
+// Stuck-job reaper (STUCK_JOB_THRESHOLD, default 10m, 0 disables):
+// - Every minute, RUNNING jobs not updated for longer than the threshold (e.g. their
+//   worker crashed mid-job) are reset to PENDING with scheduledAt = now, counting the
+//   lost attempt
+// - A job claimed but never published (no publishedAt) is requeued without counting
+//   an attempt; one published but never picked up by a worker counts it as lost
+// - A job with no attempts left goes to DEAD_LETTER instead
+// - A job that moves on while being reaped (its worker finishes it) is left alone
+// - A job whose work was done (workDoneAt set) but whose worker died before saving it
+//   COMPLETED is marked COMPLETED rather than run again
+//
+// Exhausted jobs (attempts >= maxRetries, e.g. reset by a reaper after their last
+// attempt) are moved straight to DEAD_LETTER instead of being republished only to
+// fail again. SCHEDULER_DEAD_LETTER_EXHAUSTED=false disables this.
+//
+// While the DB circuit breaker (see SetDBCircuitBreaker) is open, polls and reaper
+// runs are skipped rather than failing against a database that is known to be down.
+//
+// Backlog gauge (SCHEDULER_BACKLOG_GAUGE=true, off by default):
+// - Every poll also counts all PENDING jobs per type into the pending_jobs{type} gauge,
+//   with one grouped COUNT on the status index
+// - The poll result can't be reused: it is capped at one batch and skips jobs whose
+//   scheduledAt (e.g. a retry backoff) is still in the future
+// - Every known type is reported, so a drained backlog reads 0 rather than going stale
+//
+// SLA at-risk gauge (SCHEDULER_SLA_AT_RISK_GAUGE=true, off by default): every minute,
+// PENDING and RUNNING jobs created longer ago than their type's SLA (SLA_<TYPE>) are
+// counted into the sla_at_risk_jobs{type} gauge, one COUNT per type with an SLA.
+// Reaped jobs completed from their work done marker are checked against their SLA too.
+//
+// With HEALTH_CHECK_JOBS_ENABLED=true, finished HEALTH_CHECK canaries are deleted every
+// minute once older than HEALTH_CHECK_JOB_RETENTION, except the latest completed one.
 type JobScheduler struct {
-	jobRepository *repository.JobRepository
-	kafkaWriter   *kafka.Writer
-	pollInterval  time.Duration
-	stopCh        chan struct{}
+	jobRepository       *repository.JobRepository
+	kafkaWriter         *kafka.Writer
+	highPriorityWriter  *kafka.Writer
+	highPriorityMax     int
+	poolWriters         map[string]*kafka.Writer
+	dlqWriter           *kafka.Writer
+	stateWriter         *kafka.Writer // nil unless KAFKA_JOB_STATE_TOPIC=true
+	compressThreshold   int
+	pollInterval        time.Duration
+	maxIdleInterval     time.Duration
+	idleInterval        time.Duration // Current wait after empty polls, 0 when not idle
+	cycleBudget         time.Duration
+	stagger             map[model.JobType]time.Duration
+	maxJobAge           map[model.JobType]time.Duration
+	batchSizer          *batchSizer
+	deadLetterExhausted bool
+	backlogGauge        bool
+	slas                JobSLAs
+	slaAtRiskGauge      bool
+	stuckThreshold      time.Duration
+	canaryRetention     time.Duration // 0 when health check jobs are disabled
+	dbBreaker           *repository.DBCircuitBreaker
+	deadLetterNotifier  *DeadLetterNotifier
+	cacheService        *CacheService
+	pausedForDB         bool // Only touched by the polling goroutine
+	stopCh              chan struct{}
 }
 
 // NewJobScheduler creates a new JobScheduler with the given dependencies.
@@ -40,11 +153,132 @@ func NewJobScheduler(jobRepository *repository.JobRepository, kafkaWriter *kafka
 		}
 	}
 
+	// Per-type minimum spacing between publishes
+	stagger := make(map[model.JobType]time.Duration)
+	for _, spec := range model.JobTypeSpecs() {
+		key := "SCHEDULER_STAGGER_" + string(spec.Type)
+		if val := os.Getenv(key); val != "" {
+			if parsed, err := time.ParseDuration(val); err == nil && parsed > 0 {
+				stagger[spec.Type] = parsed
+			} else {
+				log.Printf("Ignoring invalid %s %q: must be a positive duration", key, val)
+			}
+		}
+	}
+
+	// Per-type age past which a PENDING job is no longer worth running
+	maxJobAge := make(map[model.JobType]time.Duration)
+	for _, spec := range model.JobTypeSpecs() {
+		key := "MAX_JOB_AGE_" + string(spec.Type)
+		if val := os.Getenv(key); val != "" {
+			if parsed, err := time.ParseDuration(val); err == nil && parsed > 0 {
+				maxJobAge[spec.Type] = parsed
+			} else {
+				log.Printf("Ignoring invalid %s %q: must be a positive duration", key, val)
+			}
+		}
+	}
+
+	maxIdleInterval := interval
+	if val := os.Getenv("SCHEDULER_MAX_IDLE_INTERVAL"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed >= interval {
+			maxIdleInterval = parsed
+		} else {
+			log.Printf("Ignoring invalid SCHEDULER_MAX_IDLE_INTERVAL %q: must be a duration of at least the poll interval (%v)", val, interval)
+		}
+	}
+
+	cycleBudget := interval
+	if val := os.Getenv("SCHEDULER_CYCLE_BUDGET"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed >= 0 {
+			cycleBudget = parsed
+		} else {
+			log.Printf("Ignoring invalid SCHEDULER_CYCLE_BUDGET %q: must be a non-negative duration", val)
+		}
+	}
+
+	stuckThreshold := 10 * time.Minute
+	if val := os.Getenv("STUCK_JOB_THRESHOLD"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed >= 0 {
+			stuckThreshold = parsed
+		} else {
+			log.Printf("Ignoring invalid STUCK_JOB_THRESHOLD %q: must be a non-negative duration", val)
+		}
+	}
+
+	var canaryRetention time.Duration
+	if config.GetHealthCheckJobsEnabled() {
+		canaryRetention = config.GetHealthCheckJobRetention()
+	}
+
+	// Separate topic for urgent jobs, so they don't queue behind a backlog in Kafka
+	var highPriorityWriter *kafka.Writer
+	if config.GetPriorityTopicsEnabled() {
+		highPriorityWriter = config.NewKafkaProducerWriterForTopic(config.GetHighPriorityTopic())
+	}
+
+	// One topic per worker pool, consumed only by that pool's workers
+	poolWriters := make(map[string]*kafka.Writer)
+	for _, pool := range config.GetWorkerPools() {
+		poolWriters[pool] = config.NewKafkaProducerWriterForTopic(config.GetWorkerPoolTopic(pool))
+	}
+
+	// Compacted topic the workers take jobs from, see JobStateTable
+	var stateWriter *kafka.Writer
+	if config.GetJobStateTopicEnabled() {
+		stateWriter = config.NewKafkaJobStateWriter()
+	}
+
 	return &JobScheduler{
-		jobRepository: jobRepository,
-		kafkaWriter:   kafkaWriter,
-		pollInterval:  interval,
-		stopCh:        make(chan struct{}),
+		jobRepository:       jobRepository,
+		kafkaWriter:         kafkaWriter,
+		highPriorityWriter:  highPriorityWriter,
+		highPriorityMax:     config.GetHighPriorityMax(),
+		poolWriters:         poolWriters,
+		dlqWriter:           config.NewKafkaDLQWriter(),
+		deadLetterNotifier:  defaultDeadLetterNotifier(),
+		stateWriter:         stateWriter,
+		compressThreshold:   config.GetPayloadCompressionThreshold(),
+		pollInterval:        interval,
+		maxIdleInterval:     maxIdleInterval,
+		cycleBudget:         cycleBudget,
+		stagger:             stagger,
+		maxJobAge:           maxJobAge,
+		batchSizer:          newBatchSizerFromEnv(),
+		deadLetterExhausted: os.Getenv("SCHEDULER_DEAD_LETTER_EXHAUSTED") != "false",
+		backlogGauge:        os.Getenv("SCHEDULER_BACKLOG_GAUGE") == "true",
+		slas:                NewJobSLAsFromEnv(),
+		slaAtRiskGauge:      os.Getenv("SCHEDULER_SLA_AT_RISK_GAUGE") == "true",
+		stuckThreshold:      stuckThreshold,
+		canaryRetention:     canaryRetention,
+		stopCh:              make(chan struct{}),
+	}
+}
+
+// SetDBCircuitBreaker pauses scheduling while the breaker is open.
+func (s *JobScheduler) SetDBCircuitBreaker(breaker *repository.DBCircuitBreaker) {
+	s.dbBreaker = breaker
+}
+
+// SetDeadLetterNotifier reports every job the scheduler or its reaper dead-letters to the notifier,
+// replacing the shared one from DEAD_LETTER_NOTIFY (see DeadLetterNotifier).
+func (s *JobScheduler) SetDeadLetterNotifier(notifier *DeadLetterNotifier) {
+	s.deadLetterNotifier = notifier
+}
+
+// SetCacheService keeps the cache entries of the jobs the scheduler claims, reaps and
+// dead-letters up to date. Call this at startup, before Start.
+func (s *JobScheduler) SetCacheService(cacheService *CacheService) {
+	s.cacheService = cacheService
+}
+
+// deadLetters returns the path the scheduler's dead letters take, the same as the worker's.
+func (s *JobScheduler) deadLetters() deadLetterQueue {
+	return deadLetterQueue{
+		jobRepository: s.jobRepository,
+		cacheService:  s.cacheService,
+		writer:        s.dlqWriter,
+		notifier:      s.deadLetterNotifier,
 	}
 }
 
@@ -55,19 +289,71 @@ func NewJobScheduler(jobRepository *repository.JobRepository, kafkaWriter *kafka
 func (s *JobScheduler) Start() {
 	// Job scheduling loop
 	go func() {
-		log.Printf("Job scheduler started (poll interval: %v)", s.pollInterval)
+		log.Printf("Job scheduler started (poll interval: %v, max idle interval: %v)", s.pollInterval, s.maxIdleInterval)
 		for {
 			select {
 			case <-s.stopCh:
 				log.Println("Job scheduler stopped")
 				return
 			default:
-				s.scheduleJobs()
-				time.Sleep(s.pollInterval)
+				idle := s.scheduleJobs()
+				select {
+				case <-s.stopCh:
+				case <-time.After(s.nextPollDelay(idle)):
+				}
 			}
 		}
 	}()
 
+	// Stuck-job reaper loop (every 60 seconds)
+	if s.stuckThreshold > 0 {
+		go func() {
+			log.Printf("Stuck-job reaper started (threshold: %v)", s.stuckThreshold)
+			ticker := time.NewTicker(stuckJobReapInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-s.stopCh:
+					return
+				case <-ticker.C:
+					s.reapStuckJobs(time.Now())
+				}
+			}
+		}()
+	}
+
+	// Health check purge loop (every 60 seconds)
+	if s.canaryRetention > 0 {
+		go func() {
+			ticker := time.NewTicker(healthCheckPurgeInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-s.stopCh:
+					return
+				case <-ticker.C:
+					s.purgeHealthCheckJobs(time.Now())
+				}
+			}
+		}()
+	}
+
+	// SLA at-risk gauge loop (every 60 seconds)
+	if s.slaAtRiskGauge && len(s.slas) > 0 {
+		go func() {
+			ticker := time.NewTicker(slaAtRiskInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-s.stopCh:
+					return
+				case <-ticker.C:
+					s.sampleSLAAtRisk(time.Now())
+				}
+			}
+		}()
+	}
+
 	// Statistics logging loop (every 60 seconds)
 	go func() {
 		ticker := time.NewTicker(60 * time.Second)
@@ -86,78 +372,627 @@ func (s *JobScheduler) Start() {
 // Stop gracefully stops the scheduler.
 func (s *JobScheduler) Stop() {
 	close(s.stopCh)
+	if s.highPriorityWriter != nil {
+		if err := s.highPriorityWriter.Close(); err != nil {
+			log.Printf("Error closing high-priority Kafka writer: %v", err)
+		}
+	}
+	if s.dlqWriter != nil {
+		if err := s.dlqWriter.Close(); err != nil {
+			log.Printf("Error closing DLQ Kafka writer: %v", err)
+		}
+	}
+	for pool, writer := range s.poolWriters {
+		if err := writer.Close(); err != nil {
+			log.Printf("Error closing Kafka writer for worker pool %s: %v", pool, err)
+		}
+	}
+	if s.stateWriter != nil {
+		if err := s.stateWriter.Close(); err != nil {
+			log.Printf("Error closing job state Kafka writer: %v", err)
+		}
+	}
+	s.deadLetterNotifier.Stop()
+}
+
+// nextPollDelay returns how long to wait before the next poll, growing the wait after
+// consecutive idle polls (see SCHEDULER_MAX_IDLE_INTERVAL) and resetting it otherwise.
+func (s *JobScheduler) nextPollDelay(idle bool) time.Duration {
+	if !idle {
+		s.idleInterval = 0
+		return s.pollInterval
+	}
+	if s.idleInterval == 0 {
+		s.idleInterval = s.pollInterval
+	} else {
+		s.idleInterval = min(2*s.idleInterval, max(s.maxIdleInterval, s.pollInterval))
+	}
+	return s.idleInterval
 }
 
 // scheduleJobs polls the database for PENDING jobs and publishes them to Kafka.
-func (s *JobScheduler) scheduleJobs() {
+// Reports whether the poll was idle: it ran and found no jobs to claim.
+func (s *JobScheduler) scheduleJobs() (idle bool) {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("Error in scheduler poll: %v", r)
 		}
 	}()
 
-	// Find all PENDING jobs that are scheduled to run now or in the past
-	pendingJobs, err := s.jobRepository.FindByStatusAndScheduledAtBefore(
-		model.StatusPending,
-		time.Now(),
-	)
-	if err != nil {
-		log.Printf("Error finding pending jobs: %v", err)
+	// Database known to be down: wait for the breaker's health probe instead of polling
+	if s.dbBreaker.Open() {
+		if !s.pausedForDB {
+			log.Println("Scheduler paused: database circuit breaker open")
+			s.pausedForDB = true
+		}
 		return
 	}
+	if s.pausedForDB {
+		log.Println("Scheduler resumed: database circuit breaker closed")
+		s.pausedForDB = false
+	}
+
+	if s.backlogGauge {
+		s.sampleBacklog()
+	}
+
+	// Drain a backlog batch by batch, within the cycle's time budget
+	deadline := time.Now().Add(s.cycleBudget)
+	claimed, more := s.scheduleBatch()
+	for more && time.Now().Before(deadline) {
+		select {
+		case <-s.stopCh:
+			return false
+		default:
+		}
+		_, more = s.scheduleBatch()
+	}
+	return claimed == 0
+}
+
+// scheduleBatch claims one batch of PENDING jobs and publishes them. Returns how many
+// jobs were claimed (-1 if the claim failed), and whether the next batch should be
+// claimed right away: the batch was full and every job in it was published (or moved
+// on otherwise).
+func (s *JobScheduler) scheduleBatch() (int, bool) {
+	// Claim up to one batch of PENDING jobs that are scheduled to run now or in the past
+	batchSize := s.batchSizer.Size()
+	config.GetMetrics().SetSchedulerBatchSize(batchSize)
+	pendingJobs, err := s.jobRepository.ClaimPendingJobs(batchSize)
+	if err != nil {
+		log.Printf("Error claiming pending jobs: %v", err)
+		return -1, false
+	}
+	s.batchSizer.Observe(len(pendingJobs))
 
 	if len(pendingJobs) == 0 {
 		log.Println("No pending jobs found")
-		return
+		return 0, false
 	}
+	claimed := len(pendingJobs)
+	full := claimed >= batchSize
 
 	log.Printf("Found %d pending jobs to schedule", len(pendingJobs))
 
-	// Process each job
-	for _, job := range pendingJobs {
-		func(j model.Job) {
+	pendingJobs = s.expireAgedJobs(pendingJobs, time.Now())
+	if s.deadLetterExhausted {
+		pendingJobs = s.deadLetterExhaustedJobs(pendingJobs)
+	}
+
+	slots, deferred := s.planPublishes(pendingJobs)
+	if deferred > 0 {
+		log.Printf("Staggering: %d jobs deferred to the next poll", deferred)
+		s.releaseDeferred(pendingJobs, slots)
+	}
+	s.cacheClaimed(slots)
+
+	// Process each job, pacing staggered types
+	start := time.Now()
+	allMoved := deferred == 0
+	for _, slot := range slots {
+		if wait := time.Until(start.Add(slot.offset)); wait > 0 {
+			select {
+			case <-s.stopCh:
+				return claimed, false
+			case <-time.After(wait):
+			}
+		}
+
+		moved := func(j model.Job) (moved bool) {
 			defer func() {
 				if r := recover(); r != nil {
-					log.Printf("Failed to schedule job %s: %v", j.ID, r)
+					config.JobLogger(j.ID.String(), j.ClientID).Error("Failed to schedule job", "panic", fmt.Sprint(r))
 				}
 			}()
-			s.scheduleJob(&j)
-		}(job)
+			return s.scheduleJob(&j)
+		}(slot.job)
+		allMoved = allMoved && moved
+	}
+	return claimed, full && allMoved
+}
+
+// sampleBacklog publishes the number of PENDING jobs per type to the pending_jobs gauge.
+// On error the previous sample is kept.
+func (s *JobScheduler) sampleBacklog() {
+	counts, err := s.jobRepository.CountByStatusGroupedByType(model.StatusPending)
+	if err != nil {
+		log.Printf("Error counting pending jobs by type: %v", err)
+		return
+	}
+
+	pending := make(map[string]int64, len(counts))
+	for _, spec := range model.JobTypeSpecs() {
+		pending[string(spec.Type)] = 0
+	}
+	for jobType, n := range counts {
+		pending[string(jobType)] = n
+	}
+	config.GetMetrics().SetPendingJobs(pending)
+}
+
+// healthCheckPurgeInterval is how often finished HEALTH_CHECK jobs are purged.
+const healthCheckPurgeInterval = time.Minute
+
+// purgeHealthCheckJobs deletes HEALTH_CHECK jobs that finished more than the retention
+// before now, keeping the latest completed one so its time can still be reported.
+// Returns how many were deleted.
+func (s *JobScheduler) purgeHealthCheckJobs(now time.Time) int64 {
+	if s.dbBreaker.Open() {
+		return 0
+	}
+
+	cutoff := now.Add(-s.canaryRetention)
+	latest, err := s.jobRepository.FindLatestCompletedAt(model.TypeHealthCheck)
+	if err != nil {
+		log.Printf("Error finding latest health check job: %v", err)
+		return 0
 	}
+	if latest != nil && latest.Before(cutoff) {
+		cutoff = *latest
+	}
+
+	deleted, err := s.jobRepository.DeleteFinishedByTypeBefore(model.TypeHealthCheck, cutoff)
+	if err != nil {
+		log.Printf("Error purging health check jobs: %v", err)
+		return 0
+	}
+	if deleted > 0 {
+		log.Printf("Purged %d finished health check jobs", deleted)
+	}
+	return deleted
+}
+
+// expireAgedJobs moves jobs past their expiresAt or older than their type's max age
+// to EXPIRED and returns the jobs that still need publishing.
+func (s *JobScheduler) expireAgedJobs(jobs []model.Job, now time.Time) []model.Job {
+	remaining := jobs[:0]
+	for i := range jobs {
+		job := &jobs[i]
+		errMsg, expired := s.expiry(job, now)
+		if !expired {
+			remaining = append(remaining, *job)
+			continue
+		}
+
+		logger := config.JobLogger(job.ID.String(), job.ClientID)
+		logger.Info("Job has expired, moving to EXPIRED without publishing", "type", job.Type, "reason", errMsg)
+		err := updateJobWithRetry(s.jobRepository, job, func(job *model.Job) bool {
+			if !schedulable(job) {
+				return false
+			}
+			job.Status = model.StatusExpired
+			job.CompletedAt = &now
+			job.UpdatedAt = now
+			job.ErrorMessage = &errMsg
+			return true
+		})
+		if errors.Is(err, repository.ErrStaleJob) {
+			logger.Info("Job changed since it was loaded, not expiring it", "status", job.Status)
+			continue
+		}
+		if err != nil {
+			logger.Error("Failed to expire job", "error", err)
+			continue
+		}
+		config.GetMetrics().IncJobsExpired()
+	}
+	return remaining
+}
+
+// expiry reports whether a PENDING job should no longer run, with the reason.
+// The job's own expiresAt is checked before its type's max age.
+func (s *JobScheduler) expiry(job *model.Job, now time.Time) (string, bool) {
+	if job.ExpiresAt != nil && now.After(*job.ExpiresAt) {
+		return fmt.Sprintf("expired %v past its expiresAt %s", now.Sub(*job.ExpiresAt).Round(time.Second),
+			job.ExpiresAt.UTC().Format(time.RFC3339)), true
+	}
+	age := now.Sub(job.CreatedAt)
+	if maxAge, ok := s.maxJobAge[job.Type]; ok && age > maxAge {
+		return fmt.Sprintf("expired after %v pending (max age %v)", age.Round(time.Second), maxAge), true
+	}
+	return "", false
+}
+
+// deadLetterExhaustedJobs moves jobs with no attempts left to DEAD_LETTER, with their
+// last attempt's error and failure reason, and returns the jobs that still need publishing.
+func (s *JobScheduler) deadLetterExhaustedJobs(jobs []model.Job) []model.Job {
+	remaining := jobs[:0]
+	for i := range jobs {
+		job := &jobs[i]
+		if job.Attempts < job.MaxRetries {
+			remaining = append(remaining, *job)
+			continue
+		}
+
+		logger := config.JobLogger(job.ID.String(), job.ClientID)
+		logger.Warn("Job has no attempts left, moving to DEAD_LETTER without publishing",
+			"attempts", job.Attempts, "max_retries", job.MaxRetries)
+		errMsg := "max retries exhausted before scheduling"
+		if job.ErrorMessage != nil {
+			errMsg = *job.ErrorMessage
+		}
+		reason := model.FailureUnknown
+		if job.FailureReason != nil {
+			reason = *job.FailureReason
+		}
+		err := s.deadLetters().deadLetter(job, reason, errMsg, func(job *model.Job) bool {
+			return schedulable(job) && job.Attempts >= job.MaxRetries
+		})
+		if errors.Is(err, repository.ErrStaleJob) {
+			logger.Info("Job changed since it was loaded, not dead-lettering it", "status", job.Status)
+			continue
+		}
+		if err != nil {
+			logger.Error("Failed to dead-letter exhausted job", "error", err)
+		}
+	}
+	return remaining
+}
+
+// stuckJobReapInterval is how often the reaper looks for stuck RUNNING jobs.
+const stuckJobReapInterval = time.Minute
+
+// reapStuckJobs requeues (or dead-letters, when out of attempts) RUNNING jobs not
+// updated within the stuck threshold. Returns the number of jobs reaped.
+func (s *JobScheduler) reapStuckJobs(now time.Time) int {
+	if s.dbBreaker.Open() {
+		return 0
+	}
+	cutoff := now.Add(-s.stuckThreshold)
+	jobs, err := s.jobRepository.FindRunningUpdatedBefore(cutoff)
+	if err != nil {
+		log.Printf("Error finding stuck jobs: %v", err)
+		return 0
+	}
+
+	reaped := 0
+	for i := range jobs {
+		job := &jobs[i]
+		started, published := job.ProcessingStartedAt != nil, job.PublishedAt != nil
+		logger := config.JobLogger(job.ID.String(), job.ClientID)
+		// Lost on its last attempt: decided before touching the job, which the
+		// dead-letter path re-checks against the stored copy
+		if job.WorkDoneAt == nil && (started || published) && job.Attempts+1 >= job.MaxRetries {
+			if s.reapToDeadLetter(job, cutoff) {
+				reaped++
+				logger.Warn("Reaped stuck job", "status", job.Status, "attempts", job.Attempts, "max_retries", job.MaxRetries)
+			}
+			continue
+		}
+
+		job.PublishedAt = nil
+		job.ProcessingStartedAt = nil
+		job.UpdatedAt = now
+		if job.WorkDoneAt != nil {
+			// The handler succeeded; only the COMPLETED save was lost
+			job.Status = model.StatusCompleted
+			job.CompletedAt = job.WorkDoneAt
+			job.SLABreached = s.slas.Breached(job.Type, job.WorkDoneAt.Sub(job.CreatedAt))
+		} else if !started && !published {
+			// Its scheduler died before the publish was confirmed: no worker ever had it
+			errMsg := fmt.Sprintf("claimed but never published within %v, scheduler presumed lost", s.stuckThreshold)
+			job.ErrorMessage = &errMsg
+			job.Status = model.StatusPending
+			job.ScheduledAt = &now
+		} else {
+			job.Attempts++
+			errMsg := s.stuckMessage(started)
+			job.ErrorMessage = &errMsg
+			job.Status = model.StatusPending
+			job.ScheduledAt = &now
+		}
+
+		updated, err := s.jobRepository.UpdateIfStillRunning(job, cutoff)
+		if err != nil {
+			logger.Error("Failed to reap stuck job", "error", err)
+			continue
+		}
+		if !updated {
+			continue // Finished or reaped elsewhere since it was loaded
+		}
+		reaped++
+		if s.cacheService != nil {
+			s.cacheService.UpdateJob(job)
+		}
+		if job.Status == model.StatusCompleted {
+			config.GetMetrics().RecordEndToEndLatency(string(job.Type), job.WorkDoneAt.Sub(job.CreatedAt))
+			if job.SLABreached {
+				config.GetMetrics().IncSLABreach(string(job.Type))
+			}
+		}
+		logger.Warn("Reaped stuck job", "status", job.Status, "attempts", job.Attempts, "max_retries", job.MaxRetries)
+	}
+
+	if reaped > 0 {
+		log.Printf("Stuck-job reaper: %d jobs reaped", reaped)
+	}
+	return reaped
+}
+
+// stuckMessage is the error recorded on a job the reaper counts as lost, by whether a
+// worker had started processing it.
+func (s *JobScheduler) stuckMessage(started bool) string {
+	if !started {
+		return fmt.Sprintf("published but not picked up by a worker within %v, presumed lost", s.stuckThreshold)
+	}
+	return fmt.Sprintf("stuck in RUNNING for over %v, worker presumed lost", s.stuckThreshold)
 }
 
-// scheduleJob publishes a single job to Kafka.
-func (s *JobScheduler) scheduleJob(job *model.Job) {
+// reapToDeadLetter dead-letters a stuck job whose lost attempt was its last, as long as
+// it is still RUNNING without an update since cutoff and without its work done.
+// Reports whether it was dead-lettered.
+func (s *JobScheduler) reapToDeadLetter(job *model.Job, cutoff time.Time) bool {
+	logger := config.JobLogger(job.ID.String(), job.ClientID)
+	errMsg := s.stuckMessage(job.ProcessingStartedAt != nil)
+	err := s.deadLetters().deadLetter(job, model.FailureTimeout, errMsg, func(job *model.Job) bool {
+		if job.Status != model.StatusRunning || !job.UpdatedAt.Before(cutoff) || job.WorkDoneAt != nil {
+			return false
+		}
+		job.Attempts++
+		job.ProcessingStartedAt = nil
+		return true
+	})
+	if errors.Is(err, repository.ErrStaleJob) {
+		return false // Finished or reaped elsewhere since it was loaded
+	}
+	if err != nil {
+		logger.Error("Failed to reap stuck job", "error", err)
+		return false
+	}
+	return true
+}
+
+// slaAtRiskInterval is how often the SLA at-risk gauge is sampled.
+const slaAtRiskInterval = time.Minute
+
+// sampleSLAAtRisk counts, per type with an SLA, the unfinished jobs created longer ago
+// than the SLA into the sla_at_risk_jobs gauge. Returns the counts, nil if any failed.
+func (s *JobScheduler) sampleSLAAtRisk(now time.Time) map[string]int64 {
+	if s.dbBreaker.Open() {
+		return nil
+	}
+	counts := make(map[string]int64, len(s.slas))
+	for jobType, sla := range s.slas {
+		count, err := s.jobRepository.CountUnfinishedCreatedBefore(jobType, now.Add(-sla))
+		if err != nil {
+			// Keep the last sample rather than reporting a partial one
+			log.Printf("Error counting %s jobs at risk of breaching their SLA: %v", jobType, err)
+			return nil
+		}
+		counts[string(jobType)] = count
+	}
+	config.GetMetrics().SetSLAAtRisk(counts)
+	return counts
+}
+
+// publishSlot is a job and its publish time relative to the start of the poll.
+type publishSlot struct {
+	job    model.Job
+	offset time.Duration
+}
+
+// planPublishes assigns each job a publish offset within the poll.
+//
+// The n-th job of a staggered type is offset by n * stagger; jobs whose offset
+// falls beyond the poll interval are deferred (left PENDING) and counted.
+// Unstaggered jobs publish immediately. Slots are ordered by offset, keeping
+// the query order among jobs with the same offset.
+func (s *JobScheduler) planPublishes(jobs []model.Job) ([]publishSlot, int) {
+	slots := make([]publishSlot, 0, len(jobs))
+	seen := make(map[model.JobType]int)
+	deferred := 0
+
+	for _, job := range jobs {
+		spacing, ok := s.stagger[job.Type]
+		if !ok {
+			slots = append(slots, publishSlot{job: job})
+			continue
+		}
+
+		offset := time.Duration(seen[job.Type]) * spacing
+		if offset >= s.pollInterval {
+			deferred++
+			continue
+		}
+		seen[job.Type]++
+		slots = append(slots, publishSlot{job: job, offset: offset})
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		return slots[i].offset < slots[j].offset
+	})
+	return slots, deferred
+}
+
+// schedulable reports whether the scheduler may still move a job it loaded on: PENDING,
+// or claimed RUNNING and not yet picked up by a worker.
+func schedulable(job *model.Job) bool {
+	return job.Status == model.StatusPending ||
+		(job.Status == model.StatusRunning && job.ProcessingStartedAt == nil)
+}
+
+// releaseDeferred returns the claimed jobs that got no publish slot to PENDING.
+func (s *JobScheduler) releaseDeferred(claimed []model.Job, slots []publishSlot) {
+	planned := make(map[uuid.UUID]bool, len(slots))
+	for _, slot := range slots {
+		planned[slot.job.ID] = true
+	}
+	var ids []uuid.UUID
+	for _, job := range claimed {
+		if !planned[job.ID] {
+			ids = append(ids, job.ID)
+		}
+	}
+	if err := s.jobRepository.ReleaseClaims(ids); err != nil {
+		log.Printf("Failed to release %d deferred jobs, left to the stuck-job reaper: %v", len(ids), err)
+	}
+}
+
+// cacheClaimed caches the claimed jobs about to be published, in one pipeline (see
+// CacheService.WarmUp). The claim bumped their versions, so without it a worker
+// picking a job up from the cache would start from a stale copy, whose first save
+// fails and costs a reload.
+func (s *JobScheduler) cacheClaimed(slots []publishSlot) {
+	if s.cacheService == nil || len(slots) == 0 {
+		return
+	}
+	jobs := make([]model.Job, len(slots))
+	for i, slot := range slots {
+		jobs[i] = slot.job
+	}
+	s.cacheService.WarmUp(jobs)
+}
+
+// scheduleJob publishes a single claimed job to Kafka. Reports whether the job was
+// published (or moved on otherwise): false when it was returned to PENDING for the next poll.
+func (s *JobScheduler) scheduleJob(job *model.Job) bool {
 	jobID := job.ID.String()
 
-	log.Printf("Scheduling job: id=%s, type=%s, clientId=%s, attempt=%d",
-		jobID, job.Type, job.ClientID, job.Attempts)
+	logger := config.JobLogger(jobID, job.ClientID)
+	logger.Info("Scheduling job", "type", job.Type, "attempt", job.Attempts)
+
+	writer := s.writerFor(job)
+	if writer == nil {
+		s.deadLetterUnroutable(job)
+		return true
+	}
+
+	// Continue the trace started by CreateJob
+	var traceParent string
+	if job.TraceParent != nil {
+		traceParent = *job.TraceParent
+	}
+	spanCtx, span := config.Tracer().Start(config.ExtractTraceParent(context.Background(), traceParent), "job.schedule",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("job.id", jobID),
+			attribute.String("job.type", string(job.Type)),
+			attribute.Int("job.attempt", job.Attempts)))
+	defer span.End()
 
 	// Publish job ID to Kafka
 	// Use clientId as key for partition routing
-	err := s.kafkaWriter.WriteMessages(context.Background(),
+	// The type and priority headers let workers filter and order messages without
+	// a lookup, the trace headers carry the trace to the worker
+	headers := []kafka.Header{
+		{Key: config.JobTypeHeader, Value: []byte(job.Type)},
+		{Key: config.JobPriorityHeader, Value: []byte(strconv.Itoa(job.Priority))},
+	}
+	config.InjectTraceHeaders(spanCtx, &headers)
+	if version := s.publishJobState(job); version != "" {
+		headers = append(headers, kafka.Header{Key: config.JobStateVersionHeader, Value: []byte(version)})
+	}
+	err := writer.WriteMessages(context.Background(),
 		kafka.Message{
-			Key:   []byte(job.ClientID),
-			Value: []byte(jobID),
+			Key:     []byte(job.ClientID),
+			Value:   []byte(jobID),
+			Headers: headers,
 		},
 	)
 
 	if err != nil {
 		// Failure: Kafka send failed
 		// Keep status as PENDING so it will be retried in next poll
-		log.Printf("Failed to publish job %s to Kafka: %v", jobID, err)
-		return
+		logger.Error("Failed to publish job to Kafka", "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to publish job")
+		if err := s.jobRepository.ReleaseClaims([]uuid.UUID{job.ID}); err != nil {
+			logger.Error("Failed to return unpublished job to PENDING, left to the stuck-job reaper", "error", err)
+		}
+		return false
 	}
 
-	// Success: Kafka message sent
-	log.Printf("Job %s published to Kafka", jobID)
+	// Success: Kafka message sent. The job is RUNNING since the claim; the worker
+	// sets ProcessingStartedAt once it picks it up
+	publishedAt := time.Now()
+	if _, err := s.jobRepository.MarkPublished(job.ID, publishedAt); err != nil {
+		// Published all the same; the reaper then counts an attempt if it's never picked up
+		logger.Error("Failed to record job publish time", "error", err)
+	} else {
+		job.PublishedAt = &publishedAt
+	}
+	logger.Info("Job published to Kafka", "topic", writer.Topic)
+	return true
+}
 
-	// Update job status to RUNNING
-	job.Status = model.StatusRunning
-	now := time.Now()
-	job.UpdatedAt = now
-	if err := s.jobRepository.Save(job); err != nil {
-		log.Printf("Failed to update job %s status to RUNNING: %v", jobID, err)
+// publishJobState writes the job as the worker will see it (RUNNING) to the job
+// state topic, returning the record's version, or "" when the topic is disabled or
+// the write failed: the worker then looks the job up as usual.
+func (s *JobScheduler) publishJobState(job *model.Job) string {
+	if s.stateWriter == nil {
+		return ""
+	}
+	snapshot := *job
+	snapshot.Status = model.StatusRunning
+	snapshot.ProcessingStartedAt = nil
+	snapshot.Version = job.Version
+	data, err := encodeJobState(&snapshot, s.compressThreshold)
+	if err != nil {
+		log.Printf("Failed to encode job state of %s: %v", job.ID, err)
+		return ""
+	}
+
+	version := uuid.NewString()
+	err = s.stateWriter.WriteMessages(context.Background(), kafka.Message{
+		Key:     []byte(job.ID.String()),
+		Value:   data,
+		Headers: []kafka.Header{{Key: config.JobStateVersionHeader, Value: []byte(version)}},
+	})
+	if err != nil {
+		log.Printf("Failed to publish job state of %s to %s: %v", job.ID, s.stateWriter.Topic, err)
+		return ""
+	}
+	return version
+}
+
+// writerFor returns the Kafka writer for the job: its worker pool's topic when pinned
+// to one, else the high-priority topic for urgent jobs when enabled, the job queue otherwise.
+// Returns nil when the job is pinned to a pool that isn't configured.
+func (s *JobScheduler) writerFor(job *model.Job) *kafka.Writer {
+	if pool := job.Labels.WorkerPool(); pool != "" {
+		return s.poolWriters[pool]
+	}
+	if s.highPriorityWriter != nil && job.Priority <= s.highPriorityMax {
+		return s.highPriorityWriter
+	}
+	return s.kafkaWriter
+}
+
+// deadLetterUnroutable moves a job pinned to an unconfigured worker pool to DEAD_LETTER:
+// no worker would ever consume it, and it must not run on a worker lacking the pool's capability.
+func (s *JobScheduler) deadLetterUnroutable(job *model.Job) {
+	pool := job.Labels.WorkerPool()
+	logger := config.JobLogger(job.ID.String(), job.ClientID)
+	logger.Warn("Job is pinned to a worker pool that is not configured, moving to DEAD_LETTER without publishing",
+		"worker_pool", pool)
+	errMsg := fmt.Sprintf("worker pool %q is not configured", pool)
+	err := s.deadLetters().deadLetter(job, model.FailureUnknown, errMsg, schedulable)
+	if errors.Is(err, repository.ErrStaleJob) {
+		logger.Info("Job changed since it was loaded, not dead-lettering it", "status", job.Status)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to dead-letter job", "error", err)
 	}
 }
 
@@ -169,7 +1004,8 @@ func (s *JobScheduler) LogStatistics() {
 	completed, _ := s.jobRepository.CountByStatus(model.StatusCompleted)
 	failed, _ := s.jobRepository.CountByStatus(model.StatusFailed)
 	deadLetter, _ := s.jobRepository.CountByStatus(model.StatusDeadLetter)
+	expired, _ := s.jobRepository.CountByStatus(model.StatusExpired)
 
-	log.Printf("Job Statistics - PENDING: %d, RUNNING: %d, COMPLETED: %d, FAILED: %d, DEAD_LETTER: %d",
-		pending, running, completed, failed, deadLetter)
-}
\ No newline at end of file
+	log.Printf("Job Statistics - PENDING: %d, RUNNING: %d, COMPLETED: %d, FAILED: %d, DEAD_LETTER: %d, EXPIRED: %d",
+		pending, running, completed, failed, deadLetter, expired)
+}
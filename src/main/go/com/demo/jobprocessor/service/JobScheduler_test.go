@@ -0,0 +1,867 @@
+package service
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+	"gorm.io/gorm"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+// TestPlanPublishesStaggersPerType verifies staggered jobs are spaced and capped at the poll interval.
+func TestPlanPublishesStaggersPerType(t *testing.T) {
+	s := &JobScheduler{
+		pollInterval: time.Second,
+		stagger:      map[model.JobType]time.Duration{model.TypePaymentProcess: 400 * time.Millisecond},
+	}
+
+	var jobs []model.Job
+	for i := 0; i < 4; i++ {
+		jobs = append(jobs, *model.NewJob("c", model.TypePaymentProcess, "p"))
+	}
+	jobs = append(jobs, *model.NewJob("c", model.TypeEmailConfirmation, "p"))
+
+	slots, deferred := s.planPublishes(jobs)
+
+	// Offsets 0, 400ms, 800ms fit in a 1s poll; the fourth payment (1.2s) waits for the next poll
+	if deferred != 1 {
+		t.Fatalf("expected 1 deferred job, got %d", deferred)
+	}
+	want := []struct {
+		jobType model.JobType
+		offset  time.Duration
+	}{
+		{model.TypePaymentProcess, 0},
+		{model.TypeEmailConfirmation, 0},
+		{model.TypePaymentProcess, 400 * time.Millisecond},
+		{model.TypePaymentProcess, 800 * time.Millisecond},
+	}
+	if len(slots) != len(want) {
+		t.Fatalf("expected %d slots, got %d", len(want), len(slots))
+	}
+	for i, w := range want {
+		if slots[i].job.Type != w.jobType || slots[i].offset != w.offset {
+			t.Errorf("slot %d = (%s, %v), want (%s, %v)", i, slots[i].job.Type, slots[i].offset, w.jobType, w.offset)
+		}
+	}
+}
+
+// TestPlanPublishesWithoutStagger verifies unstaggered jobs all publish immediately in query order.
+func TestPlanPublishesWithoutStagger(t *testing.T) {
+	s := &JobScheduler{pollInterval: time.Second}
+
+	jobs := []model.Job{
+		*model.NewJob("c", model.TypePaymentProcess, "p"),
+		*model.NewJob("c", model.TypeEmailConfirmation, "p"),
+	}
+
+	slots, deferred := s.planPublishes(jobs)
+	if deferred != 0 || len(slots) != 2 {
+		t.Fatalf("expected 2 immediate slots, got %d (deferred %d)", len(slots), deferred)
+	}
+	for i, slot := range slots {
+		if slot.job.ID != jobs[i].ID || slot.offset != 0 {
+			t.Errorf("slot %d out of order or delayed", i)
+		}
+	}
+}
+
+// TestWriterForRoutesByPriority verifies urgent jobs go to the high-priority topic when enabled.
+func TestWriterForRoutesByPriority(t *testing.T) {
+	regular := &kafka.Writer{Topic: "job-queue"}
+	high := &kafka.Writer{Topic: "job-queue-high"}
+
+	s := &JobScheduler{kafkaWriter: regular, highPriorityWriter: high, highPriorityMax: 3}
+	urgent := model.NewJob("c", model.TypePaymentProcess, "p")
+	urgent.Priority = 2
+	normal := model.NewJob("c", model.TypePaymentProcess, "p")
+
+	if s.writerFor(urgent) != high {
+		t.Errorf("expected priority 2 job on the high-priority topic")
+	}
+	if s.writerFor(normal) != regular {
+		t.Errorf("expected default priority job on the regular topic")
+	}
+
+	// Single-topic scheme: everything goes to the job queue
+	s.highPriorityWriter = nil
+	if s.writerFor(urgent) != regular {
+		t.Errorf("expected the regular topic when priority topics are disabled")
+	}
+}
+
+// TestWriterForRoutesByWorkerPool verifies pinned jobs go to their pool's topic whatever
+// their priority, and jobs pinned to an unconfigured pool get no writer.
+func TestWriterForRoutesByWorkerPool(t *testing.T) {
+	regular := &kafka.Writer{Topic: "job-queue"}
+	high := &kafka.Writer{Topic: "job-queue-high"}
+	onprem := &kafka.Writer{Topic: "job-queue-pool-onprem"}
+
+	s := &JobScheduler{
+		kafkaWriter:        regular,
+		highPriorityWriter: high,
+		highPriorityMax:    3,
+		poolWriters:        map[string]*kafka.Writer{"onprem": onprem},
+	}
+	pinned := model.NewJob("c", model.TypePaymentProcess, "p")
+	pinned.Priority = 1
+	pinned.Labels = model.JobLabels{model.WorkerPoolLabel: "onprem"}
+	unknown := model.NewJob("c", model.TypePaymentProcess, "p")
+	unknown.Labels = model.JobLabels{model.WorkerPoolLabel: "gpu"}
+	general := model.NewJob("c", model.TypePaymentProcess, "p")
+	general.Labels = model.JobLabels{"region": "eu-west"}
+
+	if s.writerFor(pinned) != onprem {
+		t.Errorf("expected pinned job on the pool topic")
+	}
+	if s.writerFor(unknown) != nil {
+		t.Errorf("expected no writer for an unconfigured pool")
+	}
+	if s.writerFor(general) != regular {
+		t.Errorf("expected unpinned job on the regular topic")
+	}
+}
+
+// TestScheduleJobDeadLettersUnroutableJob verifies a job pinned to a pool that is no
+// longer configured is dead-lettered instead of published.
+func TestScheduleJobDeadLettersUnroutableJob(t *testing.T) {
+	repo := newTestRepository(t)
+	s := &JobScheduler{jobRepository: repo}
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	job.Labels = model.JobLabels{model.WorkerPoolLabel: "retired"}
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	s.scheduleJob(job)
+
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusDeadLetter || saved.ErrorMessage == nil || !strings.Contains(*saved.ErrorMessage, "retired") {
+		t.Fatalf("expected DEAD_LETTER naming the pool, got %+v", saved)
+	}
+}
+
+// TestScheduleJobsDeadLettersExhaustedJobs verifies a PENDING job already at max attempts
+// is dead-lettered by the scheduler instead of being republished, with a failure reason.
+func TestScheduleJobsDeadLettersExhaustedJobs(t *testing.T) {
+	repo := newTestRepository(t)
+	s := &JobScheduler{
+		jobRepository:       repo,
+		batchSizer:          newBatchSizer(10, 10, 10, false),
+		deadLetterExhausted: true,
+	}
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	job.Attempts = job.MaxRetries
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	// No Kafka writer: publishing would panic, so reaching DEAD_LETTER proves it was never published
+	s.scheduleJobs()
+
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusDeadLetter || saved.CompletedAt == nil || saved.ErrorMessage == nil {
+		t.Fatalf("expected DEAD_LETTER with completion time and error, got %+v", saved)
+	}
+	if saved.FailureReason == nil || *saved.FailureReason != model.FailureUnknown {
+		t.Fatalf("expected failure reason unknown, got %v", saved.FailureReason)
+	}
+}
+
+// TestScheduleJobsExpiresAgedJobs verifies PENDING jobs past their type's max age are
+// expired instead of published, while other types are unaffected by age.
+func TestScheduleJobsExpiresAgedJobs(t *testing.T) {
+	repo := newTestRepository(t)
+	s := &JobScheduler{
+		jobRepository: repo,
+		batchSizer:    newBatchSizer(10, 10, 10, false),
+		maxJobAge:     map[model.JobType]time.Duration{model.TypeEmailConfirmation: time.Hour},
+	}
+
+	aged := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	aged.CreatedAt = time.Now().Add(-2 * time.Hour)
+	fresh := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_2|user@email.com|receipt")
+	oldPayment := model.NewJob("customer-1", model.TypePaymentProcess, "order_3|user@email.com|$10.00")
+	oldPayment.CreatedAt = time.Now().Add(-48 * time.Hour)
+	for _, job := range []*model.Job{aged, fresh, oldPayment} {
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+	}
+
+	before := config.GetMetrics().JobsExpired()
+	remaining := s.expireAgedJobs([]model.Job{*aged, *fresh, *oldPayment}, time.Now())
+	if len(remaining) != 2 || remaining[0].ID != fresh.ID || remaining[1].ID != oldPayment.ID {
+		t.Fatalf("expected the fresh job and the payment to remain, got %d jobs", len(remaining))
+	}
+
+	saved, err := repo.FindByID(aged.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusExpired || saved.CompletedAt == nil || saved.ErrorMessage == nil {
+		t.Fatalf("expected EXPIRED with completion time and error, got %+v", saved)
+	}
+	if got := config.GetMetrics().JobsExpired() - before; got != 1 {
+		t.Fatalf("expected 1 expiration counted, got %d", got)
+	}
+}
+
+// TestScheduleJobsExpiresJobsPastExpiresAt verifies a PENDING job past its expiresAt is
+// moved to EXPIRED without being published, while one still within it is published.
+func TestScheduleJobsExpiresJobsPastExpiresAt(t *testing.T) {
+	repo := newTestRepository(t)
+	s := &JobScheduler{
+		jobRepository: repo,
+		batchSizer:    newBatchSizer(10, 10, 10, false),
+	}
+
+	stale := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	expiresAt := time.Now().Add(-time.Minute)
+	stale.ExpiresAt = &expiresAt
+	if err := repo.Create(stale); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	// No Kafka writer: publishing would panic, so reaching EXPIRED proves it was never published
+	s.scheduleJobs()
+
+	saved, err := repo.FindByID(stale.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusExpired || saved.CompletedAt == nil || saved.ErrorMessage == nil ||
+		!strings.Contains(*saved.ErrorMessage, "expiresAt") {
+		t.Fatalf("expected EXPIRED naming the expiresAt, got %+v", saved)
+	}
+
+	live := model.NewJob("customer-1", model.TypePaymentProcess, "order_2|user@email.com|$10.00")
+	later := time.Now().Add(time.Hour)
+	live.ExpiresAt = &later
+	if remaining := s.expireAgedJobs([]model.Job{*live}, time.Now()); len(remaining) != 1 {
+		t.Fatalf("expected the unexpired job to remain, got %d jobs", len(remaining))
+	}
+}
+
+// TestSampleBacklogReportsPendingJobsPerType verifies the pending_jobs gauge counts every
+// PENDING job per type, including ones backing off, and drops to zero once they are done.
+func TestSampleBacklogReportsPendingJobsPerType(t *testing.T) {
+	repo := newTestRepository(t)
+	s := &JobScheduler{jobRepository: repo, backlogGauge: true}
+
+	backingOff := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	later := time.Now().UTC().Add(time.Hour)
+	backingOff.ScheduledAt = &later
+	jobs := []*model.Job{
+		model.NewJob("customer-1", model.TypePaymentProcess, "order_2|user@email.com|$10.00"),
+		model.NewJob("customer-1", model.TypePaymentProcess, "order_3|user@email.com|$20.00"),
+		backingOff,
+	}
+	for _, job := range jobs {
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+	}
+
+	s.sampleBacklog()
+	pending := config.GetMetrics().PendingJobs()
+	if pending[string(model.TypePaymentProcess)] != 2 || pending[string(model.TypeEmailConfirmation)] != 1 {
+		t.Fatalf("expected 2 payments and 1 email pending, got %v", pending)
+	}
+
+	for _, job := range jobs {
+		job.Status = model.StatusCompleted
+		if err := repo.UpdateJobSafe(job); err != nil {
+			t.Fatalf("complete job: %v", err)
+		}
+	}
+
+	s.sampleBacklog()
+	pending = config.GetMetrics().PendingJobs()
+	if len(pending) != len(model.JobTypeSpecs()) {
+		t.Fatalf("expected every job type to be reported, got %v", pending)
+	}
+	for jobType, n := range pending {
+		if n != 0 {
+			t.Fatalf("expected the drained backlog to read 0, got %d for %s", n, jobType)
+		}
+	}
+}
+
+// TestPurgeHealthCheckJobsKeepsLatestSuccess verifies finished canaries past the retention
+// are deleted, except the latest completed one, while recent ones and real jobs stay.
+func TestPurgeHealthCheckJobsKeepsLatestSuccess(t *testing.T) {
+	repo := newTestRepository(t)
+	s := &JobScheduler{jobRepository: repo, canaryRetention: 10 * time.Minute}
+	now := time.Now().UTC()
+
+	seed := func(jobType model.JobType, status model.JobStatus, finishedAgo time.Duration) *model.Job {
+		job := model.NewJob("monitor", jobType, "probe|user@email.com|$10.00")
+		job.Status = status
+		completedAt := now.Add(-finishedAgo)
+		job.CompletedAt = &completedAt
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		return job
+	}
+	oldest := seed(model.TypeHealthCheck, model.StatusCompleted, 3*time.Hour)
+	latestOld := seed(model.TypeHealthCheck, model.StatusCompleted, 2*time.Hour)
+	deadLettered := seed(model.TypeHealthCheck, model.StatusDeadLetter, time.Hour)
+	realJob := seed(model.TypePaymentProcess, model.StatusCompleted, 3*time.Hour)
+
+	// Every canary is past the retention: only the latest success survives
+	if deleted := s.purgeHealthCheckJobs(now); deleted != 1 {
+		t.Fatalf("expected 1 canary purged, got %d", deleted)
+	}
+	for _, job := range []*model.Job{latestOld, deadLettered, realJob} {
+		if _, err := repo.FindByID(job.ID); err != nil {
+			t.Fatalf("expected job %s to be kept: %v", job.ID, err)
+		}
+	}
+	if _, err := repo.FindByID(oldest.ID); err == nil {
+		t.Fatal("expected the oldest canary to be purged")
+	}
+
+	// A fresh success makes the older ones purgeable
+	recent := seed(model.TypeHealthCheck, model.StatusCompleted, time.Minute)
+	if deleted := s.purgeHealthCheckJobs(now); deleted != 2 {
+		t.Fatalf("expected 2 canaries purged, got %d", deleted)
+	}
+	if _, err := repo.FindByID(recent.ID); err != nil {
+		t.Fatalf("expected the recent canary to be kept: %v", err)
+	}
+	latest, err := repo.FindLatestCompletedAt(model.TypeHealthCheck)
+	if err != nil || latest == nil || !latest.Equal(*recent.CompletedAt) {
+		t.Fatalf("expected the latest success at %v, got %v (%v)", recent.CompletedAt, latest, err)
+	}
+}
+
+// TestReapStuckJobsRequeuesAbandonedJobs verifies RUNNING jobs not updated within the
+// threshold are requeued (or dead-lettered when out of attempts) and recent ones are left alone.
+func TestReapStuckJobsRequeuesAbandonedJobs(t *testing.T) {
+	repo := newTestRepository(t)
+	s := &JobScheduler{jobRepository: repo, stuckThreshold: 10 * time.Minute}
+	now := time.Now()
+
+	seed := func(updatedAgo time.Duration, attempts int) *model.Job {
+		job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+		job.Status = model.StatusRunning
+		job.Attempts = attempts
+		job.UpdatedAt = now.Add(-updatedAgo)
+		job.PublishedAt = &job.UpdatedAt
+		job.ProcessingStartedAt = &job.UpdatedAt // Its worker crashed mid-job
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		return job
+	}
+	abandoned := seed(11*time.Minute, 0)
+	lastAttempt := seed(11*time.Minute, 2)
+	active := seed(5*time.Minute, 0)
+
+	if reaped := s.reapStuckJobs(now); reaped != 2 {
+		t.Fatalf("expected 2 jobs reaped, got %d", reaped)
+	}
+
+	saved, _ := repo.FindByID(abandoned.ID)
+	if saved.Status != model.StatusPending || saved.Attempts != 1 || saved.ScheduledAt == nil || saved.ScheduledAt.After(time.Now()) {
+		t.Fatalf("expected abandoned job requeued for now with 1 attempt, got %+v", saved)
+	}
+	saved, _ = repo.FindByID(lastAttempt.ID)
+	if saved.Status != model.StatusDeadLetter || saved.Attempts != 3 || saved.CompletedAt == nil ||
+		saved.ProcessingStartedAt != nil || saved.FailureReason == nil || *saved.FailureReason != model.FailureTimeout {
+		t.Fatalf("expected job out of attempts dead-lettered as timed out, got %+v", saved)
+	}
+	saved, _ = repo.FindByID(active.ID)
+	if saved.Status != model.StatusRunning || saved.Attempts != 0 {
+		t.Fatalf("expected recently updated job left RUNNING, got %+v", saved)
+	}
+
+	// Already reaped: a second pass finds nothing
+	if reaped := s.reapStuckJobs(now); reaped != 0 {
+		t.Fatalf("expected nothing left to reap, got %d", reaped)
+	}
+}
+
+// TestReapStuckJobsTellsUnpublishedJobsApart verifies a claimed job that was never
+// published is requeued without counting an attempt, while one published but never
+// picked up by a worker counts it as lost.
+func TestReapStuckJobsTellsUnpublishedJobsApart(t *testing.T) {
+	repo := newTestRepository(t)
+	s := &JobScheduler{jobRepository: repo, stuckThreshold: 10 * time.Minute}
+	now := time.Now()
+	claimedAt := now.Add(-11 * time.Minute)
+
+	seed := func(publishedAt *time.Time) *model.Job {
+		job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+		job.Status = model.StatusRunning
+		job.Attempts = 2
+		job.UpdatedAt = claimedAt
+		job.PublishedAt = publishedAt
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		return job
+	}
+	unpublished := seed(nil)
+	unconsumed := seed(&claimedAt)
+
+	if reaped := s.reapStuckJobs(now); reaped != 2 {
+		t.Fatalf("expected 2 jobs reaped, got %d", reaped)
+	}
+
+	saved, _ := repo.FindByID(unpublished.ID)
+	if saved.Status != model.StatusPending || saved.Attempts != 2 || saved.PublishedAt != nil ||
+		saved.ErrorMessage == nil || !strings.Contains(*saved.ErrorMessage, "never published") {
+		t.Fatalf("expected unpublished job requeued with its attempts untouched, got %+v", saved)
+	}
+	saved, _ = repo.FindByID(unconsumed.ID)
+	if saved.Status != model.StatusDeadLetter || saved.Attempts != 3 ||
+		saved.ErrorMessage == nil || !strings.Contains(*saved.ErrorMessage, "not picked up") {
+		t.Fatalf("expected unconsumed job's last attempt counted, got %+v", saved)
+	}
+}
+
+// fakeKafkaTransport stands in for a broker: it serves topic metadata with one
+// partition and fails produce requests with err when set.
+type fakeKafkaTransport struct {
+	err      error
+	produced atomic.Int32
+}
+
+func (f *fakeKafkaTransport) RoundTrip(ctx context.Context, addr net.Addr, req protocol.Message) (protocol.Message, error) {
+	switch r := req.(type) {
+	case *metadata.Request:
+		topics := make([]metadata.ResponseTopic, len(r.TopicNames))
+		for i, name := range r.TopicNames {
+			topics[i] = metadata.ResponseTopic{Name: name, Partitions: []metadata.ResponsePartition{{}}}
+		}
+		return &metadata.Response{Topics: topics}, nil
+	case *produce.Request:
+		if f.err != nil {
+			return nil, f.err
+		}
+		f.produced.Add(1)
+		return &produce.Response{}, nil
+	}
+	return nil, errors.New("unexpected request")
+}
+
+// TestScheduleJobsCachesClaimedVersion verifies a scheduled job's cache entry carries
+// the version its claim saved, so the worker picking it up doesn't start from a stale copy.
+func TestScheduleJobsCachesClaimedVersion(t *testing.T) {
+	repo := newTestRepository(t)
+	s := &JobScheduler{
+		jobRepository: repo,
+		batchSizer:    newBatchSizer(10, 10, 10, false),
+		kafkaWriter: &kafka.Writer{
+			Addr:         kafka.TCP("broker:9092"),
+			Topic:        "job-queue",
+			Transport:    &fakeKafkaTransport{},
+			BatchTimeout: time.Millisecond,
+		},
+	}
+	s.SetCacheService(newTestCacheService(t))
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	s.cacheService.CacheJob(job)
+
+	s.scheduleJobs()
+
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	cached := s.cacheService.GetJob(job.ID)
+	if cached == nil || cached.Status != model.StatusRunning || cached.Version != saved.Version {
+		t.Fatalf("expected the cached job RUNNING at version %d, got %+v", saved.Version, cached)
+	}
+}
+
+// TestScheduleJobRecordsConfirmedPublish verifies a failed Kafka write leaves the job
+// PENDING without publishedAt, and a confirmed one leaves it RUNNING with publishedAt.
+func TestScheduleJobRecordsConfirmedPublish(t *testing.T) {
+	repo := newTestRepository(t)
+	transport := &fakeKafkaTransport{err: errors.New("broker unavailable")}
+	s := &JobScheduler{
+		jobRepository: repo,
+		kafkaWriter: &kafka.Writer{
+			Addr:         kafka.TCP("broker:9092"),
+			Topic:        "job-queue",
+			Transport:    transport,
+			MaxAttempts:  1,
+			BatchTimeout: time.Millisecond,
+		},
+	}
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	claim := func() *model.Job {
+		claimed, err := repo.ClaimPendingJobs(0)
+		if err != nil || len(claimed) != 1 {
+			t.Fatalf("expected to claim the job, got %d: %v", len(claimed), err)
+		}
+		return &claimed[0]
+	}
+
+	if s.scheduleJob(claim()) {
+		t.Fatal("expected a failed write to report the job not published")
+	}
+	saved, _ := repo.FindByID(job.ID)
+	if saved.Status != model.StatusPending || saved.PublishedAt != nil {
+		t.Fatalf("expected the job back to PENDING without publishedAt, got %s %v", saved.Status, saved.PublishedAt)
+	}
+
+	transport.err = nil
+	before := time.Now()
+	if !s.scheduleJob(claim()) {
+		t.Fatal("expected a confirmed write to report the job published")
+	}
+	if transport.produced.Load() != 1 {
+		t.Fatalf("expected 1 message produced, got %d", transport.produced.Load())
+	}
+	saved, _ = repo.FindByID(job.ID)
+	if saved.Status != model.StatusRunning || saved.PublishedAt == nil || saved.PublishedAt.Before(before) {
+		t.Fatalf("expected RUNNING with publishedAt set by the write, got %s %v", saved.Status, saved.PublishedAt)
+	}
+}
+
+// TestWorkerSaveKeepsPublishMark verifies a worker save from a copy loaded before the
+// scheduler recorded the publish (e.g. the cached claim) doesn't clear publishedAt.
+func TestWorkerSaveKeepsPublishMark(t *testing.T) {
+	repo := newTestRepository(t)
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	claimed, err := repo.ClaimPendingJobs(0)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("expected to claim the job, got %d: %v", len(claimed), err)
+	}
+	if marked, err := repo.MarkPublished(job.ID, time.Now()); err != nil || !marked {
+		t.Fatalf("expected the publish marked, got %v (%v)", marked, err)
+	}
+
+	workerCopy := claimed[0]
+	startedAt := time.Now()
+	workerCopy.ProcessingStartedAt = &startedAt
+	if err := repo.UpdateJobSafe(&workerCopy); err != nil {
+		t.Fatalf("worker save: %v", err)
+	}
+	saved, _ := repo.FindByID(job.ID)
+	if saved.PublishedAt == nil || saved.ProcessingStartedAt == nil {
+		t.Fatalf("expected both publishedAt and processingStartedAt kept, got %v %v", saved.PublishedAt, saved.ProcessingStartedAt)
+	}
+}
+
+// TestReapStuckJobsCompletesJobsWithWorkDone verifies a RUNNING job whose worker died after
+// recording the work done, but before saving it COMPLETED, is completed rather than retried,
+// and its cache entry updated.
+func TestReapStuckJobsCompletesJobsWithWorkDone(t *testing.T) {
+	repo := newTestRepository(t)
+	s := &JobScheduler{jobRepository: repo, stuckThreshold: 10 * time.Minute}
+	s.SetCacheService(newTestCacheService(t))
+	now := time.Now()
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	job.Status = model.StatusRunning
+	job.Attempts = 2
+	doneAt := now.Add(-12 * time.Minute)
+	job.WorkDoneAt = &doneAt
+	job.UpdatedAt = doneAt
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	s.cacheService.CacheJob(job)
+
+	if reaped := s.reapStuckJobs(now); reaped != 1 {
+		t.Fatalf("expected 1 job reaped, got %d", reaped)
+	}
+	saved, _ := repo.FindByID(job.ID)
+	if saved.Status != model.StatusCompleted || saved.Attempts != 2 || saved.CompletedAt == nil ||
+		!saved.CompletedAt.Equal(doneAt) || saved.ErrorMessage != nil {
+		t.Fatalf("expected COMPLETED at the work done time with attempts untouched, got %+v", saved)
+	}
+	if cached := s.cacheService.GetJob(job.ID); cached == nil || cached.Status != model.StatusCompleted {
+		t.Fatalf("expected the cached job COMPLETED too, got %+v", cached)
+	}
+}
+
+// newFlakyTestDB returns newTestDB with queries failing with a connection error while down is set.
+func newFlakyTestDB(t *testing.T, down *atomic.Bool) *gorm.DB {
+	t.Helper()
+	db := newTestDB(t)
+	err := db.Callback().Query().Before("gorm:query").Register("test:connection_down", func(tx *gorm.DB) {
+		if down.Load() {
+			tx.AddError(driver.ErrBadConn)
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	return db
+}
+
+// TestScheduleJobsPausesWhileDBCircuitOpen verifies repeated connection failures open
+// the breaker, after which queries fail fast and the scheduler skips its polls.
+func TestScheduleJobsPausesWhileDBCircuitOpen(t *testing.T) {
+	var down atomic.Bool
+	db := newFlakyTestDB(t, &down)
+	breaker, err := repository.NewDBCircuitBreaker(db, 2, time.Hour)
+	if err != nil {
+		t.Fatalf("new breaker: %v", err)
+	}
+	defer breaker.Stop()
+
+	repo := repository.NewJobRepository(db)
+	s := &JobScheduler{
+		jobRepository:       repo,
+		batchSizer:          newBatchSizer(10, 10, 10, false),
+		deadLetterExhausted: true,
+		stuckThreshold:      time.Minute,
+		dbBreaker:           breaker,
+	}
+
+	// Not a connection error: the database answered, so the breaker stays closed
+	if _, err := repo.FindByID(model.NewJob("customer-1", model.TypeEmailConfirmation, "").ID); err == nil {
+		t.Fatal("expected a missing job to be reported")
+	}
+	if breaker.Open() {
+		t.Fatal("expected a missing row not to count as a connection failure")
+	}
+
+	down.Store(true)
+	for i := 0; i < 2; i++ {
+		if _, err := repo.CountByStatus(model.StatusPending); err == nil || repository.IsDBUnavailable(err) {
+			t.Fatalf("expected connection failure %d to reach the database, got %v", i+1, err)
+		}
+	}
+	if !breaker.Open() || breaker.State() != repository.DBCircuitOpen {
+		t.Fatalf("expected the breaker open after 2 connection failures, got %s", breaker.State())
+	}
+
+	// Even with the connection back, calls fail fast until the health probe succeeds
+	down.Store(false)
+	if _, err := repo.CountByStatus(model.StatusPending); !repository.IsDBUnavailable(err) {
+		t.Fatalf("expected ErrDBUnavailable while open, got %v", err)
+	}
+
+	s.scheduleJobs()
+	if !s.pausedForDB {
+		t.Fatal("expected the scheduler to pause while the breaker is open")
+	}
+	if reaped := s.reapStuckJobs(time.Now()); reaped != 0 {
+		t.Fatalf("expected the reaper to skip while the breaker is open, reaped %d", reaped)
+	}
+}
+
+// TestDBCircuitBreakerClosesWhenProbeSucceeds verifies the health probe closes an open
+// breaker once the database responds, and the scheduler resumes.
+func TestDBCircuitBreakerClosesWhenProbeSucceeds(t *testing.T) {
+	var down atomic.Bool
+	db := newFlakyTestDB(t, &down)
+	breaker, err := repository.NewDBCircuitBreaker(db, 1, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("new breaker: %v", err)
+	}
+	defer breaker.Stop()
+
+	repo := repository.NewJobRepository(db)
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	job.Attempts = job.MaxRetries
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	down.Store(true)
+	repo.CountByStatus(model.StatusPending)
+	down.Store(false)
+	if !breaker.Open() {
+		t.Fatal("expected the breaker open after a connection failure")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for breaker.Open() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if breaker.Open() {
+		t.Fatal("expected the health probe to close the breaker")
+	}
+
+	// No Kafka writer: the exhausted job is dead-lettered, proving the poll ran
+	s := &JobScheduler{
+		jobRepository:       repo,
+		batchSizer:          newBatchSizer(10, 10, 10, false),
+		deadLetterExhausted: true,
+		dbBreaker:           breaker,
+		pausedForDB:         true,
+	}
+	s.scheduleJobs()
+	if s.pausedForDB {
+		t.Fatal("expected the scheduler to resume")
+	}
+	saved, err := repo.FindByID(job.ID)
+	if err != nil || saved.Status != model.StatusDeadLetter {
+		t.Fatalf("expected the poll to dead-letter the exhausted job, got %+v, %v", saved, err)
+	}
+}
+
+// TestPendingJobsOrderedByPriority verifies urgent jobs are fetched (and so published) before
+// older, less urgent ones, and jobs of equal priority oldest first.
+func TestPendingJobsOrderedByPriority(t *testing.T) {
+	repo := newTestRepository(t)
+
+	base := time.Now().Add(-time.Hour)
+	seed := func(priority int, scheduledAt time.Time) *model.Job {
+		job := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+		job.Priority = priority
+		job.ScheduledAt = &scheduledAt
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		return job
+	}
+	staleRetry := seed(model.DefaultPriority, base)
+	flashSale := seed(1, base.Add(30*time.Minute))
+	newerRetry := seed(model.DefaultPriority, base.Add(10*time.Minute))
+
+	jobs, err := repo.FindByStatusAndScheduledAtBefore(model.StatusPending, time.Now(), 10)
+	if err != nil {
+		t.Fatalf("find pending: %v", err)
+	}
+	want := []*model.Job{flashSale, staleRetry, newerRetry}
+	if len(jobs) != len(want) {
+		t.Fatalf("expected %d jobs, got %d", len(want), len(jobs))
+	}
+	for i := range want {
+		if jobs[i].ID != want[i].ID {
+			t.Fatalf("position %d: expected priority %d job, got priority %d", i, want[i].Priority, jobs[i].Priority)
+		}
+	}
+}
+
+// TestScheduleJobsDrainsFullBatches verifies each fetch is LIMITed to the batch size
+// and a full batch triggers another fetch within the same poll, until one comes back short.
+func TestScheduleJobsDrainsFullBatches(t *testing.T) {
+	db := newTestDB(t)
+	var fetches []string
+	db.Callback().Query().After("gorm:query").Register("test:record_pending_fetch", func(tx *gorm.DB) {
+		if sql := tx.Statement.SQL.String(); strings.Contains(sql, "scheduled_at <=") {
+			fetches = append(fetches, sql)
+		}
+	})
+	repo := repository.NewJobRepository(db)
+	s := &JobScheduler{
+		jobRepository:       repo,
+		batchSizer:          newBatchSizer(10, 10, 10, false),
+		cycleBudget:         time.Minute,
+		deadLetterExhausted: true,
+	}
+
+	// Exhausted jobs are dead-lettered without publishing, so no Kafka writer is needed
+	for i := 0; i < 25; i++ {
+		job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+		job.Attempts = job.MaxRetries
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+	}
+
+	s.scheduleJobs()
+
+	if len(fetches) != 3 {
+		t.Fatalf("expected 3 fetches (10, 10, 5), got %d", len(fetches))
+	}
+	for _, sql := range fetches {
+		if !strings.Contains(sql, "LIMIT 10") {
+			t.Fatalf("expected the batch size as the query limit, got %s", sql)
+		}
+	}
+	if counts, _ := repo.CountByStatusGroupedByType(model.StatusPending); counts[model.TypeEmailConfirmation] != 0 {
+		t.Fatalf("expected every job handled in one poll, %d still PENDING", counts[model.TypeEmailConfirmation])
+	}
+
+	// Without budget left after the first batch, the rest waits for the next poll
+	for i := 0; i < 25; i++ {
+		job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+		job.Attempts = job.MaxRetries
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+	}
+	fetches = nil
+	s.cycleBudget = 0
+	s.scheduleJobs()
+	if len(fetches) != 1 {
+		t.Fatalf("expected a single fetch once the cycle budget is spent, got %d", len(fetches))
+	}
+}
+
+// TestSchedulerIdlePollingBacksOff verifies the wait between polls doubles with each
+// consecutive empty poll up to SCHEDULER_MAX_IDLE_INTERVAL, and snaps back to the poll
+// interval once a poll finds jobs.
+func TestSchedulerIdlePollingBacksOff(t *testing.T) {
+	repo := newTestRepository(t)
+	s := &JobScheduler{
+		jobRepository:       repo,
+		batchSizer:          newBatchSizer(10, 10, 10, false),
+		deadLetterExhausted: true,
+		pollInterval:        100 * time.Millisecond,
+		maxIdleInterval:     time.Second,
+	}
+
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, ms := range want {
+		idle := s.scheduleJobs()
+		if !idle {
+			t.Fatalf("poll %d: expected an empty queue to be idle", i+1)
+		}
+		if got := s.nextPollDelay(idle); got != ms*time.Millisecond {
+			t.Fatalf("poll %d: expected to wait %v, got %v", i+1, ms*time.Millisecond, got)
+		}
+	}
+
+	// An exhausted job is dead-lettered without a Kafka writer, but still counts as found
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	job.Attempts = job.MaxRetries
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	idle := s.scheduleJobs()
+	if idle {
+		t.Fatal("expected a poll that found a job not to be idle")
+	}
+	if got := s.nextPollDelay(idle); got != s.pollInterval {
+		t.Fatalf("expected the wait reset to %v, got %v", s.pollInterval, got)
+	}
+	if got := s.nextPollDelay(s.scheduleJobs()); got != s.pollInterval {
+		t.Fatalf("expected the first empty poll after jobs to wait %v, got %v", s.pollInterval, got)
+	}
+}
@@ -1,11 +1,22 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 
+	"distributed-job-processor/config"
 	"distributed-job-processor/dto"
 	"distributed-job-processor/exception"
 	"distributed-job-processor/model"
@@ -14,34 +25,216 @@ import (
 
 // JobService handles business logic for creating, retrieving, and updating jobs.
 type JobService struct {
-	jobRepository *repository.JobRepository
+	jobRepository  *repository.JobRepository
+	validator      *PayloadValidator
+	labelValidator *LabelValidator
+	typeAliases    *JobTypeAliases
+	enricher       JobEnricher
+	clientDefaults *ClientDefaultsService
+	cacheService   *CacheService
+	typeBreaker    *TypeCircuitBreaker
+	attempts       *repository.JobAttemptRepository
+	compensators   map[model.JobType]CompensationHandler
+
+	// Largest accepted payload in bytes (MAX_PAYLOAD_BYTES, default 8KB)
+	maxPayloadBytes int
+
+	// Whether requests may choose their own job ID (CLIENT_JOB_IDS_ENABLED, default true)
+	clientJobIDs bool
+
+	// Whether HEALTH_CHECK canary jobs are accepted (HEALTH_CHECK_JOBS_ENABLED, default false)
+	healthCheckJobs bool
+
+	// Lifetime of jobs submitted without expiresAt (DEFAULT_JOB_TTL), 0 never expires
+	defaultJobTTL time.Duration
+
+	// Per-type max retries defaults (MAX_RETRIES_<TYPE>), see resolveSettings
+	typeMaxRetries map[model.JobType]int
+
+	// Types whose batches are all-or-nothing by default (JOB_BATCH_ATOMIC_<TYPE>=true)
+	atomicBatchTypes map[model.JobType]bool
+
+	// Day boundaries of GetDailyReport (REPORT_TIMEZONE, default UTC)
+	reportLocation *time.Location
+
+	// Stuck-job detection, see FindStuckJobs
+	stuckFactor      int
+	unstartedTimeout time.Duration
+
+	// Most dead letters one ReplayDeadLetter call requeues (MAX_REPLAY_BATCH, default 1000)
+	maxReplayBatch int
 }
 
 // NewJobService creates a new JobService with the given repository.
 func NewJobService(jobRepository *repository.JobRepository) *JobService {
-	return &JobService{jobRepository: jobRepository}
+	stuckFactor := 5
+	if val := os.Getenv("STUCK_JOB_FACTOR"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			stuckFactor = parsed
+		} else {
+			log.Printf("Ignoring invalid STUCK_JOB_FACTOR %q: must be a positive integer", val)
+		}
+	}
+
+	unstartedTimeout := 10 * time.Minute
+	if val := os.Getenv("STUCK_JOB_UNSTARTED_MINUTES"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			unstartedTimeout = time.Duration(parsed) * time.Minute
+		} else {
+			log.Printf("Ignoring invalid STUCK_JOB_UNSTARTED_MINUTES %q: must be a non-negative integer", val)
+		}
+	}
+
+	var defaultJobTTL time.Duration
+	if val := os.Getenv("DEFAULT_JOB_TTL"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed >= 0 {
+			defaultJobTTL = parsed
+		} else {
+			log.Printf("Ignoring invalid DEFAULT_JOB_TTL %q: must be a non-negative duration", val)
+		}
+	}
+
+	typeMaxRetries := make(map[model.JobType]int)
+	for _, spec := range model.JobTypeSpecs() {
+		key := "MAX_RETRIES_" + string(spec.Type)
+		if val := os.Getenv(key); val != "" {
+			if parsed, err := strconv.Atoi(val); err == nil && parsed >= model.MinMaxRetries && parsed <= model.MaxMaxRetries {
+				typeMaxRetries[spec.Type] = parsed
+			} else {
+				log.Printf("Ignoring invalid %s %q: must be an integer from %d to %d",
+					key, val, model.MinMaxRetries, model.MaxMaxRetries)
+			}
+		}
+	}
+
+	atomicBatchTypes := make(map[model.JobType]bool)
+	for _, spec := range model.JobTypeSpecs() {
+		if os.Getenv("JOB_BATCH_ATOMIC_"+string(spec.Type)) == "true" {
+			atomicBatchTypes[spec.Type] = true
+		}
+	}
+
+	maxReplayBatch := 1000
+	if val := os.Getenv("MAX_REPLAY_BATCH"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			maxReplayBatch = parsed
+		} else {
+			log.Printf("Ignoring invalid MAX_REPLAY_BATCH %q: must be a positive integer", val)
+		}
+	}
+
+	reportLocation := time.UTC
+	if val := os.Getenv("REPORT_TIMEZONE"); val != "" {
+		if loc, err := time.LoadLocation(val); err == nil {
+			reportLocation = loc
+		} else {
+			log.Printf("Ignoring invalid REPORT_TIMEZONE %q: %v", val, err)
+		}
+	}
+
+	return &JobService{
+		jobRepository:    jobRepository,
+		validator:        NewPayloadValidator(),
+		labelValidator:   NewLabelValidator(),
+		typeAliases:      NewJobTypeAliasesFromEnv(),
+		enricher:         NoopJobEnricher{},
+		compensators:     DefaultCompensationHandlers(),
+		maxPayloadBytes:  config.GetMaxPayloadBytes(),
+		clientJobIDs:     os.Getenv("CLIENT_JOB_IDS_ENABLED") != "false",
+		healthCheckJobs:  config.GetHealthCheckJobsEnabled(),
+		defaultJobTTL:    defaultJobTTL,
+		typeMaxRetries:   typeMaxRetries,
+		atomicBatchTypes: atomicBatchTypes,
+		reportLocation:   reportLocation,
+		stuckFactor:      stuckFactor,
+		unstartedTimeout: unstartedTimeout,
+		maxReplayBatch:   maxReplayBatch,
+	}
+}
+
+// SetEnricher registers the JobEnricher applied to every new job before it is saved.
+// Call this at startup; passing nil restores the default no-op enricher.
+func (s *JobService) SetEnricher(enricher JobEnricher) {
+	if enricher == nil {
+		enricher = NoopJobEnricher{}
+	}
+	s.enricher = enricher
+}
+
+// SetClientDefaultsService enables per-client defaults for settings a request omits.
+// Without it (or with nil), omitted settings use the global defaults.
+func (s *JobService) SetClientDefaultsService(clientDefaults *ClientDefaultsService) {
+	s.clientDefaults = clientDefaults
+}
+
+// SetCacheService lets status changes made here (e.g. RetryJob) evict the job's
+// cached copy, so workers don't process a stale one. Optional.
+func (s *JobService) SetCacheService(cacheService *CacheService) {
+	s.cacheService = cacheService
+}
+
+// SetTypeCircuitBreaker refuses new jobs of types shed while their breaker is open,
+// see TypeCircuitBreaker. Optional.
+func (s *JobService) SetTypeCircuitBreaker(breaker *TypeCircuitBreaker) {
+	s.typeBreaker = breaker
+}
+
+// SetJobAttemptRepository serves job attempt histories from the repository the
+// workers record them in (see JobWorker.SetJobAttemptRepository). Optional.
+func (s *JobService) SetJobAttemptRepository(attempts *repository.JobAttemptRepository) {
+	s.attempts = attempts
+}
+
+// RegisterCompensationHandler sets the CompensationHandler of a job type, replacing
+// any built-in one (see DefaultCompensationHandlers). Call this at startup.
+func (s *JobService) RegisterCompensationHandler(jobType model.JobType, handler CompensationHandler) {
+	if s.compensators == nil {
+		s.compensators = make(map[model.JobType]CompensationHandler)
+	}
+	s.compensators[jobType] = handler
 }
 
 // CreateJob creates a new job from a request.
 // The job is initially created in PENDING status and scheduled for immediate processing.
-func (s *JobService) CreateJob(clientID string, request *dto.JobRequest) (*model.Job, error) {
+// Returns PayloadTooLargeError if the payload is over MAX_PAYLOAD_BYTES,
+// PayloadValidationError if the payload is malformed for its type,
+// JobRejectedError if the registered enricher refuses the job,
+// DuplicateJobError if a client-supplied job ID is already taken, or
+// JobTypeUnavailableError if the type is being shed (see TypeCircuitBreaker).
+// The job's trace continues the one in traceCtx, if any (see config.ExtractHTTPTraceContext).
+func (s *JobService) CreateJob(traceCtx context.Context, clientID string, request *dto.JobRequest) (*model.Job, error) {
 	log.Printf("Creating new job for client: %s, type: %s", clientID, request.Type)
 
-	now := time.Now()
-	job := &model.Job{
-		ID:         uuid.New(),
-		ClientID:   clientID,
-		Type:       request.Type,
-		Status:     model.StatusPending,
-		Payload:    request.Payload,
-		Attempts:   0,
-		MaxRetries: 3,
-		CreatedAt:  now,
-		ScheduledAt: &now, // Schedule immediately
+	// Scheduler and worker spans link to this one via TraceParent
+	spanCtx, span := config.Tracer().Start(traceCtx, "job.create",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("job.client_id", clientID)))
+	defer span.End()
+
+	if err := s.validateRequest(clientID, request); err != nil {
+		span.SetStatus(codes.Error, "invalid job request")
+		return nil, err
+	}
+	if err := s.checkNotShed(request.Type); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
 	}
 
-	if err := s.jobRepository.Save(job); err != nil {
+	job, err := s.buildJob(spanCtx, clientID, request)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("job.id", job.ID.String()), attribute.String("job.type", string(job.Type)))
+
+	if err := s.jobRepository.Create(job); err != nil {
+		// A concurrent create with the same ID wins the insert: report it as a duplicate
+		if dupErr := s.checkJobIDAvailable(clientID, job.ID); exception.IsDuplicateJobError(dupErr) {
+			return nil, dupErr
+		}
 		log.Printf("Failed to create job: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to create job")
 		return nil, err
 	}
 
@@ -51,6 +244,278 @@ func (s *JobService) CreateJob(clientID string, request *dto.JobRequest) (*model
 	return job, nil
 }
 
+// jobBatchInsertSize is how many rows CreateJobsBatch inserts per statement.
+const jobBatchInsertSize = 100
+
+// CreateJobsBatch creates a job for every valid request, saving them all in one transaction.
+//
+// Items are checked like CreateJob; refused items (invalid or oversized payload, duplicate or
+// repeated job ID, enricher rejection, shed type) are reported in the response by index.
+// In partial mode they don't stop the others; in atomic mode any refused item
+// saves nothing and the valid items are reported with 424. An empty mode picks the
+// default for the batch's types (see JobBatchRequest). A database error saves
+// nothing and is returned. The jobs' traces continue the one in traceCtx, if any.
+func (s *JobService) CreateJobsBatch(traceCtx context.Context, clientID string, requests []dto.JobRequest, mode string) (*dto.JobBatchResponse, error) {
+	if mode == "" {
+		mode = s.defaultBatchMode(requests)
+	}
+	log.Printf("Creating batch of %d jobs for client: %s (mode: %s)", len(requests), clientID, mode)
+
+	spanCtx, span := config.Tracer().Start(traceCtx, "job.create_batch",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("job.client_id", clientID),
+			attribute.Int("job.batch_size", len(requests))))
+	defer span.End()
+
+	response := &dto.JobBatchResponse{
+		Mode:    mode,
+		Items:   make([]dto.JobBatchItem, len(requests)),
+		Created: []dto.JobBatchCreated{},
+		Errors:  []dto.JobBatchItemError{},
+	}
+	refuse := func(i int, status int, msg string, fieldErrors map[string]string) {
+		response.Items[i] = dto.JobBatchItem{Index: i, Status: status, Error: msg, FieldErrors: fieldErrors}
+		response.Errors = append(response.Errors, dto.JobBatchItemError{Index: i, Error: msg, FieldErrors: fieldErrors})
+	}
+	jobs := make([]*model.Job, 0, len(requests))
+	indexes := make([]int, 0, len(requests))
+	seenIDs := make(map[uuid.UUID]int)
+
+	for i := range requests {
+		request := &requests[i]
+		if err := s.validateRequest(clientID, request); err != nil {
+			if exception.IsPayloadTooLargeError(err) {
+				refuse(i, http.StatusRequestEntityTooLarge, err.Error(), nil)
+				continue
+			}
+			var fieldErrors map[string]string
+			if validationErr, ok := err.(*exception.PayloadValidationError); ok {
+				fieldErrors = validationErr.FieldErrors
+			}
+			refuse(i, http.StatusBadRequest, "Payload validation failed", fieldErrors)
+			continue
+		}
+		if err := s.checkNotShed(request.Type); err != nil {
+			refuse(i, http.StatusServiceUnavailable, err.Error(), nil)
+			continue
+		}
+		if request.JobID != nil {
+			if first, repeated := seenIDs[*request.JobID]; repeated {
+				refuse(i, http.StatusConflict, fmt.Sprintf("jobId %s is already used by item %d", *request.JobID, first), nil)
+				continue
+			}
+			seenIDs[*request.JobID] = i
+		}
+
+		job, err := s.buildJob(spanCtx, clientID, request)
+		if err != nil {
+			status := http.StatusUnprocessableEntity
+			if exception.IsDuplicateJobError(err) {
+				status = http.StatusConflict
+			} else if !exception.IsJobRejectedError(err) {
+				log.Printf("Failed to check job batch item %d, nothing saved: %v", i, err)
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "failed to create job batch")
+				return nil, err
+			}
+			refuse(i, status, err.Error(), nil)
+			continue
+		}
+		jobs = append(jobs, job)
+		indexes = append(indexes, i)
+	}
+
+	// Atomic: the valid items depend on the refused ones, so none are saved
+	if mode == dto.JobBatchModeAtomic && len(response.Errors) > 0 {
+		for _, i := range indexes {
+			response.Items[i] = dto.JobBatchItem{
+				Index:  i,
+				Status: http.StatusFailedDependency,
+				Error:  fmt.Sprintf("not created: atomic batch refused because of item %d", response.Errors[0].Index),
+			}
+		}
+		log.Printf("Atomic job batch refused: clientId=%s, refused=%d, nothing saved", clientID, len(response.Errors))
+		return response, nil
+	}
+
+	if err := s.jobRepository.CreateBatch(jobs, jobBatchInsertSize); err != nil {
+		log.Printf("Failed to create job batch, nothing saved: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to create job batch")
+		return nil, err
+	}
+
+	for i, job := range jobs {
+		response.Created = append(response.Created, dto.JobBatchCreated{Index: indexes[i], JobID: job.ID, Status: job.Status})
+		response.Items[indexes[i]] = dto.JobBatchItem{Index: indexes[i], Status: http.StatusAccepted, JobID: &job.ID}
+	}
+	span.SetAttributes(attribute.Int("job.batch_created", len(jobs)))
+
+	log.Printf("Job batch created: clientId=%s, created=%d, refused=%d",
+		clientID, len(response.Created), len(response.Errors))
+	return response, nil
+}
+
+// defaultBatchMode returns the mode of a batch that didn't choose one: atomic when any
+// item's type is configured for atomic batches (JOB_BATCH_ATOMIC_<TYPE>), partial otherwise.
+func (s *JobService) defaultBatchMode(requests []dto.JobRequest) string {
+	for _, request := range requests {
+		if s.atomicBatchTypes[s.typeAliases.Resolve(request.Type)] {
+			return dto.JobBatchModeAtomic
+		}
+	}
+	return dto.JobBatchModePartial
+}
+
+// validateRequest resolves the request's type alias and checks it, returning
+// PayloadTooLargeError for a payload over MAX_PAYLOAD_BYTES, else
+// PayloadValidationError with every problem found.
+func (s *JobService) validateRequest(clientID string, request *dto.JobRequest) error {
+	// New jobs are stored under the type's current name
+	request.Type = s.typeAliases.Resolve(request.Type)
+
+	// Refused before validating its fields: a huge payload isn't worth parsing
+	if len(request.Payload) > s.maxPayloadBytes {
+		log.Printf("Job payload too large: clientId=%s, type=%s, bytes=%d", clientID, request.Type, len(request.Payload))
+		return exception.NewPayloadTooLargeError(len(request.Payload), s.maxPayloadBytes)
+	}
+
+	// Catch malformed payloads now rather than at processing time
+	fieldErrors := s.validator.Validate(request.Type, request.Payload)
+	if request.Type == "" {
+		fieldErrors["type"] = "is required"
+	}
+	if request.Type == model.TypeHealthCheck && !s.healthCheckJobs {
+		fieldErrors["type"] = "HEALTH_CHECK jobs are disabled"
+	}
+	// Only CompensateJob creates refunds: it checks the payment was charged and links
+	// the refund to it, so a payment is refunded at most once
+	if request.Type == model.TypeRefund {
+		fieldErrors["type"] = "REFUND jobs are created by compensating a payment"
+	}
+	if request.Payload == "" {
+		fieldErrors["payload"] = "is required"
+	}
+	for field, msg := range s.labelValidator.Validate(request.Labels) {
+		fieldErrors[field] = msg
+	}
+	if request.JobID != nil && !s.clientJobIDs {
+		fieldErrors["jobId"] = "client-supplied job IDs are disabled"
+	}
+	if request.MaxRetries != nil && (*request.MaxRetries < model.MinMaxRetries || *request.MaxRetries > model.MaxMaxRetries) {
+		fieldErrors["maxRetries"] = fmt.Sprintf("must be between %d and %d", model.MinMaxRetries, model.MaxMaxRetries)
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		fieldErrors["expiresAt"] = "must be in the future"
+	}
+	if len(fieldErrors) > 0 {
+		log.Printf("Job payload invalid: clientId=%s, type=%s, errors=%v", clientID, request.Type, fieldErrors)
+		return exception.NewPayloadValidationError(fieldErrors)
+	}
+	return nil
+}
+
+// checkNotShed returns JobTypeUnavailableError if new jobs of the type are being shed.
+func (s *JobService) checkNotShed(jobType model.JobType) error {
+	if retryAfter, shed := s.typeBreaker.Shedding(jobType); shed {
+		log.Printf("Shedding new %s job: downstream circuit breaker open (retry after %v)", jobType, retryAfter)
+		return exception.NewJobTypeUnavailableError(jobType, retryAfter)
+	}
+	return nil
+}
+
+// buildJob creates the PENDING job for a validated request, traced under spanCtx and
+// enriched, without saving it. Returns DuplicateJobError, JobRejectedError, or the
+// error looking up a client-supplied job ID.
+func (s *JobService) buildJob(spanCtx context.Context, clientID string, request *dto.JobRequest) (*model.Job, error) {
+	priority, maxRetries := s.resolveSettings(clientID, request)
+
+	jobID := uuid.New()
+	if request.JobID != nil {
+		jobID = *request.JobID
+		if err := s.checkJobIDAvailable(clientID, jobID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	job := &model.Job{
+		ID:          jobID,
+		ClientID:    clientID,
+		Type:        request.Type,
+		Status:      model.StatusPending,
+		Payload:     request.Payload,
+		Attempts:    0,
+		Priority:    priority,
+		MaxRetries:  maxRetries,
+		Labels:      request.Labels,
+		CreatedAt:   now,
+		ScheduledAt: &now, // Schedule immediately
+		ExpiresAt:   request.ExpiresAt,
+	}
+	if job.ExpiresAt == nil && s.defaultJobTTL > 0 {
+		expiresAt := now.Add(s.defaultJobTTL)
+		job.ExpiresAt = &expiresAt
+	}
+	if traceParent := config.InjectTraceParent(spanCtx); traceParent != "" {
+		job.TraceParent = &traceParent
+	}
+
+	// Deployment-specific enrichment runs before the job is persisted
+	if err := s.enricher.Enrich(ctx, job); err != nil {
+		log.Printf("Job rejected by enricher: clientId=%s, type=%s, reason=%v", clientID, request.Type, err)
+		return nil, exception.NewJobRejectedError(err.Error())
+	}
+	return job, nil
+}
+
+// checkJobIDAvailable returns DuplicateJobError if a job with the ID already exists,
+// carrying its status only when it is clientID's own job so another client's jobs can't
+// be probed, or the lookup's error when it fails for any reason but not found.
+func (s *JobService) checkJobIDAvailable(clientID string, jobID uuid.UUID) error {
+	existing, err := s.jobRepository.FindByID(jobID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Job ID already taken: id=%s, status=%s, clientId=%s", jobID, existing.Status, clientID)
+	if existing.ClientID != clientID {
+		return exception.NewJobIDTakenError()
+	}
+	return exception.NewDuplicateJobError(existing.ID, existing.Status)
+}
+
+// resolveSettings returns the job's priority and max retries:
+// request value > client default > type default (max retries only) > global default.
+func (s *JobService) resolveSettings(clientID string, request *dto.JobRequest) (int, int) {
+	priority := model.DefaultPriority
+	maxRetries := model.DefaultMaxRetries
+	if typeDefault, ok := s.typeMaxRetries[request.Type]; ok {
+		maxRetries = typeDefault
+	}
+
+	if s.clientDefaults != nil && (request.Priority == nil || request.MaxRetries == nil) {
+		defaults := s.clientDefaults.GetDefaults(clientID)
+		if defaults.Priority != nil {
+			priority = *defaults.Priority
+		}
+		if defaults.MaxRetries != nil {
+			maxRetries = *defaults.MaxRetries
+		}
+	}
+
+	if request.Priority != nil {
+		priority = *request.Priority
+	}
+	if request.MaxRetries != nil {
+		maxRetries = *request.MaxRetries
+	}
+	return priority, maxRetries
+}
+
 // GetJob retrieves a job by its ID.
 // Returns JobNotFoundError if the job does not exist.
 func (s *JobService) GetJob(jobID uuid.UUID) (*model.Job, error) {
@@ -64,6 +529,33 @@ func (s *JobService) GetJob(jobID uuid.UUID) (*model.Job, error) {
 	return job, nil
 }
 
+// GetClientJob retrieves a job by its ID, as long as it belongs to clientID.
+// Returns JobNotFoundError if the job does not exist or is another client's, so job
+// IDs of other clients are never confirmed.
+func (s *JobService) GetClientJob(clientID string, jobID uuid.UUID) (*model.Job, error) {
+	job, err := s.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.ClientID != clientID {
+		log.Printf("Job %s requested by client %s, but belongs to another client", jobID, clientID)
+		return nil, exception.NewJobNotFoundError(jobID)
+	}
+	return job, nil
+}
+
+// GetJobAttempts returns the job's processing attempts, oldest first, or
+// JobNotFoundError if there is no such job of clientID's. Empty without a JobAttemptRepository.
+func (s *JobService) GetJobAttempts(clientID string, jobID uuid.UUID) ([]model.JobAttempt, error) {
+	if _, err := s.GetClientJob(clientID, jobID); err != nil {
+		return nil, err
+	}
+	if s.attempts == nil {
+		return []model.JobAttempt{}, nil
+	}
+	return s.attempts.FindByJobID(jobID)
+}
+
 // GetJobsByClient returns all jobs for a specific client.
 // Useful for client-specific analytics and tracking.
 func (s *JobService) GetJobsByClient(clientID string) ([]model.Job, error) {
@@ -71,6 +563,45 @@ func (s *JobService) GetJobsByClient(clientID string) ([]model.Job, error) {
 	return s.jobRepository.FindByClientID(clientID)
 }
 
+// GetJobsPage returns one page (0-based) of a client's jobs, newest first, and the total
+// number of matching jobs. With labels, only jobs carrying all of them match and an empty
+// clientID matches every client; a non-nil status narrows to that status.
+func (s *JobService) GetJobsPage(clientID string, labels model.JobLabels, status *model.JobStatus, page, size int) ([]model.Job, int64, error) {
+	log.Printf("Retrieving jobs: clientId=%s, labels=%v, page=%d, size=%d", clientID, labels, page, size)
+	offset := page * size
+	if len(labels) > 0 {
+		return s.jobRepository.FindByLabelsPaged(clientID, labels, status, offset, size)
+	}
+	return s.jobRepository.FindByClientIDPaged(clientID, status, offset, size)
+}
+
+// GetDeadLetterPage returns one page (0-based) of a client's dead-lettered jobs, most
+// recent first, and the total number of matching jobs. A non-nil jobType or since narrows
+// the listing.
+func (s *JobService) GetDeadLetterPage(clientID string, jobType *model.JobType, since *time.Time, page, size int) ([]model.Job, int64, error) {
+	log.Printf("Retrieving dead letters: clientId=%s, type=%v, since=%v, page=%d, size=%d", clientID, jobType, since, page, size)
+	return s.jobRepository.FindDeadLetter(clientID, jobType, since, page*size, size)
+}
+
+// SearchJobs returns one page (0-based) of the criteria's client's jobs matching criteria,
+// newest first, and the total number of matching jobs. The criteria's offset and limit
+// are set from page and size.
+func (s *JobService) SearchJobs(criteria repository.SearchCriteria, page, size int) ([]model.Job, int64, error) {
+	// The search terms are left out: payloads carry customer emails
+	log.Printf("Searching jobs: clientId=%s, from=%v, to=%v, status=%v, page=%d, size=%d",
+		criteria.ClientID, criteria.From, criteria.To, criteria.Status, page, size)
+	criteria.Offset = page * size
+	criteria.Limit = size
+	return s.jobRepository.Search(criteria)
+}
+
+// GetSLABreaches returns up to limit jobs that completed past their type's SLA,
+// most recently completed first. A non-nil jobType narrows the listing.
+func (s *JobService) GetSLABreaches(jobType *model.JobType, limit int) ([]model.Job, error) {
+	log.Printf("Retrieving SLA breaches: type=%v, limit=%d", jobType, limit)
+	return s.jobRepository.FindSLABreached(jobType, limit)
+}
+
 // GetJobsByStatus returns all jobs with a specific status.
 // Useful for monitoring and dashboards.
 func (s *JobService) GetJobsByStatus(status model.JobStatus) ([]model.Job, error) {
@@ -90,15 +621,17 @@ func (s *JobService) UpdateJobStatus(jobID uuid.UUID, newStatus model.JobStatus)
 	}
 
 	oldStatus := job.Status
-	job.Status = newStatus
+	err = updateJobWithRetry(s.jobRepository, job, func(job *model.Job) bool {
+		job.Status = newStatus
 
-	// If job is completed or moved to dead letter, set completion timestamp
-	if newStatus == model.StatusCompleted || newStatus == model.StatusDeadLetter {
-		now := time.Now()
-		job.CompletedAt = &now
-	}
-
-	if err := s.jobRepository.Save(job); err != nil {
+		// If job is completed or moved to dead letter, set completion timestamp
+		if newStatus == model.StatusCompleted || newStatus == model.StatusDeadLetter {
+			now := time.Now()
+			job.CompletedAt = &now
+		}
+		return true
+	})
+	if err != nil {
 		log.Printf("Failed to update job status: %v", err)
 		return nil, err
 	}
@@ -111,13 +644,135 @@ func (s *JobService) UpdateJobStatus(jobID uuid.UUID, newStatus model.JobStatus)
 
 // CountJobsByStatus returns the count of jobs by status.
 // Useful for dashboard metrics.
-func (s *JobService) CountJobsByStatus(status model.JobStatus) int64 {
+//
+// A failed query is returned as an error rather than a zero count, so an
+// unreachable database can't pass for a healthy, empty system.
+func (s *JobService) CountJobsByStatus(status model.JobStatus) (int64, error) {
 	count, err := s.jobRepository.CountByStatus(status)
 	if err != nil {
 		log.Printf("Error counting jobs by status %s: %v", status, err)
-		return 0
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountJobsByTypeAndStatus returns the number of jobs per type and status.
+// Every type except HEALTH_CHECK is present with every status, with 0 when no
+// job has it, so dashboards see a stable shape.
+func (s *JobService) CountJobsByTypeAndStatus() (map[model.JobType]map[model.JobStatus]int64, error) {
+	counts, err := s.jobRepository.CountByTypeAndStatus()
+	if err != nil {
+		log.Printf("Error counting jobs by type and status: %v", err)
+		return nil, err
+	}
+	for _, spec := range model.JobTypeSpecs() {
+		if spec.Type == model.TypeHealthCheck {
+			continue
+		}
+		if counts[spec.Type] == nil {
+			counts[spec.Type] = make(map[model.JobStatus]int64)
+		}
+		for _, status := range model.JobStatuses() {
+			if _, ok := counts[spec.Type][status]; !ok {
+				counts[spec.Type][status] = 0
+			}
+		}
+	}
+	return counts, nil
+}
+
+// CountDeadLettersByReason returns the number of DEAD_LETTER jobs per failure reason.
+// Every reason is present, with 0 when no job has it.
+func (s *JobService) CountDeadLettersByReason() (map[model.FailureReason]int64, error) {
+	counts, err := s.jobRepository.CountDeadLetteredByFailureReason()
+	if err != nil {
+		log.Printf("Error counting dead letters by failure reason: %v", err)
+		return nil, err
+	}
+	for _, reason := range model.FailureReasons() {
+		if _, ok := counts[reason]; !ok {
+			counts[reason] = 0
+		}
+	}
+	return counts, nil
+}
+
+// HealthCheckJobsEnabled reports whether HEALTH_CHECK canary jobs are accepted.
+func (s *JobService) HealthCheckJobsEnabled() bool {
+	return s.healthCheckJobs
+}
+
+// LastHealthCheckCompletedAt returns when the latest HEALTH_CHECK canary completed, nil if none has.
+func (s *JobService) LastHealthCheckCompletedAt() (*time.Time, error) {
+	return s.jobRepository.FindLatestCompletedAt(model.TypeHealthCheck)
+}
+
+// ReportLocation returns the default timezone of GetDailyReport.
+func (s *JobService) ReportLocation() *time.Location {
+	return s.reportLocation
+}
+
+// GetDailyReport counts, per job type, the jobs that completed, failed, or were
+// dead-lettered on the calendar day of date in loc. Every supported type is listed.
+//
+// The day runs from local midnight to the next local midnight, so it is 23 or 25
+// hours long across a DST change.
+func (s *JobService) GetDailyReport(date time.Time, loc *time.Location) (*dto.DailyJobReport, error) {
+	year, month, day := date.In(loc).Date()
+	from := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 0, 1)
+
+	counts, err := s.jobRepository.CountFinishedByTypeBetween(from.UTC(), to.UTC(), []model.JobStatus{
+		model.StatusCompleted,
+		model.StatusFailed,
+		model.StatusDeadLetter,
+	})
+	if err != nil {
+		log.Printf("Error building daily report for %s: %v", from.Format("2006-01-02"), err)
+		return nil, err
+	}
+
+	// Every supported type in spec order, then any retired type that still finished jobs
+	var order []model.JobType
+	rows := make(map[model.JobType]*dto.DailyJobTypeCounts)
+	addRow := func(jobType model.JobType) *dto.DailyJobTypeCounts {
+		row := &dto.DailyJobTypeCounts{Type: jobType}
+		order = append(order, jobType)
+		rows[jobType] = row
+		return row
+	}
+	for _, spec := range model.JobTypeSpecs() {
+		if spec.Type != model.TypeHealthCheck {
+			addRow(spec.Type)
+		}
+	}
+
+	for _, count := range counts {
+		row, ok := rows[count.Type]
+		if !ok {
+			row = addRow(count.Type)
+		}
+		switch count.Status {
+		case model.StatusCompleted:
+			row.Completed += count.Count
+		case model.StatusFailed:
+			row.Failed += count.Count
+		case model.StatusDeadLetter:
+			row.DeadLettered += count.Count
+		}
+	}
+
+	report := &dto.DailyJobReport{
+		Date:     from.Format("2006-01-02"),
+		Timezone: loc.String(),
+		From:     from,
+		To:       to,
+		Types:    make([]dto.DailyJobTypeCounts, 0, len(order)),
 	}
-	return count
+	for _, jobType := range order {
+		report.Types = append(report.Types, *rows[jobType])
+	}
+	return report, nil
 }
 
 // FindJobsReadyForScheduling finds jobs that are ready to be scheduled.
@@ -127,12 +782,245 @@ func (s *JobService) FindJobsReadyForScheduling() ([]model.Job, error) {
 	return s.jobRepository.FindByStatusAndScheduledAtBefore(
 		model.StatusPending,
 		time.Now(),
+		0,
 	)
 }
 
+// replayBatchSize is how many jobs are cloned per insert when replaying a range.
+const replayBatchSize = 500
+
+// DefaultReplaySpread returns how long replays are spread over when the request
+// doesn't say: REPLAY_SPREAD_SECONDS, default 10 minutes.
+func DefaultReplaySpread() time.Duration {
+	spread := 10 * time.Minute
+	if val := os.Getenv("REPLAY_SPREAD_SECONDS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			spread = time.Duration(parsed) * time.Second
+		}
+	}
+	return spread
+}
+
+// ReplayCompletedJobs re-runs every COMPLETED job of a type completed in [from, to),
+// e.g. after a downstream silently dropped an hour of emails. Returns the number replayed.
+//
+// Jobs are cloned, not reset, so the original completion records stay intact:
+// - Fresh ID, PENDING, attempts reset; payload, client, priority and max retries kept
+// - The charged flag is kept, so replaying a payment never charges the customer twice
+// - Scheduled evenly over spread to avoid a surge on the downstream
+func (s *JobService) ReplayCompletedJobs(jobType model.JobType, from time.Time, to time.Time, spread time.Duration) (int, error) {
+	total, err := s.jobRepository.CountCompletedByTypeBetween(jobType, from, to)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Replaying %d completed %s jobs from %v to %v over %v", total, jobType, from, to, spread)
+	if total == 0 {
+		return 0, nil
+	}
+
+	start := time.Now()
+	interval := spread / time.Duration(total)
+	replayed := 0
+
+	for offset := 0; ; offset += replayBatchSize {
+		originals, err := s.jobRepository.FindCompletedByTypeBetween(jobType, from, to, offset, replayBatchSize)
+		if err != nil {
+			return replayed, err
+		}
+		if len(originals) == 0 {
+			break
+		}
+
+		clones := make([]model.Job, len(originals))
+		for i, original := range originals {
+			scheduledAt := start.Add(time.Duration(replayed+i) * interval)
+			clones[i] = model.Job{
+				ID:          uuid.New(),
+				ClientID:    original.ClientID,
+				Type:        original.Type,
+				Status:      model.StatusPending,
+				Payload:     original.Payload,
+				Attempts:    0,
+				Priority:    original.Priority,
+				MaxRetries:  original.MaxRetries,
+				Charged:     original.Charged,
+				CreatedAt:   start,
+				ScheduledAt: &scheduledAt,
+			}
+		}
+
+		if err := s.jobRepository.CreateAll(clones); err != nil {
+			log.Printf("Replay failed after %d jobs: %v", replayed, err)
+			return replayed, err
+		}
+		replayed += len(clones)
+
+		if len(originals) < replayBatchSize {
+			break
+		}
+	}
+
+	log.Printf("Replayed %d completed %s jobs", replayed, jobType)
+	return replayed, nil
+}
+
+// CompensateJob enqueues the job undoing a COMPLETED job, built by its type's
+// CompensationHandler (e.g. a REFUND of a payment) and linked to it by CompensatesJobID.
+//
+// A job is compensated at most once: if it already was, the existing compensating job
+// is returned with created false. Returns JobNotFoundError if the job does not exist or
+// isn't clientID's, or InvalidJobStateError if it isn't COMPLETED, its type has no
+// CompensationHandler, or the handler refuses it.
+func (s *JobService) CompensateJob(clientID string, jobID uuid.UUID) (*model.Job, bool, error) {
+	job, err := s.GetClientJob(clientID, jobID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing, err := s.jobRepository.FindByCompensatesJobID(jobID)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	handler, ok := s.compensators[job.Type]
+	if !ok || job.Status != model.StatusCompleted {
+		return nil, false, exception.NewInvalidJobStateError(jobID, job.Status, "compensated")
+	}
+	compensation, err := handler.Compensate(job)
+	if err != nil {
+		return nil, false, err
+	}
+	compensation.CompensatesJobID = &job.ID
+
+	if err := s.jobRepository.Create(compensation); err != nil {
+		// Lost a race with a concurrent compensation: its job is the one that counts
+		if existing, findErr := s.jobRepository.FindByCompensatesJobID(jobID); findErr == nil && existing != nil {
+			return existing, false, nil
+		}
+		log.Printf("Failed to save compensation of job %s: %v", jobID, err)
+		return nil, false, err
+	}
+
+	log.Printf("Job compensated: id=%s, type=%s, compensatingJob=%s (%s)", jobID, job.Type, compensation.ID, compensation.Type)
+	return compensation, true, nil
+}
+
+// RetryJob requeues a DEAD_LETTER or FAILED job for a fresh set of attempts.
+// A payment dead-lettered as needs_reconciliation is taken as reconciled by the
+// operator, so its next attempt charges (see markPaymentReconciled).
+// Returns JobNotFoundError if the job does not exist or isn't clientID's, or
+// InvalidJobStateError if it is in any other status (PENDING, RUNNING, COMPLETED).
+func (s *JobService) RetryJob(clientID string, jobID uuid.UUID) (*model.Job, error) {
+	job, err := s.GetClientJob(clientID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status != model.StatusDeadLetter && job.Status != model.StatusFailed {
+		return nil, exception.NewInvalidJobStateError(jobID, job.Status, "retried")
+	}
+
+	// Marked before requeueing, so the retried attempt is sure to see it
+	if err := markPaymentReconciled(s.cacheService, job); err != nil {
+		log.Printf("Failed to mark job %s reconciled: %v", jobID, err)
+		return nil, err
+	}
+
+	oldStatus := job.Status
+	now := time.Now()
+	err = updateJobWithRetry(s.jobRepository, job, func(job *model.Job) bool {
+		// Rechecked on a reload: the job may have been retried already
+		if job.Status != model.StatusDeadLetter && job.Status != model.StatusFailed {
+			return false
+		}
+		job.Status = model.StatusPending
+		job.Attempts = 0
+		job.ScheduledAt = &now
+		job.ErrorMessage = nil
+		job.FailureReason = nil
+		job.CompletedAt = nil
+		return true
+	})
+	if errors.Is(err, repository.ErrStaleJob) {
+		return nil, exception.NewInvalidJobStateError(jobID, job.Status, "retried")
+	}
+	if err != nil {
+		log.Printf("Failed to retry job %s: %v", jobID, err)
+		return nil, err
+	}
+	if s.cacheService != nil {
+		s.cacheService.InvalidateJob(jobID, job.ClientID)
+	}
+
+	log.Printf("Job requeued for retry: id=%s, oldStatus=%s", jobID, oldStatus)
+	return job, nil
+}
+
+// MaxReplayBatch returns the most dead letters one ReplayDeadLetter call requeues.
+func (s *JobService) MaxReplayBatch() int {
+	return s.maxReplayBatch
+}
+
+// ReplayDeadLetter requeues up to limit DEAD_LETTER jobs, oldest dead letter first and
+// optionally only of one type, like RetryJob does for one job: PENDING, attempts reset,
+// scheduled now, and payments needing reconciliation marked reconciled. All are reset
+// in a single transaction. Returns the number replayed.
+//
+// limit is capped at MAX_REPLAY_BATCH; 0 or less means MAX_REPLAY_BATCH, so an outage's
+// worth of dead letters goes back in batches instead of flooding the downstream at once.
+func (s *JobService) ReplayDeadLetter(typ *model.JobType, limit int) (int, error) {
+	if limit <= 0 || limit > s.maxReplayBatch {
+		limit = s.maxReplayBatch
+	}
+
+	requeued, err := s.jobRepository.RequeueDeadLetter(typ, limit, time.Now())
+	if err != nil {
+		log.Printf("Failed to replay dead letters: type=%v, limit=%d: %v", typ, limit, err)
+		return 0, err
+	}
+	for i := range requeued {
+		if err := markPaymentReconciled(s.cacheService, &requeued[i]); err != nil {
+			log.Printf("Failed to mark replayed job %s reconciled: %v", requeued[i].ID, err)
+		}
+		if s.cacheService != nil {
+			s.cacheService.InvalidateJob(requeued[i].ID, requeued[i].ClientID)
+		}
+	}
+
+	log.Printf("Replayed %d dead-lettered jobs: type=%v, limit=%d", len(requeued), typ, limit)
+	return len(requeued), nil
+}
+
 // FindStuckJobs finds jobs that appear to be stuck (running for too long).
-// These jobs may need manual intervention.
-func (s *JobService) FindStuckJobs(minutes int) ([]model.Job, error) {
-	threshold := time.Now().Add(-time.Duration(minutes) * time.Minute)
-	return s.jobRepository.FindStuckJobs(model.StatusRunning, threshold)
-}
\ No newline at end of file
+// These jobs may need manual intervention; the scheduler's reaper separately
+// requeues jobs not updated for STUCK_JOB_THRESHOLD.
+//
+// A job is stuck when a worker has been processing it for longer than its type's
+// processing time × STUCK_JOB_FACTOR (default 5), measured from processingStartedAt.
+// RUNNING jobs no worker has picked up yet (processingStartedAt is NULL, still in
+// Kafka) are included once published more than STUCK_JOB_UNSTARTED_MINUTES ago
+// (default 10); 0 leaves them out.
+func (s *JobService) FindStuckJobs() ([]model.Job, error) {
+	now := time.Now()
+	var stuck []model.Job
+	for _, spec := range model.JobTypeSpecs() {
+		maxProcessing := time.Duration(spec.ProcessingTimeMs) * time.Millisecond * time.Duration(s.stuckFactor)
+		jobs, err := s.jobRepository.FindStuckJobs(spec.Type, now.Add(-maxProcessing))
+		if err != nil {
+			return nil, err
+		}
+		stuck = append(stuck, jobs...)
+	}
+
+	if s.unstartedTimeout > 0 {
+		jobs, err := s.jobRepository.FindUnstartedJobs(now.Add(-s.unstartedTimeout))
+		if err != nil {
+			return nil, err
+		}
+		stuck = append(stuck, jobs...)
+	}
+	return stuck, nil
+}
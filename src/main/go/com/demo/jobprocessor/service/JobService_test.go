@@ -0,0 +1,1313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/dto"
+	"distributed-job-processor/exception"
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+type rejectingEnricher struct{}
+
+func (rejectingEnricher) Enrich(ctx context.Context, job *model.Job) error {
+	return errors.New("unsupported region")
+}
+
+// TestCreateJobRejectedByEnricher verifies an enricher error rejects the job before it is saved.
+func TestCreateJobRejectedByEnricher(t *testing.T) {
+	// No repository: reaching Create would panic, proving the job is never persisted
+	s := NewJobService(nil)
+	s.SetEnricher(rejectingEnricher{})
+
+	job, err := s.CreateJob(context.Background(), "customer-1", &dto.JobRequest{
+		Type:    model.TypePaymentProcess,
+		Payload: "order_1|user@email.com|$10.00",
+	})
+
+	if job != nil {
+		t.Fatalf("expected no job, got %+v", job)
+	}
+	if !exception.IsJobRejectedError(err) {
+		t.Fatalf("expected JobRejectedError, got %v", err)
+	}
+}
+
+// TestSetEnricherNilRestoresNoop verifies the default no-op enricher is used when nil is registered.
+func TestSetEnricherNilRestoresNoop(t *testing.T) {
+	s := NewJobService(nil)
+	s.SetEnricher(nil)
+
+	if _, ok := s.enricher.(NoopJobEnricher); !ok {
+		t.Fatalf("expected NoopJobEnricher, got %T", s.enricher)
+	}
+}
+
+// TestReplayCompletedJobsClonesRange verifies only completed jobs of the type in the window are
+// cloned, the originals are untouched, and the clones are spread out.
+func TestReplayCompletedJobsClonesRange(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	windowStart := time.Now().Add(-2 * time.Hour)
+	seed := func(jobType model.JobType, status model.JobStatus, completedAt time.Time) *model.Job {
+		job := model.NewJob("customer-1", jobType, "order_1|user@email.com|receipt")
+		job.Status = status
+		job.CompletedAt = &completedAt
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		return job
+	}
+	inRange := seed(model.TypeEmailConfirmation, model.StatusCompleted, windowStart.Add(10*time.Minute))
+	seed(model.TypeEmailConfirmation, model.StatusCompleted, windowStart.Add(20*time.Minute))
+	seed(model.TypeEmailConfirmation, model.StatusCompleted, windowStart.Add(90*time.Minute)) // after the window
+	seed(model.TypeEmailConfirmation, model.StatusDeadLetter, windowStart.Add(10*time.Minute))
+	seed(model.TypePaymentProcess, model.StatusCompleted, windowStart.Add(10*time.Minute))
+
+	replayed, err := s.ReplayCompletedJobs(model.TypeEmailConfirmation, windowStart, windowStart.Add(time.Hour), time.Minute)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if replayed != 2 {
+		t.Fatalf("expected 2 replays, got %d", replayed)
+	}
+
+	pending, _ := repo.FindByStatus(model.StatusPending)
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 pending clones, got %d", len(pending))
+	}
+	for _, clone := range pending {
+		if clone.ID == inRange.ID || clone.Attempts != 0 || clone.Payload != inRange.Payload {
+			t.Fatalf("unexpected clone: %+v", clone)
+		}
+	}
+	if pending[0].ScheduledAt.Equal(*pending[1].ScheduledAt) {
+		t.Fatal("expected clones spread over the window")
+	}
+
+	original, _ := repo.FindByID(inRange.ID)
+	if original.Status != model.StatusCompleted {
+		t.Fatalf("original must stay COMPLETED, got %s", original.Status)
+	}
+}
+
+// TestFindStuckJobsUsesProcessingStart verifies stuck detection measures from when the worker
+// started, per job type, regardless of updatedAt, and includes long-unstarted jobs.
+func TestFindStuckJobsUsesProcessingStart(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	seed := func(jobType model.JobType, startedAgo *time.Duration) *model.Job {
+		job := model.NewJob("customer-1", jobType, "order_1|user@email.com|receipt")
+		job.Status = model.StatusRunning
+		if startedAgo != nil {
+			startedAt := time.Now().Add(-*startedAgo)
+			job.ProcessingStartedAt = &startedAt
+		}
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		return job
+	}
+	ago := func(d time.Duration) *time.Duration { return &d }
+
+	// EMAIL_CONFIRMATION takes 1s, so with factor 5 anything over 5s is stuck;
+	// updatedAt was just bumped by the save, which must not hide it
+	stuckEmail := seed(model.TypeEmailConfirmation, ago(6*time.Second))
+	seed(model.TypeEmailConfirmation, ago(3*time.Second))
+	// PAYMENT_PROCESS takes 2s, so 6s is still within its 10s allowance
+	seed(model.TypePaymentProcess, ago(6*time.Second))
+	// Still in Kafka, published just now
+	seed(model.TypePaymentProcess, nil)
+
+	stuck, err := s.FindStuckJobs()
+	if err != nil {
+		t.Fatalf("find stuck: %v", err)
+	}
+	if len(stuck) != 1 || stuck[0].ID != stuckEmail.ID {
+		t.Fatalf("expected only %s to be stuck, got %+v", stuckEmail.ID, stuck)
+	}
+
+	s.unstartedTimeout = time.Nanosecond
+	stuck, _ = s.FindStuckJobs()
+	if len(stuck) != 2 {
+		t.Fatalf("expected the unstarted job to be included, got %d", len(stuck))
+	}
+}
+
+// TestCreateJobStoresLabels verifies labels are persisted and read back, and oversized labels are rejected.
+func TestCreateJobStoresLabels(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	request := &dto.JobRequest{
+		Type:    model.TypeEmailConfirmation,
+		Payload: "order_1|user@email.com|receipt",
+		Labels:  model.JobLabels{"region": "eu-west", "campaign": "black-friday"},
+	}
+	job, err := s.CreateJob(context.Background(), "customer-1", request)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	stored, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if stored.Labels["region"] != "eu-west" || stored.Labels["campaign"] != "black-friday" {
+		t.Fatalf("labels not round-tripped: %v", stored.Labels)
+	}
+
+	request.Labels = model.JobLabels{"bad key": "x"}
+	if _, err := s.CreateJob(context.Background(), "customer-1", request); !exception.IsPayloadValidationError(err) {
+		t.Fatalf("expected PayloadValidationError, got %v", err)
+	}
+}
+
+// TestCreateJobAppliesDefaultTTL verifies jobs without expiresAt get DEFAULT_JOB_TTL,
+// an explicit expiresAt wins, and one in the past is rejected.
+func TestCreateJobAppliesDefaultTTL(t *testing.T) {
+	t.Setenv("DEFAULT_JOB_TTL", "2h")
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	request := &dto.JobRequest{Type: model.TypePaymentProcess, Payload: "order_1|user@email.com|$10.00"}
+	job, err := s.CreateJob(context.Background(), "customer-1", request)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	stored, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if stored.ExpiresAt == nil || !stored.ExpiresAt.Equal(stored.CreatedAt.Add(2*time.Hour)) {
+		t.Fatalf("expected expiresAt two hours after creation, got %v (created %v)", stored.ExpiresAt, stored.CreatedAt)
+	}
+
+	deadline := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	request.ExpiresAt = &deadline
+	job, err = s.CreateJob(context.Background(), "customer-1", request)
+	if err != nil || job.ExpiresAt == nil || !job.ExpiresAt.Equal(deadline) {
+		t.Fatalf("expected the requested expiresAt, got %v (%v)", job, err)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	request.ExpiresAt = &past
+	if _, err := s.CreateJob(context.Background(), "customer-1", request); !exception.IsPayloadValidationError(err) {
+		t.Fatalf("expected PayloadValidationError for a past expiresAt, got %v", err)
+	}
+}
+
+// TestHealthCheckJobsGatedAndKeptOutOfStats verifies HEALTH_CHECK jobs are refused unless
+// enabled, and never show up in job statistics or the dead-letter breakdown.
+func TestHealthCheckJobsGatedAndKeptOutOfStats(t *testing.T) {
+	repo := newTestRepository(t)
+	request := &dto.JobRequest{Type: model.TypeHealthCheck, Payload: "probe_1"}
+
+	if _, err := NewJobService(repo).CreateJob(context.Background(), "monitor", request); !exception.IsPayloadValidationError(err) {
+		t.Fatalf("expected HEALTH_CHECK to be refused by default, got %v", err)
+	}
+
+	t.Setenv("HEALTH_CHECK_JOBS_ENABLED", "true")
+	s := NewJobService(repo)
+	if _, err := s.CreateJob(context.Background(), "monitor", request); err != nil {
+		t.Fatalf("create health check: %v", err)
+	}
+	canary := model.NewJob("monitor", model.TypeHealthCheck, "probe_2")
+	canary.Status = model.StatusDeadLetter
+	if err := repo.Create(canary); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := s.CreateJob(context.Background(), "customer-1", &dto.JobRequest{Type: model.TypeEmailConfirmation, Payload: "order_1|user@email.com|receipt"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	pending, err := s.CountJobsByStatus(model.StatusPending)
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if pending != 1 {
+		t.Fatalf("expected only the real job to be counted, got %d", pending)
+	}
+	reasons, err := s.CountDeadLettersByReason()
+	if err != nil {
+		t.Fatalf("count dead letters: %v", err)
+	}
+	for reason, n := range reasons {
+		if n != 0 {
+			t.Fatalf("expected the dead-lettered canary not to be counted, got %d %s", n, reason)
+		}
+	}
+}
+
+// TestGetJobsPageFiltersAndPages verifies paging is newest first and the total ignores the page.
+func TestGetJobsPageFiltersAndPages(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+		job.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		if i%2 == 0 {
+			job.Status = model.StatusFailed
+		}
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	other := model.NewJob("customer-2", model.TypeEmailConfirmation, "order_2|user@email.com|receipt")
+	if err := repo.Create(other); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	jobs, total, err := s.GetJobsPage("customer-1", nil, nil, 1, 2)
+	if err != nil {
+		t.Fatalf("page: %v", err)
+	}
+	if total != 5 || len(jobs) != 2 {
+		t.Fatalf("expected 2 of 5 jobs, got %d of %d", len(jobs), total)
+	}
+	if !jobs[0].CreatedAt.Equal(base.Add(2*time.Minute)) || !jobs[1].CreatedAt.Equal(base.Add(time.Minute)) {
+		t.Fatalf("expected second page newest first, got %v, %v", jobs[0].CreatedAt, jobs[1].CreatedAt)
+	}
+
+	failed := model.StatusFailed
+	jobs, total, err = s.GetJobsPage("customer-1", nil, &failed, 0, 20)
+	if err != nil {
+		t.Fatalf("page: %v", err)
+	}
+	if total != 3 || len(jobs) != 3 {
+		t.Fatalf("expected 3 FAILED jobs, got %d of %d", len(jobs), total)
+	}
+	for _, job := range jobs {
+		if job.Status != model.StatusFailed {
+			t.Fatalf("unexpected status %s", job.Status)
+		}
+	}
+}
+
+// TestCountJobsByStatusReportsQueryErrors verifies a failed count isn't reported as zero.
+func TestCountJobsByStatusReportsQueryErrors(t *testing.T) {
+	db := newTestDB(t)
+	s := NewJobService(repository.NewJobRepository(db))
+
+	if count, err := s.CountJobsByStatus(model.StatusPending); err != nil || count != 0 {
+		t.Fatalf("expected 0 with no error, got %d, %v", count, err)
+	}
+
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	if _, err := s.CountJobsByStatus(model.StatusPending); err == nil {
+		t.Fatal("expected an error once the database is unreachable")
+	}
+}
+
+// TestCountDeadLettersByReason verifies dead letters are grouped by reason, legacy rows count as unknown,
+// and every reason is reported.
+func TestCountDeadLettersByReason(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	timeout := model.FailureTimeout
+	for _, reason := range []*model.FailureReason{&timeout, &timeout, nil} {
+		job := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+		job.Status = model.StatusDeadLetter
+		job.FailureReason = reason
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	failed := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	failed.Status = model.StatusFailed
+	failed.FailureReason = &timeout
+	if err := repo.Create(failed); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	counts, err := s.CountDeadLettersByReason()
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if counts[model.FailureTimeout] != 2 || counts[model.FailureUnknown] != 1 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+	if len(counts) != len(model.FailureReasons()) {
+		t.Fatalf("expected every reason to be reported, got %v", counts)
+	}
+}
+
+// TestCountJobsByTypeAndStatus verifies jobs are counted per type and status, canaries
+// are left out, and every type is reported with every status.
+func TestCountJobsByTypeAndStatus(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	seed := []struct {
+		jobType model.JobType
+		status  model.JobStatus
+	}{
+		{model.TypePaymentProcess, model.StatusCompleted},
+		{model.TypePaymentProcess, model.StatusCompleted},
+		{model.TypePaymentProcess, model.StatusFailed},
+		{model.TypePaymentProcess, model.StatusPending},
+		{model.TypeEmailConfirmation, model.StatusCompleted},
+		{model.TypeEmailConfirmation, model.StatusDeadLetter},
+		{model.TypeHealthCheck, model.StatusCompleted},
+	}
+	for _, job := range seed {
+		j := model.NewJob("customer-1", job.jobType, "order_1|user@email.com|$10.00")
+		j.Status = job.status
+		if err := repo.Create(j); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	counts, err := s.CountJobsByTypeAndStatus()
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	payments, emails := counts[model.TypePaymentProcess], counts[model.TypeEmailConfirmation]
+	if payments[model.StatusCompleted] != 2 || payments[model.StatusFailed] != 1 || payments[model.StatusPending] != 1 {
+		t.Fatalf("unexpected payment counts: %v", payments)
+	}
+	if emails[model.StatusCompleted] != 1 || emails[model.StatusDeadLetter] != 1 || emails[model.StatusFailed] != 0 {
+		t.Fatalf("unexpected email counts: %v", emails)
+	}
+	if _, ok := counts[model.TypeHealthCheck]; ok {
+		t.Fatalf("expected HEALTH_CHECK canaries not to be counted, got %v", counts)
+	}
+	for jobType, byStatus := range counts {
+		if len(byStatus) != len(model.JobStatuses()) {
+			t.Fatalf("expected every status reported for %s, got %v", jobType, byStatus)
+		}
+	}
+}
+
+// TestGetDeadLetterPageFilters verifies the dead-letter listing only returns DEAD_LETTER jobs,
+// of the client, most recent first, narrowed by type and by a since time window.
+func TestGetDeadLetterPageFilters(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+	now := time.Now().UTC()
+
+	seed := func(jobType model.JobType, status model.JobStatus, deadLetteredAgo time.Duration) *model.Job {
+		job := model.NewJob("customer-1", jobType, "order_1|user@email.com|$10.00")
+		job.Status = status
+		completedAt := now.Add(-deadLetteredAgo)
+		job.CompletedAt = &completedAt
+		message := "declined"
+		job.ErrorMessage = &message
+		job.Attempts = job.MaxRetries
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		return job
+	}
+	oldPayment := seed(model.TypePaymentProcess, model.StatusDeadLetter, 48*time.Hour)
+	recentPayment := seed(model.TypePaymentProcess, model.StatusDeadLetter, time.Hour)
+	recentEmail := seed(model.TypeEmailConfirmation, model.StatusDeadLetter, 2*time.Hour)
+	seed(model.TypePaymentProcess, model.StatusFailed, time.Hour)
+	otherClient := model.NewJob("customer-2", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	otherClient.Status = model.StatusDeadLetter
+	otherClient.CompletedAt = &now
+	if err := repo.Create(otherClient); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	all, total, err := s.GetDeadLetterPage("customer-1", nil, nil, 0, 20)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if total != 3 || len(all) != 3 || all[0].ID != recentPayment.ID || all[1].ID != recentEmail.ID || all[2].ID != oldPayment.ID {
+		t.Fatalf("expected the 3 dead letters most recent first, got %d of %d", len(all), total)
+	}
+	if all[0].ErrorMessage == nil || *all[0].ErrorMessage != "declined" || all[0].Attempts != all[0].MaxRetries {
+		t.Fatalf("expected error message and attempts for triage, got %+v", all[0])
+	}
+
+	paymentType := model.TypePaymentProcess
+	payments, total, err := s.GetDeadLetterPage("customer-1", &paymentType, nil, 0, 20)
+	if err != nil {
+		t.Fatalf("list by type: %v", err)
+	}
+	if total != 2 || len(payments) != 2 || payments[0].ID != recentPayment.ID || payments[1].ID != oldPayment.ID {
+		t.Fatalf("expected the 2 payment dead letters, got %d of %d", len(payments), total)
+	}
+
+	since := now.Add(-24 * time.Hour)
+	recent, total, err := s.GetDeadLetterPage("customer-1", &paymentType, &since, 0, 20)
+	if err != nil {
+		t.Fatalf("list by type and since: %v", err)
+	}
+	if total != 1 || len(recent) != 1 || recent[0].ID != recentPayment.ID {
+		t.Fatalf("expected only the recent payment dead letter, got %d of %d", len(recent), total)
+	}
+
+	page, total, err := s.GetDeadLetterPage("customer-1", nil, &since, 1, 1)
+	if err != nil {
+		t.Fatalf("list second page: %v", err)
+	}
+	if total != 2 || len(page) != 1 || page[0].ID != recentEmail.ID {
+		t.Fatalf("expected the email dead letter on page 1, got %d of %d", len(page), total)
+	}
+}
+
+// TestSearchJobsCombinesPayloadAndDateRange verifies a payload substring and a creation
+// window narrow one client's search together, an order ID prefix matches only the start
+// of the payload, and LIKE wildcards in the term match literally.
+func TestSearchJobsCombinesPayloadAndDateRange(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+	now := time.Now().UTC()
+
+	seed := func(clientID string, jobType model.JobType, orderID string, createdAgo time.Duration) *model.Job {
+		job := model.NewJob(clientID, jobType, orderID+"|user@email.com|$10.00")
+		job.CreatedAt = now.Add(-createdAgo)
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		return job
+	}
+	payment := seed("customer-1", model.TypePaymentProcess, "order_ORD12345", 24*time.Hour)
+	email := seed("customer-1", model.TypeEmailConfirmation, "order_ORD12345", 23*time.Hour)
+	seed("customer-1", model.TypePaymentProcess, "order_ORD12345", 72*time.Hour) // before the window
+	seed("customer-1", model.TypePaymentProcess, "order_ORD99999", 24*time.Hour)
+	seed("customer-2", model.TypePaymentProcess, "order_ORD12345", 24*time.Hour)
+
+	from, to := now.Add(-48*time.Hour), now
+	criteria := repository.SearchCriteria{ClientID: "customer-1", PayloadContains: "ORD12345", From: &from, To: &to}
+	found, total, err := s.SearchJobs(criteria, 0, 20)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if total != 2 || len(found) != 2 || found[0].ID != email.ID || found[1].ID != payment.ID {
+		t.Fatalf("expected the 2 recent ORD12345 jobs newest first, got %d of %d", len(found), total)
+	}
+
+	pending := model.StatusPending
+	criteria.Status = &pending
+	page, total, err := s.SearchJobs(criteria, 1, 1)
+	if err != nil {
+		t.Fatalf("search second page: %v", err)
+	}
+	if total != 2 || len(page) != 1 || page[0].ID != payment.ID {
+		t.Fatalf("expected the payment job on page 1, got %d of %d", len(page), total)
+	}
+
+	otherClient, total, err := s.SearchJobs(repository.SearchCriteria{ClientID: "customer-2", PayloadContains: "ORD12345", From: &from}, 0, 20)
+	if err != nil {
+		t.Fatalf("search another client: %v", err)
+	}
+	if total != 1 || len(otherClient) != 1 || otherClient[0].ClientID != "customer-2" {
+		t.Fatalf("expected only customer-2's ORD12345 job, got %d of %d", len(otherClient), total)
+	}
+
+	byOrder, total, err := s.SearchJobs(repository.SearchCriteria{ClientID: "customer-1", OrderIDPrefix: "order_ORD1"}, 0, 20)
+	if err != nil {
+		t.Fatalf("search by order ID prefix: %v", err)
+	}
+	if total != 3 || len(byOrder) != 3 {
+		t.Fatalf("expected customer-1's 3 order_ORD1... jobs, got %d of %d", len(byOrder), total)
+	}
+	midPayload, total, err := s.SearchJobs(repository.SearchCriteria{ClientID: "customer-1", OrderIDPrefix: "ORD12345"}, 0, 20)
+	if err != nil {
+		t.Fatalf("search by order ID mid-payload: %v", err)
+	}
+	if total != 0 || len(midPayload) != 0 {
+		t.Fatalf("expected the order ID prefix anchored at the payload start, got %d of %d", len(midPayload), total)
+	}
+
+	wildcard, total, err := s.SearchJobs(repository.SearchCriteria{ClientID: "customer-1", PayloadContains: "ORD_2345"}, 0, 20)
+	if err != nil {
+		t.Fatalf("search with wildcard: %v", err)
+	}
+	if total != 0 || len(wildcard) != 0 {
+		t.Fatalf("expected _ to match literally, got %d of %d", len(wildcard), total)
+	}
+}
+
+// TestGetDailyReportUsesLocalDayBoundaries verifies the report day runs from local midnight
+// to local midnight and counts outcomes per type.
+func TestGetDailyReportUsesLocalDayBoundaries(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+	est := time.FixedZone("EST", -5*60*60)
+
+	seed := func(jobType model.JobType, status model.JobStatus, completedAt time.Time) {
+		job := model.NewJob("customer-1", jobType, "order_1|user@email.com|$10.00")
+		job.Status = status
+		completedAt = completedAt.UTC()
+		job.CompletedAt = &completedAt
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	seed(model.TypePaymentProcess, model.StatusCompleted, time.Date(2024, 1, 14, 23, 30, 0, 0, est)) // previous local day
+	seed(model.TypePaymentProcess, model.StatusCompleted, time.Date(2024, 1, 15, 0, 30, 0, 0, est))
+	seed(model.TypePaymentProcess, model.StatusCompleted, time.Date(2024, 1, 15, 23, 59, 0, 0, est)) // already Jan 16 in UTC
+	seed(model.TypePaymentProcess, model.StatusDeadLetter, time.Date(2024, 1, 15, 12, 0, 0, 0, est))
+	seed(model.TypeEmailConfirmation, model.StatusFailed, time.Date(2024, 1, 15, 12, 0, 0, 0, est))
+
+	report, err := s.GetDailyReport(time.Date(2024, 1, 15, 0, 0, 0, 0, est), est)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if report.Date != "2024-01-15" || !report.From.Equal(time.Date(2024, 1, 15, 5, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected day: %s from %v", report.Date, report.From)
+	}
+
+	counts := make(map[model.JobType]dto.DailyJobTypeCounts)
+	for _, row := range report.Types {
+		counts[row.Type] = row
+	}
+	if payment := counts[model.TypePaymentProcess]; payment.Completed != 2 || payment.DeadLettered != 1 || payment.Failed != 0 {
+		t.Fatalf("unexpected PAYMENT_PROCESS counts: %+v", payment)
+	}
+	if email := counts[model.TypeEmailConfirmation]; email.Failed != 1 || email.Completed != 0 {
+		t.Fatalf("unexpected EMAIL_CONFIRMATION counts: %+v", email)
+	}
+}
+
+// TestRepositoryCreateNeverOverwrites verifies Create refuses an existing ID instead of updating the row.
+func TestRepositoryCreateNeverOverwrites(t *testing.T) {
+	repo := newTestRepository(t)
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	duplicate := *job
+	duplicate.Payload = "order_2|other@email.com|receipt"
+	if err := repo.Create(&duplicate); err == nil {
+		t.Fatal("expected creating an existing ID to fail")
+	}
+
+	stored, _ := repo.FindByID(job.ID)
+	if stored.Payload != job.Payload {
+		t.Fatalf("existing job was overwritten: %s", stored.Payload)
+	}
+}
+
+// TestCreateJobEnforcesMaxPayloadBytes verifies a payload of exactly MAX_PAYLOAD_BYTES is
+// accepted and one byte more is refused with PayloadTooLargeError, alone or in a batch.
+func TestCreateJobEnforcesMaxPayloadBytes(t *testing.T) {
+	t.Setenv("MAX_PAYLOAD_BYTES", "64")
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	prefix := "order_1|user@email.com|https://receipts.example.com/"
+	borderline := prefix + strings.Repeat("r", 64-len(prefix))
+	job, err := s.CreateJob(context.Background(), "customer-1", &dto.JobRequest{Type: model.TypeEmailConfirmation, Payload: borderline})
+	if err != nil {
+		t.Fatalf("expected a %d-byte payload accepted, got %v", len(borderline), err)
+	}
+	if stored, _ := repo.FindByID(job.ID); stored == nil || stored.Payload != borderline {
+		t.Fatal("expected the borderline payload saved in full")
+	}
+
+	oversized := borderline + "r"
+	_, err = s.CreateJob(context.Background(), "customer-1", &dto.JobRequest{Type: model.TypeEmailConfirmation, Payload: oversized})
+	if !exception.IsPayloadTooLargeError(err) {
+		t.Fatalf("expected PayloadTooLargeError for %d bytes, got %v", len(oversized), err)
+	}
+
+	response, err := s.CreateJobsBatch(context.Background(), "customer-1", []dto.JobRequest{
+		{Type: model.TypeEmailConfirmation, Payload: borderline},
+		{Type: model.TypeEmailConfirmation, Payload: oversized},
+	}, dto.JobBatchModePartial)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if response.Items[0].Status != http.StatusAccepted || response.Items[1].Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 202 and 413, got %d and %d", response.Items[0].Status, response.Items[1].Status)
+	}
+}
+
+// TestRepositoryUpdateJobSafeDetectsConcurrentSave verifies the second of two saves of
+// copies loaded at the same version fails with ErrStaleJob instead of overwriting the first.
+func TestRepositoryUpdateJobSafeDetectsConcurrentSave(t *testing.T) {
+	repo := newTestRepository(t)
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	scheduler, _ := repo.FindByID(job.ID)
+	worker, _ := repo.FindByID(job.ID)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	start := make(chan struct{})
+	for i, loaded := range []*model.Job{scheduler, worker} {
+		wg.Add(1)
+		go func(i int, loaded *model.Job) {
+			defer wg.Done()
+			<-start
+			loaded.Status = model.StatusRunning
+			loaded.Attempts = i + 1
+			errs[i] = repo.UpdateJobSafe(loaded)
+		}(i, loaded)
+	}
+	close(start)
+	wg.Wait()
+
+	var won, lost int
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			won = i
+		case errors.Is(err, repository.ErrStaleJob):
+			lost++
+		default:
+			t.Fatalf("save %d: unexpected error %v", i, err)
+		}
+	}
+	if lost != 1 {
+		t.Fatalf("expected exactly one save to detect the conflict, got errors %v", errs)
+	}
+
+	stored, _ := repo.FindByID(job.ID)
+	if stored.Version != 1 || stored.Attempts != won+1 {
+		t.Fatalf("expected the winning save stored at version 1, got attempts=%d version=%d", stored.Attempts, stored.Version)
+	}
+	loser := []*model.Job{scheduler, worker}[1-won]
+	if loser.Version != 0 {
+		t.Fatalf("expected the stale copy to keep its loaded version, got %d", loser.Version)
+	}
+}
+
+// TestConfigureDBPoolFromEnv verifies the pool limits are applied to the sql.DB and
+// its stats are exposed on GET /metrics.
+func TestConfigureDBPoolFromEnv(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "7")
+	t.Setenv("DB_MAX_IDLE_CONNS", "3")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "5m")
+	db := newTestDB(t)
+
+	if err := repository.ConfigureDBPoolFromEnv(db); err != nil {
+		t.Fatalf("configure pool: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	if got := sqlDB.Stats().MaxOpenConnections; got != 7 {
+		t.Fatalf("expected max open connections 7, got %d", got)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	config.RegisterMetricsRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(w.Body.String(), "\ndb_max_open_connections 7") {
+		t.Fatalf("expected pool stats in exposition, got:\n%s", w.Body.String())
+	}
+}
+
+// TestRepositoryUpdateRequiresExistingRow verifies UpdateJobSafe changes an existing job
+// but never inserts a job that was deleted or never created.
+func TestRepositoryUpdateRequiresExistingRow(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	createdAt := job.CreatedAt
+
+	updated, err := s.UpdateJobStatus(job.ID, model.StatusCompleted)
+	if err != nil {
+		t.Fatalf("update status: %v", err)
+	}
+	stored, _ := repo.FindByID(job.ID)
+	if stored.Status != model.StatusCompleted || stored.CompletedAt == nil {
+		t.Fatalf("status not updated: %+v", stored)
+	}
+	if !stored.CreatedAt.Equal(createdAt) {
+		t.Fatalf("createdAt changed from %v to %v", createdAt, stored.CreatedAt)
+	}
+
+	if err := repo.Delete(updated); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := repo.UpdateJobSafe(updated); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected ErrRecordNotFound for a deleted job, got %v", err)
+	}
+	if _, err := repo.FindByID(job.ID); err == nil {
+		t.Fatal("update resurrected a deleted job")
+	}
+
+	never := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_3|user@email.com|receipt")
+	if err := repo.UpdateJobSafe(never); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected ErrRecordNotFound for an unsaved job, got %v", err)
+	}
+}
+
+// TestRetryJobRequeuesDeadLetter verifies a dead-lettered job is reset for a fresh set of attempts.
+func TestRetryJobRequeuesDeadLetter(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	completedAt := time.Now()
+	errMsg := "smtp unavailable"
+	job.Status = model.StatusDeadLetter
+	job.Attempts = 3
+	job.CompletedAt = &completedAt
+	job.ErrorMessage = &errMsg
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	if _, err := s.RetryJob("customer-1", job.ID); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+
+	saved, _ := repo.FindByID(job.ID)
+	if saved.Status != model.StatusPending || saved.Attempts != 0 || saved.ErrorMessage != nil || saved.CompletedAt != nil {
+		t.Fatalf("job not reset: %+v", saved)
+	}
+	if saved.ScheduledAt == nil || saved.ScheduledAt.Before(completedAt) {
+		t.Fatalf("expected job scheduled now, got %v", saved.ScheduledAt)
+	}
+}
+
+// TestRetryJobRejectsInvalidStates verifies only DEAD_LETTER and FAILED jobs can be retried.
+func TestRetryJobRejectsInvalidStates(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	for _, status := range []model.JobStatus{model.StatusPending, model.StatusRunning, model.StatusCompleted} {
+		t.Run(string(status), func(t *testing.T) {
+			job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+			job.Status = status
+			if err := repo.Create(job); err != nil {
+				t.Fatalf("seed job: %v", err)
+			}
+
+			if _, err := s.RetryJob("customer-1", job.ID); !exception.IsInvalidJobStateError(err) {
+				t.Fatalf("expected InvalidJobStateError, got %v", err)
+			}
+			saved, _ := repo.FindByID(job.ID)
+			if saved.Status != status {
+				t.Fatalf("status changed to %s", saved.Status)
+			}
+		})
+	}
+
+	if _, err := s.RetryJob("customer-1", uuid.New()); !exception.IsJobNotFoundError(err) {
+		t.Fatalf("expected JobNotFoundError for unknown job, got %v", err)
+	}
+
+	deadLettered := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	deadLettered.Status = model.StatusDeadLetter
+	if err := repo.Create(deadLettered); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	if _, err := s.RetryJob("customer-2", deadLettered.ID); !exception.IsJobNotFoundError(err) {
+		t.Fatalf("expected JobNotFoundError retrying another client's job, got %v", err)
+	}
+}
+
+// seedDeadLetters saves n DEAD_LETTER jobs of a type, dead-lettered an hour ago.
+func seedDeadLetters(t *testing.T, repo *repository.JobRepository, jobType model.JobType, n int) []*model.Job {
+	t.Helper()
+	jobs := make([]*model.Job, n)
+	for i := range jobs {
+		job := model.NewJob("customer-1", jobType, "order_1|user@email.com|receipt")
+		completedAt := time.Now().Add(-time.Hour)
+		errMsg := "downstream unavailable"
+		job.Status = model.StatusDeadLetter
+		job.Attempts = 3
+		job.CompletedAt = &completedAt
+		job.ErrorMessage = &errMsg
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		jobs[i] = job
+	}
+	return jobs
+}
+
+// TestReplayDeadLetterRequeuesOnlyTheType verifies a type filter leaves other types dead-lettered.
+func TestReplayDeadLetterRequeuesOnlyTheType(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+	payments := seedDeadLetters(t, repo, model.TypePaymentProcess, 2)
+	emails := seedDeadLetters(t, repo, model.TypeEmailConfirmation, 1)
+
+	jobType := model.TypePaymentProcess
+	replayed, err := s.ReplayDeadLetter(&jobType, 10)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if replayed != 2 {
+		t.Fatalf("expected 2 replayed, got %d", replayed)
+	}
+
+	for _, job := range payments {
+		saved, _ := repo.FindByID(job.ID)
+		if saved.Status != model.StatusPending || saved.Attempts != 0 || saved.ErrorMessage != nil || saved.CompletedAt != nil {
+			t.Fatalf("payment not reset: %+v", saved)
+		}
+		if saved.ScheduledAt == nil || time.Since(*saved.ScheduledAt) > time.Minute {
+			t.Fatalf("expected payment scheduled now, got %v", saved.ScheduledAt)
+		}
+	}
+	if saved, _ := repo.FindByID(emails[0].ID); saved.Status != model.StatusDeadLetter {
+		t.Fatalf("expected email left dead-lettered, got %s", saved.Status)
+	}
+}
+
+// TestReplayDeadLetterCapsAtMaxReplayBatch verifies an unfiltered replay takes every type
+// but never more than MAX_REPLAY_BATCH jobs, whatever limit is asked for.
+func TestReplayDeadLetterCapsAtMaxReplayBatch(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+	s.maxReplayBatch = 3
+	seedDeadLetters(t, repo, model.TypePaymentProcess, 2)
+	seedDeadLetters(t, repo, model.TypeEmailConfirmation, 2)
+
+	replayed, err := s.ReplayDeadLetter(nil, 100)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if replayed != 3 {
+		t.Fatalf("expected replay capped at 3, got %d", replayed)
+	}
+	if left, _ := repo.CountByStatus(model.StatusDeadLetter); left != 1 {
+		t.Fatalf("expected 1 job left dead-lettered, got %d", left)
+	}
+
+	if replayed, _ := s.ReplayDeadLetter(nil, 0); replayed != 1 {
+		t.Fatalf("expected the last dead letter replayed, got %d", replayed)
+	}
+	if replayed, _ := s.ReplayDeadLetter(nil, 0); replayed != 0 {
+		t.Fatalf("expected nothing left to replay, got %d", replayed)
+	}
+}
+
+// TestCreateJobRejectsDuplicateClientID verifies a client-supplied ID that is already taken
+// returns DuplicateJobError with the existing status and leaves the existing job untouched.
+func TestCreateJobRejectsDuplicateClientID(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	jobID := uuid.New()
+	request := &dto.JobRequest{
+		JobID:   &jobID,
+		Type:    model.TypeEmailConfirmation,
+		Payload: "order_1|user@email.com|receipt",
+	}
+	job, err := s.CreateJob(context.Background(), "customer-1", request)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if job.ID != jobID {
+		t.Fatalf("expected client-supplied ID %s, got %s", jobID, job.ID)
+	}
+	if _, err := s.UpdateJobStatus(jobID, model.StatusCompleted); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	request.Payload = "order_2|other@email.com|receipt"
+	_, err = s.CreateJob(context.Background(), "customer-1", request)
+	dupErr, ok := err.(*exception.DuplicateJobError)
+	if !ok {
+		t.Fatalf("expected DuplicateJobError, got %v", err)
+	}
+	if dupErr.Status != model.StatusCompleted {
+		t.Fatalf("expected existing status COMPLETED, got %s", dupErr.Status)
+	}
+
+	stored, _ := repo.FindByID(jobID)
+	if stored.Payload != "order_1|user@email.com|receipt" || stored.Status != model.StatusCompleted {
+		t.Fatalf("existing job was clobbered: %+v", stored)
+	}
+}
+
+// TestCreateJobHidesOtherClientsDuplicate verifies an ID taken by another client's job
+// is refused without revealing that job's ID or status.
+func TestCreateJobHidesOtherClientsDuplicate(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	jobID := uuid.New()
+	request := &dto.JobRequest{
+		JobID:   &jobID,
+		Type:    model.TypeEmailConfirmation,
+		Payload: "order_1|user@email.com|receipt",
+	}
+	if _, err := s.CreateJob(context.Background(), "customer-1", request); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	request.Payload = "order_2|other@email.com|receipt"
+	_, err := s.CreateJob(context.Background(), "customer-2", request)
+	dupErr, ok := err.(*exception.DuplicateJobError)
+	if !ok {
+		t.Fatalf("expected DuplicateJobError, got %v", err)
+	}
+	if dupErr.Status != "" || dupErr.JobID != uuid.Nil {
+		t.Fatalf("expected no details of another client's job, got %+v", dupErr)
+	}
+}
+
+// TestCreateJobsBatchReportsPerItemErrors verifies valid items are saved while invalid,
+// duplicate, and repeated-ID items are reported by index without failing the batch.
+func TestCreateJobsBatchReportsPerItemErrors(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	existing := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_0|user@email.com|receipt")
+	if err := repo.Create(existing); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	repeatedID := uuid.New()
+
+	response, err := s.CreateJobsBatch(context.Background(), "customer-1", []dto.JobRequest{
+		{Type: model.TypePaymentProcess, Payload: "order_1|user@email.com|$10.00"},
+		{Type: model.TypePaymentProcess, Payload: "order_2|user@email.com|free"},
+		{JobID: &existing.ID, Type: model.TypeEmailConfirmation, Payload: "order_3|user@email.com"},
+		{JobID: &repeatedID, Type: model.TypeEmailConfirmation, Payload: "order_4|user@email.com"},
+		{JobID: &repeatedID, Type: model.TypeEmailConfirmation, Payload: "order_5|user@email.com"},
+	}, "")
+	if err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+
+	if len(response.Created) != 2 || response.Created[0].Index != 0 || response.Created[1].Index != 3 {
+		t.Fatalf("expected items 0 and 3 created, got %+v", response.Created)
+	}
+	if response.Created[1].JobID != repeatedID {
+		t.Fatalf("expected the client-supplied ID to be kept, got %s", response.Created[1].JobID)
+	}
+	if len(response.Errors) != 3 {
+		t.Fatalf("expected 3 item errors, got %+v", response.Errors)
+	}
+	if e := response.Errors[0]; e.Index != 1 || e.FieldErrors["amount"] == "" {
+		t.Fatalf("expected an amount error for item 1, got %+v", e)
+	}
+	if e := response.Errors[1]; e.Index != 2 || e.Error == "" {
+		t.Fatalf("expected a duplicate error for item 2, got %+v", e)
+	}
+	if e := response.Errors[2]; e.Index != 4 || e.Error == "" {
+		t.Fatalf("expected a repeated ID error for item 4, got %+v", e)
+	}
+
+	for _, created := range response.Created {
+		saved, err := repo.FindByID(created.JobID)
+		if err != nil || saved.Status != model.StatusPending || saved.ClientID != "customer-1" {
+			t.Fatalf("expected PENDING job %s saved, got %+v, %v", created.JobID, saved, err)
+		}
+	}
+
+	wantStatuses := []int{http.StatusAccepted, http.StatusBadRequest, http.StatusConflict, http.StatusAccepted, http.StatusConflict}
+	if response.Mode != dto.JobBatchModePartial || len(response.Items) != len(wantStatuses) {
+		t.Fatalf("expected one partial-mode item per request, got %+v", response)
+	}
+	for i, item := range response.Items {
+		if item.Index != i || item.Status != wantStatuses[i] {
+			t.Fatalf("expected item %d with status %d, got %+v", i, wantStatuses[i], item)
+		}
+	}
+	if response.Items[0].JobID == nil || *response.Items[0].JobID != response.Created[0].JobID {
+		t.Fatalf("expected the created item to carry its job ID, got %+v", response.Items[0])
+	}
+}
+
+// TestCreateJobsBatchAtomicSavesNothingOnRefusal verifies an atomic batch with a refused
+// item creates nothing, reporting the valid items as 424, and that atomic is the default
+// for types configured with JOB_BATCH_ATOMIC_<TYPE>.
+func TestCreateJobsBatchAtomicSavesNothingOnRefusal(t *testing.T) {
+	t.Setenv("JOB_BATCH_ATOMIC_PAYMENT_PROCESS", "true")
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	response, err := s.CreateJobsBatch(context.Background(), "customer-1", []dto.JobRequest{
+		{Type: model.TypePaymentProcess, Payload: "order_1|user@email.com|$10.00"},
+		{Type: model.TypePaymentProcess, Payload: "order_2|user@email.com|free"},
+	}, "")
+	if err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+	if response.Mode != dto.JobBatchModeAtomic || len(response.Created) != 0 || len(response.Errors) != 1 {
+		t.Fatalf("expected an atomic batch refused whole, got %+v", response)
+	}
+	if response.Items[0].Status != http.StatusFailedDependency || response.Items[1].Status != http.StatusBadRequest {
+		t.Fatalf("expected statuses 424 and 400, got %+v", response.Items)
+	}
+	if total, _ := repo.CountByStatus(model.StatusPending); total != 0 {
+		t.Fatalf("expected nothing saved, found %d jobs", total)
+	}
+
+	// Email batches stay best effort, and a request can opt out of atomicity
+	response, err = s.CreateJobsBatch(context.Background(), "customer-1", []dto.JobRequest{
+		{Type: model.TypeEmailConfirmation, Payload: "order_3|user@email.com"},
+		{Type: model.TypePaymentProcess, Payload: "order_4|user@email.com|free"},
+	}, dto.JobBatchModePartial)
+	if err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+	if response.Mode != dto.JobBatchModePartial || len(response.Created) != 1 || response.Items[0].Status != http.StatusAccepted {
+		t.Fatalf("expected the valid item created in partial mode, got %+v", response)
+	}
+}
+
+// TestRepositoryCreateBatchRollsBack verifies a failing insert saves none of the batch,
+// including rows from statements that already succeeded.
+func TestRepositoryCreateBatchRollsBack(t *testing.T) {
+	repo := newTestRepository(t)
+
+	existing := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_0|user@email.com|receipt")
+	if err := repo.Create(existing); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	fresh := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	conflicting := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_2|user@email.com|receipt")
+	conflicting.ID = existing.ID
+
+	// One row per statement: the fresh row is inserted before the conflict fails
+	if err := repo.CreateBatch([]*model.Job{fresh, conflicting}, 1); err == nil {
+		t.Fatal("expected the conflicting insert to fail")
+	}
+	if _, err := repo.FindByID(fresh.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected the fresh row rolled back, got %v", err)
+	}
+}
+
+// TestRepositoryRejectsInconsistentAttempts verifies writes need 0 <= attempts <= maxRetries+1,
+// and rows already out of range are clamped when loaded.
+func TestRepositoryRejectsInconsistentAttempts(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewJobRepository(db)
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	job.MaxRetries = 3
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	for _, tc := range []struct {
+		attempts int
+		valid    bool
+	}{{-1, false}, {0, true}, {3, true}, {4, true}, {5, false}} {
+		job.Attempts = tc.attempts
+		err := repo.UpdateJobSafe(job)
+		if tc.valid && err != nil {
+			t.Fatalf("attempts %d: expected the update to be accepted, got %v", tc.attempts, err)
+		}
+		if !tc.valid && !errors.Is(err, model.ErrInconsistentAttempts) {
+			t.Fatalf("attempts %d: expected ErrInconsistentAttempts, got %v", tc.attempts, err)
+		}
+	}
+
+	invalid := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_2|user@email.com|receipt")
+	invalid.Attempts = -1
+	if err := repo.Create(invalid); !errors.Is(err, model.ErrInconsistentAttempts) {
+		t.Fatalf("expected the create to be rejected, got %v", err)
+	}
+
+	// Corrupted behind the repository's back: clamped to maxRetries+1 on read
+	if err := db.Model(&model.Job{}).Where("id = ?", job.ID).UpdateColumn("attempts", 9).Error; err != nil {
+		t.Fatalf("corrupt attempts: %v", err)
+	}
+	loaded, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if loaded.Attempts != 4 {
+		t.Fatalf("expected attempts clamped to 4, got %d", loaded.Attempts)
+	}
+	pending, err := repo.FindByStatus(model.StatusPending)
+	if err != nil || len(pending) != 1 || pending[0].Attempts != 4 {
+		t.Fatalf("expected list reads clamped too, got %+v, %v", pending, err)
+	}
+}
+
+// TestRepositoryAttemptsValidationDisabled verifies VALIDATE_JOB_ATTEMPTS=false turns the check off.
+func TestRepositoryAttemptsValidationDisabled(t *testing.T) {
+	t.Setenv("VALIDATE_JOB_ATTEMPTS", "false")
+	repo := newTestRepository(t)
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	job.Attempts = 9
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("expected the create to be accepted with validation disabled, got %v", err)
+	}
+	if loaded, _ := repo.FindByID(job.ID); loaded == nil || loaded.Attempts != 9 {
+		t.Fatalf("expected attempts left as stored, got %+v", loaded)
+	}
+}
+
+// TestRepositoryUpdateWithNilScheduledAt verifies saving a job whose ScheduledAt was
+// cleared in memory keeps the stored value instead of violating the NOT NULL column.
+func TestRepositoryUpdateWithNilScheduledAt(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewJobRepository(db)
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	scheduledAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	job.ScheduledAt = &scheduledAt
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	job.ScheduledAt = nil
+	job.Status = model.StatusRunning
+	if err := repo.UpdateJobSafe(job); err != nil {
+		t.Fatalf("expected the update to succeed, got %v", err)
+	}
+	stored, _ := repo.FindByID(job.ID)
+	if stored.Status != model.StatusRunning || stored.ScheduledAt == nil || !stored.ScheduledAt.Equal(scheduledAt) {
+		t.Fatalf("expected RUNNING with scheduledAt kept at %v, got %+v", scheduledAt, stored)
+	}
+
+	// Disabled: the update still succeeds, rescheduled to now
+	t.Setenv("PRESERVE_SCHEDULED_AT", "false")
+	repo = repository.NewJobRepository(db)
+	job.ScheduledAt = nil
+	if err := repo.UpdateJobSafe(job); err != nil {
+		t.Fatalf("expected the update to succeed with preservation disabled, got %v", err)
+	}
+	stored, _ = repo.FindByID(job.ID)
+	if stored.ScheduledAt == nil || !stored.ScheduledAt.Before(scheduledAt) {
+		t.Fatalf("expected scheduledAt reset to now, got %v", stored.ScheduledAt)
+	}
+}
+
+// TestClaimPendingJobsAreDisjoint verifies concurrent claims never hand out the same
+// job, and ReleaseClaims returns an unpublished claim to PENDING. SQLite serializes the
+// two transactions; on Postgres the same guarantee comes from FOR UPDATE SKIP LOCKED,
+// which needs a Postgres driver this module doesn't have.
+func TestClaimPendingJobsAreDisjoint(t *testing.T) {
+	repo := newTestRepository(t)
+	for i := 0; i < 20; i++ {
+		if err := repo.Create(model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	claims := make([][]model.Job, 2)
+	errs := make([]error, 2)
+	for i := range claims {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			claims[i], errs[i] = repo.ClaimPendingJobs(10)
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[uuid.UUID]bool)
+	for i, claimed := range claims {
+		if errs[i] != nil {
+			t.Fatalf("claim %d: %v", i, errs[i])
+		}
+		for _, job := range claimed {
+			if seen[job.ID] {
+				t.Fatalf("job %s claimed twice", job.ID)
+			}
+			seen[job.ID] = true
+		}
+	}
+	if len(seen) != 20 {
+		t.Fatalf("expected 20 jobs claimed, got %d", len(seen))
+	}
+
+	first := claims[0][0]
+	stored, err := repo.FindByID(first.ID)
+	if err != nil {
+		t.Fatalf("load job: %v", err)
+	}
+	if stored.Status != model.StatusRunning || stored.ProcessingStartedAt != nil || stored.Version != first.Version {
+		t.Fatalf("expected claimed job RUNNING, not started, version %d, got %s %v %d",
+			first.Version, stored.Status, stored.ProcessingStartedAt, stored.Version)
+	}
+
+	if err := repo.ReleaseClaims([]uuid.UUID{first.ID}); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	stored, _ = repo.FindByID(first.ID)
+	if stored.Status != model.StatusPending {
+		t.Fatalf("expected released job PENDING, got %s", stored.Status)
+	}
+}
+
+// TestCompensateJobCreatesLinkedRefund verifies compensating a completed payment
+// enqueues one REFUND linked to it, only for its own client, and jobs with nothing to
+// undo are refused, as are refunds created directly.
+func TestCompensateJobCreatesLinkedRefund(t *testing.T) {
+	repo := newTestRepository(t)
+	s := NewJobService(repo)
+
+	payment := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00|sku_1|qty_2")
+	payment.Status = model.StatusCompleted
+	payment.Charged = true
+	if err := repo.Create(payment); err != nil {
+		t.Fatalf("seed payment: %v", err)
+	}
+
+	if _, _, err := s.CompensateJob("customer-2", payment.ID); !exception.IsJobNotFoundError(err) {
+		t.Fatalf("expected JobNotFoundError compensating another client's job, got %v", err)
+	}
+	refund, created, err := s.CompensateJob("customer-1", payment.ID)
+	if err != nil {
+		t.Fatalf("compensate: %v", err)
+	}
+	if !created || refund.Type != model.TypeRefund || refund.Status != model.StatusPending {
+		t.Fatalf("expected a new PENDING refund, got created=%v %+v", created, refund)
+	}
+	stored, err := repo.FindByID(refund.ID)
+	if err != nil {
+		t.Fatalf("load refund: %v", err)
+	}
+	if stored.CompensatesJobID == nil || *stored.CompensatesJobID != payment.ID {
+		t.Fatalf("expected the refund linked to payment %s, got %v", payment.ID, stored.CompensatesJobID)
+	}
+	if want := "order_1|user@email.com|$10.00|" + payment.ID.String(); stored.Payload != want {
+		t.Fatalf("expected payload %q, got %q", want, stored.Payload)
+	}
+
+	again, created, err := s.CompensateJob("customer-1", payment.ID)
+	if err != nil || created || again.ID != refund.ID {
+		t.Fatalf("expected the existing refund back, got created=%v %+v, err %v", created, again, err)
+	}
+
+	uncharged := model.NewJob("customer-1", model.TypePaymentProcess, "order_2|user@email.com|$10.00")
+	uncharged.Status = model.StatusCompleted
+	email := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	email.Status = model.StatusCompleted
+	running := model.NewJob("customer-1", model.TypePaymentProcess, "order_3|user@email.com|$10.00")
+	running.Status = model.StatusRunning
+	running.Charged = true
+	for _, job := range []*model.Job{uncharged, email, running} {
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		if _, _, err := s.CompensateJob("customer-1", job.ID); !exception.IsInvalidJobStateError(err) {
+			t.Fatalf("expected InvalidJobStateError compensating %s %s job, got %v", job.Status, job.Type, err)
+		}
+	}
+
+	direct := &dto.JobRequest{Type: model.TypeRefund, Payload: "order_2|user@email.com|$10.00|" + uncharged.ID.String()}
+	if _, err := s.CreateJob(context.Background(), "customer-1", direct); !exception.IsPayloadValidationError(err) {
+		t.Fatalf("expected PayloadValidationError creating a REFUND directly, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+)
+
+// JobStateTable is a worker's in-memory copy of the compacted job state topic
+// (KAFKA_JOB_STATE_TOPIC=true, see config.GetJobStateTopicEnabled), so the common
+// path takes the job straight from memory instead of Redis or the database.
+//
+// Flow:
+// 1. The scheduler writes the job (key=jobId, value=job JSON, stored payload form)
+//    with a fresh version header, then publishes the job ID with the same header
+// 2. Every worker reads every partition of the state topic into the table
+// 3. On a job ID message, the worker takes the record whose version matches the
+//    message's and writes a tombstone, so the topic only holds jobs in flight
+// 4. No matching record (the table hasn't caught up, or the record was lost):
+//    the worker falls back to the cache and database, as in the default mode
+//
+// Tradeoffs against the default ID-only mode:
+// - Saves the cache/DB round-trip per job, but every worker holds every in-flight
+//   job in memory and reads the whole topic (all partitions) on startup
+// - The record is the job as of scheduling; changes made in the database after that
+//   (e.g. an admin status change) aren't seen by the worker, as with a stale cache
+//   entry. The version check only guarantees the record belongs to this publish
+// - Publishing costs a second Kafka write; if it fails the ID is still published,
+//   without a version, and the worker uses the default lookup
+// - A tombstone that lands after a retry's newer record deletes it; that retry
+//   just falls back to the default lookup
+// - Records never tombstoned (e.g. jobs whose ID message was lost) are dropped
+//   after KAFKA_JOB_STATE_RETENTION
+type JobStateTable struct {
+	readers    []*kafka.Reader
+	tombstones *kafka.Writer
+
+	mu      sync.Mutex
+	records map[uuid.UUID]jobStateRecord
+}
+
+// jobStateRecord is the latest state topic record of a job, decoded on take.
+type jobStateRecord struct {
+	version string
+	data    []byte
+}
+
+// jobStateRetryDelay is how long a state topic reader waits after a failed read.
+const jobStateRetryDelay = 1 * time.Second
+
+// NewJobStateTableFromEnv creates a JobStateTable reading the job state topic, or
+// returns nil when KAFKA_JOB_STATE_TOPIC isn't enabled.
+func NewJobStateTableFromEnv() *JobStateTable {
+	if !config.GetJobStateTopicEnabled() {
+		return nil
+	}
+	tombstones := config.NewKafkaJobStateWriter()
+	// Tombstones are best-effort and mustn't hold up processing
+	tombstones.Async = true
+	log.Printf("Worker reads job state from compacted topic %s", config.GetJobStateTopic())
+	return newJobStateTable(config.NewKafkaJobStateReaders(), tombstones)
+}
+
+func newJobStateTable(readers []*kafka.Reader, tombstones *kafka.Writer) *JobStateTable {
+	return &JobStateTable{
+		readers:    readers,
+		tombstones: tombstones,
+		records:    make(map[uuid.UUID]jobStateRecord),
+	}
+}
+
+// encodeJobState returns the state topic value of a job: the same JSON as its
+// cache entry, with large payloads compressed.
+func encodeJobState(job *model.Job, compressThreshold int) ([]byte, error) {
+	encoded, err := job.EncodedCopy(compressThreshold)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encoded)
+}
+
+// run reads every state topic partition into the table until stop is closed.
+func (t *JobStateTable) run(stop <-chan struct{}) {
+	for _, reader := range t.readers {
+		go t.readLoop(reader, stop)
+	}
+}
+
+func (t *JobStateTable) readLoop(reader *kafka.Reader, stop <-chan struct{}) {
+	for {
+		msg, err := reader.ReadMessage(context.Background())
+		if err != nil {
+			select {
+			case <-stop:
+				return
+			case <-time.After(jobStateRetryDelay):
+			}
+			log.Printf("Error reading job state partition %d: %v", reader.Config().Partition, err)
+			continue
+		}
+		t.apply(msg)
+	}
+}
+
+// apply stores a state topic record, or removes the job for a tombstone.
+func (t *JobStateTable) apply(msg kafka.Message) {
+	jobID, err := uuid.ParseBytes(msg.Key)
+	if err != nil {
+		log.Printf("Ignoring job state record with invalid key %q", msg.Key)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if msg.Value == nil {
+		delete(t.records, jobID)
+		return
+	}
+	t.records[jobID] = jobStateRecord{version: headerValue(msg, config.JobStateVersionHeader), data: msg.Value}
+}
+
+// Take removes and returns the job's record if it has the given version, writing
+// a tombstone for it. Returns nil (use the default lookup) when there is no such
+// record or the version is empty. A nil table never has records.
+func (t *JobStateTable) Take(jobID uuid.UUID, version string) *model.Job {
+	if t == nil || version == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	record, ok := t.records[jobID]
+	if ok && record.version == version {
+		delete(t.records, jobID)
+	}
+	t.mu.Unlock()
+	if !ok || record.version != version {
+		return nil
+	}
+
+	if t.tombstones != nil {
+		if err := t.tombstones.WriteMessages(context.Background(), kafka.Message{Key: []byte(jobID.String())}); err != nil {
+			log.Printf("Failed to tombstone job state of %s: %v", jobID, err)
+		}
+	}
+
+	job, err := decodeJobState(record.data)
+	if err != nil {
+		log.Printf("Error decoding job state of %s: %v", jobID, err)
+		return nil
+	}
+	return job
+}
+
+// decodeJobState parses a state topic value written by encodeJobState.
+func decodeJobState(data []byte) (*model.Job, error) {
+	var job model.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("deserializing job: %w", err)
+	}
+	if err := job.DecodePayloadInPlace(); err != nil {
+		return nil, fmt.Errorf("decompressing payload: %w", err)
+	}
+	return &job, nil
+}
+
+// Close closes the state topic readers and the tombstone writer.
+func (t *JobStateTable) Close() {
+	for _, reader := range t.readers {
+		closeReader(reader)
+	}
+	if t.tombstones != nil {
+		if err := t.tombstones.Close(); err != nil {
+			log.Printf("Error closing job state Kafka writer: %v", err)
+		}
+	}
+}
+
+// headerValue returns the value of the message's header with the given key, or "".
+func headerValue(msg kafka.Message, key string) string {
+	for _, header := range msg.Headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+)
+
+// stateRecord returns a job state topic record of job with the given version.
+func stateRecord(t *testing.T, job *model.Job, version string) kafka.Message {
+	t.Helper()
+	data, err := encodeJobState(job, 1024)
+	if err != nil {
+		t.Fatalf("encode job state: %v", err)
+	}
+	return kafka.Message{
+		Key:     []byte(job.ID.String()),
+		Value:   data,
+		Headers: []kafka.Header{{Key: config.JobStateVersionHeader, Value: []byte(version)}},
+	}
+}
+
+// TestJobStateTableTakesOnlyTheMatchingVersion verifies a worker only uses the record
+// written with the message it received, once, and that tombstones remove records.
+func TestJobStateTableTakesOnlyTheMatchingVersion(t *testing.T) {
+	table := newJobStateTable(nil, nil)
+
+	payload := "order_1|customer@email.com|" + strings.Repeat("<p>Thanks!</p>", 200)
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, payload)
+	job.Status = model.StatusRunning
+	table.apply(stateRecord(t, job, "v1"))
+
+	// A retry's message names a newer record the table hasn't read yet
+	if got := table.Take(job.ID, "v2"); got != nil {
+		t.Fatalf("expected no job for a version not read yet, got %+v", got)
+	}
+	if got := table.Take(job.ID, ""); got != nil {
+		t.Fatalf("expected no job for a message without a version, got %+v", got)
+	}
+
+	got := table.Take(job.ID, "v1")
+	if got == nil || got.ID != job.ID || got.Payload != payload || got.Status != model.StatusRunning {
+		t.Fatalf("expected the recorded job with its plain payload, got %+v", got)
+	}
+	if again := table.Take(job.ID, "v1"); again != nil {
+		t.Fatal("expected a record to be taken only once")
+	}
+
+	table.apply(stateRecord(t, job, "v2"))
+	table.apply(kafka.Message{Key: []byte(job.ID.String())})
+	if got := table.Take(job.ID, "v2"); got != nil {
+		t.Fatal("expected a tombstone to remove the record")
+	}
+
+	var disabled *JobStateTable
+	if got := disabled.Take(job.ID, "v1"); got != nil {
+		t.Fatal("expected a nil table to have no records")
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"distributed-job-processor/model"
+)
+
+// JobTypeAliases maps retired job type names to their current names, so a type
+// can be renamed without a big-bang migration of stored rows and queued messages.
+//
+// Configuration: JOB_TYPE_ALIASES=OLD=NEW[,OLD=NEW...], e.g. JOB_TYPE_ALIASES=PAYMENT=PAYMENT_PROCESS.
+// NEW must be a supported type (see model.JobTypeSpecs); OLD must not be one.
+//
+// Both sides consult it:
+// - JobService.CreateJob stores new jobs under the current name
+// - JobWorker processes old-named jobs (DB rows, in-flight messages) with the current
+//   handler, and the row is saved under the current name afterwards
+//
+// Every resolution through an alias is logged, so operators can see the old name
+// is still in circulation.
+//
+// Deprecation path for renaming OLD to NEW:
+// 1. Rename the type in model.JobType and the spec table, and deploy with OLD=NEW
+// 2. Clients move to NEW; old submissions keep working and are logged
+// 3. Once the logs are quiet and no unfinished jobs of type OLD remain, drop the alias
+type JobTypeAliases struct {
+	aliases map[model.JobType]model.JobType
+}
+
+// NewJobTypeAliasesFromEnv creates JobTypeAliases from JOB_TYPE_ALIASES.
+func NewJobTypeAliasesFromEnv() *JobTypeAliases {
+	aliases := make(map[model.JobType]model.JobType)
+	for _, pair := range strings.Split(os.Getenv("JOB_TYPE_ALIASES"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		oldName, newName, ok := strings.Cut(pair, "=")
+		if !ok {
+			log.Printf("Ignoring invalid JOB_TYPE_ALIASES entry %q: must be OLD=NEW", pair)
+			continue
+		}
+		aliases[model.JobType(strings.TrimSpace(oldName))] = model.JobType(strings.TrimSpace(newName))
+	}
+	return NewJobTypeAliases(aliases)
+}
+
+// NewJobTypeAliases creates JobTypeAliases from an old name -> current name map.
+// Aliases to unsupported types, or shadowing supported ones, are dropped.
+func NewJobTypeAliases(aliases map[model.JobType]model.JobType) *JobTypeAliases {
+	valid := make(map[model.JobType]model.JobType, len(aliases))
+	for oldName, newName := range aliases {
+		if _, ok := model.LookupJobTypeSpec(newName); !ok {
+			log.Printf("Ignoring job type alias %s -> %s: %s is not a supported type", oldName, newName, newName)
+			continue
+		}
+		if _, ok := model.LookupJobTypeSpec(oldName); ok {
+			log.Printf("Ignoring job type alias %s -> %s: %s is still a supported type", oldName, newName, oldName)
+			continue
+		}
+		valid[oldName] = newName
+		log.Printf("Job type alias: %s -> %s", oldName, newName)
+	}
+	return &JobTypeAliases{aliases: valid}
+}
+
+// Resolve returns the current name for a job type, which is the type itself unless
+// it is an alias. Safe to call on a nil *JobTypeAliases.
+func (a *JobTypeAliases) Resolve(jobType model.JobType) model.JobType {
+	if a == nil {
+		return jobType
+	}
+	current, ok := a.aliases[jobType]
+	if !ok {
+		return jobType
+	}
+	log.Printf("Deprecated job type %s in use, resolved to %s", jobType, current)
+	return current
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"distributed-job-processor/dto"
+	"distributed-job-processor/model"
+)
+
+// TestJobTypeAliasesResolve verifies valid aliases resolve and invalid ones are dropped.
+func TestJobTypeAliasesResolve(t *testing.T) {
+	aliases := NewJobTypeAliases(map[model.JobType]model.JobType{
+		"PAYMENT":                   model.TypePaymentProcess,
+		"SMS_CONFIRMATION":          "SMS",                    // unsupported target
+		model.TypeEmailConfirmation: model.TypePaymentProcess, // shadows a supported type
+	})
+
+	cases := map[model.JobType]model.JobType{
+		"PAYMENT":                   model.TypePaymentProcess,
+		"SMS_CONFIRMATION":          "SMS_CONFIRMATION",
+		model.TypeEmailConfirmation: model.TypeEmailConfirmation,
+		model.TypePaymentProcess:    model.TypePaymentProcess,
+	}
+	for jobType, want := range cases {
+		if got := aliases.Resolve(jobType); got != want {
+			t.Errorf("Resolve(%s) = %s, want %s", jobType, got, want)
+		}
+	}
+
+	var none *JobTypeAliases
+	if got := none.Resolve("PAYMENT"); got != "PAYMENT" {
+		t.Fatalf("expected nil aliases to resolve to the type itself, got %s", got)
+	}
+}
+
+// TestCreateJobStoresCurrentTypeName verifies jobs submitted under an old name are stored under the new one.
+func TestCreateJobStoresCurrentTypeName(t *testing.T) {
+	t.Setenv("JOB_TYPE_ALIASES", "EMAIL=EMAIL_CONFIRMATION, bogus")
+	s := NewJobService(newTestRepository(t))
+
+	job, err := s.CreateJob(context.Background(), "customer-1", &dto.JobRequest{
+		Type:    "EMAIL",
+		Payload: "order_1|not-an-email|receipt",
+	})
+	if job != nil || err == nil {
+		t.Fatal("expected the EMAIL_CONFIRMATION payload rules to apply to the alias")
+	}
+
+	job, err = s.CreateJob(context.Background(), "customer-1", &dto.JobRequest{
+		Type:    "EMAIL",
+		Payload: "order_1|user@email.com|receipt",
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if job.Type != model.TypeEmailConfirmation {
+		t.Fatalf("expected type %s, got %s", model.TypeEmailConfirmation, job.Type)
+	}
+}
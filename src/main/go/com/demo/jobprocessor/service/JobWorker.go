@@ -2,15 +2,26 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"math"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"distributed-job-processor/config"
+	"distributed-job-processor/exception"
 	"distributed-job-processor/model"
 	"distributed-job-processor/repository"
 )
@@ -21,246 +32,1310 @@ import (
 // 1. Consume job ID from Kafka
 // 2. Check Redis cache for job details (cache-aside pattern)
 // 3. If cache miss, fetch from database and cache result
-// 4. Process job based on type (simulate with time.Sleep)
+// 4. Process job with its type's JobHandler (built-in handlers simulate with time.Sleep)
 // 5. Update job status to COMPLETED
 // 6. Update cache
 // 7. Acknowledge Kafka message (commit offset)
 //
 // Error Handling (Retry Logic with Exponential Backoff):
-// - On failure: Increment attempts counter
+// - On failure: Increment attempts counter and record the failure reason
+//   (see FailureClassifier; handlers may return a JobFailure)
 // - If attempts < maxRetries:
 //   - Set status back to PENDING
-//   - Set scheduledAt = now + 2^attempts seconds (exponential backoff)
+//   - Set scheduledAt = now + a random delay in [0, 2^attempts] seconds (exponential
+//     backoff with full jitter, capped at MAX_BACKOFF_SECONDS, default 300), so a
+//     wave of failures doesn't retry in lockstep. The base and first delay can be
+//     set per type, see BackoffConfig
 //   - Scheduler will pick it up again later
 // - If attempts >= maxRetries:
 //   - Set status to DEAD_LETTER
 //   - Job will not be retried automatically
 //
-// Simulated Processing Times:
+// Simulated Processing Times (see PaymentHandler and EmailHandler):
 // - PAYMENT_PROCESS: 2 seconds (simulates Stripe API call)
 // - EMAIL_CONFIRMATION: 1 second (simulates SendGrid API call)
+//
+// Handlers (see JobHandler): each job type's work is done by the handler registered
+// for it, the built-in ones by default; RegisterHandler adds or replaces one.
+//
+// Bulkheads (BULKHEAD_MAX_<TYPE>): per-type cap on jobs in flight, see Bulkhead.
+//
+// Type circuit breakers (see SetTypeCircuitBreaker): every attempt's outcome is
+// reported, so a type whose downstream keeps failing can be shed at intake. While a
+// type's breaker is open (or half-open with its trial running), its jobs are deferred
+// like a full bulkhead's, without calling the downstream or counting an attempt.
+//
+// Attempt history (see SetJobAttemptRepository): every attempt is also saved as a
+// JobAttempt row, served by GET /api/jobs/:id/attempts.
+//
+// Client limits (MAX_CONCURRENT_PER_CLIENT, default 4): per-client cap on jobs in
+// flight, see ClientLimiter.
+//
+// Processing timeouts (PROCESS_TIMEOUT_<TYPE>, e.g. PROCESS_TIMEOUT_PAYMENT_PROCESS=10s,
+// default 30s): processing that runs past its type's timeout is cancelled and
+// handled as a retryable failure.
+//
+// Priority topics (KAFKA_PRIORITY_TOPICS=true):
+// - A second reader consumes the high-priority topic
+// - Each goroutine takes a high-priority message whenever one is waiting,
+//   and only falls back to the regular topic when the high-priority one is empty
+//
+// Job state topic (KAFKA_JOB_STATE_TOPIC=true, off by default): jobs are taken from an
+// in-memory copy of the compacted topic the scheduler writes, falling back to the
+// cache/DB lookup, see JobStateTable.
+//
+// Dead-letter topic (KAFKA_TOPIC_DLQ, default "job-queue-dlq"): dead-lettered jobs
+// are also published there, keyed by job ID with a deadLetterEnvelope value, like the
+// ones the scheduler dead-letters (see deadLetterQueue).
+//
+// Multiple clusters (KAFKA_CONSUMER_CLUSTERS, see config.GetConsumerClusters):
+// - Each goroutine has one reader per cluster (and per topic), merged into its loop
+// - Each message is committed on the cluster it came from
+//
+// Fetch errors (e.g. Kafka down) back off exponentially from 1s up to
+// KAFKA_FETCH_BACKOFF_MAX (default 30s), resetting on the first successful fetch.
+// Only the first error of an outage is logged, then a summary every minute.
+//
+// Renamed job types (JOB_TYPE_ALIASES, see JobTypeAliases) are processed under
+// their current name.
+//
+// Priority buffering (WORKER_PRIORITY_WINDOW, e.g. WORKER_PRIORITY_WINDOW=50ms, default 0):
+// - Each goroutine holds the messages its readers fetch for up to the window and
+//   processes them most urgent first, by the job-priority header (see priorityBuffer)
+// - Trades up to one window of latency for priority order within each goroutine's
+//   partitions
+// - 0 keeps processing in the order Kafka delivers
+// - At most WORKER_PRIORITY_BUFFER_SIZE messages (default 2x concurrency) are held,
+//   split evenly between the goroutines
+//
+// Worker pools (WORKER_POOL, e.g. WORKER_POOL=onprem, default the general pool):
+// - The worker consumes only the pool's topic (see config.GetWorkerPools), so it
+//   runs exactly the jobs pinned to the pool
+// - Give each pool its own KAFKA_CONSUMER_GROUP_ID
+//
+// Type filtering (WORKER_TYPES, e.g. WORKER_TYPES=EMAIL_CONFIRMATION, default all types):
+// - Messages whose job-type header names another type are committed and skipped
+// - Messages without the header are processed, as before
+// - Each specialized fleet needs its own KAFKA_CONSUMER_GROUP_ID; fleets sharing
+//   a group would skip each other's partitions' jobs for good
+//
+// Crash windows (work done marker, see JobHandler):
+// - Crash before the handler's side effect is saved: the job stays RUNNING and its offset
+//   uncommitted, so it is redelivered (or reaped) and the handler runs again
+// - Crash after a handler saves workDoneAt, in the same write as its side effect (see
+//   PaymentHandler's charged flag), but before the COMPLETED save: the job stays RUNNING
+//   with workDoneAt set; a redelivery completes it without rerunning the handler, and
+//   the stuck-job reaper marks it COMPLETED instead of retrying it
+// - Handlers without a side effect to protect save no marker, so the attempt costs no
+//   extra write; a crash before the COMPLETED save just runs them again
+// - Crash after the COMPLETED save but before the offset commit: the job is redelivered
+//   and completed again, still without rerunning the handler
+//
+// Delivery semantics (DELIVERY_SEMANTICS, default at-least-once):
+// - at-least-once: the offset is committed after processing, so a crash at any point
+//   redelivers the message and the job runs again (see the crash windows above)
+// - at-most-once: the offset is committed before the handler runs, so a crash during
+//   processing loses the attempt instead of repeating it. For non-idempotent jobs that
+//   can't tolerate a second run; a job whose commit fails isn't processed
+// - at-most-once only covers Kafka redelivery: the stuck-job reaper still requeues a
+//   job left RUNNING by a crashed worker, so side effects need their own guard too
+//   (see PaymentHandler's idempotency lock)
+//
+// Dry run (WORKER_DRY_RUN=true, off by default), for load testing the pipeline itself:
+// - Jobs are consumed, saved RUNNING and COMPLETED, cached, and committed as usual
+// - The transformers and handler never run: no simulated downstream delay, no charge
+//   or email. Never enable it on a worker consuming real jobs
+//
+// Chaos (CHAOS_ENABLED=true with CHAOS_FAILURE_RATE_<TYPE>, off by default, see
+// ChaosInjector): a fraction of each type's attempts fail with a simulated retryable
+// error instead of running, to exercise retries and dead-lettering. Combines with
+// dry run, whose surviving attempts then complete without their handlers.
+//
+// Paused partitions (see SetPartitionPauseService and partitionGate):
+// - Messages of partitions paused through the admin API are parked, uncommitted,
+//   while the other partitions keep being processed
+// - Delivery stays at-least-once: if the worker stops or the partition is rebalanced
+//   away while paused, the parked messages are redelivered from the last committed
+//   offset, possibly to another worker; a job that was also replayed here on resume
+//   may then run twice
 type JobWorker struct {
-	jobRepository *repository.JobRepository
-	cacheService  *CacheService
-	kafkaReader   *kafka.Reader
-	concurrency   int
-	stopCh        chan struct{}
+	jobRepository       *repository.JobRepository
+	cacheService        *CacheService
+	kafkaReaders        []*kafka.Reader // concurrency per cluster, see readersOf
+	ownedReaders        bool            // No merging: each goroutine consumes one reader directly
+	highPriorityReaders []*kafka.Reader // concurrency per cluster, see readersOf
+	dlqWriter           *kafka.Writer
+	jobState            *JobStateTable
+	concurrency         int
+	bulkhead            *Bulkhead
+	clientLimiter       *ClientLimiter
+	processTimeouts     map[model.JobType]time.Duration
+	workerTypes         map[model.JobType]bool
+	typeAliases         *JobTypeAliases
+	failureClassifier   *FailureClassifier
+	retryClassifier     *RetryClassifier
+	transformers        *TransformerChain
+	handlers            map[model.JobType]JobHandler
+	deadLetterNotifier  *DeadLetterNotifier
+	partitionGate       *partitionGate
+	typeBreaker         *TypeCircuitBreaker
+	attemptRepository   *repository.JobAttemptRepository
+	atMostOnce          bool
+	dryRun              bool
+	chaos               *ChaosInjector
+	slas                JobSLAs
+	fetchBackoffMax     time.Duration
+	backoffs            map[model.JobType]BackoffConfig
+	defaultBackoff      BackoffConfig // Types without an entry in backoffs
+	priorityWindow      time.Duration
+	priorityBufferSize  int
+	lagInterval         time.Duration
+	stopCh              chan struct{}
+	stopOnce            sync.Once
 }
 
 // NewJobWorker creates a new JobWorker with the given dependencies.
 func NewJobWorker(jobRepository *repository.JobRepository, cacheService *CacheService, concurrency int) *JobWorker {
-	reader := config.NewKafkaConsumerReader(config.GetJobQueueTopic())
+	clusters := config.GetConsumerClusters()
+	topic := config.GetJobQueueTopic()
+	pool := config.GetWorkerPool()
+	if pool != "" {
+		topic = config.GetWorkerPoolTopic(pool)
+		log.Printf("Worker belongs to worker pool %s, consuming only %s", pool, topic)
+	}
+
+	// Floor for retry delays, e.g. RETRY_MIN_DELAY=30s to avoid hammering an expensive downstream
+	var retryMinDelay time.Duration
+	if val := os.Getenv("RETRY_MIN_DELAY"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed >= 0 {
+			retryMinDelay = parsed
+		} else {
+			log.Printf("Ignoring invalid RETRY_MIN_DELAY %q: must be a non-negative duration", val)
+		}
+	}
+
+	retryMaxBackoff := defaultMaxBackoffSeconds
+	if val := os.Getenv("MAX_BACKOFF_SECONDS"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			retryMaxBackoff = parsed
+		} else {
+			log.Printf("Ignoring invalid MAX_BACKOFF_SECONDS %q: must be a positive integer", val)
+		}
+	}
+	// A floor above the cap would override the cap on every retry
+	if maxBackoff := time.Duration(retryMaxBackoff) * time.Second; retryMinDelay > maxBackoff {
+		log.Printf("RETRY_MIN_DELAY %v is above MAX_BACKOFF_SECONDS %d: clamping it to %v", retryMinDelay, retryMaxBackoff, maxBackoff)
+		retryMinDelay = maxBackoff
+	}
+
+	fetchBackoffMax := defaultFetchBackoffMax
+	if val := os.Getenv("KAFKA_FETCH_BACKOFF_MAX"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed >= fetchBackoffMin {
+			fetchBackoffMax = parsed
+		} else {
+			log.Printf("Ignoring invalid KAFKA_FETCH_BACKOFF_MAX %q: must be a duration of at least %v", val, fetchBackoffMin)
+		}
+	}
+
+	defaultBackoff := BackoffConfig{
+		Base:           defaultBackoffBase,
+		InitialSeconds: defaultBackoffInitialSeconds,
+		MaxSeconds:     retryMaxBackoff,
+		MinDelay:       retryMinDelay,
+	}
+	backoffs := make(map[model.JobType]BackoffConfig)
+	for _, spec := range model.JobTypeSpecs() {
+		backoff := defaultBackoff
+		key := "BACKOFF_BASE_" + string(spec.Type)
+		if val := os.Getenv(key); val != "" {
+			if parsed, err := strconv.ParseFloat(val, 64); err == nil && parsed >= 1 {
+				backoff.Base = parsed
+			} else {
+				log.Printf("Ignoring invalid %s %q: must be a number of at least 1", key, val)
+			}
+		}
+		key = "BACKOFF_INITIAL_SECONDS_" + string(spec.Type)
+		if val := os.Getenv(key); val != "" {
+			if parsed, err := strconv.ParseFloat(val, 64); err == nil && parsed > 0 {
+				backoff.InitialSeconds = parsed
+			} else {
+				log.Printf("Ignoring invalid %s %q: must be a positive number", key, val)
+			}
+		}
+		backoffs[spec.Type] = backoff
+	}
+
+	processTimeouts := make(map[model.JobType]time.Duration)
+	for _, spec := range model.JobTypeSpecs() {
+		timeout := defaultProcessTimeout
+		key := "PROCESS_TIMEOUT_" + string(spec.Type)
+		if val := os.Getenv(key); val != "" {
+			if parsed, err := time.ParseDuration(val); err == nil && parsed > 0 {
+				timeout = parsed
+			} else {
+				log.Printf("Ignoring invalid %s %q: must be a positive duration", key, val)
+			}
+		}
+		processTimeouts[spec.Type] = timeout
+	}
+
+	var priorityWindow time.Duration
+	if val := os.Getenv("WORKER_PRIORITY_WINDOW"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed >= 0 {
+			priorityWindow = parsed
+		} else {
+			log.Printf("Ignoring invalid WORKER_PRIORITY_WINDOW %q: must be a non-negative duration", val)
+		}
+	}
+
+	priorityBufferSize := 2 * concurrency
+	if val := os.Getenv("WORKER_PRIORITY_BUFFER_SIZE"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			priorityBufferSize = parsed
+		} else {
+			log.Printf("Ignoring invalid WORKER_PRIORITY_BUFFER_SIZE %q: must be a positive integer", val)
+		}
+	}
+
+	lagInterval := defaultConsumerLagInterval
+	if val := os.Getenv("KAFKA_LAG_INTERVAL"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed >= 0 {
+			lagInterval = parsed
+		} else {
+			log.Printf("Ignoring invalid KAFKA_LAG_INTERVAL %q: must be a non-negative duration", val)
+		}
+	}
+
+	var atMostOnce bool
+	switch val := os.Getenv("DELIVERY_SEMANTICS"); val {
+	case "", DeliveryAtLeastOnce:
+	case DeliveryAtMostOnce:
+		atMostOnce = true
+		log.Printf("Worker delivery is at-most-once: offsets are committed before processing")
+	default:
+		log.Printf("Ignoring invalid DELIVERY_SEMANTICS %q: must be %s or %s", val, DeliveryAtLeastOnce, DeliveryAtMostOnce)
+	}
+
+	workerTypes := parseWorkerTypes(os.Getenv("WORKER_TYPES"))
+	if workerTypes != nil {
+		log.Printf("Worker only processes job types: %s", os.Getenv("WORKER_TYPES"))
+	}
+
+	// Every consume goroutine gets readers of its own, see Start
+	var highPriorityReaders []*kafka.Reader
+	if config.GetPriorityTopicsEnabled() && pool == "" {
+		highPriorityReaders = newConsumerReaders(clusters, config.GetHighPriorityTopic(), concurrency)
+	}
+	readers := newConsumerReaders(clusters, topic, concurrency)
+	ownedReaders := len(clusters) == 1 && len(highPriorityReaders) == 0 && priorityWindow == 0
 
 	return &JobWorker{
-		jobRepository: jobRepository,
-		cacheService:  cacheService,
-		kafkaReader:   reader,
-		concurrency:   concurrency,
-		stopCh:        make(chan struct{}),
+		jobRepository:       jobRepository,
+		cacheService:        cacheService,
+		kafkaReaders:        readers,
+		ownedReaders:        ownedReaders,
+		highPriorityReaders: highPriorityReaders,
+		dlqWriter:           config.NewKafkaDLQWriter(),
+		deadLetterNotifier:  defaultDeadLetterNotifier(),
+		jobState:            NewJobStateTableFromEnv(),
+		bulkhead:            NewBulkheadFromEnv(),
+		clientLimiter:       NewClientLimiterFromEnv(),
+		processTimeouts:     processTimeouts,
+		workerTypes:         workerTypes,
+		typeAliases:         NewJobTypeAliasesFromEnv(),
+		failureClassifier:   NewFailureClassifierFromEnv(),
+		retryClassifier:     NewRetryClassifierFromEnv(),
+		transformers:        NewTransformerChain(),
+		handlers:            DefaultJobHandlers(jobRepository, cacheService),
+		atMostOnce:          atMostOnce,
+		dryRun:              os.Getenv("WORKER_DRY_RUN") == "true",
+		chaos:               NewChaosInjectorFromEnv(),
+		slas:                NewJobSLAsFromEnv(),
+		fetchBackoffMax:     fetchBackoffMax,
+		concurrency:         concurrency,
+		backoffs:            backoffs,
+		defaultBackoff:      defaultBackoff,
+		priorityWindow:      priorityWindow,
+		priorityBufferSize:  priorityBufferSize,
+		lagInterval:         lagInterval,
+		stopCh:              make(chan struct{}),
+	}
+}
+
+// newConsumerReaders creates perCluster readers of topic on every cluster, all in the
+// worker's consumer group, cluster by cluster.
+func newConsumerReaders(clusters [][]string, topic string, perCluster int) []*kafka.Reader {
+	readers := make([]*kafka.Reader, 0, len(clusters)*perCluster)
+	for _, brokers := range clusters {
+		for i := 0; i < perCluster; i++ {
+			readers = append(readers, config.NewKafkaConsumerReaderForBrokers(brokers, topic))
+		}
+	}
+	return readers
+}
+
+// readersOf returns the readers of the consume goroutine workerID owns, one per cluster:
+// readers hold concurrency per cluster, see newConsumerReaders.
+func (w *JobWorker) readersOf(readers []*kafka.Reader, workerID int) []*kafka.Reader {
+	var owned []*kafka.Reader
+	for i := workerID; i < len(readers); i += w.concurrency {
+		owned = append(owned, readers[i])
+	}
+	return owned
+}
+
+// SetTransformerChain registers the PayloadTransformers run before every handler.
+// Call this at startup, before Start; passing nil restores the default no-op chain.
+func (w *JobWorker) SetTransformerChain(chain *TransformerChain) {
+	if chain == nil {
+		chain = NewTransformerChain()
+	}
+	w.transformers = chain
+}
+
+// RegisterHandler sets the JobHandler of a job type, replacing any built-in one
+// (see DefaultJobHandlers). Call this at startup, before Start.
+func (w *JobWorker) RegisterHandler(jobType model.JobType, handler JobHandler) {
+	if w.handlers == nil {
+		w.handlers = make(map[model.JobType]JobHandler)
+	}
+	w.handlers[jobType] = handler
+}
+
+// SetDeadLetterNotifier reports every job this worker dead-letters to the notifier,
+// replacing the shared one from DEAD_LETTER_NOTIFY (see DeadLetterNotifier).
+func (w *JobWorker) SetDeadLetterNotifier(notifier *DeadLetterNotifier) {
+	w.deadLetterNotifier = notifier
+}
+
+// deadLetters returns the path this worker's dead letters take.
+func (w *JobWorker) deadLetters() deadLetterQueue {
+	return deadLetterQueue{
+		jobRepository: w.jobRepository,
+		cacheService:  w.cacheService,
+		writer:        w.dlqWriter,
+		notifier:      w.deadLetterNotifier,
 	}
 }
 
+// partitionReplayWorkerID is the worker ID logged for parked messages processed on resume.
+const partitionReplayWorkerID = -1
+
+// SetPartitionPauseService makes the worker honor partitions paused in the registry.
+// Call this at startup, before Start.
+func (w *JobWorker) SetPartitionPauseService(pauses *PartitionPauseService) {
+	w.partitionGate = newPartitionGate(pauses, func(fetched fetchedMessage) {
+		w.processJob(fetched.msg, uncommitted{}, partitionReplayWorkerID)
+	})
+}
+
+// uncommitted is the messageCommitter of parked messages processed on resume: their
+// reader may have lost the partition meanwhile, so nothing is committed, see partitionGate.
+type uncommitted struct{}
+
+func (uncommitted) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	return nil
+}
+
+// SetTypeCircuitBreaker asks the per-type breakers before every attempt and reports
+// its outcome to them, see TypeCircuitBreaker. Call this at startup, before Start.
+func (w *JobWorker) SetTypeCircuitBreaker(breaker *TypeCircuitBreaker) {
+	w.typeBreaker = breaker
+}
+
+// SetJobAttemptRepository saves a JobAttempt row for every attempt this worker makes.
+// Call this at startup, before Start; without it no history is recorded.
+func (w *JobWorker) SetJobAttemptRepository(attempts *repository.JobAttemptRepository) {
+	w.attemptRepository = attempts
+}
+
 // Start begins consuming messages from Kafka with the configured concurrency.
 // Equivalent to Spring's @KafkaListener with setConcurrency(4).
-// Multiple goroutines consume from the same reader (Kafka handles partition assignment).
+//
+// Each goroutine owns its readers: they are members of the same consumer group, so
+// Kafka assigns each of them its own partitions, and a goroutine only ever commits
+// messages its own readers fetched. Goroutines beyond the partition count get no
+// partitions and stay idle until a rebalance hands them some. A resumed partition's
+// parked messages (see partitionGate) are processed by the gate, uncommitted.
+//
+// With one cluster and neither priority topics nor buffering, each goroutine consumes
+// its one reader directly, in the order it fetched (see consumeLoop). Otherwise it
+// merges its reader of every cluster and topic, each fed by a fetch loop (see
+// consumeMergedLoop).
 func (w *JobWorker) Start() {
 	log.Printf("Job worker started with concurrency: %d", w.concurrency)
+	if w.dryRun {
+		log.Printf("Worker dry run active (WORKER_DRY_RUN): jobs are completed without running their handlers")
+	}
+	if w.chaos != nil {
+		log.Printf("Worker chaos active (CHAOS_ENABLED): attempts fail on purpose at their type's CHAOS_FAILURE_RATE")
+	}
+	if w.partitionGate != nil {
+		go w.partitionGate.run(w.stopCh)
+	}
+	if w.jobState != nil {
+		w.jobState.run(w.stopCh)
+	}
+	if w.lagInterval > 0 {
+		go w.consumerLagLoop()
+	}
+
+	// Single cluster, single topic, no buffering: each goroutine consumes its own reader
+	if w.ownedReaders {
+		for i, reader := range w.kafkaReaders {
+			go w.consumeLoop(i, reader)
+		}
+		return
+	}
+
+	// Otherwise each goroutine merges its own readers through channels of its own
+	if clusters := len(w.kafkaReaders) / w.concurrency; clusters > 1 {
+		log.Printf("Consuming from %d Kafka clusters", clusters)
+	}
+	bufferSize := max(w.priorityBufferSize/w.concurrency, 1)
+	if w.priorityWindow > 0 {
+		log.Printf("Priority buffering enabled (window: %v, buffer size: %d per goroutine)", w.priorityWindow, bufferSize)
+	} else if len(w.highPriorityReaders) > 0 {
+		log.Printf("Priority topics enabled: high-priority topic %s is drained first", config.GetHighPriorityTopic())
+	}
 
 	for i := 0; i < w.concurrency; i++ {
-		go w.consumeLoop(i)
+		regularCh := make(chan fetchedMessage)
+		var highPriorityCh chan fetchedMessage
+
+		// Priority buffering: every reader of the goroutine feeds its buffer
+		if w.priorityWindow > 0 {
+			bufferCh := make(chan fetchedMessage)
+			for _, reader := range w.readersOf(w.kafkaReaders, i) {
+				go w.fetchLoop(reader, bufferCh)
+			}
+			for _, reader := range w.readersOf(w.highPriorityReaders, i) {
+				go w.fetchLoop(reader, bufferCh)
+			}
+			go newPriorityBuffer(w.priorityWindow, bufferSize).run(bufferCh, regularCh, w.stopCh)
+		} else {
+			for _, reader := range w.readersOf(w.kafkaReaders, i) {
+				go w.fetchLoop(reader, regularCh)
+			}
+			if len(w.highPriorityReaders) > 0 {
+				highPriorityCh = make(chan fetchedMessage)
+				for _, reader := range w.readersOf(w.highPriorityReaders, i) {
+					go w.fetchLoop(reader, highPriorityCh)
+				}
+			}
+		}
+		go w.consumeMergedLoop(i, regularCh, highPriorityCh)
 	}
 }
 
-// Stop gracefully stops the worker.
+// Stop gracefully stops the worker, closing the readers of every cluster.
+// Only the first call has any effect.
 func (w *JobWorker) Stop() {
+	w.stopOnce.Do(w.stop)
+}
+
+func (w *JobWorker) stop() {
 	close(w.stopCh)
-	if err := w.kafkaReader.Close(); err != nil {
-		log.Printf("Error closing Kafka reader: %v", err)
+	for _, reader := range w.kafkaReaders {
+		closeReader(reader)
+	}
+	for _, reader := range w.highPriorityReaders {
+		closeReader(reader)
+	}
+	if w.dlqWriter != nil {
+		if err := w.dlqWriter.Close(); err != nil {
+			log.Printf("Error closing DLQ Kafka writer: %v", err)
+		}
+	}
+	if w.jobState != nil {
+		w.jobState.Close()
+	}
+	w.deadLetterNotifier.Stop()
+}
+
+// closeReader closes a Kafka reader, logging which cluster failed to close.
+func closeReader(reader *kafka.Reader) {
+	if err := reader.Close(); err != nil {
+		log.Printf("Error closing Kafka reader for %s on %v: %v", reader.Config().Topic, reader.Config().Brokers, err)
 	}
 }
 
-// consumeLoop is the main consume loop for a single worker goroutine.
-func (w *JobWorker) consumeLoop(workerID int) {
+// consumeLoop is the main consume loop for a worker goroutine owning reader. Messages
+// are fetched, processed, and committed one at a time, so the reader's offsets are
+// committed in order and no other goroutine commits on it.
+func (w *JobWorker) consumeLoop(workerID int, reader *kafka.Reader) {
 	log.Printf("Worker goroutine %d started", workerID)
+	backoff := newFetchBackoff(fmt.Sprintf("Worker %d", workerID), w.fetchBackoffMax)
 
 	for {
 		select {
 		case <-w.stopCh:
+			backoff.Reset()
 			log.Printf("Worker goroutine %d stopped", workerID)
 			return
 		default:
-			msg, err := w.kafkaReader.FetchMessage(context.Background())
+			msg, err := reader.FetchMessage(context.Background())
 			if err != nil {
-				log.Printf("Worker %d: Error fetching message: %v", workerID, err)
-				time.Sleep(1 * time.Second)
+				w.sleepOrStop(backoff.Failure(err))
 				continue
 			}
+			backoff.Success()
 
-			w.processJob(msg, workerID)
+			if !w.partitionGate.Admit(fetchedMessage{msg: msg, reader: reader}) {
+				continue
+			}
+			w.processJob(msg, reader, workerID)
 		}
 	}
 }
 
+// fetchedMessage is a Kafka message and the reader it must be committed on.
+type fetchedMessage struct {
+	msg    kafka.Message
+	reader *kafka.Reader
+}
+
+// fetchLoop feeds messages from one reader to the consume goroutine owning it.
+// The channel is unbuffered, so at most one message per reader waits outside Kafka.
+func (w *JobWorker) fetchLoop(reader *kafka.Reader, out chan<- fetchedMessage) {
+	source := fmt.Sprintf("Fetch from %s on %v", reader.Config().Topic, reader.Config().Brokers)
+	backoff := newFetchBackoff(source, w.fetchBackoffMax)
+	defer backoff.Reset()
+
+	for {
+		msg, err := reader.FetchMessage(context.Background())
+		if err != nil {
+			select {
+			case <-w.stopCh:
+				return
+			default:
+			}
+			w.sleepOrStop(backoff.Failure(err))
+			continue
+		}
+		backoff.Success()
+
+		fetched := fetchedMessage{msg: msg, reader: reader}
+		if !w.partitionGate.Admit(fetched) {
+			continue
+		}
+		select {
+		case out <- fetched:
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+// sleepOrStop waits for d, returning early if the worker is stopped.
+func (w *JobWorker) sleepOrStop(d time.Duration) {
+	select {
+	case <-w.stopCh:
+	case <-time.After(d):
+	}
+}
+
+const (
+	fetchBackoffMin           = 1 * time.Second
+	defaultFetchBackoffMax    = 30 * time.Second
+	fetchErrorSummaryInterval = 1 * time.Minute
+)
+
+// fetchBackoff paces one fetch loop through a run of consecutive fetch errors.
+//
+// Delays double from fetchBackoffMin up to max. The first error of a run is
+// logged in full, later ones only as a summary every fetchErrorSummaryInterval,
+// and recovery is logged once. Not safe for concurrent use; each loop owns one.
+type fetchBackoff struct {
+	source      string
+	max         time.Duration
+	failures    int
+	firstErrAt  time.Time
+	lastSummary time.Time
+}
+
+func newFetchBackoff(source string, max time.Duration) *fetchBackoff {
+	return &fetchBackoff{source: source, max: max}
+}
+
+// Failure records a failed fetch and returns how long to wait before the next one.
+func (b *fetchBackoff) Failure(err error) time.Duration {
+	now := time.Now()
+	b.failures++
+	config.GetMetrics().AddKafkaFetchFailures(1)
+
+	if b.failures == 1 {
+		b.firstErrAt = now
+		b.lastSummary = now
+		log.Printf("%s: Error fetching message: %v", b.source, err)
+	} else if now.Sub(b.lastSummary) >= fetchErrorSummaryInterval {
+		b.lastSummary = now
+		log.Printf("%s: Still failing, %d consecutive fetch errors since %s, last: %v",
+			b.source, b.failures, b.firstErrAt.Format(time.RFC3339), err)
+	}
+
+	return b.delay()
+}
+
+// Success ends a run of failures, if any.
+func (b *fetchBackoff) Success() {
+	if b.failures == 0 {
+		return
+	}
+	log.Printf("%s: Recovered after %d consecutive fetch errors over %v",
+		b.source, b.failures, time.Since(b.firstErrAt).Round(time.Second))
+	b.Reset()
+}
+
+// Reset clears the failure run without logging, e.g. when the loop stops.
+func (b *fetchBackoff) Reset() {
+	config.GetMetrics().AddKafkaFetchFailures(-int64(b.failures))
+	b.failures = 0
+}
+
+// delay is fetchBackoffMin * 2^(failures-1), capped at max.
+func (b *fetchBackoff) delay() time.Duration {
+	d := fetchBackoffMin
+	for i := 1; i < b.failures && d < b.max; i++ {
+		d *= 2
+	}
+	return min(d, b.max)
+}
+
+// consumeMergedLoop is the consume loop for a worker goroutine when messages come
+// from several readers (priority topics and/or multiple clusters) or a priority
+// buffer. Only the goroutine's own readers feed regularCh and highPriorityCh, so it
+// never commits on another goroutine's reader.
+func (w *JobWorker) consumeMergedLoop(workerID int, regularCh, highPriorityCh <-chan fetchedMessage) {
+	log.Printf("Worker goroutine %d started (merged readers)", workerID)
+
+	for {
+		fetched, ok := w.nextPriorityMessage(regularCh, highPriorityCh)
+		if !ok {
+			log.Printf("Worker goroutine %d stopped", workerID)
+			return
+		}
+		w.processJob(fetched.msg, fetched.reader, workerID)
+	}
+}
+
+// nextPriorityMessage waits for the next message, preferring the high-priority topic.
+// highPriorityCh is nil without priority topics, so only regularCh is read.
+// Returns false once the worker is stopped.
+func (w *JobWorker) nextPriorityMessage(regularCh, highPriorityCh <-chan fetchedMessage) (fetchedMessage, bool) {
+	// Take a waiting high-priority message before considering the regular topic
+	select {
+	case fetched := <-highPriorityCh:
+		return fetched, true
+	default:
+	}
+
+	select {
+	case <-w.stopCh:
+		return fetchedMessage{}, false
+	case fetched := <-highPriorityCh:
+		return fetched, true
+	case fetched := <-regularCh:
+		return fetched, true
+	}
+}
+
+// parseWorkerTypes parses a comma-separated WORKER_TYPES list into a set.
+// Returns nil (process every type) when the list is empty or has no known types.
+func parseWorkerTypes(val string) map[model.JobType]bool {
+	var types map[model.JobType]bool
+	for _, name := range strings.Split(val, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		jobType := model.JobType(strings.ToUpper(name))
+		if _, ok := model.LookupJobTypeSpec(jobType); !ok {
+			log.Printf("Ignoring unknown job type %q in WORKER_TYPES", name)
+			continue
+		}
+		if types == nil {
+			types = make(map[model.JobType]bool)
+		}
+		types[jobType] = true
+	}
+	return types
+}
+
+// handlesMessage reports whether this worker processes the message's job type.
+// Messages without a job-type header are always handled.
+func (w *JobWorker) handlesMessage(msg kafka.Message) bool {
+	if w.workerTypes == nil {
+		return true
+	}
+	for _, header := range msg.Headers {
+		if header.Key == config.JobTypeHeader {
+			return w.workerTypes[w.typeAliases.Resolve(model.JobType(header.Value))]
+		}
+	}
+	return true
+}
+
 // processJob processes a single job message from Kafka.
 //
 // Configuration:
 // - Manual acknowledgment: Only ack after successful DB update
 // - Consumer group: "job-workers" (enables parallel processing)
 // - Multiple instances can run in parallel
-func (w *JobWorker) processJob(msg kafka.Message, workerID int) {
+// - The offset is committed on the reader the message was fetched from
+func (w *JobWorker) processJob(msg kafka.Message, reader messageCommitter, workerID int) {
+	logger := config.Logger().With(config.LogKeyWorkerID, workerID)
+
+	// Not ours: commit so skipped messages don't pile up as consumer lag
+	if !w.handlesMessage(msg) {
+		if err := reader.CommitMessages(context.Background(), msg); err != nil {
+			logger.Error("Failed to commit skipped message", "offset", msg.Offset, "error", err)
+		}
+		return
+	}
+
 	jobIDStr := string(msg.Value)
 	jobID, err := uuid.Parse(jobIDStr)
 	if err != nil {
-		log.Printf("Worker %d: Invalid job ID: %s", workerID, jobIDStr)
+		logger.Warn("Invalid job ID", config.LogKeyJobID, jobIDStr)
 		// Commit invalid message to avoid reprocessing
-		w.kafkaReader.CommitMessages(context.Background(), msg)
+		reader.CommitMessages(context.Background(), msg)
 		return
 	}
 
-	log.Printf("Worker %d received job %s from partition %d", workerID, jobID, msg.Partition)
+	logger = logger.With(config.LogKeyJobID, jobIDStr)
+	logger.Info("Received job", "partition", msg.Partition)
 
-	// Fetch job from cache first (cache-aside pattern)
-	job := w.cacheService.GetJob(jobID)
+	// Continue the trace propagated in the message headers
+	spanCtx, span := config.Tracer().Start(config.ExtractTraceContext(context.Background(), msg.Headers), "job.process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("job.id", jobIDStr),
+			attribute.Int("messaging.kafka.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.offset", msg.Offset)))
+	defer span.End()
+
+	// Take the job from the job state topic when enabled and caught up,
+	// else from cache first (cache-aside pattern)
+	_, lookupSpan := config.Tracer().Start(spanCtx, "cache.lookup")
+	job := w.jobState.Take(jobID, headerValue(msg, config.JobStateVersionHeader))
+	lookupSpan.SetAttributes(attribute.Bool("job_state.hit", job != nil))
+	if job == nil {
+		job = w.cacheService.GetJob(jobID)
+		lookupSpan.SetAttributes(attribute.Bool("cache.hit", job != nil))
+	}
 
 	if job == nil {
 		// Cache miss - fetch from database
-		log.Printf("Cache miss for job %s, fetching from database", jobID)
-		job, err = w.jobRepository.FindByID(jobID)
+		logger.Debug("Cache miss, fetching job from database")
+		job, err = w.findJobOrWait(jobID, logger)
+		if repository.IsDBUnavailable(err) {
+			// Stopped while waiting: left uncommitted for redelivery
+			lookupSpan.RecordError(err)
+			lookupSpan.SetStatus(codes.Error, "database unavailable")
+			lookupSpan.End()
+			return
+		}
 		if err != nil {
-			log.Printf("Worker %d: Job not found: %s", workerID, jobID)
-			w.kafkaReader.CommitMessages(context.Background(), msg)
+			logger.Warn("Job not found", "error", err)
+			lookupSpan.RecordError(err)
+			lookupSpan.SetStatus(codes.Error, "job not found")
+			lookupSpan.End()
+			reader.CommitMessages(context.Background(), msg)
 			return
 		}
 
 		// Cache for future requests
 		w.cacheService.CacheJob(job)
 	}
+	lookupSpan.End()
+	logger = logger.With(config.LogKeyClientID, job.ClientID)
+
+	// Old type names (JOB_TYPE_ALIASES) run the current handler; the row is saved
+	// under the current name with the outcome
+	job.Type = w.typeAliases.Resolve(job.Type)
+	span.SetAttributes(attribute.String("job.type", string(job.Type)), attribute.Int("job.attempt", job.Attempts+1))
+
+	// Client limit: a client with too many jobs in flight goes back to the scheduler
+	if !w.clientLimiter.TryAcquire(job.ClientID) {
+		logger.Info("Client at concurrency limit, deferring job")
+		w.deferMessage(job, bulkheadDeferDelay, msg, reader, logger)
+		return
+	}
+	defer w.clientLimiter.Release(job.ClientID)
+
+	// Bulkhead: a type at its concurrency limit goes back to the scheduler
+	if !w.bulkhead.TryAcquire(job.Type) {
+		logger.Info("Bulkhead full, deferring job", "type", job.Type)
+		w.deferMessage(job, bulkheadDeferDelay, msg, reader, logger)
+		return
+	}
+	defer w.bulkhead.Release(job.Type)
+
+	// Type circuit breaker: while a type's downstream is failing, its jobs go back to the
+	// scheduler until the breaker lets a trial through, without spending an attempt
+	trialID, allowed := w.typeBreaker.Allow(job.Type)
+	if !allowed {
+		logger.Info("Type circuit breaker open, deferring job", "type", job.Type)
+		config.GetMetrics().IncTypeCircuitShortCircuit(string(job.Type))
+		delay, open := w.typeBreaker.OpenFor(job.Type)
+		if !open {
+			delay = bulkheadDeferDelay
+		}
+		w.deferMessage(job, delay, msg, reader, logger)
+		return
+	}
+
+	// At-most-once: acknowledge before any work, so a crash from here on loses the
+	// attempt instead of repeating it
+	if w.atMostOnce {
+		if err := reader.CommitMessages(context.Background(), msg); err != nil {
+			logger.Error("Failed to commit message before processing, leaving it for redelivery", "error", err)
+			return
+		}
+	}
+
+	startedAt := time.Now()
+	attemptNumber := job.Attempts + 1
+	w.markProcessingStarted(job)
 
 	// Process the job
-	processErr := w.processJobInternal(job)
+	processErr := w.processJobInternal(spanCtx, job)
+	finishedAt := time.Now()
+	w.recordTypeOutcome(job.Type, trialID, processErr)
 
 	if processErr != nil {
-		log.Printf("Worker %d: Failed to process job %s: %v", workerID, jobID, processErr)
+		logger.Warn("Failed to process job", "error", processErr)
+		span.RecordError(processErr)
+		span.SetStatus(codes.Error, "job processing failed")
 
 		// Handle failure with retry logic
 		w.handleJobFailure(job, processErr)
 	}
+	w.recordAttempt(job.ID, attemptNumber, startedAt, finishedAt, processErr)
 
-	// Acknowledge Kafka message (commit offset)
+	// Acknowledge Kafka message (commit offset), unless already done (at-most-once)
 	// Only after successful DB update
 	// Job will be retried via scheduler based on scheduledAt if it failed
-	if err := w.kafkaReader.CommitMessages(context.Background(), msg); err != nil {
-		log.Printf("Worker %d: Failed to commit message for job %s: %v", workerID, jobID, err)
-		return
+	if !w.atMostOnce {
+		if err := reader.CommitMessages(context.Background(), msg); err != nil {
+			logger.Error("Failed to commit message", "error", err)
+			return
+		}
 	}
 
 	if processErr == nil {
-		log.Printf("Worker %d: Job %s processed successfully and acknowledged", workerID, jobID)
+		logger.Info("Job processed successfully and acknowledged")
 	}
 }
 
-// processJobInternal processes the job based on its type.
-//
-// In a real system, this would:
-// - PAYMENT_PROCESS: Call Stripe/PayPal API to charge card
-// - EMAIL_CONFIRMATION: Call SendGrid/SES API to send email
+// findJobOrWait loads the job from the database, waiting out an open DB circuit breaker
+// with backoff. The message stays uncommitted meanwhile, and no later message of the
+// reader is processed, whose commit would skip it. Returns repository.ErrDBUnavailable
+// only once the worker is stopped.
+func (w *JobWorker) findJobOrWait(jobID uuid.UUID, logger *slog.Logger) (*model.Job, error) {
+	delay := fetchBackoffMin
+	for {
+		job, err := w.jobRepository.FindByID(jobID)
+		if !repository.IsDBUnavailable(err) {
+			return job, err
+		}
+		logger.Warn("Database unavailable, holding the message uncommitted", "retry_in", delay)
+		select {
+		case <-w.stopCh:
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, defaultFetchBackoffMax)
+	}
+}
+
+// Delivery semantics, see DELIVERY_SEMANTICS on JobWorker.
+const (
+	DeliveryAtLeastOnce = "at-least-once"
+	DeliveryAtMostOnce  = "at-most-once"
+)
+
+// messageCommitter commits consumed messages: the kafka.Reader they were fetched from.
+type messageCommitter interface {
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// markProcessingStarted records when this attempt began, which is what the
+// stuck-job check measures against. Best-effort: a failed save only delays detection.
+func (w *JobWorker) markProcessingStarted(job *model.Job) {
+	now := time.Now()
+	err := updateJobWithRetry(w.jobRepository, job, func(job *model.Job) bool {
+		job.ProcessingStartedAt = &now
+		job.UpdatedAt = now
+		return true
+	})
+	if err != nil {
+		log.Printf("Failed to record processing start for job %s: %v", job.ID, err)
+	}
+}
+
+// recordAttempt appends the attempt to the job's history, when a JobAttemptRepository
+// is set. Best-effort: a failed save only leaves a gap in the history.
+func (w *JobWorker) recordAttempt(jobID uuid.UUID, attemptNumber int, startedAt, finishedAt time.Time, processErr error) {
+	if w.attemptRepository == nil {
+		return
+	}
+	attempt := &model.JobAttempt{
+		JobID:         jobID,
+		AttemptNumber: attemptNumber,
+		StartedAt:     startedAt,
+		FinishedAt:    finishedAt,
+		Status:        model.AttemptSucceeded,
+	}
+	if processErr != nil {
+		errMsg := processErr.Error()
+		attempt.Status = model.AttemptFailed
+		attempt.Error = &errMsg
+	}
+	if err := w.attemptRepository.Save(attempt); err != nil {
+		log.Printf("Failed to record attempt %d of job %s: %v", attemptNumber, jobID, err)
+	}
+}
+
+// defaultProcessTimeout bounds processing of job types without PROCESS_TIMEOUT_<TYPE>.
+const defaultProcessTimeout = 30 * time.Second
+
+// processJobInternal processes the job with its type's JobHandler.
 //
-// For this project, we simulate with time.Sleep to mimic API latency.
-func (w *JobWorker) processJobInternal(job *model.Job) error {
-	log.Printf("Processing job: id=%s, type=%s, clientId=%s, attempt=%d/%d",
-		job.ID, job.Type, job.ClientID, job.Attempts+1, job.MaxRetries)
-
-	// Simulate different processing times based on job type
-	switch job.Type {
-	case model.TypePaymentProcess:
-		// Simulate Stripe API call (2 seconds)
-		log.Printf("Simulating payment processing for job %s", job.ID)
-		time.Sleep(2 * time.Second)
-		log.Printf("Payment processed: %s", job.Payload)
-
-	case model.TypeEmailConfirmation:
-		// Simulate SendGrid API call (1 second)
-		log.Printf("Simulating email send for job %s", job.ID)
-		time.Sleep(1 * time.Second)
-		log.Printf("Email sent: %s", job.Payload)
+// The handler gets the payload as rewritten by the transformer chain (see PayloadTransformer
+// and HandlerPayload); a transformer error fails the attempt before the handler runs.
+// A type without a handler fails permanently.
+// A job whose work was already done (workDoneAt set by an earlier attempt) is completed
+// without running the transformers or handler again, as is every job in a dry run.
+// Processing is bounded by the type's timeout; exceeding it returns an error
+// (and the job is not marked completed).
+// The transform, handler call, and completion save are traced as children of the span in traceCtx.
+func (w *JobWorker) processJobInternal(traceCtx context.Context, job *model.Job) error {
+	logger := config.JobLogger(job.ID.String(), job.ClientID)
+	logger.Info("Processing job", "type", job.Type, "attempt", job.Attempts+1, "max_retries", job.MaxRetries)
 
-	default:
-		return fmt.Errorf("unknown job type: %s", job.Type)
+	if job.WorkDoneAt != nil {
+		logger.Info("Work already done by an earlier attempt, completing without rerunning the handler",
+			"work_done_at", job.WorkDoneAt.Format(time.RFC3339))
+		return w.completeJob(traceCtx, job)
+	}
+	if err := w.chaos.Inject(job); err != nil {
+		logger.Warn("Chaos: failing attempt on purpose", "error", err)
+		return err
+	}
+	if w.dryRun {
+		logger.Debug("Dry run, completing without running the handler")
+		return w.completeJob(traceCtx, job)
+	}
+
+	processCtx := ctx
+	timeout, hasTimeout := w.processTimeouts[job.Type]
+	if hasTimeout {
+		var cancel context.CancelFunc
+		processCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	payload := job.Payload
+	if w.transformers.Len() > 0 {
+		_, transformSpan := config.Tracer().Start(traceCtx, "job.transform")
+		transformed, err := w.transformers.Apply(processCtx, job)
+		if err != nil {
+			transformSpan.RecordError(err)
+			transformSpan.SetStatus(codes.Error, "payload transform failed")
+		}
+		transformSpan.End()
+		if errors.Is(err, context.DeadlineExceeded) {
+			return NewJobFailure(model.FailureTimeout, fmt.Errorf("payload transform exceeded timeout of %v", timeout))
+		}
+		if err != nil {
+			return fmt.Errorf("payload transform failed: %w", err)
+		}
+		payload = transformed
+	}
+
+	// Unregistered types can't succeed on a retry, so they dead-letter right away
+	handler, ok := w.handlers[job.Type]
+	if !ok {
+		return exception.NewNonRetryableError(fmt.Errorf("no handler registered for job type %s", job.Type))
+	}
+
+	_, handleSpan := config.Tracer().Start(traceCtx, "job.handle")
+	err := handler.Handle(withHandlerPayload(processCtx, payload), job)
+	if err != nil {
+		handleSpan.RecordError(err)
+		handleSpan.SetStatus(codes.Error, "job handler failed")
+	}
+	handleSpan.End()
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return NewJobFailure(model.FailureTimeout, fmt.Errorf("processing exceeded timeout of %v", timeout))
+	}
+	if err != nil {
+		return err
 	}
+	return w.completeJob(traceCtx, job)
+}
+
+// completeJob saves the job COMPLETED and updates its cache entry.
+func (w *JobWorker) completeJob(traceCtx context.Context, job *model.Job) error {
+	logger := config.JobLogger(job.ID.String(), job.ClientID)
 
 	// Mark job as completed
 	now := time.Now()
-	job.Status = model.StatusCompleted
-	job.CompletedAt = &now
-	job.UpdatedAt = now
+	var endToEnd time.Duration
 
-	if err := w.jobRepository.Save(job); err != nil {
+	_, saveSpan := config.Tracer().Start(traceCtx, "db.save")
+	err := updateJobWithRetry(w.jobRepository, job, func(job *model.Job) bool {
+		// Finished elsewhere in the meantime, e.g. by a redelivered copy
+		if job.Status.IsFinished() {
+			return false
+		}
+		job.Status = model.StatusCompleted
+		job.CompletedAt = &now
+		job.UpdatedAt = now
+
+		// What the client waited: scheduling delay, retries, and queue time included
+		endToEnd = now.Sub(job.CreatedAt)
+		job.SLABreached = w.slas.Breached(job.Type, endToEnd)
+		return true
+	})
+	if err != nil && !errors.Is(err, repository.ErrStaleJob) {
+		saveSpan.RecordError(err)
+		saveSpan.SetStatus(codes.Error, "failed to save completed job")
+	}
+	saveSpan.End()
+	if errors.Is(err, repository.ErrStaleJob) {
+		logger.Info("Job already finished elsewhere, keeping its state", "status", job.Status)
+		w.cacheService.UpdateJob(job)
+		return nil
+	}
+	if err != nil {
 		return fmt.Errorf("failed to save completed job: %w", err)
 	}
 
 	// Update cache with completed job
 	w.cacheService.UpdateJob(job)
 
-	log.Printf("Job %s completed successfully: type=%s, processingTime=%dms",
-		job.ID, job.Type, getProcessingTime(job.Type))
+	config.GetMetrics().RecordEndToEndLatency(string(job.Type), endToEnd)
+	if job.SLABreached {
+		config.GetMetrics().IncSLABreach(string(job.Type))
+		logger.Warn("Job breached its SLA", "type", job.Type, "end_to_end_ms", endToEnd.Milliseconds(),
+			"sla_ms", w.slas[job.Type].Milliseconds())
+	}
+
+	logger.Info("Job completed successfully", "type", job.Type, "processing_time_ms", getProcessingTime(job.Type),
+		"end_to_end_ms", endToEnd.Milliseconds())
 
 	return nil
 }
 
+// sleepContext stands in for a downstream call taking d, returning early with
+// the context's error if it is cancelled or times out first.
+func sleepContext(c context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-c.Done():
+		return c.Err()
+	}
+}
+
 // handleJobFailure handles job failure with retry logic and exponential backoff.
 //
-// Retry Strategy:
-// - Attempt 1 fails: Retry in 2^1 = 2 seconds
-// - Attempt 2 fails: Retry in 2^2 = 4 seconds
-// - Attempt 3 fails: Retry in 2^3 = 8 seconds
+// Retry Strategy (by default, see BackoffConfig for per-type schedules and computeBackoff):
+// - Attempt n fails: Retry after a random delay in [0, 2^n] seconds (full jitter), so
+//   at most 2s, 4s, then 8s, never more than MAX_BACKOFF_SECONDS (default 300)
+// - Every delay is floored at RETRY_MIN_DELAY (default 0), itself clamped to the cap
 // - Attempt 4: Move to DEAD_LETTER (max 3 retries exceeded)
+// - A permanent failure (see RetryClassifier), e.g. an exception.NonRetryableError
+//   returned by the handler, moves to DEAD_LETTER right away, whatever attempts are left
+// - Dead letters take the shared path, see deadLetterQueue
 func (w *JobWorker) handleJobFailure(job *model.Job, jobErr error) {
-	// Increment attempt counter
-	job.Attempts++
 	errMsg := jobErr.Error()
-	job.ErrorMessage = &errMsg
-	job.UpdatedAt = time.Now()
+	reason := w.failureClassifier.Classify(jobErr)
+	permanent := w.retryClassifier.IsPermanent(jobErr)
+	var delay time.Duration
 
-	if job.Attempts < job.MaxRetries {
-		// Calculate exponential backoff delay: 2^attempts seconds
-		delaySeconds := int64(math.Pow(2, float64(job.Attempts)))
+	// Max retries exceeded, or not worth retrying - move to dead letter queue
+	lastAttempt := func(job *model.Job) bool {
+		return permanent || job.Attempts+1 >= job.MaxRetries
+	}
 
-		log.Printf("Job %s failed (attempt %d/%d), will retry in %ds: %s",
-			job.ID, job.Attempts, job.MaxRetries, delaySeconds, jobErr.Error())
+	// Both changes are applied again to the stored job if it was saved concurrently,
+	// so the attempt counts on top of whatever that save recorded
+	var err error
+	retry := !lastAttempt(job)
+	if retry {
+		err = updateJobWithRetry(w.jobRepository, job, func(job *model.Job) bool {
+			// Finished elsewhere in the meantime, e.g. by a redelivered copy
+			if job.Status.IsFinished() {
+				return false
+			}
+			// The concurrent save used up the attempts this one was to retry with
+			if lastAttempt(job) {
+				retry = false
+				return false
+			}
 
-		// Set status back to PENDING for scheduler to pick up
-		job.Status = model.StatusPending
+			// Increment attempt counter
+			job.Attempts++
+			job.ErrorMessage = &errMsg
+			job.FailureReason = &reason
+			job.UpdatedAt = time.Now()
+			delay = computeBackoff(job.Attempts, w.backoffFor(job.Type))
 
-		// Schedule for retry after exponential backoff delay
-		retryAt := time.Now().Add(time.Duration(delaySeconds) * time.Second)
-		job.ScheduledAt = &retryAt
+			// Set status back to PENDING for scheduler to pick up
+			job.Status = model.StatusPending
 
-	} else {
-		// Max retries exceeded - move to dead letter queue
-		log.Printf("Job %s moved to DEAD_LETTER after %d attempts: %s",
-			job.ID, job.Attempts, jobErr.Error())
+			// Schedule for retry after exponential backoff delay
+			retryAt := time.Now().Add(delay)
+			job.ScheduledAt = &retryAt
+			return true
+		})
+	}
+	if !retry {
+		err = w.deadLetters().deadLetter(job, reason, errMsg, func(job *model.Job) bool {
+			if job.Status.IsFinished() {
+				return false
+			}
+			job.Attempts++
+			return true
+		})
+	}
 
-		job.Status = model.StatusDeadLetter
-		now := time.Now()
-		job.CompletedAt = &now
+	logger := config.JobLogger(job.ID.String(), job.ClientID).With(
+		"attempt", job.Attempts, "max_retries", job.MaxRetries, "failure_reason", reason, "error", errMsg)
+	if errors.Is(err, repository.ErrStaleJob) {
+		logger.Info("Job already finished elsewhere, not recording the failure", "status", job.Status)
+		w.cacheService.UpdateJob(job)
+		return
+	}
+	switch {
+	case job.Status == model.StatusPending:
+		logger.Warn("Job failed, will retry", "retry_in", delay.String())
+	case permanent:
+		logger.Error("Job moved to DEAD_LETTER on permanent failure")
+	default:
+		logger.Error("Job moved to DEAD_LETTER after max attempts")
+	}
+	if err != nil {
+		logger.Error("Failed to save job failure state", "error", err)
 	}
 
-	if err := w.jobRepository.Save(job); err != nil {
-		log.Printf("Failed to save job failure state for %s: %v", job.ID, err)
+	// Update cache; a dead letter's entry was updated on its way to the DLQ
+	if retry {
+		w.cacheService.UpdateJob(job)
 	}
+}
+
+// recordTypeOutcome reports an attempt, and its trial ID if it was the half-open trial
+// (see TypeCircuitBreaker.Allow), to the type's circuit breaker. Permanent failures are
+// the job's fault, not the downstream's, so they aren't counted.
+func (w *JobWorker) recordTypeOutcome(jobType model.JobType, trialID string, processErr error) {
+	switch {
+	case processErr == nil:
+		w.typeBreaker.RecordSuccess(jobType, trialID)
+	case !w.retryClassifier.IsPermanent(processErr):
+		w.typeBreaker.RecordFailure(jobType, trialID)
+	}
+}
+
+// bulkheadDeferDelay is how long a job turned away by a full bulkhead (or a client
+// at its limit, or a half-open type circuit breaker) waits before being rescheduled.
+const bulkheadDeferDelay = 1 * time.Second
+
+// deferMessage defers the message's job (see deferJob) and commits the message. If the
+// job can't be saved PENDING, the message is left uncommitted rather than strand the
+// job RUNNING; the scheduler's reaper reschedules it once it counts as stuck.
+func (w *JobWorker) deferMessage(job *model.Job, delay time.Duration, msg kafka.Message, reader messageCommitter, logger *slog.Logger) {
+	if err := w.deferJob(job, delay); err != nil {
+		logger.Error("Failed to defer job, leaving message uncommitted", "error", err)
+		return
+	}
+	if err := reader.CommitMessages(context.Background(), msg); err != nil {
+		logger.Error("Failed to commit message", "error", err)
+	}
+}
 
-	// Update cache
+// deferJob returns a job to PENDING without counting an attempt, so the scheduler
+// republishes it after delay, once its type (or client) can take it again. A job that
+// finished in the meantime is left as is.
+func (w *JobWorker) deferJob(job *model.Job, delay time.Duration) error {
+	retryAt := time.Now().Add(delay)
+	err := updateJobWithRetry(w.jobRepository, job, func(job *model.Job) bool {
+		if job.Status.IsFinished() {
+			return false
+		}
+		job.Status = model.StatusPending
+		job.ScheduledAt = &retryAt
+		job.UpdatedAt = time.Now()
+		return true
+	})
+	if err != nil && !errors.Is(err, repository.ErrStaleJob) {
+		return err
+	}
 	w.cacheService.UpdateJob(job)
+	return nil
+}
+
+// Retry backoff defaults: 2s after the first failure, doubling with each attempt,
+// capped at 300s when MAX_BACKOFF_SECONDS is unset.
+const (
+	defaultBackoffBase           = 2.0
+	defaultBackoffInitialSeconds = 2.0
+	defaultMaxBackoffSeconds     = 300
+)
+
+// BackoffConfig is a job type's retry delay schedule: the delay after attempt n is
+// InitialSeconds * Base^(n-1) seconds, capped at MaxSeconds and floored at MinDelay.
+//
+// Configuration, per type (e.g. BACKOFF_BASE_EMAIL_CONFIRMATION=1.5):
+// - BACKOFF_BASE_<TYPE> (default 2, at least 1): growth per attempt; lower backs off
+//   more gently, 1 retries at a constant delay
+// - BACKOFF_INITIAL_SECONDS_<TYPE> (default 2): delay after the first failure, e.g.
+//   BACKOFF_INITIAL_SECONDS_PAYMENT_PROCESS=1 to retry payments sooner
+// - MAX_BACKOFF_SECONDS (default 300) and RETRY_MIN_DELAY (default 0) apply to every type;
+//   a RETRY_MIN_DELAY above the cap is clamped to it
+type BackoffConfig struct {
+	Base           float64
+	InitialSeconds float64
+	MaxSeconds     int
+	MinDelay       time.Duration
+}
+
+// backoffFor returns the retry schedule of a job type.
+func (w *JobWorker) backoffFor(jobType model.JobType) BackoffConfig {
+	if backoff, ok := w.backoffs[jobType]; ok {
+		return backoff
+	}
+	return w.defaultBackoff
+}
+
+// backoffJitter returns a value in [0, 1) scaling each retry delay.
+// Tests replace it with a seeded source.
+var backoffJitter = rand.Float64
+
+// computeBackoff returns the retry delay after the given number of attempts, with full
+// jitter: a random delay in [0, min(InitialSeconds * Base^(attempts-1), MaxSeconds)]
+// seconds, but never less than MinDelay (NewJobWorker keeps the floor at or below the cap).
+func computeBackoff(attempts int, cfg BackoffConfig) time.Duration {
+	ceiling := math.Min(cfg.InitialSeconds*math.Pow(cfg.Base, float64(attempts-1)), float64(cfg.MaxSeconds))
+	delay := time.Duration(ceiling * backoffJitter() * float64(time.Second))
+	if delay < cfg.MinDelay {
+		return cfg.MinDelay
+	}
+	return delay
 }
 
 // getProcessingTime returns the simulated processing time for a job type.
 func getProcessingTime(jobType model.JobType) int {
-	switch jobType {
-	case model.TypePaymentProcess:
-		return 2000
-	case model.TypeEmailConfirmation:
-		return 1000
-	default:
+	spec, ok := model.LookupJobTypeSpec(jobType)
+	if !ok {
 		return 0
 	}
-}
\ No newline at end of file
+	return spec.ProcessingTimeMs
+}
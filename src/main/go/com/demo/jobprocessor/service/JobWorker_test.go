@@ -0,0 +1,865 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/exception"
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+// TestReprocessChargedPaymentSkipsCharge simulates redelivery of a payment job whose
+// charge succeeded but whose completion was never recorded (e.g. worker crash).
+func TestReprocessChargedPaymentSkipsCharge(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+
+	job := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	job.Status = model.StatusRunning
+	job.Charged = true
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	start := time.Now()
+	if err := w.processJobInternal(context.Background(), job); err != nil {
+		t.Fatalf("processJobInternal: %v", err)
+	}
+
+	// The simulated charge takes 2s; skipping it must be near-instant
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("charged job was charged again (took %v)", elapsed)
+	}
+
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusCompleted || !saved.Charged {
+		t.Fatalf("expected COMPLETED and charged, got status=%s charged=%v", saved.Status, saved.Charged)
+	}
+}
+
+// TestChargePaymentPersistsFlag verifies the charged flag and the work done marker are
+// saved as soon as the charge succeeds.
+func TestChargePaymentPersistsFlag(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+
+	job := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	if err := w.handlers[model.TypePaymentProcess].Handle(context.Background(), job); err != nil {
+		t.Fatalf("handle payment: %v", err)
+	}
+
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if !saved.Charged || saved.WorkDoneAt == nil {
+		t.Fatalf("expected the charged flag and work done marker persisted, got charged=%v workDoneAt=%v", saved.Charged, saved.WorkDoneAt)
+	}
+	if saved.Status != model.StatusRunning {
+		t.Fatalf("charge must not complete the job, got status=%s", saved.Status)
+	}
+	if cached := w.cacheService.GetJob(job.ID); cached == nil || !cached.Charged {
+		t.Fatal("expected cached job to carry the charged flag")
+	}
+}
+
+// TestComputeBackoffMinDelayFloor verifies the floor lifts short delays and leaves longer ones alone.
+func TestComputeBackoffMinDelayFloor(t *testing.T) {
+	// Jitter at its upper bound, so delays are the full 2^attempts seconds
+	previous := backoffJitter
+	backoffJitter = func() float64 { return 1 }
+	t.Cleanup(func() { backoffJitter = previous })
+
+	tests := []struct {
+		attempts int
+		minDelay time.Duration
+		want     time.Duration
+	}{
+		{attempts: 1, minDelay: 0, want: 2 * time.Second},
+		{attempts: 1, minDelay: 30 * time.Second, want: 30 * time.Second},
+		{attempts: 3, minDelay: 5 * time.Second, want: 8 * time.Second},
+		{attempts: 5, minDelay: 30 * time.Second, want: 32 * time.Second},
+	}
+
+	for _, tt := range tests {
+		cfg := BackoffConfig{Base: 2, InitialSeconds: 2, MaxSeconds: defaultMaxBackoffSeconds, MinDelay: tt.minDelay}
+		if got := computeBackoff(tt.attempts, cfg); got != tt.want {
+			t.Errorf("computeBackoff(%d, %v) = %v, want %v", tt.attempts, tt.minDelay, got, tt.want)
+		}
+	}
+}
+
+// TestNewJobWorkerClampsMinDelayToCap verifies a RETRY_MIN_DELAY above MAX_BACKOFF_SECONDS
+// is clamped to the cap for every type, so the cap still bounds every retry.
+func TestNewJobWorkerClampsMinDelayToCap(t *testing.T) {
+	t.Setenv("KAFKA_BOOTSTRAP_SERVERS", "127.0.0.1:1")
+	t.Setenv("RETRY_MIN_DELAY", "10m")
+	t.Setenv("MAX_BACKOFF_SECONDS", "60")
+
+	w := NewJobWorker(nil, nil, 1)
+	defer w.Stop()
+	for _, jobType := range []model.JobType{model.TypePaymentProcess, model.TypeEmailConfirmation} {
+		backoff := w.backoffFor(jobType)
+		if backoff.MinDelay != time.Minute {
+			t.Fatalf("%s: expected the floor clamped to 1m, got %v", jobType, backoff.MinDelay)
+		}
+		if got := computeBackoff(1, backoff); got > time.Duration(backoff.MaxSeconds)*time.Second {
+			t.Fatalf("%s: delay %v over the cap", jobType, got)
+		}
+	}
+}
+
+// TestComputeBackoffSchedules verifies per-type base and initial delay settings give
+// the expected, non-decreasing schedule up to the cap.
+func TestComputeBackoffSchedules(t *testing.T) {
+	// Jitter at its upper bound, so delays are the full ceiling
+	previous := backoffJitter
+	backoffJitter = func() float64 { return 1 }
+	t.Cleanup(func() { backoffJitter = previous })
+
+	tests := []struct {
+		name string
+		cfg  BackoffConfig
+		want []time.Duration
+	}{
+		{
+			name: "gentle email",
+			cfg:  BackoffConfig{Base: 1.5, InitialSeconds: 2, MaxSeconds: 300},
+			want: []time.Duration{2 * time.Second, 3 * time.Second, 4500 * time.Millisecond, 6750 * time.Millisecond},
+		},
+		{
+			name: "fast payment",
+			cfg:  BackoffConfig{Base: 2, InitialSeconds: 1, MaxSeconds: 5},
+			want: []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var last time.Duration
+			for i, want := range tt.want {
+				got := computeBackoff(i+1, tt.cfg)
+				if got != want {
+					t.Fatalf("attempt %d: expected %v, got %v", i+1, want, got)
+				}
+				if got < last {
+					t.Fatalf("attempt %d: delay %v shorter than the previous %v", i+1, got, last)
+				}
+				last = got
+			}
+		})
+	}
+}
+
+// TestComputeBackoffJitterBounds verifies jittered delays stay within [0, cap] and vary.
+func TestComputeBackoffJitterBounds(t *testing.T) {
+	previous := backoffJitter
+	backoffJitter = rand.New(rand.NewPCG(1, 2)).Float64
+	t.Cleanup(func() { backoffJitter = previous })
+
+	cfg := BackoffConfig{Base: 2, InitialSeconds: 2, MaxSeconds: 300}
+	seen := make(map[time.Duration]bool)
+	for attempts := 0; attempts <= 40; attempts++ {
+		for i := 0; i < 20; i++ {
+			delay := computeBackoff(attempts, cfg)
+			ceiling := time.Duration(math.Min(math.Pow(2, float64(attempts)), 300)) * time.Second
+			if delay < 0 || delay > ceiling {
+				t.Fatalf("attempts=%d: delay %v outside [0, %v]", attempts, delay, ceiling)
+			}
+			seen[delay] = true
+		}
+	}
+	if len(seen) < 100 {
+		t.Fatalf("expected jittered delays to vary, got %d distinct values", len(seen))
+	}
+
+	cfg.MinDelay = time.Minute
+	if got := computeBackoff(3, cfg); got != time.Minute {
+		t.Fatalf("expected the min delay floor to lift the jittered delay, got %v", got)
+	}
+}
+
+// TestNextPriorityMessagePrefersHighPriority verifies a waiting high-priority message
+// is taken before a waiting regular one.
+func TestNextPriorityMessagePrefersHighPriority(t *testing.T) {
+	w := &JobWorker{stopCh: make(chan struct{})}
+	highPriorityCh := make(chan fetchedMessage, 1)
+	regularCh := make(chan fetchedMessage, 1)
+	regularCh <- fetchedMessage{msg: kafka.Message{Value: []byte("regular")}}
+	highPriorityCh <- fetchedMessage{msg: kafka.Message{Value: []byte("high")}}
+
+	first, ok := w.nextPriorityMessage(regularCh, highPriorityCh)
+	if !ok || string(first.msg.Value) != "high" {
+		t.Fatalf("expected the high-priority message first, got %q", first.msg.Value)
+	}
+	second, ok := w.nextPriorityMessage(regularCh, highPriorityCh)
+	if !ok || string(second.msg.Value) != "regular" {
+		t.Fatalf("expected the regular message next, got %q", second.msg.Value)
+	}
+
+	close(w.stopCh)
+	if _, ok := w.nextPriorityMessage(regularCh, highPriorityCh); ok {
+		t.Fatal("expected no message after stop")
+	}
+}
+
+// TestNextPriorityMessageWithoutPriorityTopics verifies merged readers work with no high-priority channel.
+func TestNextPriorityMessageWithoutPriorityTopics(t *testing.T) {
+	w := &JobWorker{stopCh: make(chan struct{})}
+	regularCh := make(chan fetchedMessage, 1)
+	regularCh <- fetchedMessage{msg: kafka.Message{Value: []byte("eu-west")}}
+
+	fetched, ok := w.nextPriorityMessage(regularCh, nil)
+	if !ok || string(fetched.msg.Value) != "eu-west" {
+		t.Fatalf("expected the regular message, got %q", fetched.msg.Value)
+	}
+}
+
+// TestHandlesMessageFiltersByType verifies WORKER_TYPES filtering on the job-type header.
+func TestHandlesMessageFiltersByType(t *testing.T) {
+	typed := func(jobType model.JobType) kafka.Message {
+		return kafka.Message{Headers: []kafka.Header{{Key: config.JobTypeHeader, Value: []byte(jobType)}}}
+	}
+
+	all := &JobWorker{workerTypes: parseWorkerTypes("")}
+	if !all.handlesMessage(typed(model.TypePaymentProcess)) {
+		t.Fatal("expected every type to be handled by default")
+	}
+
+	email := &JobWorker{workerTypes: parseWorkerTypes(" email_confirmation, UNKNOWN")}
+	if !email.handlesMessage(typed(model.TypeEmailConfirmation)) {
+		t.Fatal("expected EMAIL_CONFIRMATION to be handled")
+	}
+	if email.handlesMessage(typed(model.TypePaymentProcess)) {
+		t.Fatal("expected PAYMENT_PROCESS to be skipped")
+	}
+	if !email.handlesMessage(kafka.Message{Value: []byte("no-header")}) {
+		t.Fatal("expected messages without a type header to be handled")
+	}
+}
+
+// TestFetchBackoffDoublesAndResets verifies fetch error delays grow to the cap and
+// that the failure gauge is cleared on recovery.
+func TestFetchBackoffDoublesAndResets(t *testing.T) {
+	b := newFetchBackoff("test", 5*time.Second)
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		delays = append(delays, b.Failure(errors.New("broker unavailable")))
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Fatalf("delay %d: expected %v, got %v", i, want[i], delays[i])
+		}
+	}
+
+	b.Success()
+	if got := b.Failure(errors.New("broker unavailable")); got != time.Second {
+		t.Fatalf("expected backoff to reset after success, got %v", got)
+	}
+	b.Success()
+	if got := config.GetMetrics().KafkaFetchFailures(); got != 0 {
+		t.Fatalf("expected no consecutive failures after recovery, got %d", got)
+	}
+}
+
+// TestHealthCheckJobCompletesImmediately verifies the canary handler does no work but
+// still goes through the completion save.
+func TestHealthCheckJobCompletesImmediately(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+
+	job := model.NewJob("monitor", model.TypeHealthCheck, "probe_1")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	start := time.Now()
+	if err := w.processJobInternal(context.Background(), job); err != nil {
+		t.Fatalf("processJobInternal: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected the canary to complete immediately, took %v", elapsed)
+	}
+
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusCompleted || saved.CompletedAt == nil {
+		t.Fatalf("expected COMPLETED with completion time, got %+v", saved)
+	}
+}
+
+// TestWorkerLogsCarryJobID verifies the worker's structured log lines are JSON with
+// the job_id and client_id keys set, through completion and failure handling.
+func TestWorkerLogsCarryJobID(t *testing.T) {
+	var buf bytes.Buffer
+	prior := config.Logger()
+	config.SetLogger(config.NewJSONLogger(&buf, slog.LevelDebug))
+	t.Cleanup(func() { config.SetLogger(prior) })
+
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+	job := model.NewJob("monitor", model.TypeHealthCheck, "probe_1")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	if err := w.processJobInternal(context.Background(), job); err != nil {
+		t.Fatalf("processJobInternal: %v", err)
+	}
+	w.handleJobFailure(job, errors.New("boom"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) < 3 {
+		t.Fatalf("expected start, completion, and failure lines, got %q", buf.String())
+	}
+	for _, line := range lines {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("expected a JSON log line, got %q: %v", line, err)
+		}
+		if entry[config.LogKeyJobID] != job.ID.String() || entry[config.LogKeyClientID] != "monitor" {
+			t.Fatalf("expected job_id and client_id on every worker line, got %q", line)
+		}
+		if entry["level"] == nil || entry["msg"] == nil {
+			t.Fatalf("expected level and msg keys, got %q", line)
+		}
+	}
+}
+
+// TestProcessingTimeoutSchedulesRetry verifies processing past the type's timeout is
+// cancelled, not marked completed, and scheduled for retry.
+func TestProcessingTimeoutSchedulesRetry(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+	w.processTimeouts = map[model.JobType]time.Duration{model.TypeEmailConfirmation: 50 * time.Millisecond}
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	start := time.Now()
+	err := w.processJobInternal(context.Background(), job)
+	if err == nil || err.Error() != "processing exceeded timeout of 50ms" {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	// The simulated send takes 1s; it must be cancelled at the deadline
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("slow handler was not cancelled (took %v)", elapsed)
+	}
+
+	w.handleJobFailure(job, err)
+
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusPending || saved.Attempts != 1 || saved.CompletedAt != nil {
+		t.Fatalf("expected PENDING retry after 1 attempt, got status=%s attempts=%d", saved.Status, saved.Attempts)
+	}
+	if saved.ErrorMessage == nil || !strings.Contains(*saved.ErrorMessage, "exceeded timeout") {
+		t.Fatalf("expected timeout error message, got %v", saved.ErrorMessage)
+	}
+	if saved.FailureReason == nil || *saved.FailureReason != model.FailureTimeout {
+		t.Fatalf("expected failure reason timeout, got %v", saved.FailureReason)
+	}
+	if !saved.ScheduledAt.After(start) {
+		t.Fatalf("expected retry scheduled in the future, got %v", saved.ScheduledAt)
+	}
+}
+
+// TestDeadLetterMessageEnvelope verifies dead-lettered jobs are keyed by ID with a JSON envelope,
+// and that dead-lettering still saves without a DLQ writer.
+func TestDeadLetterMessageEnvelope(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	job.Status = model.StatusRunning
+	job.Attempts = job.MaxRetries - 1
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	w.handleJobFailure(job, errors.New("smtp unavailable"))
+
+	saved, _ := repo.FindByID(job.ID)
+	if saved.Status != model.StatusDeadLetter {
+		t.Fatalf("expected DEAD_LETTER, got %s", saved.Status)
+	}
+
+	msg, err := deadLetterMessage(job)
+	if err != nil {
+		t.Fatalf("deadLetterMessage: %v", err)
+	}
+	if string(msg.Key) != job.ID.String() {
+		t.Fatalf("expected key %s, got %s", job.ID, msg.Key)
+	}
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(msg.Value, &envelope); err != nil {
+		t.Fatalf("envelope is not JSON: %v", err)
+	}
+	if envelope["clientId"] != "customer-1" || envelope["type"] != "EMAIL_CONFIRMATION" ||
+		envelope["attempts"] != float64(job.MaxRetries) || envelope["errorMessage"] != "smtp unavailable" {
+		t.Fatalf("unexpected envelope: %v", envelope)
+	}
+}
+
+// TestConsumeGoroutinesOwnTheirReaders verifies the worker creates consumer group readers
+// per goroutine, so no goroutine commits on another's reader: one each without merging,
+// and one per cluster and topic each when merging.
+func TestConsumeGoroutinesOwnTheirReaders(t *testing.T) {
+	t.Setenv("KAFKA_BOOTSTRAP_SERVERS", "127.0.0.1:1")
+
+	w := NewJobWorker(nil, nil, 3)
+	defer w.Stop()
+	if !w.ownedReaders || len(w.kafkaReaders) != 3 {
+		t.Fatalf("expected 3 owned readers, got owned=%v readers=%d", w.ownedReaders, len(w.kafkaReaders))
+	}
+	seen := make(map[*kafka.Reader]bool)
+	for _, reader := range w.kafkaReaders {
+		if seen[reader] {
+			t.Fatal("expected a distinct reader per goroutine")
+		}
+		seen[reader] = true
+		if reader.Config().GroupID != config.GetConsumerGroupID() {
+			t.Fatalf("expected every reader in group %s, got %s", config.GetConsumerGroupID(), reader.Config().GroupID)
+		}
+	}
+
+	t.Setenv("KAFKA_CONSUMER_CLUSTERS", "127.0.0.1:1;127.0.0.1:2")
+	t.Setenv("KAFKA_PRIORITY_TOPICS", "true")
+	merged := NewJobWorker(nil, nil, 3)
+	defer merged.Stop()
+	if merged.ownedReaders || len(merged.kafkaReaders) != 6 || len(merged.highPriorityReaders) != 6 {
+		t.Fatalf("expected 6 readers per topic when merging, got owned=%v readers=%d high-priority=%d",
+			merged.ownedReaders, len(merged.kafkaReaders), len(merged.highPriorityReaders))
+	}
+	owners := make(map[*kafka.Reader]int)
+	for i := 0; i < merged.concurrency; i++ {
+		for _, readers := range [][]*kafka.Reader{merged.kafkaReaders, merged.highPriorityReaders} {
+			owned := merged.readersOf(readers, i)
+			if len(owned) != 2 || owned[0].Config().Brokers[0] == owned[1].Config().Brokers[0] {
+				t.Fatalf("expected goroutine %d to own one reader per cluster, got %d", i, len(owned))
+			}
+			for _, reader := range owned {
+				if prev, ok := owners[reader]; ok {
+					t.Fatalf("reader owned by goroutines %d and %d", prev, i)
+				}
+				owners[reader] = i
+			}
+		}
+	}
+}
+
+// TestWorkDoneMarkerCrashWindows seeds the state a crash leaves at each step of a
+// successful attempt and verifies the handler only runs again when the work wasn't
+// recorded as done (by a handler's side-effect save, see PaymentHandler).
+func TestWorkDoneMarkerCrashWindows(t *testing.T) {
+	doneAt := time.Now().Add(-time.Minute)
+	cases := []struct {
+		name        string
+		status      model.JobStatus
+		workDoneAt  *time.Time
+		wantHandled int
+	}{
+		{"crash before the side effect and marker are saved", model.StatusRunning, nil, 1},
+		{"crash after the marker, before the COMPLETED save", model.StatusRunning, &doneAt, 0},
+		{"crash after the COMPLETED save, before the commit", model.StatusCompleted, &doneAt, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			w := newTestWorker(t, repo)
+			handled := 0
+			w.RegisterHandler(model.TypeHealthCheck, JobHandlerFunc(func(ctx context.Context, job *model.Job) error {
+				handled++
+				return nil
+			}))
+
+			job := model.NewJob("customer-1", model.TypeHealthCheck, "probe_1")
+			job.Status = tc.status
+			job.WorkDoneAt = tc.workDoneAt
+			if err := repo.Create(job); err != nil {
+				t.Fatalf("seed job: %v", err)
+			}
+
+			// Redelivery after the crash
+			if err := w.processJobInternal(context.Background(), job); err != nil {
+				t.Fatalf("processJobInternal: %v", err)
+			}
+			if handled != tc.wantHandled {
+				t.Fatalf("expected the handler to run %d times, ran %d", tc.wantHandled, handled)
+			}
+			saved, err := repo.FindByID(job.ID)
+			if err != nil {
+				t.Fatalf("reload job: %v", err)
+			}
+			if saved.Status != model.StatusCompleted {
+				t.Fatalf("expected COMPLETED, got %+v", saved)
+			}
+		})
+	}
+}
+
+// recordingCommitter is a messageCommitter appending "commit" to the shared event log.
+type recordingCommitter struct {
+	events *[]string
+}
+
+func (c recordingCommitter) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	*c.events = append(*c.events, "commit")
+	return nil
+}
+
+// TestDeliverySemanticsCommitOrder verifies the offset is committed after the handler
+// by default and before it with at-most-once delivery.
+func TestDeliverySemanticsCommitOrder(t *testing.T) {
+	cases := []struct {
+		name       string
+		atMostOnce bool
+		want       string
+	}{
+		{"at-least-once", false, "handle,commit"},
+		{"at-most-once", true, "commit,handle"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			w := newTestWorker(t, repo)
+			w.atMostOnce = tc.atMostOnce
+			var events []string
+			w.RegisterHandler(model.TypeHealthCheck, JobHandlerFunc(func(ctx context.Context, job *model.Job) error {
+				events = append(events, "handle")
+				return nil
+			}))
+
+			job := model.NewJob("monitor", model.TypeHealthCheck, "probe_1")
+			job.Status = model.StatusRunning
+			if err := repo.Create(job); err != nil {
+				t.Fatalf("seed job: %v", err)
+			}
+
+			w.processJob(kafka.Message{Value: []byte(job.ID.String())}, recordingCommitter{&events}, 0)
+
+			if got := strings.Join(events, ","); got != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, got)
+			}
+			saved, err := repo.FindByID(job.ID)
+			if err != nil {
+				t.Fatalf("reload job: %v", err)
+			}
+			if saved.Status != model.StatusCompleted {
+				t.Fatalf("expected COMPLETED, got %s", saved.Status)
+			}
+		})
+	}
+}
+
+// TestPaymentLockSkipsRedeliveredCharge simulates a crash after the payment lock was
+// taken: the redelivered job holds the lock, so it is neither charged again nor marked
+// charged but left to reconciliation, while another job for the same order is refused.
+func TestPaymentLockSkipsRedeliveredCharge(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+	handler := w.handlers[model.TypePaymentProcess]
+
+	job := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	// The lock the crashed attempt took before charging
+	if err := w.cacheService.redisClient.Set(context.Background(), "payment:customer-1:order_1:charge", job.ID.String(), time.Hour).Err(); err != nil {
+		t.Fatalf("seed lock: %v", err)
+	}
+
+	start := time.Now()
+	err := handler.Handle(context.Background(), job)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("redelivered job was charged again (took %v)", elapsed)
+	}
+	if !exception.IsNonRetryableError(err) || w.failureClassifier.Classify(err) != model.FailureNeedsReconciliation {
+		t.Fatalf("expected a permanent needs_reconciliation failure for the interrupted charge, got %v", err)
+	}
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Charged {
+		t.Fatal("expected the interrupted charge not to be recorded as charged")
+	}
+	if paymentLockKey("customer-2", job.Payload) == paymentLockKey(job.ClientID, job.Payload) {
+		t.Fatal("expected another client's order with the same ID to take its own lock")
+	}
+
+	other := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	other.Status = model.StatusRunning
+	if err := repo.Create(other); err != nil {
+		t.Fatalf("seed other job: %v", err)
+	}
+	if err := handler.Handle(context.Background(), other); !exception.IsNonRetryableError(err) {
+		t.Fatalf("expected a permanent failure for a second charge of the order, got %v", err)
+	}
+}
+
+// TestRetryAfterReconciliationTakesOverPaymentLock verifies an operator retry of a payment
+// dead-lettered as needs_reconciliation lets its next attempt take its own lock over and
+// charge, once, while the lock keeps other jobs for the order out.
+func TestRetryAfterReconciliationTakesOverPaymentLock(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+	handler := w.handlers[model.TypePaymentProcess]
+	s := NewJobService(repo)
+	s.SetCacheService(w.cacheService)
+
+	reason := model.FailureNeedsReconciliation
+	job := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	job.Status = model.StatusDeadLetter
+	job.FailureReason = &reason
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	lockKey := "payment:customer-1:order_1:charge"
+	if err := w.cacheService.redisClient.Set(context.Background(), lockKey, job.ID.String(), time.Hour).Err(); err != nil {
+		t.Fatalf("seed lock: %v", err)
+	}
+
+	retried, err := s.RetryJob("customer-1", job.ID)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if err := handler.Handle(context.Background(), retried); err != nil {
+		t.Fatalf("expected the reconciled retry to charge, got %v", err)
+	}
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if !saved.Charged {
+		t.Fatal("expected the reconciled retry to be recorded as charged")
+	}
+	if holder, _ := w.cacheService.redisClient.Get(context.Background(), lockKey).Result(); holder != job.ID.String() {
+		t.Fatalf("expected the lock still held by the job, got %q", holder)
+	}
+
+	// The mark is used up: a crash during that charge needs reconciling again
+	retried.Charged = false
+	err = handler.Handle(context.Background(), retried)
+	if w.failureClassifier.Classify(err) != model.FailureNeedsReconciliation {
+		t.Fatalf("expected needs_reconciliation once the mark is used, got %v", err)
+	}
+}
+
+// TestFailedAttemptsRecordHistory verifies each failed attempt saves its own JobAttempt
+// row, served oldest first by GetJobAttempts.
+func TestFailedAttemptsRecordHistory(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewJobRepository(db)
+	attempts := repository.NewJobAttemptRepository(db)
+	w := newTestWorker(t, repo)
+	w.SetJobAttemptRepository(attempts)
+	w.RegisterHandler(model.TypeHealthCheck, JobHandlerFunc(func(ctx context.Context, job *model.Job) error {
+		return fmt.Errorf("probe %d failed", job.Attempts+1)
+	}))
+
+	job := model.NewJob("monitor", model.TypeHealthCheck, "probe_1")
+	job.MaxRetries = 5
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	var events []string
+	for i := 0; i < 3; i++ {
+		w.processJob(kafka.Message{Value: []byte(job.ID.String())}, recordingCommitter{&events}, 0)
+	}
+
+	jobService := NewJobService(repo)
+	jobService.SetJobAttemptRepository(attempts)
+	history, err := jobService.GetJobAttempts(job.ClientID, job.ID)
+	if err != nil {
+		t.Fatalf("load attempts: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(history))
+	}
+	for i, attempt := range history {
+		wantErr := fmt.Sprintf("probe %d failed", i+1)
+		if attempt.AttemptNumber != i+1 || attempt.Status != model.AttemptFailed || attempt.Error == nil || *attempt.Error != wantErr {
+			t.Fatalf("attempt %d: expected number %d FAILED with %q, got %+v", i, i+1, wantErr, attempt)
+		}
+		if attempt.FinishedAt.Before(attempt.StartedAt) {
+			t.Fatalf("attempt %d finished before it started: %+v", i, attempt)
+		}
+	}
+}
+
+// TestChaosFailureInjection verifies a failure rate of 1 fails every attempt until the
+// job dead-letters without running its handler, and a rate of 0 fails none.
+func TestChaosFailureInjection(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+	handled := 0
+	w.RegisterHandler(model.TypeHealthCheck, JobHandlerFunc(func(ctx context.Context, job *model.Job) error {
+		handled++
+		return nil
+	}))
+
+	w.chaos = NewChaosInjector(map[model.JobType]float64{model.TypeHealthCheck: 1}, 1)
+	job := model.NewJob("monitor", model.TypeHealthCheck, "probe_1")
+	job.MaxRetries = 3
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	var events []string
+	for i := 0; i < job.MaxRetries; i++ {
+		w.processJob(kafka.Message{Value: []byte(job.ID.String())}, recordingCommitter{&events}, 0)
+	}
+	failed, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("load job: %v", err)
+	}
+	if failed.Status != model.StatusDeadLetter || failed.Attempts != job.MaxRetries || handled != 0 {
+		t.Fatalf("expected DEAD_LETTER after %d attempts without the handler, got %s after %d attempts, handled %d",
+			job.MaxRetries, failed.Status, failed.Attempts, handled)
+	}
+
+	w.chaos = NewChaosInjector(map[model.JobType]float64{model.TypeHealthCheck: 0}, 1)
+	for i := 0; i < 5; i++ {
+		job := model.NewJob("monitor", model.TypeHealthCheck, fmt.Sprintf("probe_%d", i))
+		job.Status = model.StatusRunning
+		if err := repo.Create(job); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+		w.processJob(kafka.Message{Value: []byte(job.ID.String())}, recordingCommitter{&events}, 0)
+		done, err := repo.FindByID(job.ID)
+		if err != nil {
+			t.Fatalf("load job: %v", err)
+		}
+		if done.Status != model.StatusCompleted {
+			t.Fatalf("expected job %d COMPLETED with a failure rate of 0, got %s", i, done.Status)
+		}
+	}
+	if handled != 5 {
+		t.Fatalf("expected the handler to run 5 times, ran %d", handled)
+	}
+}
+
+// TestChaosInertUnlessEnabled verifies failure rates are ignored without CHAOS_ENABLED.
+func TestChaosInertUnlessEnabled(t *testing.T) {
+	t.Setenv("CHAOS_FAILURE_RATE_HEALTH_CHECK", "1")
+	if chaos := NewChaosInjectorFromEnv(); chaos != nil {
+		t.Fatal("expected no chaos without CHAOS_ENABLED")
+	}
+	t.Setenv("CHAOS_ENABLED", "true")
+	if chaos := NewChaosInjectorFromEnv(); chaos == nil || chaos.rates[model.TypeHealthCheck] != 1 {
+		t.Fatalf("expected a HEALTH_CHECK failure rate of 1, got %+v", chaos)
+	}
+}
+
+// TestDryRunSkipsHandler verifies a dry-run worker completes a payment job without
+// its simulated gateway call, still saving the outcome.
+func TestDryRunSkipsHandler(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+	w.dryRun = true
+
+	job := model.NewJob("customer-1", model.TypePaymentProcess, "order_1|user@email.com|$10.00")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	start := time.Now()
+	if err := w.processJobInternal(context.Background(), job); err != nil {
+		t.Fatalf("process job: %v", err)
+	}
+	// The payment handler alone takes 2s
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected dry run to skip the handler, took %v", elapsed)
+	}
+
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusCompleted || saved.Charged {
+		t.Fatalf("expected COMPLETED without a charge, got %s (charged: %v)", saved.Status, saved.Charged)
+	}
+}
+
+// TestProcessJobHoldsMessageWhileDBUnavailable verifies a job that can't be loaded
+// because the DB breaker is open is neither dropped nor committed, and a stopped
+// worker leaves it for redelivery.
+func TestProcessJobHoldsMessageWhileDBUnavailable(t *testing.T) {
+	var down atomic.Bool
+	db := newFlakyTestDB(t, &down)
+	breaker, err := repository.NewDBCircuitBreaker(db, 1, time.Hour)
+	if err != nil {
+		t.Fatalf("new breaker: %v", err)
+	}
+	defer breaker.Stop()
+
+	repo := repository.NewJobRepository(db)
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	down.Store(true)
+	repo.CountByStatus(model.StatusPending)
+	down.Store(false)
+	if !breaker.Open() {
+		t.Fatal("expected the breaker open after a connection failure")
+	}
+
+	w := newTestWorker(t, repo)
+	w.stopCh = make(chan struct{})
+	var events []string
+	done := make(chan struct{})
+	go func() {
+		w.processJob(kafka.Message{Value: []byte(job.ID.String())}, recordingCommitter{&events}, 0)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected the worker to wait for the database")
+	case <-time.After(50 * time.Millisecond):
+	}
+	w.Stop()
+	w.Stop() // A second Stop is a no-op
+	<-done
+	if len(events) != 0 {
+		t.Fatalf("expected the message left uncommitted, got %v", events)
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+)
+
+// labelKeyPattern keeps keys usable as query parameters (GET /api/jobs?label.region=eu).
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// LabelValidator bounds the size of job labels so they can't be used to bloat rows.
+//
+// Limits (env, defaults):
+// - JOB_LABELS_MAX_COUNT: labels per job (10)
+// - JOB_LABELS_MAX_KEY_LENGTH: characters per key (63)
+// - JOB_LABELS_MAX_VALUE_LENGTH: characters per value (255)
+//
+// The workerPool label must name one of KAFKA_WORKER_POOLS (see config.GetWorkerPools).
+type LabelValidator struct {
+	maxCount       int
+	maxKeyLength   int
+	maxValueLength int
+	workerPools    []string
+}
+
+// NewLabelValidator creates a new LabelValidator configured from env.
+func NewLabelValidator() *LabelValidator {
+	return &LabelValidator{
+		maxCount:       config.PositiveIntFromEnv("JOB_LABELS_MAX_COUNT", 10),
+		maxKeyLength:   config.PositiveIntFromEnv("JOB_LABELS_MAX_KEY_LENGTH", 63),
+		maxValueLength: config.PositiveIntFromEnv("JOB_LABELS_MAX_VALUE_LENGTH", 255),
+		workerPools:    config.GetWorkerPools(),
+	}
+}
+
+// Validate returns field name -> error message for every problem found in the labels.
+// Field names are "labels" for the whole map and "labels.<key>" for a single label.
+func (v *LabelValidator) Validate(labels model.JobLabels) map[string]string {
+	fieldErrors := make(map[string]string)
+
+	if len(labels) > v.maxCount {
+		fieldErrors["labels"] = fmt.Sprintf("must have at most %d entries", v.maxCount)
+		return fieldErrors
+	}
+
+	for key, value := range labels {
+		field := "labels." + key
+		switch {
+		case len(key) > v.maxKeyLength:
+			fieldErrors[field] = fmt.Sprintf("key must be at most %d characters", v.maxKeyLength)
+		case !labelKeyPattern.MatchString(key):
+			fieldErrors[field] = "key may only contain letters, digits, '_', '.' and '-'"
+		case len(value) > v.maxValueLength:
+			fieldErrors[field] = fmt.Sprintf("value must be at most %d characters", v.maxValueLength)
+		case key == model.WorkerPoolLabel && !slices.Contains(v.workerPools, value):
+			if len(v.workerPools) == 0 {
+				fieldErrors[field] = "worker pools are not configured"
+			} else {
+				fieldErrors[field] = fmt.Sprintf("unknown worker pool, must be one of: %s", strings.Join(v.workerPools, ", "))
+			}
+		}
+	}
+
+	return fieldErrors
+}
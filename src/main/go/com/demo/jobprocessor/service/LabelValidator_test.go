@@ -0,0 +1,62 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"distributed-job-processor/model"
+)
+
+// TestValidateLabels verifies label count, key format, and key/value lengths are bounded.
+func TestValidateLabels(t *testing.T) {
+	v := NewLabelValidator()
+
+	tooMany := model.JobLabels{}
+	for i := 0; i <= v.maxCount; i++ {
+		tooMany[fmt.Sprintf("key%d", i)] = "v"
+	}
+
+	cases := []struct {
+		name   string
+		labels model.JobLabels
+		field  string // expected error field, empty when valid
+	}{
+		{"none", nil, ""},
+		{"valid", model.JobLabels{"region": "eu-west", "campaign": "black-friday"}, ""},
+		{"too many", tooMany, "labels"},
+		{"key too long", model.JobLabels{strings.Repeat("k", v.maxKeyLength+1): "v"}, "labels." + strings.Repeat("k", v.maxKeyLength+1)},
+		{"bad key", model.JobLabels{"region&x": "eu"}, "labels.region&x"},
+		{"value too long", model.JobLabels{"region": strings.Repeat("v", v.maxValueLength+1)}, "labels.region"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := v.Validate(tc.labels)
+			if tc.field == "" && len(errs) > 0 {
+				t.Fatalf("expected valid labels, got %v", errs)
+			}
+			if tc.field != "" && errs[tc.field] == "" {
+				t.Fatalf("expected %s error, got %v", tc.field, errs)
+			}
+		})
+	}
+}
+
+// TestValidateWorkerPoolLabel verifies the workerPool label must name a configured pool.
+func TestValidateWorkerPoolLabel(t *testing.T) {
+	field := "labels." + model.WorkerPoolLabel
+	if errs := NewLabelValidator().Validate(model.JobLabels{model.WorkerPoolLabel: "onprem"}); errs[field] == "" {
+		t.Fatalf("expected pinning to be rejected without configured pools, got %v", errs)
+	}
+
+	t.Setenv("KAFKA_WORKER_POOLS", "onprem, gpu")
+	v := NewLabelValidator()
+	if errs := v.Validate(model.JobLabels{model.WorkerPoolLabel: "gpu"}); len(errs) > 0 {
+		t.Fatalf("expected configured pool to be accepted, got %v", errs)
+	}
+	errs := v.Validate(model.JobLabels{model.WorkerPoolLabel: "arm"})
+	if !strings.Contains(errs[field], "onprem, gpu") {
+		t.Fatalf("expected unknown pool error listing the pools, got %v", errs)
+	}
+}
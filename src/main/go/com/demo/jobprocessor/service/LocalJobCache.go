@@ -0,0 +1,130 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"distributed-job-processor/model"
+)
+
+// localJobCache is a small in-process LRU of recently cached jobs, each kept for a short
+// TTL, that CacheService falls back to while Redis is unreachable. Entries are shallow
+// copies: callers get their own Job, sharing only its pointed-to values.
+type localJobCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // Front is the most recently used
+	entries map[uuid.UUID]*list.Element
+}
+
+type localJobCacheEntry struct {
+	job       model.Job
+	expiresAt time.Time
+}
+
+// newLocalJobCache returns a cache of at most size jobs, or nil when size is 0 or less.
+func newLocalJobCache(size int, ttl time.Duration) *localJobCache {
+	if size <= 0 {
+		return nil
+	}
+	return &localJobCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[uuid.UUID]*list.Element),
+	}
+}
+
+// get returns a copy of the cached job, or nil when absent or expired.
+func (c *localJobCache) get(jobID uuid.UUID) *model.Job {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[jobID]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*localJobCacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, jobID)
+		return nil
+	}
+	c.order.MoveToFront(elem)
+	job := entry.job
+	return &job
+}
+
+// put caches a copy of the job, evicting the least recently used one when full.
+func (c *localJobCache) put(job *model.Job) {
+	if c == nil || job == nil || job.ID == uuid.Nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &localJobCacheEntry{job: *job, expiresAt: c.now().Add(c.ttl)}
+	if elem, ok := c.entries[job.ID]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[job.ID] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*localJobCacheEntry).job.ID)
+	}
+}
+
+// remove drops the job, if cached.
+func (c *localJobCache) remove(jobID uuid.UUID) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[jobID]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, jobID)
+	}
+}
+
+// removeClient drops every cached job of the client.
+func (c *localJobCache) removeClient(clientID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, elem := range c.entries {
+		if elem.Value.(*localJobCacheEntry).job.ClientID == clientID {
+			c.order.Remove(elem)
+			delete(c.entries, id)
+		}
+	}
+}
+
+// clear drops every cached job.
+func (c *localJobCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[uuid.UUID]*list.Element)
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// partitionGate holds back the messages of paused partitions inside a worker
+// (see PartitionPauseService and JobWorker.SetPartitionPauseService).
+//
+// kafka-go's consumer group reader can't stop fetching a single partition, so paused
+// partitions are still fetched, but their messages are parked in memory instead of
+// being processed, and are not committed. Once resumed, the parked messages are
+// processed one at a time in offset order; messages fetched meanwhile queue behind
+// them, so the partition's offsets are still committed in order.
+//
+// Parked messages are processed without being committed: the partition may have been
+// rebalanced to another worker meanwhile, whose commits a late one here would rewind.
+// Their offsets are covered by the commit of the partition's next message, which the
+// reader only fetches while it still owns the partition. Until then (or if it lost
+// the partition) they are redelivered after a restart or rebalance, and run again.
+//
+// At most PARTITION_PAUSE_MAX_PARKED messages (default 1000) are parked per worker;
+// beyond that, the fetching goroutine waits until a partition is resumed, stalling
+// every partition it reads. The paused set is refreshed every PARTITION_PAUSE_REFRESH
+// (default 5s), so a pause or resume takes up to that long to apply.
+type partitionGate struct {
+	pauses    *PartitionPauseService
+	refresh   time.Duration
+	maxParked int
+	process   func(fetchedMessage)
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	paused   map[string]bool
+	parked   map[string][]fetchedMessage
+	draining map[string]bool
+	total    int
+	stopped  bool
+}
+
+func newPartitionGate(pauses *PartitionPauseService, process func(fetchedMessage)) *partitionGate {
+	refresh := 5 * time.Second
+	if val := os.Getenv("PARTITION_PAUSE_REFRESH"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed > 0 {
+			refresh = parsed
+		} else {
+			log.Printf("Ignoring invalid PARTITION_PAUSE_REFRESH %q: must be a positive duration", val)
+		}
+	}
+
+	maxParked := 1000
+	if val := os.Getenv("PARTITION_PAUSE_MAX_PARKED"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			maxParked = parsed
+		} else {
+			log.Printf("Ignoring invalid PARTITION_PAUSE_MAX_PARKED %q: must be a positive integer", val)
+		}
+	}
+
+	g := &partitionGate{
+		pauses:    pauses,
+		refresh:   refresh,
+		maxParked: maxParked,
+		process:   process,
+		paused:    make(map[string]bool),
+		parked:    make(map[string][]fetchedMessage),
+		draining:  make(map[string]bool),
+	}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+// run refreshes the paused set until stop is closed. Parked messages are then dropped
+// uncommitted, to be redelivered by Kafka.
+func (g *partitionGate) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(g.refresh)
+	defer ticker.Stop()
+	for {
+		g.sync()
+		select {
+		case <-stop:
+			g.mu.Lock()
+			g.stopped = true
+			g.cond.Broadcast()
+			g.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync reloads the paused set, draining the parked messages of resumed partitions.
+// On error the previous set is kept.
+func (g *partitionGate) sync() {
+	list, err := g.pauses.List()
+	if err != nil {
+		log.Printf("Error loading paused partitions, keeping the previous set: %v", err)
+		return
+	}
+	paused := make(map[string]bool, len(list))
+	for _, p := range list {
+		paused[partitionKey(p.Topic, p.Partition)] = true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for key := range paused {
+		if !g.paused[key] {
+			log.Printf("Partition %s paused", key)
+		}
+	}
+	g.paused = paused
+	for key, queue := range g.parked {
+		if !paused[key] && !g.draining[key] {
+			log.Printf("Partition %s resumed, processing %d parked messages", key, len(queue))
+			g.draining[key] = true
+			go g.drain(key)
+		}
+	}
+}
+
+// Admit reports whether a fetched message should be processed now. Messages of a paused
+// partition, or of a resumed one whose parked messages aren't processed yet, are parked
+// and false is returned. Safe to call on a nil *partitionGate (admits everything).
+func (g *partitionGate) Admit(fetched fetchedMessage) bool {
+	if g == nil {
+		return true
+	}
+	key := partitionKey(fetched.msg.Topic, fetched.msg.Partition)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.held(key) && g.total >= g.maxParked && !g.stopped {
+		g.cond.Wait()
+	}
+	if g.stopped {
+		return false
+	}
+	if !g.held(key) {
+		return true
+	}
+	g.parked[key] = append(g.parked[key], fetched)
+	g.total++
+	return false
+}
+
+// held reports whether new messages of the partition must be parked. Caller holds mu.
+func (g *partitionGate) held(key string) bool {
+	return g.paused[key] || len(g.parked[key]) > 0
+}
+
+// drain processes a resumed partition's parked messages in order, until none are left
+// or the partition is paused again. Each message stays parked until it is processed,
+// so newly fetched ones can't be committed ahead of it.
+func (g *partitionGate) drain(key string) {
+	for {
+		g.mu.Lock()
+		queue := g.parked[key]
+		if g.stopped || g.paused[key] || len(queue) == 0 {
+			if len(queue) == 0 {
+				delete(g.parked, key)
+			}
+			delete(g.draining, key)
+			g.mu.Unlock()
+			return
+		}
+		next := queue[0]
+		g.mu.Unlock()
+
+		g.process(next)
+
+		g.mu.Lock()
+		g.parked[key] = g.parked[key][1:]
+		g.total--
+		g.cond.Broadcast()
+		g.mu.Unlock()
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"distributed-job-processor/model"
+)
+
+// TestPartitionPauseServiceRegistry verifies pauses are listed in order, keep their
+// first pause time, and that resuming reports whether the partition was paused.
+func TestPartitionPauseServiceRegistry(t *testing.T) {
+	_, client := newTestRedis(t)
+	pauses := NewPartitionPauseService(client)
+
+	for _, p := range []struct {
+		topic     string
+		partition int
+	}{{"job-queue", 3}, {"job-queue-high", 0}, {"job-queue", 1}, {"job-queue", 3}} {
+		if err := pauses.Pause(p.topic, p.partition); err != nil {
+			t.Fatalf("pause: %v", err)
+		}
+	}
+
+	paused, err := pauses.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(paused) != 3 || paused[0].Partition != 1 || paused[1].Partition != 3 || paused[2].Topic != "job-queue-high" {
+		t.Fatalf("unexpected paused partitions %+v", paused)
+	}
+	if paused[0].PausedAt.IsZero() {
+		t.Fatalf("expected a pause time, got %+v", paused[0])
+	}
+
+	if resumed, err := pauses.Resume("job-queue", 3); err != nil || !resumed {
+		t.Fatalf("expected partition 3 resumed, got %v (%v)", resumed, err)
+	}
+	if resumed, err := pauses.Resume("job-queue", 3); err != nil || resumed {
+		t.Fatalf("expected resuming twice to report not paused, got %v (%v)", resumed, err)
+	}
+}
+
+// TestPartitionGateParksPausedPartitionUntilResumed verifies messages of a paused
+// partition are held while other partitions pass, then processed in order on resume.
+func TestPartitionGateParksPausedPartitionUntilResumed(t *testing.T) {
+	_, client := newTestRedis(t)
+	pauses := NewPartitionPauseService(client)
+	processed := make(chan int64, 10)
+	g := newPartitionGate(pauses, func(fetched fetchedMessage) {
+		processed <- fetched.msg.Offset
+	})
+
+	message := func(partition int, offset int64) fetchedMessage {
+		return fetchedMessage{msg: kafka.Message{Topic: "job-queue", Partition: partition, Offset: offset}}
+	}
+
+	if err := pauses.Pause("job-queue", 3); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	g.sync()
+
+	if !g.Admit(message(0, 7)) {
+		t.Fatal("expected a message of an unpaused partition to be admitted")
+	}
+	for offset := int64(10); offset < 13; offset++ {
+		if g.Admit(message(3, offset)) {
+			t.Fatalf("expected offset %d of the paused partition to be parked", offset)
+		}
+	}
+	if len(processed) != 0 {
+		t.Fatal("expected nothing processed while paused")
+	}
+
+	if _, err := pauses.Resume("job-queue", 3); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	g.sync()
+
+	for want := int64(10); want < 13; want++ {
+		select {
+		case got := <-processed:
+			if got != want {
+				t.Fatalf("expected parked offset %d next, got %d", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("parked offset %d was not processed after resume", want)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !g.Admit(message(3, 13)) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the drained partition to admit messages again")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestPartitionGateReplaysWithoutCommitting verifies the worker processes a resumed
+// partition's parked messages without committing them on their reader, which may have
+// lost the partition to another worker meanwhile.
+func TestPartitionGateReplaysWithoutCommitting(t *testing.T) {
+	repo := newTestRepository(t)
+	_, client := newTestRedis(t)
+	w := newTestWorker(t, repo)
+	w.SetPartitionPauseService(NewPartitionPauseService(client))
+
+	job := model.NewJob("monitor", model.TypeHealthCheck, "probe_1")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	// No reader: committing on it would panic
+	w.partitionGate.process(fetchedMessage{msg: kafka.Message{Topic: "job-queue", Partition: 3, Value: []byte(job.ID.String())}})
+
+	done, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if done.Status != model.StatusCompleted {
+		t.Fatalf("expected the parked job processed, got %s", done.Status)
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PartitionPauseService is the registry of paused Kafka partitions, shared by the admin
+// API and every worker (see JobWorker.SetPartitionPauseService).
+//
+// Pausing a partition stops workers from processing its messages while every other
+// partition keeps running, e.g. while one partition's jobs wedge their consumer. It is
+// finer-grained than stopping the workers.
+//
+// Redis Key Format: partitions:paused
+// Redis Value: Hash of {topic}/{partition} to the pause time (RFC 3339)
+type PartitionPauseService struct {
+	redisClient *redis.Client
+}
+
+// pausedPartitionsKey is the Redis hash holding the paused partitions.
+const pausedPartitionsKey = "partitions:paused"
+
+// PausedPartition is one paused partition of a topic.
+type PausedPartition struct {
+	Topic     string    `json:"topic"`
+	Partition int       `json:"partition"`
+	PausedAt  time.Time `json:"pausedAt"`
+}
+
+// NewPartitionPauseService creates a new PartitionPauseService.
+func NewPartitionPauseService(redisClient *redis.Client) *PartitionPauseService {
+	return &PartitionPauseService{redisClient: redisClient}
+}
+
+// Pause marks a partition paused. Pausing a paused partition keeps its original pause time.
+func (s *PartitionPauseService) Pause(topic string, partition int) error {
+	return s.redisClient.HSetNX(ctx, pausedPartitionsKey, partitionKey(topic, partition),
+		time.Now().UTC().Format(time.RFC3339)).Err()
+}
+
+// Resume clears a partition's pause. Returns false if it wasn't paused.
+func (s *PartitionPauseService) Resume(topic string, partition int) (bool, error) {
+	removed, err := s.redisClient.HDel(ctx, pausedPartitionsKey, partitionKey(topic, partition)).Result()
+	return removed > 0, err
+}
+
+// List returns the paused partitions, ordered by topic and partition.
+func (s *PartitionPauseService) List() ([]PausedPartition, error) {
+	entries, err := s.redisClient.HGetAll(ctx, pausedPartitionsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	paused := make([]PausedPartition, 0, len(entries))
+	for key, pausedAt := range entries {
+		topic, partition, ok := parsePartitionKey(key)
+		if !ok {
+			continue
+		}
+		at, _ := time.Parse(time.RFC3339, pausedAt)
+		paused = append(paused, PausedPartition{Topic: topic, Partition: partition, PausedAt: at})
+	}
+	sort.Slice(paused, func(i, j int) bool {
+		if paused[i].Topic != paused[j].Topic {
+			return paused[i].Topic < paused[j].Topic
+		}
+		return paused[i].Partition < paused[j].Partition
+	})
+	return paused, nil
+}
+
+// partitionKey identifies a partition as {topic}/{partition}.
+func partitionKey(topic string, partition int) string {
+	return fmt.Sprintf("%s/%d", topic, partition)
+}
+
+// parsePartitionKey splits a partitionKey back into its topic and partition.
+func parsePartitionKey(key string) (string, int, bool) {
+	i := strings.LastIndex(key, "/")
+	if i <= 0 {
+		return "", 0, false
+	}
+	partition, err := strconv.Atoi(key[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return key[:i], partition, true
+}
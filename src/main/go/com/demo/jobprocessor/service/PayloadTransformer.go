@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+
+	"distributed-job-processor/model"
+)
+
+// PayloadTransformer is an extension point for deployment-specific payload processing
+// between creation and handling, e.g.:
+// - Decrypt a field encrypted at submission
+// - Resolve a reference ("customer_42") to the value the handler needs
+// - Enrich the payload with data from an external service
+//
+// The worker runs the TransformerChain before invoking the job's handler, within the
+// job's processing timeout. Transform receives the payload as left by the previous
+// transformer and returns the payload for the next one. Only the handler sees the
+// result: the stored job keeps the payload as submitted. job must not be modified.
+//
+// Returning an error fails the attempt without running the handler. Errors are
+// transient by default and the job is retried with backoff; wrap the error in an
+// exception.NonRetryableError when retrying can't help (e.g. undecryptable field), and in a
+// JobFailure to set its failure reason.
+type PayloadTransformer interface {
+	Transform(ctx context.Context, job *model.Job, payload string) (string, error)
+}
+
+// TransformerChain applies PayloadTransformers in registration order.
+// The zero value, and a nil chain, leave payloads untouched; that is the worker's default.
+type TransformerChain struct {
+	transformers []PayloadTransformer
+}
+
+// NewTransformerChain returns a chain applying the transformers in the given order.
+func NewTransformerChain(transformers ...PayloadTransformer) *TransformerChain {
+	return &TransformerChain{transformers: transformers}
+}
+
+// Len returns the number of transformers in the chain. Safe to call on a nil chain.
+func (c *TransformerChain) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.transformers)
+}
+
+// Apply runs job's payload through every transformer and returns the result.
+// The first error stops the chain and is returned as is.
+func (c *TransformerChain) Apply(ctx context.Context, job *model.Job) (string, error) {
+	payload := job.Payload
+	if c == nil {
+		return payload, nil
+	}
+	for _, transformer := range c.transformers {
+		transformed, err := transformer.Transform(ctx, job, payload)
+		if err != nil {
+			return "", err
+		}
+		payload = transformed
+	}
+	return payload, nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"distributed-job-processor/exception"
+	"distributed-job-processor/model"
+)
+
+// transformerFunc adapts a function to a PayloadTransformer.
+type transformerFunc func(ctx context.Context, job *model.Job, payload string) (string, error)
+
+func (f transformerFunc) Transform(ctx context.Context, job *model.Job, payload string) (string, error) {
+	return f(ctx, job, payload)
+}
+
+// TestTransformerChainAppliesInOrder verifies each transformer sees the previous one's
+// output, and that the stored job keeps its submitted payload.
+func TestTransformerChainAppliesInOrder(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+
+	var seen []string
+	w.SetTransformerChain(NewTransformerChain(
+		transformerFunc(func(ctx context.Context, job *model.Job, payload string) (string, error) {
+			seen = append(seen, payload)
+			return strings.Replace(payload, "enc:dXNlckBlbWFpbC5jb20=", "user@email.com", 1), nil
+		}),
+		transformerFunc(func(ctx context.Context, job *model.Job, payload string) (string, error) {
+			seen = append(seen, payload)
+			return payload + "|https://shop.example/receipts/1", nil
+		}),
+	))
+
+	job := model.NewJob("customer-1", model.TypeHealthCheck, "order_1|enc:dXNlckBlbWFpbC5jb20=")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	payload, err := w.transformers.Apply(context.Background(), job)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if payload != "order_1|user@email.com|https://shop.example/receipts/1" {
+		t.Fatalf("unexpected transformed payload %q", payload)
+	}
+	if len(seen) != 2 || seen[1] != "order_1|user@email.com" {
+		t.Fatalf("expected the second transformer to see the first one's output, got %v", seen)
+	}
+
+	if err := w.processJobInternal(context.Background(), job); err != nil {
+		t.Fatalf("processJobInternal: %v", err)
+	}
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusCompleted || saved.Payload != "order_1|enc:dXNlckBlbWFpbC5jb20=" {
+		t.Fatalf("expected COMPLETED with the submitted payload, got status=%s payload=%q", saved.Status, saved.Payload)
+	}
+}
+
+// TestPayloadTransformErrorsRetryUnlessPermanent verifies a failing transformer fails the
+// attempt; transient errors are retried while permanent ones dead-letter immediately.
+func TestPayloadTransformErrorsRetryUnlessPermanent(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		wantStatus model.JobStatus
+	}{
+		{"transient", errors.New("key service unavailable"), model.StatusPending},
+		{"permanent", exception.NewNonRetryableError(NewJobFailure(model.FailureInvalidPayload, errors.New("field not decryptable"))), model.StatusDeadLetter},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			w := newTestWorker(t, repo)
+			w.SetTransformerChain(NewTransformerChain(
+				transformerFunc(func(ctx context.Context, job *model.Job, payload string) (string, error) {
+					return "", tc.err
+				}),
+			))
+
+			job := model.NewJob("customer-1", model.TypeHealthCheck, "probe_1")
+			job.Status = model.StatusRunning
+			if err := repo.Create(job); err != nil {
+				t.Fatalf("seed job: %v", err)
+			}
+
+			err := w.processJobInternal(context.Background(), job)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected the transformer error, got %v", err)
+			}
+			w.handleJobFailure(job, err)
+
+			saved, err := repo.FindByID(job.ID)
+			if err != nil {
+				t.Fatalf("reload job: %v", err)
+			}
+			if saved.Status != tc.wantStatus || saved.Attempts != 1 {
+				t.Fatalf("expected %s after 1 attempt, got status=%s attempts=%d", tc.wantStatus, saved.Status, saved.Attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"distributed-job-processor/model"
+)
+
+// emailPattern is a pragmatic RFC 5322-ish address check, not a full parser:
+// a non-empty local part of allowed characters, one @, and a dotted domain
+// whose labels don't start or end with a hyphen.
+var emailPattern = regexp.MustCompile(
+	`^[A-Za-z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)+$`,
+)
+
+// amountPattern matches a positive amount with an optional currency symbol and
+// at most two decimals, e.g. "$99.99", "10", "€5.5".
+var amountPattern = regexp.MustCompile(`^[$€£]?[0-9]+(?:\.[0-9]{1,2})?$`)
+
+// PayloadValidator checks job payloads against the job type spec table at creation.
+//
+// Payloads are pipe-delimited; field positions and formats come from model.JobTypeSpecs.
+//
+// Checks, for every job type:
+// - Required fields must be present and non-blank
+// - Email fields must be a well-formed address
+//   (VALIDATE_EMAIL_FORMAT=false disables, default on)
+// - Amount fields must be a positive amount, e.g. "$99.99"
+// - Optional fields are only checked when present
+//
+// A malformed payload would otherwise only fail at (simulated) processing time,
+// after taking a Kafka slot, and burn retries on its way to the dead letter queue.
+type PayloadValidator struct {
+	validateEmail bool
+}
+
+// NewPayloadValidator creates a new PayloadValidator configured from env.
+func NewPayloadValidator() *PayloadValidator {
+	validateEmail := true
+	if val := os.Getenv("VALIDATE_EMAIL_FORMAT"); val == "false" {
+		validateEmail = false
+		log.Println("Email format validation is DISABLED")
+	}
+
+	return &PayloadValidator{
+		validateEmail: validateEmail,
+	}
+}
+
+// Validate returns field name -> error message for every problem found in the payload.
+// An empty map means the payload is acceptable.
+func (v *PayloadValidator) Validate(jobType model.JobType, payload string) map[string]string {
+	fieldErrors := make(map[string]string)
+
+	spec, ok := model.LookupJobTypeSpec(jobType)
+	if !ok {
+		return fieldErrors
+	}
+	values := strings.Split(payload, "|")
+
+	for i, field := range spec.PayloadFields {
+		value := ""
+		if i < len(values) {
+			value = strings.TrimSpace(values[i])
+		}
+
+		if value == "" {
+			if field.Required {
+				fieldErrors[field.Name] = "is required"
+			}
+			continue
+		}
+
+		switch field.Format {
+		case model.FormatEmail:
+			if v.validateEmail && !isValidEmail(value) {
+				fieldErrors[field.Name] = "must be a valid email address"
+			}
+		case model.FormatAmount:
+			if !isValidAmount(value) {
+				fieldErrors[field.Name] = "must be a positive amount, e.g. $99.99"
+			}
+		}
+	}
+
+	return fieldErrors
+}
+
+// isValidAmount reports whether s is a positive amount in the payload's money format.
+func isValidAmount(s string) bool {
+	if !amountPattern.MatchString(s) {
+		return false
+	}
+	amount, err := strconv.ParseFloat(strings.TrimLeft(s, "$€£"), 64)
+	return err == nil && amount > 0
+}
+
+// isValidEmail reports whether s looks like a deliverable email address.
+func isValidEmail(s string) bool {
+	if len(s) > 254 {
+		return false
+	}
+	at := strings.LastIndex(s, "@")
+	if at < 1 || at > 64 {
+		return false
+	}
+	local := s[:at]
+	if strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		return false
+	}
+	return emailPattern.MatchString(s)
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"distributed-job-processor/dto"
+	"distributed-job-processor/exception"
+	"distributed-job-processor/model"
+)
+
+// TestValidateEmailFormat verifies email confirmation payloads are checked for a well-formed address.
+func TestValidateEmailFormat(t *testing.T) {
+	v := NewPayloadValidator()
+
+	cases := []struct {
+		name    string
+		payload string
+		valid   bool
+	}{
+		{"valid", "order_1|customer@email.com|receipt_url", true},
+		{"valid with plus and subdomain", "order_1|first.last+tag@mail.example.co.uk", true},
+		{"missing @", "order_1|customer.email.com|receipt_url", false},
+		{"empty local part", "order_1|@email.com|receipt_url", false},
+		{"no domain dot", "order_1|customer@localhost|receipt_url", false},
+		{"missing field", "order_1", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := v.Validate(model.TypeEmailConfirmation, tc.payload)
+			if tc.valid && len(errs) > 0 {
+				t.Fatalf("expected valid payload, got %v", errs)
+			}
+			if !tc.valid && errs["customerEmail"] == "" {
+				t.Fatalf("expected customerEmail error, got %v", errs)
+			}
+		})
+	}
+}
+
+// TestValidatePayloadPerJobType verifies each job type's accepted payloads and the
+// field reported for each rejected one.
+func TestValidatePayloadPerJobType(t *testing.T) {
+	v := NewPayloadValidator()
+
+	cases := []struct {
+		name      string
+		jobType   model.JobType
+		payload   string
+		wantField string // Empty for an accepted payload
+	}{
+		{"payment minimal", model.TypePaymentProcess, "order_1|customer@email.com|$99.99", ""},
+		{"payment with sku and quantity", model.TypePaymentProcess, "order_1|customer@email.com|10|SKU-1|2", ""},
+		{"payment without currency symbol", model.TypePaymentProcess, "order_1|customer@email.com|5.5", ""},
+		{"payment missing order", model.TypePaymentProcess, " |customer@email.com|$99.99", "orderId"},
+		{"payment invalid email", model.TypePaymentProcess, "order_1|customer.email.com|$99.99", "customerEmail"},
+		{"payment missing amount", model.TypePaymentProcess, "order_1|customer@email.com", "amount"},
+		{"payment unparseable amount", model.TypePaymentProcess, "order_1|customer@email.com|ninety", "amount"},
+		{"payment zero amount", model.TypePaymentProcess, "order_1|customer@email.com|$0.00", "amount"},
+		{"payment negative amount", model.TypePaymentProcess, "order_1|customer@email.com|-5", "amount"},
+		{"payment fractional cents", model.TypePaymentProcess, "order_1|customer@email.com|$9.999", "amount"},
+		{"email minimal", model.TypeEmailConfirmation, "order_1|customer@email.com", ""},
+		{"email with receipt", model.TypeEmailConfirmation, "order_1|customer@email.com|https://r.example.com/1", ""},
+		{"email missing order", model.TypeEmailConfirmation, "|customer@email.com|receipt", "orderId"},
+		{"email garbage", model.TypeEmailConfirmation, "garbage", "customerEmail"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := v.Validate(tc.jobType, tc.payload)
+			if tc.wantField == "" {
+				if len(errs) > 0 {
+					t.Fatalf("expected valid payload, got %v", errs)
+				}
+				return
+			}
+			if errs[tc.wantField] == "" {
+				t.Fatalf("expected %s error, got %v", tc.wantField, errs)
+			}
+		})
+	}
+}
+
+// TestValidateEmailFormatDisabled verifies VALIDATE_EMAIL_FORMAT=false turns the check off.
+func TestValidateEmailFormatDisabled(t *testing.T) {
+	t.Setenv("VALIDATE_EMAIL_FORMAT", "false")
+	v := NewPayloadValidator()
+
+	if errs := v.Validate(model.TypeEmailConfirmation, "order_1|not-an-email|receipt_url"); len(errs) > 0 {
+		t.Fatalf("expected no errors with validation disabled, got %v", errs)
+	}
+}
+
+// TestCreateJobRejectsInvalidEmail verifies a malformed address is refused before the job is saved.
+func TestCreateJobRejectsInvalidEmail(t *testing.T) {
+	// No repository: reaching Create would panic, proving the job is never persisted
+	s := NewJobService(nil)
+
+	_, err := s.CreateJob(context.Background(), "customer-1", &dto.JobRequest{
+		Type:    model.TypeEmailConfirmation,
+		Payload: "order_1|@email.com|receipt_url",
+	})
+	if !exception.IsPayloadValidationError(err) {
+		t.Fatalf("expected PayloadValidationError, got %v", err)
+	}
+}
+
+// TestCreateJobRejectsMalformedPaymentPayload verifies payment payloads are checked at
+// creation and every problem is reported by field.
+func TestCreateJobRejectsMalformedPaymentPayload(t *testing.T) {
+	s := NewJobService(nil)
+
+	_, err := s.CreateJob(context.Background(), "customer-1", &dto.JobRequest{
+		Type:    model.TypePaymentProcess,
+		Payload: "order_1|not-an-email|free",
+	})
+	validationErr, ok := err.(*exception.PayloadValidationError)
+	if !ok {
+		t.Fatalf("expected PayloadValidationError, got %v", err)
+	}
+	if validationErr.FieldErrors["customerEmail"] == "" || validationErr.FieldErrors["amount"] == "" {
+		t.Fatalf("expected customerEmail and amount errors, got %v", validationErr.FieldErrors)
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/exception"
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+// PaymentHandler handles PAYMENT_PROCESS jobs: the (simulated) card charge, at most once per job.
+//
+// In a real system this would call Stripe/PayPal; here it sleeps 2 seconds.
+//
+// Kafka delivery is at-least-once, so a job can be reprocessed after a crash or
+// a failed commit. The charged flag is persisted (DB and cache) the instant the
+// charge succeeds, before any remaining work, so a reprocessed job skips the
+// charge instead of billing the customer twice.
+//
+// The flag can't cover a crash between the charge and its save, nor a copy of the
+// job that hasn't seen the save yet, so the charge is also guarded by an idempotency
+// lock in Redis, taken (SETNX) before charging, per client since order IDs are only
+// unique within one:
+// Redis Key Format: payment:{clientId}:{orderId}:charge (value: the charging job's ID)
+// - Lock already held by this job, which isn't marked charged: an earlier attempt
+//   took the lock and then crashed, before or after the charge went through. The
+//   outcome is unknown, so the job is neither charged again nor marked charged: it
+//   dead-letters with reason needs_reconciliation, for an operator to check with the
+//   gateway. This is what keeps payments safe with DELIVERY_SEMANTICS=at-most-once
+//   too, where the stuck-job reaper can still requeue a job that crashed mid-charge.
+//   Once the operator has checked the gateway, retrying or replaying the job marks it
+//   reconciled (see markPaymentReconciled) and its next attempt takes the lock over
+//   and charges
+// - Held by another job: the order was charged by a different job; permanent failure
+// - Released when the charge fails, so a retry can charge
+// - Expires after PAYMENT_IDEMPOTENCY_TTL (default 24h, 0 disables the lock)
+//
+// Redis errors fail closed: the job is retried rather than risking a double charge.
+type PaymentHandler struct {
+	jobRepository  *repository.JobRepository
+	cacheService   *CacheService
+	redisClient    *redis.Client
+	idempotencyTTL time.Duration
+}
+
+// defaultPaymentIdempotencyTTL is how long a payment lock is held when PAYMENT_IDEMPOTENCY_TTL is unset.
+const defaultPaymentIdempotencyTTL = 24 * time.Hour
+
+// NewPaymentHandler creates a PaymentHandler recording charges in the repository and
+// cache, and locking charges in the cache's Redis.
+func NewPaymentHandler(jobRepository *repository.JobRepository, cacheService *CacheService) *PaymentHandler {
+	ttl := paymentIdempotencyTTL()
+	var redisClient *redis.Client
+	if cacheService != nil && ttl > 0 {
+		redisClient = cacheService.redisClient
+	}
+	return &PaymentHandler{
+		jobRepository:  jobRepository,
+		cacheService:   cacheService,
+		redisClient:    redisClient,
+		idempotencyTTL: ttl,
+	}
+}
+
+// Handle charges the job's payment unless it was already charged.
+func (h *PaymentHandler) Handle(ctx context.Context, job *model.Job) error {
+	logger := config.JobLogger(job.ID.String(), job.ClientID)
+	if job.Charged {
+		logger.Info("Payment already charged, skipping charge")
+		return nil
+	}
+
+	lockKey := paymentLockKey(job.ClientID, HandlerPayload(ctx, job))
+	if h.redisClient != nil {
+		acquired, err := h.redisClient.SetNX(ctx, lockKey, job.ID.String(), h.idempotencyTTL).Result()
+		if err != nil {
+			return fmt.Errorf("taking payment lock: %w", err)
+		}
+		if !acquired {
+			holder, err := h.redisClient.Get(ctx, lockKey).Result()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return fmt.Errorf("reading payment lock: %w", err)
+			}
+			if holder != job.ID.String() {
+				return exception.NewNonRetryableError(fmt.Errorf("order already charged by job %s", holder))
+			}
+			reconciled, err := h.takeOverLock(ctx, job.ID, lockKey)
+			if err != nil {
+				return err
+			}
+			if !reconciled {
+				logger.Warn("Payment lock already held by this job, charge outcome unknown, leaving it to reconciliation")
+				return exception.NewNonRetryableError(NewJobFailure(model.FailureNeedsReconciliation,
+					errors.New("an earlier attempt crashed while charging: check the gateway before charging again")))
+			}
+			logger.Info("Payment reconciled by an operator, taking over the payment lock to charge")
+		}
+	}
+
+	// Simulate Stripe API call (2 seconds)
+	logger.Debug("Simulating payment processing")
+	if err := sleepContext(ctx, 2*time.Second); err != nil {
+		if h.redisClient != nil {
+			if delErr := h.redisClient.Del(context.Background(), lockKey).Err(); delErr != nil {
+				logger.Error("Failed to release payment lock", "error", delErr)
+			}
+		}
+		return err
+	}
+
+	if err := h.recordCharge(job); err != nil {
+		return err
+	}
+	logger.Debug("Payment processed", "payload", HandlerPayload(ctx, job))
+	return nil
+}
+
+// recordCharge persists the job's charged flag, and its work as done (see JobHandler),
+// to the database and cache in one write.
+func (h *PaymentHandler) recordCharge(job *model.Job) error {
+
+	// Recorded on top of any concurrent save: the charge happened whatever else changed
+	err := updateJobWithRetry(h.jobRepository, job, func(job *model.Job) bool {
+		now := time.Now()
+		job.Charged = true
+		job.WorkDoneAt = &now
+		job.UpdatedAt = now
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("payment charged but failed to record charge: %w", err)
+	}
+	h.cacheService.UpdateJob(job)
+	return nil
+}
+
+// takeOverLock reports whether the job was marked reconciled since its lock was taken,
+// consuming the mark and renewing the lock for this attempt if so. The mark is used
+// once, so a crash during this attempt's charge needs reconciling again.
+func (h *PaymentHandler) takeOverLock(ctx context.Context, jobID uuid.UUID, lockKey string) (bool, error) {
+	consumed, err := h.redisClient.Del(ctx, paymentReconciledKey(jobID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("reading payment reconciliation mark: %w", err)
+	}
+	if consumed == 0 {
+		return false, nil
+	}
+	if err := h.redisClient.Expire(ctx, lockKey, h.idempotencyTTL).Err(); err != nil {
+		return false, fmt.Errorf("renewing payment lock: %w", err)
+	}
+	return true, nil
+}
+
+// markPaymentReconciled records that an operator has reconciled a payment job
+// dead-lettered as needs_reconciliation and requeued it, so its next attempt takes
+// over the lock it still holds instead of dead-lettering again (see PaymentHandler).
+// A no-op for other jobs, or without Redis or payment locks.
+func markPaymentReconciled(cacheService *CacheService, job *model.Job) error {
+	if job.FailureReason == nil || *job.FailureReason != model.FailureNeedsReconciliation {
+		return nil
+	}
+	ttl := paymentIdempotencyTTL()
+	if cacheService == nil || ttl == 0 {
+		return nil
+	}
+	return cacheService.redisClient.Set(context.Background(), paymentReconciledKey(job.ID), "1", ttl).Err()
+}
+
+// paymentIdempotencyTTL returns how long a payment lock is held: PAYMENT_IDEMPOTENCY_TTL,
+// default 24h, 0 disables the lock.
+func paymentIdempotencyTTL() time.Duration {
+	val := os.Getenv("PAYMENT_IDEMPOTENCY_TTL")
+	if val == "" {
+		return defaultPaymentIdempotencyTTL
+	}
+	parsed, err := time.ParseDuration(val)
+	if err != nil || parsed < 0 {
+		log.Printf("Ignoring invalid PAYMENT_IDEMPOTENCY_TTL %q: must be a non-negative duration", val)
+		return defaultPaymentIdempotencyTTL
+	}
+	return parsed
+}
+
+// paymentReconciledKey returns the Redis key marking a job's payment reconciled.
+func paymentReconciledKey(jobID uuid.UUID) string {
+	return "payment:reconciled:" + jobID.String()
+}
+
+// paymentLockKey returns the Redis key of the payment lock of the client's order in
+// payload, whose first field is the order ID.
+func paymentLockKey(clientID, payload string) string {
+	orderID, _, _ := strings.Cut(payload, "|")
+	return "payment:" + clientID + ":" + strings.TrimSpace(orderID) + ":charge"
+}
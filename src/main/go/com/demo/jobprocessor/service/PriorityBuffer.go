@@ -0,0 +1,129 @@
+package service
+
+import (
+	"container/heap"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+)
+
+// priorityBuffer reorders fetched messages by job priority before the worker
+// goroutines take them (WORKER_PRIORITY_WINDOW, see JobWorker).
+//
+// When a message arrives at an empty buffer, a window opens: messages fetched
+// during the window are collected and nothing is handed out. After it, waiting
+// messages go to free goroutines most urgent first (equal priorities in arrival
+// order), and messages fetched meanwhile still join the queue. The window
+// reopens once the buffer drains.
+//
+// At most max messages are held; beyond that, fetching waits for a free goroutine.
+type priorityBuffer struct {
+	window time.Duration
+	max    int
+	queue  priorityQueue
+	seq    uint64
+}
+
+func newPriorityBuffer(window time.Duration, max int) *priorityBuffer {
+	return &priorityBuffer{window: window, max: max}
+}
+
+// run moves messages from in to out in priority order until stop is closed.
+func (b *priorityBuffer) run(in <-chan fetchedMessage, out chan<- fetchedMessage, stop <-chan struct{}) {
+	var windowEnd time.Time
+	for {
+		if b.queue.Len() == 0 {
+			select {
+			case <-stop:
+				return
+			case fetched := <-in:
+				b.push(fetched)
+				windowEnd = time.Now().Add(b.window)
+			}
+			continue
+		}
+
+		// A full buffer stops taking messages until one is handed out
+		accept := in
+		if b.queue.Len() >= b.max {
+			accept = nil
+		}
+
+		if wait := time.Until(windowEnd); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case fetched := <-accept:
+				b.push(fetched)
+			case <-timer.C:
+			}
+			timer.Stop()
+			continue
+		}
+
+		select {
+		case <-stop:
+			return
+		case out <- b.queue[0].fetched:
+			heap.Pop(&b.queue)
+		case fetched := <-accept:
+			b.push(fetched)
+		}
+	}
+}
+
+func (b *priorityBuffer) push(fetched fetchedMessage) {
+	b.seq++
+	heap.Push(&b.queue, bufferedMessage{fetched: fetched, priority: messagePriority(fetched.msg), seq: b.seq})
+}
+
+// messagePriority reads the job-priority header, falling back to the default
+// priority for messages published before the header existed.
+func messagePriority(msg kafka.Message) int {
+	for _, header := range msg.Headers {
+		if header.Key == config.JobPriorityHeader {
+			if priority, err := strconv.Atoi(string(header.Value)); err == nil {
+				return priority
+			}
+			break
+		}
+	}
+	return model.DefaultPriority
+}
+
+// bufferedMessage is a queued message with its sort keys.
+type bufferedMessage struct {
+	fetched  fetchedMessage
+	priority int
+	seq      uint64
+}
+
+// priorityQueue is a min-heap of messages by priority, then arrival order.
+type priorityQueue []bufferedMessage
+
+func (q priorityQueue) Len() int { return len(q) }
+
+func (q priorityQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority < q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q priorityQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *priorityQueue) Push(x any) { *q = append(*q, x.(bufferedMessage)) }
+
+func (q *priorityQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+)
+
+// priorityMessage builds a fetched message for a job with the given priority header.
+func priorityMessage(offset int64, priority string) fetchedMessage {
+	msg := kafka.Message{Offset: offset}
+	if priority != "" {
+		msg.Headers = []kafka.Header{{Key: config.JobPriorityHeader, Value: []byte(priority)}}
+	}
+	return fetchedMessage{msg: msg}
+}
+
+// TestPriorityBufferReleasesMostUrgentFirst verifies messages fetched within the
+// window are handed out by priority, equal priorities in arrival order.
+func TestPriorityBufferReleasesMostUrgentFirst(t *testing.T) {
+	in := make(chan fetchedMessage)
+	out := make(chan fetchedMessage)
+	stop := make(chan struct{})
+	defer close(stop)
+	go newPriorityBuffer(100*time.Millisecond, 10).run(in, out, stop)
+
+	// Offsets 0-4 with priorities 5, 1, (none = default 5), 3, 1
+	for offset, priority := range []string{"5", "1", "", "3", "1"} {
+		in <- priorityMessage(int64(offset), priority)
+	}
+
+	var got []int64
+	for range 5 {
+		select {
+		case fetched := <-out:
+			got = append(got, fetched.msg.Offset)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out after %v", got)
+		}
+	}
+
+	want := []int64{1, 4, 3, 0, 2}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected offsets %v, got %v", want, got)
+		}
+	}
+}
+
+// TestMessagePriorityDefaults verifies messages without a valid header get the default priority.
+func TestMessagePriorityDefaults(t *testing.T) {
+	cases := map[string]int{"2": 2, "": model.DefaultPriority, "urgent": model.DefaultPriority}
+	for header, want := range cases {
+		if got := messagePriority(priorityMessage(0, header).msg); got != want {
+			t.Fatalf("header %q: expected priority %d, got %d", header, want, got)
+		}
+	}
+	if got := messagePriority(kafka.Message{Headers: []kafka.Header{{Key: config.JobTypeHeader, Value: []byte("1")}}}); got != model.DefaultPriority {
+		t.Fatalf("expected other headers to be ignored, got priority %d", got)
+	}
+}
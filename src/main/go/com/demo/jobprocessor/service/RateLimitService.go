@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -13,16 +14,33 @@ import (
 //
 // Strategy: Token Bucket
 // - Each client gets a bucket with MAX_REQUESTS tokens
-// - Each request consumes 1 token
+// - Each request consumes 1 token (a batch of n jobs consumes n)
 // - Bucket refills to MAX_REQUESTS every WINDOW_SECONDS
 //
 // Example: 100 requests per 60 seconds
 // - Client can burst up to 100 requests immediately
 // - Then must wait for bucket to refill
 //
+// Bucket identity (RATE_LIMIT_KEY):
+// - header (default): the X-Client-Id header
+// - apikey: the authenticated API key's identity, so a client can't get a
+//   fresh bucket just by changing the header. Falls back to the header
+//   when the request isn't authenticated.
+//
 // Redis Key Format: rate_limit:{clientId}
 // Redis Value: Hash with {count: Integer, resetTime: Long}
 //
+// Strategy (RATE_LIMIT_STRATEGY):
+// - fixed (default): the token bucket above. The bucket refills all at once, so a
+//   client can send MAX_REQUESTS just before a refill and MAX_REQUESTS again just
+//   after it: up to 2x the limit in a short burst.
+// - sliding: a sliding-window log. Every allowed request is recorded with its time,
+//   and a request is refused when MAX_REQUESTS were allowed within the last
+//   WINDOW_SECONDS, at any point in time. No boundary burst, at the cost of one
+//   sorted set member per request.
+//   Redis Key Format: rate_limit:sliding:{clientId}
+//   Redis Value: Sorted set of request IDs scored by their time in milliseconds
+//
 // Benefits:
 // - Prevents one bot from monopolizing system during flash sales
 // - Ensures fair access to limited inventory
@@ -32,8 +50,17 @@ type RateLimitService struct {
 	enabled       bool
 	maxRequests   int
 	windowSeconds int
+	keyByAPIKey   bool
+	sliding       bool
+	now           func() time.Time
 }
 
+// Rate limiting strategies, see RATE_LIMIT_STRATEGY.
+const (
+	RateLimitStrategyFixed   = "fixed"
+	RateLimitStrategySliding = "sliding"
+)
+
 // NewRateLimitService creates a new RateLimitService with the given Redis client.
 func NewRateLimitService(redisClient *redis.Client) *RateLimitService {
 	enabled := true
@@ -55,23 +82,63 @@ func NewRateLimitService(redisClient *redis.Client) *RateLimitService {
 		}
 	}
 
+	keyByAPIKey := false
+	switch val := os.Getenv("RATE_LIMIT_KEY"); val {
+	case "", "header":
+	case "apikey":
+		keyByAPIKey = true
+	default:
+		log.Printf("Unknown RATE_LIMIT_KEY %q, keying rate limits by header", val)
+	}
+
+	sliding := false
+	switch val := os.Getenv("RATE_LIMIT_STRATEGY"); val {
+	case "", RateLimitStrategyFixed:
+	case RateLimitStrategySliding:
+		sliding = true
+	default:
+		log.Printf("Unknown RATE_LIMIT_STRATEGY %q, using the fixed window", val)
+	}
+
 	return &RateLimitService{
 		redisClient:   redisClient,
 		enabled:       enabled,
 		maxRequests:   maxRequests,
 		windowSeconds: windowSeconds,
+		keyByAPIKey:   keyByAPIKey,
+		sliding:       sliding,
+		now:           time.Now,
 	}
 }
 
+// KeyByAPIKey reports whether buckets are keyed by the authenticated API key identity.
+func (s *RateLimitService) KeyByAPIKey() bool {
+	return s.keyByAPIKey
+}
+
 // IsAllowed checks if the client is allowed to make a request.
 // Returns true if allowed, false if rate limit exceeded.
 func (s *RateLimitService) IsAllowed(clientID string) bool {
+	return s.IsAllowedN(clientID, 1)
+}
+
+// IsAllowedN checks if the client may make a request costing n tokens, e.g. a
+// batch of n jobs, and consumes them if so. All or nothing: a request that would
+// overdraw the bucket consumes no tokens.
+func (s *RateLimitService) IsAllowedN(clientID string, n int) bool {
 	if !s.enabled {
 		return true
 	}
+	if n > s.maxRequests {
+		log.Printf("Rate limit exceeded for client %s: request for %d tokens exceeds the limit of %d", clientID, n, s.maxRequests)
+		return false
+	}
+	if s.sliding {
+		return s.isAllowedSliding(clientID, n)
+	}
 
 	key := s.getRateLimitKey(clientID)
-	now := time.Now().Unix()
+	now := s.now().Unix()
 
 	// Get current count and reset time from Redis
 	count, errCount := s.redisClient.HGet(ctx, key, "count").Int()
@@ -81,7 +148,7 @@ func (s *RateLimitService) IsAllowed(clientID string) bool {
 	if errCount != nil || errReset != nil || now >= resetTime {
 		// Initialize new bucket
 		pipe := s.redisClient.Pipeline()
-		pipe.HSet(ctx, key, "count", 1)
+		pipe.HSet(ctx, key, "count", n)
 		pipe.HSet(ctx, key, "resetTime", now+int64(s.windowSeconds))
 		pipe.Expire(ctx, key, time.Duration(s.windowSeconds+10)*time.Second) // Extra 10s buffer
 		if _, err := pipe.Exec(ctx); err != nil {
@@ -90,18 +157,18 @@ func (s *RateLimitService) IsAllowed(clientID string) bool {
 			return true
 		}
 
-		log.Printf("Rate limit initialized for client %s: 1/%d requests", clientID, s.maxRequests)
+		log.Printf("Rate limit initialized for client %s: %d/%d requests", clientID, n, s.maxRequests)
 		return true
 	}
 
 	// Check if under limit
-	if count < s.maxRequests {
+	if count+n <= s.maxRequests {
 		// Increment counter
-		if err := s.redisClient.HIncrBy(ctx, key, "count", 1).Err(); err != nil {
+		if err := s.redisClient.HIncrBy(ctx, key, "count", int64(n)).Err(); err != nil {
 			log.Printf("Error incrementing rate limit for client %s: %v", clientID, err)
 			return true
 		}
-		log.Printf("Rate limit for client %s: %d/%d requests", clientID, count+1, s.maxRequests)
+		log.Printf("Rate limit for client %s: %d/%d requests", clientID, count+n, s.maxRequests)
 		return true
 	}
 
@@ -112,14 +179,71 @@ func (s *RateLimitService) IsAllowed(clientID string) bool {
 	return false
 }
 
+// slidingWindowScript trims requests older than the window from the log, then records
+// n new requests if that keeps the log within the limit. All or nothing, atomically.
+// Returns {1, count after} when allowed, {0, count} when refused.
+//
+// KEYS[1]: the log; ARGV: now (ms), window (ms), limit, n, request ID prefix
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count + n > limit then
+	return {0, count}
+end
+for i = 1, n do
+	redis.call('ZADD', KEYS[1], now, ARGV[5] .. ':' .. i)
+end
+redis.call('PEXPIRE', KEYS[1], window + 10000)
+return {1, count + n}
+`)
+
+// isAllowedSliding is IsAllowedN for the sliding-window strategy.
+func (s *RateLimitService) isAllowedSliding(clientID string, n int) bool {
+	key := s.getSlidingRateLimitKey(clientID)
+	window := time.Duration(s.windowSeconds) * time.Second
+
+	result, err := slidingWindowScript.Run(ctx, s.redisClient, []string{key},
+		s.now().UnixMilli(), window.Milliseconds(), s.maxRequests, n, uuid.NewString()).Int64Slice()
+	if err != nil || len(result) != 2 {
+		log.Printf("Error checking sliding rate limit for client %s: %v", clientID, err)
+		// Fail open: Allow request if Redis is down
+		return true
+	}
+
+	if result[0] == 0 {
+		log.Printf("Rate limit exceeded for client %s: %d/%d requests in the last %ds",
+			clientID, result[1], s.maxRequests, s.windowSeconds)
+		return false
+	}
+	log.Printf("Rate limit for client %s: %d/%d requests in the last %ds", clientID, result[1], s.maxRequests, s.windowSeconds)
+	return true
+}
+
+// slidingWindowStart returns the score (ms) at or below which logged requests have left the window.
+func (s *RateLimitService) slidingWindowStart() int64 {
+	return s.now().UnixMilli() - (time.Duration(s.windowSeconds) * time.Second).Milliseconds()
+}
+
 // GetRemainingRequests returns the number of remaining requests for a client in the current window.
 func (s *RateLimitService) GetRemainingRequests(clientID string) int64 {
 	if !s.enabled {
 		return int64(s.maxRequests)
 	}
+	if s.sliding {
+		after := "(" + strconv.FormatInt(s.slidingWindowStart(), 10)
+		count, err := s.redisClient.ZCount(ctx, s.getSlidingRateLimitKey(clientID), after, "+inf").Result()
+		if err != nil {
+			return int64(s.maxRequests)
+		}
+		return max(int64(s.maxRequests)-count, 0)
+	}
 
 	key := s.getRateLimitKey(clientID)
-	now := time.Now().Unix()
+	now := s.now().Unix()
 
 	count, errCount := s.redisClient.HGet(ctx, key, "count").Int()
 	resetTime, errReset := s.redisClient.HGet(ctx, key, "resetTime").Int64()
@@ -136,14 +260,27 @@ func (s *RateLimitService) GetRemainingRequests(clientID string) int64 {
 }
 
 // GetSecondsUntilReset returns seconds until rate limit resets for a client.
-// Returns 0 if no active limit.
+// With the sliding window, that is when the oldest request in the window leaves it,
+// freeing at least one request. Returns 0 if no active limit.
 func (s *RateLimitService) GetSecondsUntilReset(clientID string) int64 {
 	if !s.enabled {
 		return 0
 	}
+	if s.sliding {
+		windowStart := s.slidingWindowStart()
+		after := "(" + strconv.FormatInt(windowStart, 10)
+		oldest, err := s.redisClient.ZRangeByScoreWithScores(ctx, s.getSlidingRateLimitKey(clientID), &redis.ZRangeBy{
+			Min: after, Max: "+inf", Count: 1,
+		}).Result()
+		if err != nil || len(oldest) == 0 {
+			return 0
+		}
+		// Round up, so a client waiting this long is always let through
+		return (int64(oldest[0].Score) - windowStart + 999) / 1000
+	}
 
 	key := s.getRateLimitKey(clientID)
-	now := time.Now().Unix()
+	now := s.now().Unix()
 
 	resetTime, err := s.redisClient.HGet(ctx, key, "resetTime").Int64()
 	if err != nil || now >= resetTime {
@@ -153,10 +290,9 @@ func (s *RateLimitService) GetSecondsUntilReset(clientID string) int64 {
 	return resetTime - now
 }
 
-// ResetRateLimit resets the rate limit for a client (admin function).
+// ResetRateLimit resets the rate limit for a client (admin function), under either strategy.
 func (s *RateLimitService) ResetRateLimit(clientID string) {
-	key := s.getRateLimitKey(clientID)
-	if err := s.redisClient.Del(ctx, key).Err(); err != nil {
+	if err := s.redisClient.Del(ctx, s.getRateLimitKey(clientID), s.getSlidingRateLimitKey(clientID)).Err(); err != nil {
 		log.Printf("Error resetting rate limit for client %s: %v", clientID, err)
 		return
 	}
@@ -166,4 +302,9 @@ func (s *RateLimitService) ResetRateLimit(clientID string) {
 // getRateLimitKey returns the Redis key for rate limiting.
 func (s *RateLimitService) getRateLimitKey(clientID string) string {
 	return "rate_limit:" + clientID
-}
\ No newline at end of file
+}
+
+// getSlidingRateLimitKey returns the Redis key of a client's sliding-window log.
+func (s *RateLimitService) getSlidingRateLimitKey(clientID string) string {
+	return "rate_limit:sliding:" + clientID
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// TestIsAllowedNCountsBatchSize verifies a batch consumes one token per job and is
+// refused whole, without consuming tokens, when it would overdraw the bucket.
+func TestIsAllowedNCountsBatchSize(t *testing.T) {
+	t.Setenv("RATE_LIMIT_MAX_REQUESTS", "10")
+	_, client := newTestRedis(t)
+	s := NewRateLimitService(client)
+
+	if !s.IsAllowedN("customer-1", 6) {
+		t.Fatal("expected a batch of 6 within a limit of 10")
+	}
+	if s.IsAllowedN("customer-1", 5) {
+		t.Fatal("expected a batch of 5 refused with 4 tokens left")
+	}
+	if got := s.GetRemainingRequests("customer-1"); got != 4 {
+		t.Fatalf("expected the refused batch to consume nothing, got %d remaining", got)
+	}
+	if !s.IsAllowedN("customer-1", 4) || s.IsAllowed("customer-1") {
+		t.Fatal("expected the last 4 tokens to be usable and the bucket then empty")
+	}
+	if s.IsAllowedN("customer-2", 11) {
+		t.Fatal("expected a batch larger than the limit to be refused")
+	}
+}
+
+// burstAcrossBoundary sends 1 request at start, 9 just before start+window and
+// 10 right at start+window, returning how many of the last 10 were allowed.
+func burstAcrossBoundary(t *testing.T, s *RateLimitService, clock *time.Time) int {
+	t.Helper()
+	start := *clock
+	if !s.IsAllowed("customer-1") {
+		t.Fatal("expected the first request allowed")
+	}
+	*clock = start.Add(59 * time.Second)
+	if !s.IsAllowedN("customer-1", 9) {
+		t.Fatal("expected 9 more requests allowed within the limit")
+	}
+	*clock = start.Add(60 * time.Second)
+	allowed := 0
+	for range 10 {
+		if s.IsAllowed("customer-1") {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+// TestSlidingWindowPreventsBoundaryBurst verifies the fixed window lets a client send
+// twice the limit around a refill, while the sliding window holds it to the limit
+// over any window-long period.
+func TestSlidingWindowPreventsBoundaryBurst(t *testing.T) {
+	t.Setenv("RATE_LIMIT_MAX_REQUESTS", "10")
+	t.Setenv("RATE_LIMIT_WINDOW_SECONDS", "60")
+	clock := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	_, client := newTestRedis(t)
+	fixed := NewRateLimitService(client)
+	fixed.now = func() time.Time { return clock }
+	if got := burstAcrossBoundary(t, fixed, &clock); got != 10 {
+		t.Fatalf("expected the fixed window to allow a full second burst, got %d", got)
+	}
+
+	t.Setenv("RATE_LIMIT_STRATEGY", "sliding")
+	clock = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	_, client = newTestRedis(t)
+	sliding := NewRateLimitService(client)
+	sliding.now = func() time.Time { return clock }
+	// Only the request from the start of the window has left it
+	if got := burstAcrossBoundary(t, sliding, &clock); got != 1 {
+		t.Fatalf("expected the sliding window to allow 1 request at the boundary, got %d", got)
+	}
+	if got := sliding.GetRemainingRequests("customer-1"); got != 0 {
+		t.Fatalf("expected no requests remaining, got %d", got)
+	}
+	if got := sliding.GetSecondsUntilReset("customer-1"); got != 59 {
+		t.Fatalf("expected the 9 requests logged at 59s to leave the window in 59s, got %d", got)
+	}
+
+	clock = clock.Add(59 * time.Second)
+	if !sliding.IsAllowedN("customer-1", 9) || sliding.IsAllowed("customer-1") {
+		t.Fatal("expected exactly the 9 expired requests to be available again")
+	}
+
+	sliding.ResetRateLimit("customer-1")
+	if got := sliding.GetRemainingRequests("customer-1"); got != 10 {
+		t.Fatalf("expected a reset to clear the sliding log, got %d remaining", got)
+	}
+}
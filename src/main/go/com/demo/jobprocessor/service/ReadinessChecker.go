@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+
+	"distributed-job-processor/config"
+)
+
+// ReadinessChecker verifies the dependencies an instance needs to serve traffic are reachable.
+//
+// Backs the readiness probe (GET /api/jobs/ready), as opposed to /health, which stays a
+// liveness probe and answers UP as long as the process runs:
+// - postgres: SELECT 1 on the GORM DB
+// - redis: PING (see config.PingRedis)
+// - kafka: a TCP dial of the bootstrap servers, the first that answers wins
+//
+// Checks run concurrently; each must answer within READINESS_CHECK_TIMEOUT (default 2s)
+// or counts as DOWN, so a hung dependency can't hang the probe.
+type ReadinessChecker struct {
+	checks  map[string]DependencyCheck
+	timeout time.Duration
+}
+
+// DependencyCheck returns nil when the dependency is reachable.
+type DependencyCheck func(ctx context.Context) error
+
+// Dependency statuses reported by ReadinessChecker.Check.
+const (
+	DependencyUp   = "UP"
+	DependencyDown = "DOWN"
+)
+
+// NewReadinessChecker creates a ReadinessChecker for the database, Redis, and the Kafka
+// bootstrap servers (comma-separated, as in KAFKA_BOOTSTRAP_SERVERS).
+func NewReadinessChecker(db *gorm.DB, redisClient *redis.Client, kafkaBrokers string) *ReadinessChecker {
+	timeout := 2 * time.Second
+	if val := os.Getenv("READINESS_CHECK_TIMEOUT"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed > 0 {
+			timeout = parsed
+		} else {
+			log.Printf("Ignoring invalid READINESS_CHECK_TIMEOUT %q: must be a positive duration", val)
+		}
+	}
+
+	return &ReadinessChecker{
+		checks: map[string]DependencyCheck{
+			"postgres": postgresCheck(db),
+			"redis":    redisCheck(redisClient),
+			"kafka":    kafkaCheck(kafkaBrokers),
+		},
+		timeout: timeout,
+	}
+}
+
+// Check runs every dependency check and returns whether all passed, with each
+// dependency's status (DependencyUp or DependencyDown). Failures are logged.
+func (r *ReadinessChecker) Check(ctx context.Context) (bool, map[string]string) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	statuses := make(map[string]string, len(r.checks))
+	ready := true
+	for name, check := range r.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := runCheck(ctx, check)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("Readiness check %s failed: %v", name, err)
+				statuses[name] = DependencyDown
+				ready = false
+				return
+			}
+			statuses[name] = DependencyUp
+		}()
+	}
+	wg.Wait()
+	return ready, statuses
+}
+
+// runCheck bounds a check by ctx even when the check itself ignores it.
+func runCheck(ctx context.Context, check DependencyCheck) error {
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func postgresCheck(db *gorm.DB) DependencyCheck {
+	return func(ctx context.Context) error {
+		return db.WithContext(ctx).Exec("SELECT 1").Error
+	}
+}
+
+func redisCheck(client *redis.Client) DependencyCheck {
+	return func(ctx context.Context) error {
+		return config.PingRedis(client)
+	}
+}
+
+func kafkaCheck(brokers string) DependencyCheck {
+	return func(ctx context.Context) error {
+		var lastErr error
+		for _, broker := range strings.Split(brokers, ",") {
+			if broker = strings.TrimSpace(broker); broker == "" {
+				continue
+			}
+			conn, err := kafka.DialContext(ctx, "tcp", broker)
+			if err == nil {
+				return conn.Close()
+			}
+			lastErr = err
+		}
+		if lastErr == nil {
+			return fmt.Errorf("no Kafka bootstrap servers configured")
+		}
+		return lastErr
+	}
+}
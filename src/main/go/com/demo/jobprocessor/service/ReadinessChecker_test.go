@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestReadinessCheckTimesOutHungDependency verifies a check that never answers is
+// reported DOWN once the timeout passes instead of hanging the probe.
+func TestReadinessCheckTimesOutHungDependency(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	r := &ReadinessChecker{
+		checks: map[string]DependencyCheck{
+			"postgres": func(context.Context) error { return nil },
+			"kafka":    func(context.Context) error { <-release; return nil },
+		},
+		timeout: 50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	ready, statuses := r.Check(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the probe to give up after the timeout, took %v", elapsed)
+	}
+	if ready || statuses["kafka"] != DependencyDown || statuses["postgres"] != DependencyUp {
+		t.Fatalf("expected only kafka DOWN, got ready=%v %v", ready, statuses)
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+)
+
+// RefundHandler handles REFUND jobs, created by compensating a payment (see
+// PaymentCompensation).
+//
+// In a real system this would call the gateway's refund API with the payment's
+// charge; here it sleeps 1 second.
+type RefundHandler struct{}
+
+// Handle issues the (simulated) refund.
+func (RefundHandler) Handle(ctx context.Context, job *model.Job) error {
+	logger := config.JobLogger(job.ID.String(), job.ClientID)
+
+	// Simulate Stripe refund API call (1 second)
+	logger.Debug("Simulating refund")
+	if err := sleepContext(ctx, 1*time.Second); err != nil {
+		return err
+	}
+	logger.Debug("Refund issued", "payload", HandlerPayload(ctx, job))
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"distributed-job-processor/exception"
+)
+
+// Retry classifications, see RetryClassifier.
+const (
+	RetryClassRetryable = "retryable"
+	RetryClassPermanent = "permanent"
+)
+
+// RetryClassifier decides whether a failed attempt is worth retrying, so operators can
+// tune retry behavior for real downstream error strings without code changes:
+// 1. An exception.NonRetryableError in the error chain is permanent
+// 2. The first RETRY_CLASSIFICATION_RULES entry whose substring occurs in the message
+// 3. Anything else is retryable
+//
+// RETRY_CLASSIFICATION_RULES holds class=substring[|substring...] entries separated by
+// ';', with class retryable or permanent, e.g.
+// "retryable=connection reset|503;permanent=invalid card|card declined". Matching is
+// case-insensitive and rules are tried in order, so an earlier retryable rule can carve
+// an exception out of a broader permanent one.
+//
+// A permanent failure moves the job straight to DEAD_LETTER, whatever attempts are left.
+type RetryClassifier struct {
+	rules []RetryRule
+}
+
+// RetryRule classifies errors whose message contains Substring.
+type RetryRule struct {
+	Substring      string `json:"substring"`
+	Classification string `json:"classification"`
+}
+
+// NewRetryClassifierFromEnv creates a RetryClassifier from RETRY_CLASSIFICATION_RULES.
+func NewRetryClassifierFromEnv() *RetryClassifier {
+	var rules []RetryRule
+	for _, entry := range strings.Split(os.Getenv("RETRY_CLASSIFICATION_RULES"), ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, substrings, ok := strings.Cut(entry, "=")
+		class := strings.ToLower(strings.TrimSpace(name))
+		if !ok || (class != RetryClassRetryable && class != RetryClassPermanent) {
+			log.Printf("Ignoring invalid RETRY_CLASSIFICATION_RULES entry %q: must be retryable=substring or permanent=substring", entry)
+			continue
+		}
+		for _, substr := range strings.Split(substrings, "|") {
+			if substr = strings.TrimSpace(substr); substr != "" {
+				rules = append(rules, RetryRule{Substring: strings.ToLower(substr), Classification: class})
+			}
+		}
+	}
+	return &RetryClassifier{rules: rules}
+}
+
+// Classify returns RetryClassRetryable or RetryClassPermanent for a failed attempt's error.
+// Safe to call on a nil *RetryClassifier (no rules).
+func (c *RetryClassifier) Classify(err error) string {
+	if exception.IsNonRetryableError(err) {
+		return RetryClassPermanent
+	}
+	if c != nil {
+		msg := strings.ToLower(err.Error())
+		for _, rule := range c.rules {
+			if strings.Contains(msg, rule.Substring) {
+				return rule.Classification
+			}
+		}
+	}
+	return RetryClassRetryable
+}
+
+// IsPermanent reports whether err should dead-letter the job without further retries.
+func (c *RetryClassifier) IsPermanent(err error) bool {
+	return c.Classify(err) == RetryClassPermanent
+}
+
+// Rules returns the configured rules in the order they are tried.
+// Safe to call on a nil *RetryClassifier.
+func (c *RetryClassifier) Rules() []RetryRule {
+	if c == nil {
+		return []RetryRule{}
+	}
+	return append([]RetryRule{}, c.rules...)
+}
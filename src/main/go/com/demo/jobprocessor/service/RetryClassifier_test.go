@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"distributed-job-processor/exception"
+	"distributed-job-processor/model"
+)
+
+func TestRetryClassifierClassify(t *testing.T) {
+	t.Setenv("RETRY_CLASSIFICATION_RULES", "retryable=Connection Reset|503; permanent=invalid card|card declined|status 4; bogus=x")
+	c := NewRetryClassifierFromEnv()
+
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{errors.New("read tcp 10.0.0.1: connection reset by peer"), RetryClassRetryable},
+		{errors.New("gateway returned 503"), RetryClassRetryable},
+		{errors.New("payment rejected: Invalid Card number"), RetryClassPermanent},
+		{fmt.Errorf("charge: %w", errors.New("card declined")), RetryClassPermanent},
+		{errors.New("status 404 from gateway"), RetryClassPermanent},
+		{errors.New("something odd happened"), RetryClassRetryable},
+		{exception.NewNonRetryableError(errors.New("connection reset")), RetryClassPermanent},
+	}
+	for _, tc := range cases {
+		if got := c.Classify(tc.err); got != tc.want {
+			t.Errorf("Classify(%q) = %s, want %s", tc.err, got, tc.want)
+		}
+	}
+
+	if rules := c.Rules(); len(rules) != 5 || rules[0] != (RetryRule{Substring: "connection reset", Classification: RetryClassRetryable}) {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+	var nilClassifier *RetryClassifier
+	if got := nilClassifier.Classify(errors.New("invalid card")); got != RetryClassRetryable {
+		t.Fatalf("expected a nil classifier to retry, got %s", got)
+	}
+}
+
+// TestHandleJobFailureDeadLettersPermanentRuleMatch verifies a failure matching a permanent
+// rule skips the remaining retries, while an unmatched one is retried.
+func TestHandleJobFailureDeadLettersPermanentRuleMatch(t *testing.T) {
+	t.Setenv("RETRY_CLASSIFICATION_RULES", "permanent=invalid card")
+
+	cases := []struct {
+		name       string
+		err        error
+		wantStatus model.JobStatus
+	}{
+		{"permanent", errors.New("gateway: invalid card"), model.StatusDeadLetter},
+		{"unknown", errors.New("gateway hiccup"), model.StatusPending},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			w := newTestWorker(t, repo)
+			w.retryClassifier = NewRetryClassifierFromEnv()
+
+			job := model.NewJob("customer-1", model.TypeHealthCheck, "probe_1")
+			job.Status = model.StatusRunning
+			if err := repo.Create(job); err != nil {
+				t.Fatalf("seed job: %v", err)
+			}
+			w.handleJobFailure(job, tc.err)
+
+			saved, err := repo.FindByID(job.ID)
+			if err != nil {
+				t.Fatalf("reload job: %v", err)
+			}
+			if saved.Status != tc.wantStatus || saved.Attempts != 1 {
+				t.Fatalf("expected %s after 1 attempt, got status=%s attempts=%d", tc.wantStatus, saved.Status, saved.Attempts)
+			}
+		})
+	}
+}
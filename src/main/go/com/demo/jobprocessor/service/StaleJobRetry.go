@@ -0,0 +1,36 @@
+package service
+
+import (
+	"errors"
+
+	"distributed-job-processor/model"
+	"distributed-job-processor/repository"
+)
+
+// staleJobRetries is how many times a save that lost to a concurrent one is reloaded and retried.
+const staleJobRetries = 3
+
+// updateJobWithRetry applies change to the job and saves it with UpdateJobSafe. When the
+// save finds the job was written since it was loaded (repository.ErrStaleJob), the job is
+// reloaded and change applied again to the fresh copy, up to staleJobRetries times.
+//
+// change returns false when the stored job no longer calls for it (e.g. another worker
+// already finished the job); nothing is saved and ErrStaleJob is returned. Either way,
+// once reloaded *job holds the stored state, so callers see what they lost to.
+func updateJobWithRetry(repo *repository.JobRepository, job *model.Job, change func(job *model.Job) bool) error {
+	for attempt := 0; ; attempt++ {
+		if !change(job) {
+			return repository.ErrStaleJob
+		}
+		err := repo.UpdateJobSafe(job)
+		if !errors.Is(err, repository.ErrStaleJob) || attempt == staleJobRetries {
+			return err
+		}
+
+		fresh, err := repo.FindByID(job.ID)
+		if err != nil {
+			return err
+		}
+		*job = *fresh
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"distributed-job-processor/model"
+)
+
+// TestWorkerFailureReappliedOnStaleCopy verifies a worker saving a stale copy (loaded
+// before the scheduler's RUNNING update) reloads it and records the attempt on top.
+func TestWorkerFailureReappliedOnStaleCopy(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	stale := *job
+
+	job.Status = model.StatusRunning
+	if err := repo.UpdateJobSafe(job); err != nil {
+		t.Fatalf("save RUNNING: %v", err)
+	}
+
+	w.handleJobFailure(&stale, errors.New("smtp unavailable"))
+
+	stored, _ := repo.FindByID(job.ID)
+	if stored.Version != 2 || stored.Attempts != 1 || stored.Status != model.StatusPending {
+		t.Fatalf("expected the failure saved on top of RUNNING, got status=%s attempts=%d version=%d",
+			stored.Status, stored.Attempts, stored.Version)
+	}
+	if stale.Version != stored.Version {
+		t.Fatalf("expected the worker's copy refreshed to version %d, got %d", stored.Version, stale.Version)
+	}
+}
+
+// TestCompleteJobKeepsJobFinishedElsewhere verifies a worker holding a stale copy
+// doesn't overwrite a job another worker already dead-lettered.
+func TestCompleteJobKeepsJobFinishedElsewhere(t *testing.T) {
+	repo := newTestRepository(t)
+	w := newTestWorker(t, repo)
+
+	job := model.NewJob("customer-1", model.TypeEmailConfirmation, "order_1|user@email.com|receipt")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	stale := *job
+
+	job.Status = model.StatusDeadLetter
+	if err := repo.UpdateJobSafe(job); err != nil {
+		t.Fatalf("save DEAD_LETTER: %v", err)
+	}
+
+	if err := w.completeJob(context.Background(), &stale); err != nil {
+		t.Fatalf("complete job: %v", err)
+	}
+	stored, _ := repo.FindByID(job.ID)
+	if stored.Status != model.StatusDeadLetter || stored.Version != 1 {
+		t.Fatalf("expected the DEAD_LETTER save kept, got status=%s version=%d", stored.Status, stored.Version)
+	}
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"distributed-job-processor/config"
+	"distributed-job-processor/model"
+)
+
+// TypeCircuitBreaker tracks, per job type, whether the type's downstream (e.g. the
+// payment gateway) is failing, stops workers calling it meanwhile, and feeds that back
+// to intake so the API stops accepting work it can't do.
+//
+// Workers ask before every processing attempt and report its outcome (see
+// JobWorker.SetTypeCircuitBreaker):
+// - Closed: attempts run normally; TYPE_CIRCUIT_FAILURE_THRESHOLD consecutive retryable
+//   failures of a type open its breaker. Permanent failures (bad payload, card declined)
+//   say nothing about the downstream and don't count; any success resets the count.
+// - Open: for TYPE_CIRCUIT_OPEN_DURATION, attempts are deferred without calling the
+//   downstream or counting an attempt, so an outage doesn't burn the jobs' retries.
+// - Half-open: once the open duration has passed, one trial attempt across all workers
+//   is let through, others are still deferred. Its success closes the breaker, its
+//   failure reopens it for another open duration. Only the trial's own outcome counts:
+//   attempts that started before the breaker opened and finish meanwhile are ignored.
+//   A trial lost with its worker is given up after the open duration, letting another
+//   one through.
+//
+// Load shedding is opt-in per type (SHED_WHEN_CIRCUIT_OPEN_<TYPE>=true): while a shed
+// type's breaker is open, JobService refuses new jobs of that type with
+// JobTypeUnavailableError (503 + Retry-After) instead of accepting jobs that would just
+// pile up and dead-letter. GET /api/jobs/health lists the open and shed types.
+//
+// Configuration: TYPE_CIRCUIT_FAILURE_THRESHOLD (default 5, 0 disables the breaker) and
+// TYPE_CIRCUIT_OPEN_DURATION (default 30s).
+//
+// State lives in Redis so the API and every worker see the breakers opened by any worker:
+// Redis Key Format: circuit:{type}:failures (consecutive failure count),
+// circuit:{type}:open (set while open, expiring when the breaker turns half-open),
+// circuit:{type}:half_open (set from opening until the breaker closes) and
+// circuit:{type}:trial (held by the half-open trial attempt, value: its trial ID)
+//
+// Redis errors fail open: a failure isn't counted, attempts run and intake is never refused.
+type TypeCircuitBreaker struct {
+	redisClient *redis.Client
+	threshold   int64
+	openFor     time.Duration
+	shedTypes   map[model.JobType]bool
+}
+
+// TypeCircuitState is one type's breaker as reported by States.
+type TypeCircuitState struct {
+	Type              model.JobType `json:"type"`
+	Shedding          bool          `json:"shedding"`
+	RetryAfterSeconds int           `json:"retryAfterSeconds"`
+}
+
+// NewTypeCircuitBreakerFromEnv creates a TypeCircuitBreaker from TYPE_CIRCUIT_* and
+// SHED_WHEN_CIRCUIT_OPEN_<TYPE> env vars. Returns nil when TYPE_CIRCUIT_FAILURE_THRESHOLD is 0.
+func NewTypeCircuitBreakerFromEnv(redisClient *redis.Client) *TypeCircuitBreaker {
+	threshold := 5
+	if val := os.Getenv("TYPE_CIRCUIT_FAILURE_THRESHOLD"); val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			threshold = parsed
+		} else {
+			log.Printf("Ignoring invalid TYPE_CIRCUIT_FAILURE_THRESHOLD %q: must be a non-negative integer", val)
+		}
+	}
+	if threshold == 0 {
+		return nil
+	}
+
+	openFor := 30 * time.Second
+	if val := os.Getenv("TYPE_CIRCUIT_OPEN_DURATION"); val != "" {
+		if parsed, err := time.ParseDuration(val); err == nil && parsed >= time.Second {
+			openFor = parsed
+		} else {
+			log.Printf("Ignoring invalid TYPE_CIRCUIT_OPEN_DURATION %q: must be a duration of at least 1s", val)
+		}
+	}
+
+	shedTypes := make(map[model.JobType]bool)
+	for _, spec := range model.JobTypeSpecs() {
+		if os.Getenv("SHED_WHEN_CIRCUIT_OPEN_"+string(spec.Type)) == "true" {
+			shedTypes[spec.Type] = true
+		}
+	}
+	return NewTypeCircuitBreaker(redisClient, threshold, openFor, shedTypes)
+}
+
+// NewTypeCircuitBreaker creates a TypeCircuitBreaker opening after threshold consecutive
+// failures for openFor, shedding new jobs of shedTypes while open.
+func NewTypeCircuitBreaker(redisClient *redis.Client, threshold int, openFor time.Duration, shedTypes map[model.JobType]bool) *TypeCircuitBreaker {
+	for jobType := range shedTypes {
+		log.Printf("Type circuit breaker: shedding new %s jobs while its breaker is open", jobType)
+	}
+	return &TypeCircuitBreaker{
+		redisClient: redisClient,
+		threshold:   int64(threshold),
+		openFor:     openFor,
+		shedTypes:   shedTypes,
+	}
+}
+
+// Allow reports whether an attempt of the type may call its downstream: always while
+// closed, never while open, and only for the trial attempt while half-open, which
+// also gets the trial ID (empty for any other attempt). Every allowed attempt must be
+// followed by RecordSuccess or RecordFailure with that trial ID. A nil breaker always
+// allows.
+func (b *TypeCircuitBreaker) Allow(jobType model.JobType) (string, bool) {
+	if b == nil {
+		return "", true
+	}
+	pipe := b.redisClient.Pipeline()
+	open := pipe.Exists(ctx, typeCircuitOpenKey(jobType))
+	halfOpen := pipe.Exists(ctx, typeCircuitHalfOpenKey(jobType))
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error reading %s circuit breaker: %v", jobType, err)
+		return "", true
+	}
+	if open.Val() > 0 {
+		return "", false
+	}
+	if halfOpen.Val() == 0 {
+		return "", true
+	}
+
+	trialID := uuid.NewString()
+	trial, err := b.redisClient.SetNX(ctx, typeCircuitTrialKey(jobType), trialID, b.openFor).Result()
+	if err != nil {
+		log.Printf("Error starting %s circuit trial: %v", jobType, err)
+		return "", true
+	}
+	if !trial {
+		return "", false
+	}
+	log.Printf("Type circuit breaker HALF-OPEN for %s: letting a trial attempt through", jobType)
+	config.GetMetrics().SetTypeCircuitState(string(jobType), config.TypeCircuitHalfOpen)
+	return trialID, true
+}
+
+// Outcomes of typeCircuitOutcomeScript.
+const (
+	typeCircuitIgnored = iota
+	typeCircuitCounted
+	typeCircuitTrialEnded
+)
+
+// typeCircuitOutcomeScript applies an attempt's outcome atomically. While the breaker
+// is open or half-open, only the trial holding the trial key may end it, deleting the
+// half_open and trial keys on success; anyone else's outcome is ignored. While closed,
+// a success resets the failure count and a failure increments it.
+// Returns {outcome, failure count}.
+//
+// KEYS: failures, half_open, trial; ARGV: trial ID, "1" for a success
+var typeCircuitOutcomeScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	if ARGV[1] == '' or redis.call('GET', KEYS[3]) ~= ARGV[1] then
+		return {0, 0}
+	end
+	if ARGV[2] == '1' then
+		redis.call('DEL', KEYS[1], KEYS[2], KEYS[3])
+	end
+	return {2, 0}
+end
+if ARGV[2] == '1' then
+	redis.call('DEL', KEYS[1])
+	return {1, 0}
+end
+return {1, redis.call('INCR', KEYS[1])}
+`)
+
+// recordOutcome runs typeCircuitOutcomeScript for an attempt of the type.
+func (b *TypeCircuitBreaker) recordOutcome(jobType model.JobType, trialID string, success bool) (int64, int64, error) {
+	succeeded := "0"
+	if success {
+		succeeded = "1"
+	}
+	keys := []string{typeCircuitFailuresKey(jobType), typeCircuitHalfOpenKey(jobType), typeCircuitTrialKey(jobType)}
+	result, err := typeCircuitOutcomeScript.Run(ctx, b.redisClient, keys, trialID, succeeded).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return result[0], result[1], nil
+}
+
+// RecordSuccess resets the type's consecutive failure count while closed, or closes its
+// breaker if trialID is the half-open trial's. Nil-safe.
+func (b *TypeCircuitBreaker) RecordSuccess(jobType model.JobType, trialID string) {
+	if b == nil {
+		return
+	}
+	outcome, _, err := b.recordOutcome(jobType, trialID, true)
+	if err != nil {
+		log.Printf("Error resetting %s circuit failures: %v", jobType, err)
+		return
+	}
+	if outcome == typeCircuitIgnored {
+		return
+	}
+	if outcome == typeCircuitTrialEnded {
+		log.Printf("Type circuit breaker CLOSED for %s: trial attempt succeeded", jobType)
+	}
+	config.GetMetrics().SetTypeCircuitState(string(jobType), config.TypeCircuitClosed)
+}
+
+// RecordFailure counts a retryable failure of the type, opening its breaker once the
+// count reaches the threshold, or reopens it right away if trialID is the half-open
+// trial's. Nil-safe.
+func (b *TypeCircuitBreaker) RecordFailure(jobType model.JobType, trialID string) {
+	if b == nil {
+		return
+	}
+	outcome, failures, err := b.recordOutcome(jobType, trialID, false)
+	if err != nil {
+		log.Printf("Error counting %s circuit failure: %v", jobType, err)
+		return
+	}
+	if outcome == typeCircuitIgnored {
+		return
+	}
+	if outcome == typeCircuitTrialEnded {
+		if b.open(jobType) {
+			log.Printf("Type circuit breaker reOPENED for %s: trial attempt failed (for %v)", jobType, b.openFor)
+		}
+		return
+	}
+	if failures < b.threshold {
+		return
+	}
+	if b.open(jobType) {
+		log.Printf("Type circuit breaker OPEN for %s after %d consecutive failures (for %v, shedding: %v)",
+			jobType, failures, b.openFor, b.shedTypes[jobType])
+	}
+}
+
+// open opens the type's breaker for openFor, half-open after that. Reports whether it did.
+func (b *TypeCircuitBreaker) open(jobType model.JobType) bool {
+	pipe := b.redisClient.TxPipeline()
+	pipe.Set(ctx, typeCircuitOpenKey(jobType), time.Now().UTC().Format(time.RFC3339), b.openFor)
+	pipe.Set(ctx, typeCircuitHalfOpenKey(jobType), time.Now().UTC().Format(time.RFC3339), 0)
+	pipe.Del(ctx, typeCircuitFailuresKey(jobType))
+	pipe.Del(ctx, typeCircuitTrialKey(jobType))
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error opening %s circuit breaker: %v", jobType, err)
+		return false
+	}
+	config.GetMetrics().SetTypeCircuitState(string(jobType), config.TypeCircuitOpen)
+	return true
+}
+
+// OpenFor returns how long the type's breaker stays open, or false if it is closed.
+// A nil breaker is never open.
+func (b *TypeCircuitBreaker) OpenFor(jobType model.JobType) (time.Duration, bool) {
+	if b == nil {
+		return 0, false
+	}
+	ttl, err := b.redisClient.PTTL(ctx, typeCircuitOpenKey(jobType)).Result()
+	if err != nil {
+		log.Printf("Error reading %s circuit breaker: %v", jobType, err)
+		return 0, false
+	}
+	// Negative when the key is missing (closed) or has no expiry
+	if ttl <= 0 {
+		return 0, false
+	}
+	return ttl, true
+}
+
+// Shedding returns how long new jobs of the type are refused, or false if they are
+// accepted: the type opted into shedding and its breaker is open.
+func (b *TypeCircuitBreaker) Shedding(jobType model.JobType) (time.Duration, bool) {
+	if b == nil || !b.shedTypes[jobType] {
+		return 0, false
+	}
+	return b.OpenFor(jobType)
+}
+
+// States returns the types whose breaker is open, in JobTypeSpecs order.
+func (b *TypeCircuitBreaker) States() []TypeCircuitState {
+	states := []TypeCircuitState{}
+	if b == nil {
+		return states
+	}
+	for _, spec := range model.JobTypeSpecs() {
+		if openFor, open := b.OpenFor(spec.Type); open {
+			states = append(states, TypeCircuitState{
+				Type:              spec.Type,
+				Shedding:          b.shedTypes[spec.Type],
+				RetryAfterSeconds: int((openFor + time.Second - 1) / time.Second),
+			})
+		}
+	}
+	return states
+}
+
+// typeCircuitFailuresKey returns the Redis key counting a type's consecutive failures.
+func typeCircuitFailuresKey(jobType model.JobType) string {
+	return "circuit:" + string(jobType) + ":failures"
+}
+
+// typeCircuitOpenKey returns the Redis key set while a type's breaker is open.
+func typeCircuitOpenKey(jobType model.JobType) string {
+	return "circuit:" + string(jobType) + ":open"
+}
+
+// typeCircuitHalfOpenKey returns the Redis key set from a type's breaker opening until
+// it closes: once the open key has expired, the breaker is half-open.
+func typeCircuitHalfOpenKey(jobType model.JobType) string {
+	return "circuit:" + string(jobType) + ":half_open"
+}
+
+// typeCircuitTrialKey returns the Redis key held by a half-open type's trial attempt.
+func typeCircuitTrialKey(jobType model.JobType) string {
+	return "circuit:" + string(jobType) + ":trial"
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"distributed-job-processor/dto"
+	"distributed-job-processor/exception"
+	"distributed-job-processor/model"
+)
+
+// TestTypeCircuitBreakerOpensOnConsecutiveRetryableFailures verifies only retryable
+// failures count, a success resets the count, and the breaker stops being open after its duration.
+func TestTypeCircuitBreakerOpensOnConsecutiveRetryableFailures(t *testing.T) {
+	mr, client := newTestRedis(t)
+	w := newTestWorker(t, newTestRepository(t))
+	breaker := NewTypeCircuitBreaker(client, 3, 30*time.Second, nil)
+	w.SetTypeCircuitBreaker(breaker)
+
+	downstream := errors.New("stripe: 503 service unavailable")
+	declined := exception.NewNonRetryableError(NewJobFailure(model.FailureDeclined, errors.New("card declined")))
+
+	w.recordTypeOutcome(model.TypePaymentProcess, "", downstream)
+	w.recordTypeOutcome(model.TypePaymentProcess, "", downstream)
+	w.recordTypeOutcome(model.TypePaymentProcess, "", nil)
+	w.recordTypeOutcome(model.TypePaymentProcess, "", downstream)
+	w.recordTypeOutcome(model.TypePaymentProcess, "", downstream)
+	for range 5 {
+		w.recordTypeOutcome(model.TypePaymentProcess, "", declined)
+	}
+	if _, open := breaker.OpenFor(model.TypePaymentProcess); open {
+		t.Fatal("expected the breaker closed: the success reset the count and permanent failures don't count")
+	}
+
+	w.recordTypeOutcome(model.TypePaymentProcess, "", downstream)
+	openFor, open := breaker.OpenFor(model.TypePaymentProcess)
+	if !open || openFor <= 0 || openFor > 30*time.Second {
+		t.Fatalf("expected the breaker open for up to 30s, got open=%v for %v", open, openFor)
+	}
+	if _, open := breaker.OpenFor(model.TypeEmailConfirmation); open {
+		t.Fatal("expected other types unaffected")
+	}
+
+	mr.FastForward(31 * time.Second)
+	if _, open := breaker.OpenFor(model.TypePaymentProcess); open {
+		t.Fatal("expected the breaker no longer open after its open duration")
+	}
+}
+
+// TestTypeCircuitBreakerHalfOpenTrial verifies an open breaker refuses every attempt,
+// lets exactly one trial through once its open duration has passed, reopens when the
+// trial fails and closes when a later trial succeeds.
+func TestTypeCircuitBreakerHalfOpenTrial(t *testing.T) {
+	mr, client := newTestRedis(t)
+	breaker := NewTypeCircuitBreaker(client, 2, 30*time.Second, nil)
+	allowed := func(jobType model.JobType) bool {
+		_, ok := breaker.Allow(jobType)
+		return ok
+	}
+
+	if !allowed(model.TypePaymentProcess) {
+		t.Fatal("expected attempts allowed while closed")
+	}
+	breaker.RecordFailure(model.TypePaymentProcess, "")
+	breaker.RecordFailure(model.TypePaymentProcess, "")
+	if allowed(model.TypePaymentProcess) {
+		t.Fatal("expected attempts refused while open")
+	}
+	if !allowed(model.TypeEmailConfirmation) {
+		t.Fatal("expected other types unaffected")
+	}
+
+	mr.FastForward(31 * time.Second)
+	trialID, ok := breaker.Allow(model.TypePaymentProcess)
+	if !ok || trialID == "" {
+		t.Fatal("expected a trial attempt allowed once half-open")
+	}
+	if allowed(model.TypePaymentProcess) {
+		t.Fatal("expected only one trial attempt while half-open")
+	}
+
+	// A failed trial reopens the breaker right away, without a run of failures
+	breaker.RecordFailure(model.TypePaymentProcess, trialID)
+	if _, open := breaker.OpenFor(model.TypePaymentProcess); !open || allowed(model.TypePaymentProcess) {
+		t.Fatal("expected the breaker reopened after the failed trial")
+	}
+
+	mr.FastForward(31 * time.Second)
+	trialID, ok = breaker.Allow(model.TypePaymentProcess)
+	if !ok {
+		t.Fatal("expected a new trial attempt once half-open again")
+	}
+	breaker.RecordSuccess(model.TypePaymentProcess, trialID)
+	for range 3 {
+		if !allowed(model.TypePaymentProcess) {
+			t.Fatal("expected every attempt allowed after the trial closed the breaker")
+		}
+	}
+	breaker.RecordFailure(model.TypePaymentProcess, "")
+	if _, open := breaker.OpenFor(model.TypePaymentProcess); open {
+		t.Fatal("expected a closed breaker to need the full threshold of failures again")
+	}
+}
+
+// TestTypeCircuitBreakerIgnoresOutcomesOfNonTrialAttempts verifies attempts that
+// started before the breaker opened can't close or reopen it when they finish while it
+// is open or half-open: only the trial's outcome does.
+func TestTypeCircuitBreakerIgnoresOutcomesOfNonTrialAttempts(t *testing.T) {
+	mr, client := newTestRedis(t)
+	breaker := NewTypeCircuitBreaker(client, 1, 30*time.Second, nil)
+
+	breaker.RecordFailure(model.TypePaymentProcess, "")
+	// A straggler succeeding while open doesn't close the breaker early
+	breaker.RecordSuccess(model.TypePaymentProcess, "")
+	mr.FastForward(31 * time.Second)
+	trialID, ok := breaker.Allow(model.TypePaymentProcess)
+	if !ok || trialID == "" {
+		t.Fatal("expected the breaker still to need a trial after a straggler's success")
+	}
+
+	// Stragglers finishing during the trial change nothing either way
+	breaker.RecordFailure(model.TypePaymentProcess, "")
+	if _, open := breaker.OpenFor(model.TypePaymentProcess); open {
+		t.Fatal("expected a straggler's failure not to reopen the half-open breaker")
+	}
+	breaker.RecordSuccess(model.TypePaymentProcess, "")
+	breaker.RecordSuccess(model.TypePaymentProcess, "another-trial")
+	if _, ok := breaker.Allow(model.TypePaymentProcess); ok {
+		t.Fatal("expected a straggler's success not to close the half-open breaker")
+	}
+
+	breaker.RecordSuccess(model.TypePaymentProcess, trialID)
+	if _, ok := breaker.Allow(model.TypePaymentProcess); !ok {
+		t.Fatal("expected the trial's success to close the breaker")
+	}
+}
+
+// TestProcessJobDefersWhileTypeCircuitOpen verifies a job of a type whose breaker is
+// open goes back to PENDING without running its handler, counting an attempt or
+// counting against the breaker.
+func TestProcessJobDefersWhileTypeCircuitOpen(t *testing.T) {
+	repo := newTestRepository(t)
+	_, client := newTestRedis(t)
+	w := newTestWorker(t, repo)
+	breaker := NewTypeCircuitBreaker(client, 1, time.Minute, nil)
+	w.SetTypeCircuitBreaker(breaker)
+	handled := 0
+	w.RegisterHandler(model.TypeHealthCheck, JobHandlerFunc(func(ctx context.Context, job *model.Job) error {
+		handled++
+		return nil
+	}))
+
+	breaker.RecordFailure(model.TypeHealthCheck, "")
+	job := model.NewJob("monitor", model.TypeHealthCheck, "probe_1")
+	job.Status = model.StatusRunning
+	if err := repo.Create(job); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	var events []string
+	w.processJob(kafka.Message{Value: []byte(job.ID.String())}, recordingCommitter{&events}, 0)
+
+	saved, err := repo.FindByID(job.ID)
+	if err != nil {
+		t.Fatalf("reload job: %v", err)
+	}
+	if saved.Status != model.StatusPending || saved.Attempts != 0 || handled != 0 {
+		t.Fatalf("expected PENDING with 0 attempts and no handler run, got status=%s attempts=%d handled=%d",
+			saved.Status, saved.Attempts, handled)
+	}
+	if saved.ScheduledAt == nil || saved.ScheduledAt.Before(time.Now().Add(50*time.Second)) {
+		t.Fatalf("expected the job deferred until the breaker turns half-open, got %v", saved.ScheduledAt)
+	}
+	if len(events) != 1 || events[0] != "commit" {
+		t.Fatalf("expected the message committed, got %v", events)
+	}
+	if openFor, open := breaker.OpenFor(model.TypeHealthCheck); !open || openFor < 50*time.Second {
+		t.Fatalf("expected the short circuit not to reopen the breaker, got open=%v for %v", open, openFor)
+	}
+}
+
+// TestCreateJobShedsOptedInTypesWhileCircuitOpen verifies new jobs of a shed type are
+// refused with the time left on the breaker, while types that didn't opt in are accepted.
+func TestCreateJobShedsOptedInTypesWhileCircuitOpen(t *testing.T) {
+	_, client := newTestRedis(t)
+	s := NewJobService(newTestRepository(t))
+	breaker := NewTypeCircuitBreaker(client, 1, time.Minute, map[model.JobType]bool{model.TypePaymentProcess: true})
+	s.SetTypeCircuitBreaker(breaker)
+
+	payment := dto.JobRequest{Type: model.TypePaymentProcess, Payload: "order_1|user@email.com|$10.00"}
+	email := dto.JobRequest{Type: model.TypeEmailConfirmation, Payload: "order_1|user@email.com"}
+
+	if _, err := s.CreateJob(context.Background(), "customer-1", &payment); err != nil {
+		t.Fatalf("expected payments accepted while the breaker is closed, got %v", err)
+	}
+
+	breaker.RecordFailure(model.TypePaymentProcess, "")
+	breaker.RecordFailure(model.TypeEmailConfirmation, "")
+
+	_, err := s.CreateJob(context.Background(), "customer-1", &payment)
+	var shedErr *exception.JobTypeUnavailableError
+	if !errors.As(err, &shedErr) || shedErr.Type != model.TypePaymentProcess {
+		t.Fatalf("expected JobTypeUnavailableError for payments, got %v", err)
+	}
+	if got := shedErr.RetryAfterSeconds(); got < 59 || got > 60 {
+		t.Fatalf("expected Retry-After of about 60s, got %d", got)
+	}
+
+	// Emails' breaker is open too, but they didn't opt into shedding
+	if _, err := s.CreateJob(context.Background(), "customer-1", &email); err != nil {
+		t.Fatalf("expected emails accepted, got %v", err)
+	}
+
+	batch, err := s.CreateJobsBatch(context.Background(), "customer-1", []dto.JobRequest{payment, email}, dto.JobBatchModePartial)
+	if err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+	if batch.Items[0].Status != http.StatusServiceUnavailable || batch.Items[1].Status != http.StatusAccepted {
+		t.Fatalf("expected the payment refused with 503 and the email accepted, got %+v", batch.Items)
+	}
+
+	states := breaker.States()
+	if len(states) != 2 || states[0].Type != model.TypePaymentProcess || !states[0].Shedding || states[1].Shedding {
+		t.Fatalf("expected payments (shedding) and emails (not shedding) open, got %+v", states)
+	}
+}
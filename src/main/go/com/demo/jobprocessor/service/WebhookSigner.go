@@ -0,0 +1,119 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader carries the signature of a webhook callback request.
+const WebhookSignatureHeader = "X-Signature"
+
+// WebhookSigner signs webhook callback requests with HMAC-SHA256 so clients can
+// verify a callback genuinely came from us and was not replayed. Every webhook we
+// send is signed, e.g. the dead-letter summaries of DeadLetterNotifier.
+//
+// Each registered callback URL has its own shared secret, configured as a JSON
+// object in WEBHOOK_SECRETS, e.g. {"https://shop.example.com/hooks/jobs": "whsec_..."}.
+//
+// Verification algorithm (for client implementers):
+// 1. Read the X-Signature header, e.g. "t=1705312800,v1=5257a869e7ecebed..."
+//   and split it on "," into t (Unix seconds) and v1 (hex signature)
+// 2. Build the signed payload: the t value, a ".", then the raw request body
+//   bytes exactly as received (before any JSON parsing)
+// 3. Compute HMAC-SHA256 of the signed payload keyed with your secret, hex encode it
+// 4. Compare with v1 using a constant-time comparison; reject on mismatch
+// 5. Reject if t is more than a few minutes from your clock (we allow 5), so a
+//   captured request can't be replayed later
+//
+// VerifyWebhookSignature implements these steps.
+type WebhookSigner struct {
+	secrets map[string]string
+	now     func() time.Time
+}
+
+// NewWebhookSignerFromEnv creates a WebhookSigner with secrets from WEBHOOK_SECRETS.
+func NewWebhookSignerFromEnv() *WebhookSigner {
+	secrets := make(map[string]string)
+	if val := os.Getenv("WEBHOOK_SECRETS"); val != "" {
+		if err := json.Unmarshal([]byte(val), &secrets); err != nil {
+			log.Printf("Ignoring invalid WEBHOOK_SECRETS: must be a JSON object of callback URL to secret: %v", err)
+			secrets = make(map[string]string)
+		}
+	}
+	return NewWebhookSigner(secrets)
+}
+
+// NewWebhookSigner creates a WebhookSigner with the given callback URL -> secret map.
+func NewWebhookSigner(secrets map[string]string) *WebhookSigner {
+	return &WebhookSigner{secrets: secrets, now: time.Now}
+}
+
+// SignRequest sets the X-Signature header on a callback request with the secret of
+// its URL. body must be the exact bytes sent as the request body.
+// Returns an error if no secret is registered for the URL, so unsigned callbacks are never sent.
+func (s *WebhookSigner) SignRequest(req *http.Request, body []byte) error {
+	secret, ok := s.secrets[req.URL.String()]
+	if !ok || secret == "" {
+		return fmt.Errorf("no webhook secret registered for %s", req.URL.String())
+	}
+	req.Header.Set(WebhookSignatureHeader, signWebhook(secret, s.now(), body))
+	return nil
+}
+
+// signWebhook returns the X-Signature header value for body sent at timestamp.
+func signWebhook(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, computeWebhookSignature(secret, ts, body))
+}
+
+// computeWebhookSignature is hex(HMAC-SHA256(secret, timestamp + "." + body)).
+func computeWebhookSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks an X-Signature header against the received body,
+// rejecting signatures older (or newer) than tolerance relative to now.
+func VerifyWebhookSignature(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signature = value
+		}
+	}
+	if ts == "" || signature == "" {
+		return errors.New("malformed signature header")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+
+	expected := computeWebhookSignature(secret, ts, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
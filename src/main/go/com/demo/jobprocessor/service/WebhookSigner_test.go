@@ -0,0 +1,46 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWebhookSignatureVerifies verifies a signed callback passes verification and
+// tampered, replayed, or wrongly keyed callbacks don't.
+func TestWebhookSignatureVerifies(t *testing.T) {
+	url := "https://shop.example.com/hooks/jobs"
+	signer := NewWebhookSigner(map[string]string{url: "whsec_test"})
+	sentAt := time.Unix(1705312800, 0)
+	signer.now = func() time.Time { return sentAt }
+
+	body := []byte(`{"jobId":"550e8400-e29b-41d4-a716-446655440000","status":"COMPLETED"}`)
+	req, _ := http.NewRequest(http.MethodPost, url, nil)
+	if err := signer.SignRequest(req, body); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	header := req.Header.Get(WebhookSignatureHeader)
+	if !strings.HasPrefix(header, "t=1705312800,v1=") {
+		t.Fatalf("unexpected header %q", header)
+	}
+
+	tolerance := 5 * time.Minute
+	if err := VerifyWebhookSignature("whsec_test", header, body, tolerance, sentAt.Add(time.Minute)); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+	if err := VerifyWebhookSignature("whsec_test", header, []byte(`{"status":"FAILED"}`), tolerance, sentAt); err == nil {
+		t.Fatal("expected tampered body to fail")
+	}
+	if err := VerifyWebhookSignature("other", header, body, tolerance, sentAt); err == nil {
+		t.Fatal("expected wrong secret to fail")
+	}
+	if err := VerifyWebhookSignature("whsec_test", header, body, tolerance, sentAt.Add(time.Hour)); err == nil {
+		t.Fatal("expected replayed signature to fail")
+	}
+
+	unregistered, _ := http.NewRequest(http.MethodPost, "https://unknown.example.com/hook", nil)
+	if err := signer.SignRequest(unregistered, body); err == nil {
+		t.Fatal("expected an error for a URL without a secret")
+	}
+}